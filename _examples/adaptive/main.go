@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	// Behave like "limit_req rate=10r/s burst=5;": excess requests are
+	// queued and released at the configured rate instead of being rejected.
+	opts := ratelimit.NginxLimitReq(10, 5, false)
+
+	// Tell clients how loaded the server is, so they can back off before
+	// requests start being rejected.
+	opts.Backpressure = &ratelimit.Backpressure{
+		Capacity: 20,
+	}
+
+	app := gin.Default()
+	app.Use(ratelimit.New(opts))
+	app.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, World!")
+	})
+
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := app.Run(addr); err != nil {
+		panic(err)
+	}
+}
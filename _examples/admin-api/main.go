@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func main() {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		log.Fatal("ADMIN_TOKEN must be set")
+	}
+
+	m := ratelimit.NewManager(ratelimit.Options{
+		Rate:  rate.Every(time.Second),
+		Burst: 5,
+		AdminAuth: &ratelimit.AdminAuth{
+			ValidateToken: ratelimit.StaticTokens(map[string]ratelimit.AdminPrincipal{
+				token: {Name: "ops", Roles: []string{"operator"}},
+			}),
+			Roles: map[string][]ratelimit.AdminPermission{
+				"operator": {ratelimit.AdminRead, ratelimit.AdminReset, ratelimit.AdminBan},
+			},
+		},
+	})
+
+	app := gin.Default()
+	app.GET("/", m.Handler(), func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, World!")
+	})
+
+	// The admin endpoints require "Authorization: Bearer $ADMIN_TOKEN". In
+	// production, also serve them on an internal listener.
+	admin := app.Group("/admin")
+	m.RegisterAdmin(admin)
+	// Browse to /admin/dashboard/ and enter the token to watch the limiter.
+	admin.GET("/dashboard/*filepath", ratelimit.DashboardHandler())
+
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := app.Run(addr); err != nil {
+		panic(err)
+	}
+}
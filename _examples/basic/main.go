@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func main() {
+	app := gin.Default()
+
+	// Default usage: one request per second per client IP.
+	app.GET("/", ratelimit.New(ratelimit.Options{
+		Rate:  rate.Every(time.Second),
+		Burst: 1,
+	}), func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, World!")
+	})
+
+	// Custom usage: five requests per minute per API key, with a JSON
+	// response once the limit is exceeded.
+	app.GET("/custom", ratelimit.New(ratelimit.Options{
+		Rate:  rate.Every(time.Minute),
+		Burst: 5,
+		KeyFunc: func(c *gin.Context) string {
+			return c.GetHeader("X-API-KEY")
+		},
+		OnLimitExceeded: func(c *gin.Context, l *rate.Limiter) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"message": "Too many requests",
+			})
+		},
+	}), func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, Custom World!")
+	})
+
+	// Routes without the middleware are not limited.
+	app.GET("/unlimited", func(c *gin.Context) {
+		c.String(http.StatusOK, "This is an unlimited route")
+	})
+
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := app.Run(addr); err != nil {
+		panic(err)
+	}
+}
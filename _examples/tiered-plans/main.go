@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// plans maps API keys to their subscription plan. A real application would
+// look them up in its database.
+var plans = map[string]string{
+	"free-key": "free",
+	"pro-key":  "pro",
+}
+
+func main() {
+	apiKey := func(c *gin.Context) string {
+		return c.GetHeader("X-API-KEY")
+	}
+
+	// One limiter per plan, each keyed by API key.
+	limiters := map[string]gin.HandlerFunc{
+		"free": ratelimit.New(ratelimit.Options{
+			Rate:    rate.Every(time.Second),
+			Burst:   2,
+			KeyFunc: apiKey,
+		}),
+		"pro": ratelimit.New(ratelimit.Options{
+			Rate:    rate.Every(100 * time.Millisecond),
+			Burst:   20,
+			KeyFunc: apiKey,
+		}),
+	}
+
+	app := gin.Default()
+	app.Use(func(c *gin.Context) {
+		plan, ok := plans[apiKey(c)]
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		limiters[plan](c)
+	})
+	app.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, %s plan!", plans[apiKey(c)])
+	})
+
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := app.Run(addr); err != nil {
+		panic(err)
+	}
+}
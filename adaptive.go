@@ -0,0 +1,195 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// LearnedLimitTTL is how long a limit learned from a 429 response applies
+// before the configured limit is restored.
+const LearnedLimitTTL = time.Minute
+
+// Sources of learned limits.
+const (
+	LearnedFromHeaders    = "headers"
+	LearnedFromRetryAfter = "retry-after"
+)
+
+// LearnedLimit is a limit the outbound transport learned from an upstream
+// server's responses. It caps the configured limit of its key until it
+// expires.
+type LearnedLimit struct {
+	Key   string  `json:"key"`
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	// Source is what the limit was learned from: LearnedFromHeaders for
+	// RateLimit or X-RateLimit headers, LearnedFromRetryAfter for 429
+	// responses without them.
+	Source  string    `json:"source"`
+	Expires time.Time `json:"expires"`
+}
+
+// learnedLimits holds the limits learned per key.
+type learnedLimits struct {
+	// size is the number of limits held, read without the lock so keys
+	// are not slowed down while nothing has been learned.
+	size atomic.Int32
+
+	mu     sync.Mutex
+	limits map[string]LearnedLimit
+}
+
+func newLearnedLimits() *learnedLimits {
+	return &learnedLimits{limits: make(map[string]LearnedLimit)}
+}
+
+// get returns the unexpired limit learned for key.
+func (l *learnedLimits) get(key string, now time.Time) (LearnedLimit, bool) {
+	if l.size.Load() == 0 {
+		return LearnedLimit{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ll, ok := l.limits[key]
+	if ok && !now.Before(ll.Expires) {
+		delete(l.limits, key)
+		l.size.Store(int32(len(l.limits)))
+		return LearnedLimit{}, false
+	}
+	return ll, ok
+}
+
+func (l *learnedLimits) set(ll LearnedLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[ll.Key] = ll
+	l.size.Store(int32(len(l.limits)))
+}
+
+// list returns the unexpired limits, sorted by key.
+func (l *learnedLimits) list(now time.Time) []LearnedLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []LearnedLimit
+	for key, ll := range l.limits {
+		if !now.Before(ll.Expires) {
+			delete(l.limits, key)
+			continue
+		}
+		out = append(out, ll)
+	}
+	l.size.Store(int32(len(l.limits)))
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// capLimits lowers r and burst to the limit learned for key, if any.
+func (l *learnedLimits) capLimits(key string, r rate.Limit, burst int, now time.Time) (rate.Limit, int) {
+	ll, ok := l.get(key, now)
+	if !ok {
+		return r, burst
+	}
+	return min(r, rate.Limit(ll.Rate)), min(burst, ll.Burst)
+}
+
+// learn adjusts the limit of key after a response from upstream. Quota
+// headers set the rate that spends the remaining quota evenly until it
+// resets, and an exhausted quota is returned as a delay to wait out. A
+// 429 response without them halves the current rate for LearnedLimitTTL.
+func (m *Manager) learn(key string, resp *http.Response, now time.Time) (time.Duration, bool) {
+	if remaining, reset, ok := parseQuotaHeaders(resp.Header, now); ok {
+		if remaining == 0 {
+			return reset, true
+		}
+		_, burst := m.configuredLimits(key)
+		m.learned.set(LearnedLimit{
+			Key:     key,
+			Rate:    float64(remaining) / reset.Seconds(),
+			Burst:   max(1, min(remaining, burst)),
+			Source:  LearnedFromHeaders,
+			Expires: now.Add(reset),
+		})
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if r, burst := m.limitsFor(key); r != rate.Inf && r > 0 {
+		m.learned.set(LearnedLimit{
+			Key:     key,
+			Rate:    float64(r) / 2,
+			Burst:   max(1, burst/2),
+			Source:  LearnedFromRetryAfter,
+			Expires: now.Add(LearnedLimitTTL),
+		})
+	}
+	return 0, false
+}
+
+// unixResetThreshold tells reset times given as Unix timestamps, as in
+// X-RateLimit-Reset, from delays in seconds.
+const unixResetThreshold = 1_000_000_000
+
+// parseQuotaHeaders returns the requests remaining in an upstream quota
+// and the time until it resets, read from the RateLimit header of the IETF
+// draft ("r=50;t=30"), its older RateLimit-Remaining and RateLimit-Reset
+// form, or the common X-RateLimit-Remaining and X-RateLimit-Reset headers.
+func parseQuotaHeaders(h http.Header, now time.Time) (int, time.Duration, bool) {
+	remaining, reset := h.Get("RateLimit-Remaining"), h.Get("RateLimit-Reset")
+	if remaining == "" {
+		remaining, reset = h.Get("X-RateLimit-Remaining"), h.Get("X-RateLimit-Reset")
+	}
+	if remaining == "" {
+		remaining, reset = structuredQuota(h.Get("RateLimit"))
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(remaining))
+	if err != nil || n < 0 {
+		return 0, 0, false
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(reset), 10, 64)
+	if err != nil || secs < 0 {
+		return 0, 0, false
+	}
+	d := time.Duration(secs) * time.Second
+	if secs >= unixResetThreshold {
+		d = time.Unix(secs, 0).Sub(now)
+	}
+	if d <= 0 {
+		return 0, 0, false
+	}
+	return n, d, true
+}
+
+// structuredQuota returns the remaining and reset parameters of the first
+// policy in a RateLimit header, such as `"default";r=50;t=30` or
+// "limit=100, remaining=50, reset=30".
+func structuredQuota(v string) (remaining, reset string) {
+	for _, item := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' }) {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "r", "remaining":
+			if remaining == "" {
+				remaining = value
+			}
+		case "t", "reset":
+			if reset == "" {
+				reset = value
+			}
+		}
+	}
+	return remaining, reset
+}
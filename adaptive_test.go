@@ -0,0 +1,113 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestParseQuotaHeaders(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	parse := func(kv ...string) (int, time.Duration, bool) {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return parseQuotaHeaders(h, now)
+	}
+
+	n, d, ok := parse("RateLimit-Remaining", "50", "RateLimit-Reset", "30")
+	assert.True(t, ok)
+	assert.Equal(t, 50, n)
+	assert.Equal(t, 30*time.Second, d)
+
+	// X-RateLimit-Reset is commonly a Unix timestamp.
+	n, d, ok = parse("X-RateLimit-Remaining", "10", "X-RateLimit-Reset", "1700000060")
+	assert.True(t, ok)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, time.Minute, d)
+
+	n, d, ok = parse("RateLimit", `"default";r=5;t=10`)
+	assert.True(t, ok)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 10*time.Second, d)
+
+	n, _, ok = parse("RateLimit", "limit=100, remaining=0, reset=5")
+	assert.True(t, ok)
+	assert.Zero(t, n)
+
+	_, _, ok = parse("RateLimit-Remaining", "50")
+	assert.False(t, ok)
+	_, _, ok = parse("X-RateLimit-Remaining", "ten", "X-RateLimit-Reset", "5")
+	assert.False(t, ok)
+	_, _, ok = parse()
+	assert.False(t, ok)
+}
+
+func TestAdaptivePacing(t *testing.T) {
+	var remaining, status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if remaining >= 0 {
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", "10")
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+
+	m := NewManager(Options{Rate: 100, Burst: 20})
+	client := &http.Client{Transport: m.Transport(nil, nil)}
+	get := func() {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	t.Run("Headers", func(t *testing.T) {
+		remaining, status = 5, http.StatusOK
+		get()
+
+		// The remaining quota is spread over the time until it resets.
+		r, burst := m.limitsFor(host)
+		assert.Equal(t, rate.Limit(0.5), r)
+		assert.Equal(t, 5, burst)
+
+		st := m.Stats()
+		require.Len(t, st.Learned, 1)
+		assert.Equal(t, host, st.Learned[0].Key)
+		assert.Equal(t, LearnedFromHeaders, st.Learned[0].Source)
+	})
+
+	t.Run("TooManyRequests", func(t *testing.T) {
+		m.learned.set(LearnedLimit{Key: host, Rate: 10, Burst: 4, Expires: time.Now().Add(time.Hour)})
+		remaining, status = -1, http.StatusTooManyRequests
+		get()
+
+		r, burst := m.limitsFor(host)
+		assert.Equal(t, rate.Limit(5), r)
+		assert.Equal(t, 2, burst)
+		assert.Equal(t, LearnedFromRetryAfter, m.Stats().Learned[0].Source)
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		m.learned.set(LearnedLimit{Key: host, Rate: 1, Burst: 1, Expires: time.Now()})
+		r, burst := m.limitsFor(host)
+		assert.Equal(t, rate.Limit(100), r)
+		assert.Equal(t, 20, burst)
+		assert.Empty(t, m.Stats().Learned)
+	})
+}
@@ -0,0 +1,374 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RegisterAdmin mounts the administration endpoints of the manager on the
+// given router:
+//
+//	GET  /config          the effective configuration, as returned by ConfigJSON
+//	POST /evaluate        evaluates a SyntheticRequest without consuming tokens
+//	GET  /stats           decision counters, as returned by Stats
+//	GET  /decisions       a Server-Sent Events stream of decisions, sampled
+//	                      down to ?rate= events per second
+//	GET  /keys            lists the keys seen, filtered by ?prefix=, ordered
+//	                      by ?sort=key|denied|lastSeen and paged with ?limit=
+//	                      and the ?cursor= returned as "next"
+//	GET  /limits          the limits applying to ?key=, or to the identity
+//	                      of ?type= and ?id=, as returned by LimitsOf
+//	GET  /suggestions     the limits suggested by Options.Tuning
+//	POST /reset           {"key": "..."} refills the bucket of a key
+//	POST /ban             {"key": "...", "duration": "1h"} bans a key; without
+//	                      a duration the ban lasts until it is lifted, and an
+//	                      RFC 3339 "start" schedules it
+//	POST /unban           {"key": "..."} lifts a ban
+//	POST /override        {"key": "...", "rate": 10, "burst": 20} replaces the
+//	                      limits of a key, between the optional RFC 3339
+//	                      "start" and "end" times
+//	POST /clear-override  {"key": "..."} restores the configured limits
+//	POST /forget          {"key": "..."} deletes the data kept about a key
+//	                      and returns the ForgetReport
+//	POST /note            {"key": "...", "fields": {"ticket": "OPS-1"}}
+//	                      replaces the note of a key, listed by /keys;
+//	                      without fields the note is deleted
+//
+// Each mutation also has a bulk form under /bulk, such as /bulk/ban, that
+// takes {"keys": [...], "prefixes": [...]} instead of a single key, along
+// with the same parameters; see KeySelector.
+//
+// Changes are attributed to the user set by an authentication middleware
+// under gin.AuthUserKey, such as gin.BasicAuth, or to the client IP
+// otherwise, and recorded with Options.AuditSink.
+//
+// The endpoints expose internal state and must not be reachable by
+// untrusted clients. Set Options.AdminAuth to require a token and a
+// permission for each endpoint: AdminRead for /config, /evaluate, /stats,
+// /decisions, /keys, /limits and /suggestions, AdminReset for /reset, AdminBan for
+// /ban and /unban, AdminOverride for /override and /clear-override,
+// including their bulk forms, AdminForget for /forget and AdminNote for
+// /note. Without it, mount the endpoints on an internal listener or behind
+// authentication.
+func (m *Manager) RegisterAdmin(r gin.IRouter) {
+	r.GET("/config", m.adminGuard(AdminRead), m.adminConfig)
+	r.POST("/evaluate", m.adminGuard(AdminRead), m.adminEvaluate)
+	r.GET("/stats", m.adminGuard(AdminRead), m.adminStats)
+	r.GET("/decisions", m.adminGuard(AdminRead), m.adminDecisions)
+	r.GET("/keys", m.adminGuard(AdminRead), m.adminKeys)
+	r.GET("/limits", m.adminGuard(AdminRead), m.adminLimits)
+	r.GET("/suggestions", m.adminGuard(AdminRead), m.adminSuggestions)
+	r.POST("/reset", m.adminGuard(AdminReset), m.adminMutation(func(ctx context.Context, req adminRequest) error {
+		return m.Reset(ctx, req.Key)
+	}))
+	r.POST("/ban", m.adminGuard(AdminBan), m.adminMutation(func(ctx context.Context, req adminRequest) error {
+		return m.ScheduleBan(ctx, req.Key, req.Start, time.Duration(req.Duration))
+	}))
+	r.POST("/unban", m.adminGuard(AdminBan), m.adminMutation(func(ctx context.Context, req adminRequest) error {
+		return m.Unban(ctx, req.Key)
+	}))
+	r.POST("/override", m.adminGuard(AdminOverride), m.adminMutation(func(ctx context.Context, req adminRequest) error {
+		return m.SetOverride(ctx, req.Key, req.override())
+	}))
+	r.POST("/clear-override", m.adminGuard(AdminOverride),
+		m.adminMutation(func(ctx context.Context, req adminRequest) error {
+			return m.ClearOverride(ctx, req.Key)
+		}))
+	r.POST("/forget", m.adminGuard(AdminForget), m.adminForget)
+	r.POST("/note", m.adminGuard(AdminNote), m.adminMutation(func(ctx context.Context, req adminRequest) error {
+		return m.SetNote(ctx, req.Key, req.Fields)
+	}))
+
+	bulk := r.Group("/bulk")
+	bulk.POST("/reset", m.adminGuard(AdminReset), m.adminBulk(func(ctx context.Context, req adminBulkRequest) error {
+		return m.BulkReset(ctx, req.KeySelector)
+	}))
+	bulk.POST("/ban", m.adminGuard(AdminBan), m.adminBulk(func(ctx context.Context, req adminBulkRequest) error {
+		return m.bulkBan(ctx, req.KeySelector, req.Start, time.Duration(req.Duration))
+	}))
+	bulk.POST("/unban", m.adminGuard(AdminBan), m.adminBulk(func(ctx context.Context, req adminBulkRequest) error {
+		return m.BulkUnban(ctx, req.KeySelector)
+	}))
+	bulk.POST("/override", m.adminGuard(AdminOverride), m.adminBulk(func(ctx context.Context, req adminBulkRequest) error {
+		return m.BulkSetOverride(ctx, req.KeySelector, req.override())
+	}))
+	bulk.POST("/clear-override", m.adminGuard(AdminOverride),
+		m.adminBulk(func(ctx context.Context, req adminBulkRequest) error {
+			return m.BulkClearOverride(ctx, req.KeySelector)
+		}))
+}
+
+// adminRequest is the body of the mutating admin endpoints.
+type adminRequest struct {
+	Key string `json:"key" binding:"required"`
+	adminParams
+}
+
+// adminBulkRequest is the body of the bulk admin endpoints.
+type adminBulkRequest struct {
+	KeySelector
+	adminParams
+}
+
+// adminParams are the parameters of the mutating admin endpoints.
+type adminParams struct {
+	Duration adminDuration     `json:"duration"`
+	Rate     float64           `json:"rate"`
+	Burst    int               `json:"burst"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Fields   map[string]string `json:"fields"`
+}
+
+// override returns the override described by the parameters.
+func (p adminParams) override() Override {
+	return Override{Rate: rate.Limit(p.Rate), Burst: p.Burst, Start: p.Start, End: p.End}
+}
+
+// adminDuration is a time.Duration decoded from strings such as "1h30m".
+type adminDuration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *adminDuration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = adminDuration(v)
+	return nil
+}
+
+// adminActor identifies who is calling the admin endpoints.
+func adminActor(c *gin.Context) string {
+	if user := c.GetString(gin.AuthUserKey); user != "" {
+		return user
+	}
+	return c.ClientIP()
+}
+
+// adminMutation wraps a mutating operation in a handler that decodes the
+// request and attributes the change to the caller.
+func (m *Manager) adminMutation(apply func(context.Context, adminRequest) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req adminRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := WithActor(c.Request.Context(), adminActor(c))
+		if err := apply(ctx, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// adminForget deletes the data kept about the key in the body.
+func (m *Manager) adminForget(c *gin.Context) {
+	var req adminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := WithActor(c.Request.Context(), adminActor(c))
+	report, err := m.Forget(ctx, req.Key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// adminBulk wraps a bulk operation in a handler that decodes and validates
+// the request and attributes the change to the caller.
+func (m *Manager) adminBulk(apply func(context.Context, adminBulkRequest) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req adminBulkRequest
+		err := c.ShouldBindJSON(&req)
+		if err == nil {
+			err = req.validate()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := WithActor(c.Request.Context(), adminActor(c))
+		if err := apply(ctx, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// adminConfig serves the effective configuration.
+func (m *Manager) adminConfig(c *gin.Context) {
+	data, err := m.ConfigJSON()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// adminEvaluate evaluates the synthetic request in the body.
+func (m *Manager) adminEvaluate(c *gin.Context) {
+	var sr SyntheticRequest
+	if err := c.ShouldBindJSON(&sr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ev, err := m.Evaluate(sr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"key":        ev.Key,
+		"identity":   ev.Identity,
+		"class":      ev.Classification,
+		"allowed":    ev.Allowed,
+		"bypass":     ev.Bypass,
+		"denylisted": ev.Denylisted,
+		"banned":     ev.Banned,
+		"cost":       ev.Cost,
+		"limit":      ev.Limit,
+		"tokens":     ev.Tokens,
+		"delay":      ev.Delay.String(),
+		"retryAfter": ev.RetryAfter.String(),
+	})
+}
+
+// adminStats serves the decision counters.
+func (m *Manager) adminStats(c *gin.Context) {
+	c.JSON(http.StatusOK, m.Stats())
+}
+
+// adminLimits serves the limits applying to a key or identity.
+func (m *Manager) adminLimits(c *gin.Context) {
+	var q struct {
+		Key  string `form:"key"`
+		Type string `form:"type"`
+		ID   string `form:"id"`
+	}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch {
+	case q.Key != "":
+		c.JSON(http.StatusOK, m.LimitsOf(q.Key))
+	case q.Type != "" && q.ID != "":
+		c.JSON(http.StatusOK, m.EffectiveLimits(Identity{Type: q.Type, ID: q.ID}))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ratelimit: key or type and id required"})
+	}
+}
+
+// adminSuggestions serves the limits suggested by Options.Tuning.
+func (m *Manager) adminSuggestions(c *gin.Context) {
+	if m.tuner == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ratelimit: tuning is not enabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"targetDenial": m.tuner.cfg.TargetDenial,
+		"suggestions":  m.SuggestLimits(),
+	})
+}
+
+// decisionStreamKeepAlive is how often an idle decision stream sends a
+// comment, so proxies do not time it out.
+const decisionStreamKeepAlive = 15 * time.Second
+
+// adminDecisions streams sampled decisions as Server-Sent Events until the
+// client disconnects. Each connection is capped at its own rate so a
+// watcher cannot add much load to a server already under pressure.
+func (m *Manager) adminDecisions(c *gin.Context) {
+	var q struct {
+		Rate float64 `form:"rate"`
+	}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if q.Rate <= 0 {
+		q.Rate = DefaultDecisionStreamRate
+	}
+	q.Rate = min(q.Rate, MaxDecisionStreamRate)
+	sampler := rate.NewLimiter(rate.Limit(q.Rate), max(1, int(q.Rate)))
+
+	ctx := c.Request.Context()
+	decisions := m.WatchDecisions(ctx, MaxDecisionStreamRate)
+	keepAlive := time.NewTicker(decisionStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Header("Content-Type", "text/event-stream")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	var skipped uint64
+	for {
+		select {
+		case d, ok := <-decisions:
+			if !ok {
+				return
+			}
+			if !sampler.Allow() {
+				skipped++
+				continue
+			}
+			c.SSEvent("decision", newDecisionEvent(d, skipped))
+			skipped = 0
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// adminKeysQuery is the query string of the key listing endpoint.
+type adminKeysQuery struct {
+	Prefix string `form:"prefix"`
+	Sort   string `form:"sort"`
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"`
+}
+
+// adminKeys serves a page of the key listing.
+func (m *Manager) adminKeys(c *gin.Context) {
+	var q adminKeysQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := m.Keys(KeyQuery{
+		Prefix: q.Prefix,
+		Sort:   KeySort(q.Sort),
+		Limit:  q.Limit,
+		Cursor: q.Cursor,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminPermission is the capability required by an admin endpoint.
+type AdminPermission string
+
+// Admin permissions.
+const (
+	// AdminRead allows reading the configuration, statistics and decisions,
+	// evaluating requests and listing keys.
+	AdminRead AdminPermission = "read"
+	// AdminReset allows refilling the bucket of a key.
+	AdminReset AdminPermission = "reset"
+	// AdminBan allows banning and unbanning keys.
+	AdminBan AdminPermission = "ban"
+	// AdminOverride allows setting and clearing per-key overrides.
+	AdminOverride AdminPermission = "override"
+	// AdminForget allows deleting the data kept about a key.
+	AdminForget AdminPermission = "forget"
+	// AdminNote allows setting the notes of keys.
+	AdminNote AdminPermission = "note"
+)
+
+// Errors reported by AuthorizeAdmin.
+var (
+	// ErrInvalidToken is returned for missing or unknown tokens, including
+	// by token validators.
+	ErrInvalidToken = errors.New("ratelimit: invalid admin token")
+	// ErrPermissionDenied is returned for callers lacking a permission.
+	ErrPermissionDenied = errors.New("ratelimit: admin permission denied")
+)
+
+// AdminPrincipal identifies an authenticated caller of the admin endpoints.
+type AdminPrincipal struct {
+	// Name identifies the caller in the audit trail.
+	Name string
+	// Roles are looked up in AdminAuth.Roles to grant permissions.
+	Roles []string
+}
+
+// AdminAuth protects the admin endpoints. Every request is authenticated
+// and then checked against the permission its endpoint requires.
+type AdminAuth struct {
+	// ValidateToken validates the bearer token sent in the Authorization
+	// header and returns the caller it belongs to. Requests without a token,
+	// or whose token is rejected, get 401 Unauthorized. If nil, the caller
+	// is the user set under gin.AuthUserKey by an authentication middleware
+	// such as gin.BasicAuth, and requests without one are rejected.
+	ValidateToken func(ctx context.Context, token string) (AdminPrincipal, error)
+
+	// Authorize reports whether the caller may use endpoints requiring
+	// perm. Denied requests get 403 Forbidden. If nil, the caller's Roles
+	// are looked up in Roles.
+	Authorize func(p AdminPrincipal, perm AdminPermission) bool
+
+	// Roles maps role names to the permissions they grant. It is only used
+	// when Authorize is nil; if both are nil, every authenticated caller
+	// may use every endpoint.
+	Roles map[string][]AdminPermission
+}
+
+// StaticTokens returns a token validator for a fixed set of tokens, for use
+// as AdminAuth.ValidateToken.
+func StaticTokens(tokens map[string]AdminPrincipal) func(context.Context, string) (AdminPrincipal, error) {
+	return func(_ context.Context, token string) (AdminPrincipal, error) {
+		// Compare against every token so the time taken does not reveal
+		// how much of a valid token was guessed.
+		var found AdminPrincipal
+		ok := false
+		for t, p := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				found, ok = p, true
+			}
+		}
+		if !ok {
+			return AdminPrincipal{}, ErrInvalidToken
+		}
+		return found, nil
+	}
+}
+
+// authenticate resolves the caller of an admin request.
+func (a *AdminAuth) authenticate(c *gin.Context) (AdminPrincipal, bool) {
+	if a.ValidateToken == nil {
+		user := c.GetString(gin.AuthUserKey)
+		return AdminPrincipal{Name: user}, user != ""
+	}
+
+	p, err := a.validate(c.Request.Context(), bearerToken(c.GetHeader("Authorization")))
+	return p, err == nil
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header.
+func bearerToken(header string) string {
+	scheme, token, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// validate resolves the caller holding token.
+func (a *AdminAuth) validate(ctx context.Context, token string) (AdminPrincipal, error) {
+	if a.ValidateToken == nil || token == "" {
+		return AdminPrincipal{}, ErrInvalidToken
+	}
+	return a.ValidateToken(ctx, token)
+}
+
+// authorize reports whether p holds perm.
+func (a *AdminAuth) authorize(p AdminPrincipal, perm AdminPermission) bool {
+	if a.Authorize != nil {
+		return a.Authorize(p, perm)
+	}
+	if a.Roles == nil {
+		return true
+	}
+	for _, role := range p.Roles {
+		for _, granted := range a.Roles[role] {
+			if granted == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AuthorizeAdmin checks that the caller holding token may use endpoints
+// requiring perm under Options.AdminAuth, for admin transports other than
+// RegisterAdmin. Without AdminAuth every caller is accepted with an empty
+// principal; without AdminAuth.ValidateToken every token is rejected.
+func (m *Manager) AuthorizeAdmin(ctx context.Context, token string, perm AdminPermission) (AdminPrincipal, error) {
+	a := m.opts.AdminAuth
+	if a == nil {
+		return AdminPrincipal{}, nil
+	}
+	p, err := a.validate(ctx, token)
+	if err != nil {
+		return AdminPrincipal{}, ErrInvalidToken
+	}
+	if !a.authorize(p, perm) {
+		return p, ErrPermissionDenied
+	}
+	return p, nil
+}
+
+// adminGuard returns a handler that rejects admin requests whose caller
+// lacks perm. Accepted callers are recorded under gin.AuthUserKey so their
+// changes are attributed to them.
+func (m *Manager) adminGuard(perm AdminPermission) gin.HandlerFunc {
+	a := m.opts.AdminAuth
+	return func(c *gin.Context) {
+		if a == nil {
+			return
+		}
+
+		p, ok := a.authenticate(c)
+		if !ok {
+			if a.ValidateToken != nil {
+				c.Header("WWW-Authenticate", `Bearer realm="ratelimit"`)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		if !a.authorize(p, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "missing permission " + string(perm),
+			})
+			return
+		}
+		c.Set(gin.AuthUserKey, p.Name)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(opts Options) *gin.Engine {
+		r := gin.New()
+		NewManager(opts).RegisterAdmin(r.Group("/admin"))
+		return r
+	}
+	call := func(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/admin"+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Roles", func(t *testing.T) {
+		sink := &memoryAuditSink{}
+		r := newRouter(Options{
+			Rate:      rate.Inf,
+			Burst:     1,
+			AuditSink: sink,
+			AdminAuth: &AdminAuth{
+				ValidateToken: StaticTokens(map[string]AdminPrincipal{
+					"viewer-token":   {Name: "viewer", Roles: []string{"viewer"}},
+					"operator-token": {Name: "operator", Roles: []string{"viewer", "operator"}},
+				}),
+				Roles: map[string][]AdminPermission{
+					"viewer":   {AdminRead},
+					"operator": {AdminReset, AdminBan},
+				},
+			},
+		})
+
+		w := call(r, "GET", "/config", "", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer realm="ratelimit"`, w.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, http.StatusUnauthorized, call(r, "GET", "/config", "guessed", "").Code)
+
+		assert.Equal(t, http.StatusOK, call(r, "GET", "/config", "viewer-token", "").Code)
+		assert.Equal(t, http.StatusForbidden, call(r, "POST", "/unban", "viewer-token", `{"key": "k"}`).Code)
+
+		assert.Equal(t, http.StatusNoContent, call(r, "POST", "/unban", "operator-token", `{"key": "k"}`).Code)
+		assert.Equal(t, http.StatusForbidden, call(r, "POST", "/override", "operator-token", `{"key": "k"}`).Code)
+
+		if assert.Len(t, sink.entries, 1) {
+			assert.Equal(t, "operator", sink.entries[0].Actor)
+			assert.Equal(t, AuditUnban, sink.entries[0].Action)
+		}
+	})
+
+	t.Run("Authorize", func(t *testing.T) {
+		r := gin.New()
+		m := NewManager(Options{
+			Rate:  rate.Inf,
+			Burst: 1,
+			AdminAuth: &AdminAuth{
+				Authorize: func(p AdminPrincipal, perm AdminPermission) bool {
+					return p.Name == "alice" || perm == AdminRead
+				},
+			},
+		})
+		m.RegisterAdmin(r.Group("/admin", gin.BasicAuth(gin.Accounts{
+			"alice": "secret",
+			"bob":   "secret",
+		})))
+		serve := func(user, method, path, body string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/admin"+path, strings.NewReader(body))
+			req.SetBasicAuth(user, "secret")
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, serve("bob", "GET", "/config", ""))
+		assert.Equal(t, http.StatusForbidden, serve("bob", "POST", "/reset", `{"key": "k"}`))
+		assert.Equal(t, http.StatusNoContent, serve("alice", "POST", "/reset", `{"key": "k"}`))
+	})
+
+	t.Run("RequiresUser", func(t *testing.T) {
+		r := newRouter(Options{Rate: rate.Inf, Burst: 1, AdminAuth: &AdminAuth{}})
+
+		w := call(r, "GET", "/config", "", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:  rate.Inf,
+			Burst: 1,
+			AdminAuth: &AdminAuth{
+				ValidateToken: StaticTokens(nil),
+				Roles:         map[string][]AdminPermission{"viewer": {AdminRead}},
+			},
+		})
+		data, err := m.ConfigJSON()
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"adminAuth": {
+    "validateToken": "custom",
+    "authorize": "default",
+    "roles": {
+      "viewer": [
+        "read"
+      ]
+    }
+  }`)
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// Algorithm is a rate limiting algorithm that keeps its own state per key,
+// as an alternative to the token buckets kept in Options.Store. A limit of
+// rate r and burst b allows b requests in every window of b/r.
+type Algorithm interface {
+	// Take records n requests of key at now if the limit allows all of
+	// them, and reports the outcome. With n zero, it records nothing and
+	// reports whether one request would be allowed.
+	Take(ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time) (Allowance, error)
+	// Reset forgets the requests recorded for key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Allowance is the outcome of Algorithm.Take and Store.Allow.
+type Allowance struct {
+	// Allowed reports whether the requests may proceed.
+	Allowed bool
+	// Remaining is the number of requests still allowed in the current
+	// window.
+	Remaining int
+	// RetryAfter is how long until the requests would be allowed. It is
+	// zero when they are allowed now, and negative when they never will
+	// be.
+	RetryAfter time.Duration
+	// Delay is how long allowed requests must be held before proceeding,
+	// for algorithms that pace requests.
+	Delay time.Duration
+}
+
+// maxLocalLimiters is the most local token buckets a manager keeps when
+// its Store decides requests itself.
+const maxLocalLimiters = 10000
+
+// errStoreReset is returned when resetting keys of a store that cannot
+// forget them.
+var errStoreReset = errors.New("ratelimit: store does not support resetting keys")
+
+// storeAlgorithm returns the Algorithm deciding requests with s: s
+// itself if it is an Algorithm, and Store.Allow otherwise.
+func storeAlgorithm(s Store) Algorithm {
+	if alg, ok := s.(Algorithm); ok {
+		return alg
+	}
+	return allowAlgorithm{s}
+}
+
+// allowAlgorithm is an Algorithm calling Store.Allow. Keys are reset if
+// the store also has the Reset method of Algorithm.
+type allowAlgorithm struct {
+	store Store
+}
+
+// Take implements Algorithm.
+func (a allowAlgorithm) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, _ time.Time,
+) (Allowance, error) {
+	return a.store.Allow(ctx, key, Limit{Rate: r, Burst: burst, N: n})
+}
+
+// Reset implements Algorithm.
+func (a allowAlgorithm) Reset(ctx context.Context, key string) error {
+	if s, ok := a.store.(interface {
+		Reset(ctx context.Context, key string) error
+	}); ok {
+		return s.Reset(ctx, key)
+	}
+	return errStoreReset
+}
+
+// takeAlgorithm runs the configured algorithm for a request. If the
+// algorithm fails, the request is allowed and the error recorded in c.
+// The returned limiter reflects the allowance, for OnLimitExceeded.
+func (m *Manager) takeAlgorithm(c *gin.Context, key string, r rate.Limit, burst, n int) (*rate.Limiter, bool) {
+	if m.regions != nil {
+		m.regions.requests.Add(1)
+	}
+	now := time.Now()
+	if wait, ok := m.denials.denied(key, r, burst, n, now); ok {
+		return allowanceLimiter(r, burst, Allowance{RetryAfter: wait}, now), false
+	}
+	a, err := m.opts.Algorithm.Take(c.Request.Context(), key, r, burst, n, now)
+	if err != nil {
+		_ = c.Error(err)
+		return rate.NewLimiter(r, burst), true
+	}
+	if !a.Allowed {
+		m.denials.add(key, r, burst, n, a, now)
+	}
+	return allowanceLimiter(r, burst, a, now), a.Allowed && hold(c, a.Delay, m.queue)
+}
+
+// hold holds a request for the delay of its allowance. It reports false if
+// q is full or the request is canceled first.
+func hold(c *gin.Context, delay time.Duration, q *waitQueue) bool {
+	if delay <= 0 {
+		return true
+	}
+	if !q.enter() {
+		return false
+	}
+	defer q.leave()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+// allowanceLimiter returns a token bucket in the state described by a, so
+// handlers written for token buckets, such as DenyResponse, report the
+// remaining requests and the wait of other algorithms.
+func allowanceLimiter(r rate.Limit, burst int, a Allowance, now time.Time) *rate.Limiter {
+	l := rate.NewLimiter(r, burst)
+	if r <= 0 || r == rate.Inf || a.Allowed || a.RetryAfter <= 0 {
+		if spent := burst - a.Remaining; spent > 0 {
+			l.AllowN(now, spent)
+		}
+		return l
+	}
+	// Leave the bucket short of a token by as much as the wait, spending
+	// the burst earlier and letting it refill until now.
+	tokens := 1 - a.RetryAfter.Seconds()*float64(r)
+	debt := min(burst, int(math.Ceil(-tokens)))
+	since := time.Duration(math.Ceil((tokens + float64(debt)) / float64(r) * float64(time.Second)))
+	l.AllowN(now.Add(-since), burst)
+	if debt > 0 {
+		l.ReserveN(now.Add(-since), debt)
+	}
+	return l
+}
+
+// logWindow returns the window of a limit of rate r and burst requests,
+// or zero if requests never expire.
+func logWindow(r rate.Limit, burst int) time.Duration {
+	if r <= 0 {
+		return 0
+	}
+	return time.Duration(float64(burst) / float64(r) * float64(time.Second))
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrCostsUnsupported is returned by AllowAll when the Algorithm cannot
+// charge keys different costs in a single step.
+var ErrCostsUnsupported = errors.New("ratelimit: algorithm cannot charge different costs at once")
+
+// KeyCost is one of the buckets charged together by AllowAll.
+type KeyCost struct {
+	// Key is the key of the bucket, such as "user:42" or "org:acme".
+	Key string
+	// Cost is the number of tokens the operation takes from the bucket.
+	// If zero, it takes one.
+	Cost int
+	// Rate and Burst are the limit of the bucket. If Burst is zero, the
+	// key is limited as its requests are, with its hierarchy and
+	// overrides.
+	Rate  rate.Limit
+	Burst int
+}
+
+// CostAlgorithm is a LevelAlgorithm that can take a different number of
+// requests from every level in a single step.
+type CostAlgorithm interface {
+	LevelAlgorithm
+	// TakeCosts records costs[i] requests at now in levels[i] if every
+	// level allows its cost. A zero cost only checks that the level allows
+	// one request. It returns the allowance of the most restrictive level
+	// and the index of that level.
+	TakeCosts(ctx context.Context, levels []Level, costs []int, now time.Time) (Allowance, int, error)
+}
+
+// AllowAll charges the buckets of a composite operation, such as those of
+// a user, its organization and a global budget, atomically: either every
+// bucket has enough tokens and all of them are charged, or none is. It
+// returns the allowance of the most restrictive bucket and its index in
+// keys. Banned keys deny the operation.
+//
+// With Options.Algorithm, the buckets are charged in one step if it is a
+// CostAlgorithm, or a LevelAlgorithm and every cost is the same; otherwise
+// AllowAll returns ErrCostsUnsupported rather than charging them one at a
+// time. GCRA and redisstore.NewGCRA are CostAlgorithms, the latter
+// charging every bucket in one script. Operations are never delayed.
+func (m *Manager) AllowAll(ctx context.Context, keys []KeyCost) (Allowance, int, error) {
+	if len(keys) == 0 {
+		return Allowance{Allowed: true}, 0, nil
+	}
+	now := time.Now()
+	levels := make([]Level, len(keys))
+	costs := make([]int, len(keys))
+	for i, k := range keys {
+		if m.controls.banned(k.Key, now) {
+			return Allowance{RetryAfter: -1}, i, nil
+		}
+		r, burst := k.Rate, k.Burst
+		if burst == 0 {
+			r, burst = m.limitsFor(k.Key)
+		} else {
+			r, burst = m.scale(r, burst)
+		}
+		levels[i] = Level{Key: k.Key, Rate: r, Burst: burst}
+		costs[i] = max(k.Cost, 1)
+	}
+
+	if m.opts.Algorithm != nil {
+		return takeCosts(ctx, m.opts.Algorithm, levels, costs, now)
+	}
+	limiters, denied := m.reserveAll(levels, func(i int) int { return costs[i] }, now)
+	if denied >= 0 {
+		return limiterAllowance(limiters[denied], false, costs[denied], now), denied, nil
+	}
+	as := make([]Allowance, len(limiters))
+	for i, l := range limiters {
+		as[i] = limiterAllowance(l, true, costs[i], now)
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// limiterAllowance describes the bucket of l at now after it allowed or
+// denied a request of cost tokens.
+func limiterAllowance(l *rate.Limiter, allowed bool, cost int, now time.Time) Allowance {
+	tokens := l.TokensAt(now)
+	a := Allowance{Allowed: allowed, Remaining: max(0, int(tokens))}
+	switch r := l.Limit(); {
+	case a.Allowed:
+	case r <= 0 || cost > l.Burst():
+		a.RetryAfter = -1
+	default:
+		a.RetryAfter = time.Duration((float64(cost) - tokens) / float64(r) * float64(time.Second))
+	}
+	return a
+}
+
+// takeCosts takes costs from levels with alg in one step, if alg supports
+// it.
+func takeCosts(ctx context.Context, alg Algorithm, levels []Level, costs []int, now time.Time) (Allowance, int, error) {
+	if ca, ok := alg.(CostAlgorithm); ok {
+		return ca.TakeCosts(ctx, levels, costs, now)
+	}
+	if la, ok := alg.(LevelAlgorithm); ok && sameCosts(costs) {
+		return la.TakeLevels(ctx, levels, costs[0], now)
+	}
+	return Allowance{}, 0, ErrCostsUnsupported
+}
+
+// sameCosts reports whether every cost is the same.
+func sameCosts(costs []int) bool {
+	for _, c := range costs {
+		if c != costs[0] {
+			return false
+		}
+	}
+	return true
+}
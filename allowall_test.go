@@ -0,0 +1,76 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestAllowAll(t *testing.T) {
+	ctx := context.Background()
+	keys := []KeyCost{
+		{Key: "user:42", Cost: 1},
+		{Key: "org:acme", Cost: 60, Rate: Per(100, time.Minute), Burst: 100},
+	}
+
+	for name, alg := range map[string]Algorithm{"Token bucket": nil, "GCRA": GCRA()} {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(Options{Rate: Per(10, time.Minute), Burst: 10, Algorithm: alg})
+			a, _, err := m.AllowAll(ctx, keys)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 9, a.Remaining)
+
+			// The organization cannot afford 60 more, so nothing is charged.
+			a, i, err := m.AllowAll(ctx, keys)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, 1, i)
+			assert.Equal(t, 40, a.Remaining)
+			assert.InDelta(t, 12*time.Second, a.RetryAfter, float64(time.Second))
+
+			a, _, err = m.AllowAll(ctx, keys[:1])
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 8, a.Remaining)
+
+			a, _, err = m.AllowAll(ctx, []KeyCost{{Key: "user:7", Cost: 11}})
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, time.Duration(-1), a.RetryAfter)
+		})
+	}
+
+	t.Run("Banned", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1})
+		require.NoError(t, m.Ban(ctx, "org:acme", 0))
+		a, i, err := m.AllowAll(ctx, keys)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 1, i)
+
+		// The user was not charged.
+		a, _, err = m.AllowAll(ctx, keys[:1])
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Inf, Burst: 1, Algorithm: FixedWindow(time.Minute)})
+		_, _, err := m.AllowAll(ctx, keys)
+		assert.ErrorIs(t, err, ErrCostsUnsupported)
+
+		// Equal costs are taken as levels.
+		a, _, err := m.AllowAll(ctx, []KeyCost{{Key: "a"}, {Key: "b", Rate: 1, Burst: 1}})
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// BatchItem is a chunk of work charged by AllowBatch.
+type BatchItem struct {
+	// Key is the rate limiting key the work is done for, as generated by
+	// the KeyFunc of the middleware.
+	Key string
+	// N is the number of tokens the work takes. If less than one, it takes
+	// one.
+	N int
+}
+
+// AllowN takes n tokens from the budgets of key outside of a request, so
+// background jobs draw on the same budgets as the HTTP API: the bucket of
+// key, with its hierarchy, overrides and learned limits, and the global
+// limit and Options.Limits, as a request of key costing n tokens would.
+// Banned keys are denied, and decisions count in the key statistics.
+// Route, organization and duplicate limits depend on the request, and do
+// not apply.
+//
+// Unlike the middleware, AllowN never waits: a denied allowance carries
+// the RetryAfter after which the tokens may be available, or -1 if they
+// never are. Errors of Options.Algorithm are returned.
+func (m *Manager) AllowN(ctx context.Context, key string, n int) (Allowance, error) {
+	now := time.Now()
+	n = max(n, 1)
+	if m.controls.banned(key, now) {
+		m.stats.record(key, "", false, now)
+		return Allowance{RetryAfter: -1}, nil
+	}
+	a, err := m.allowN(ctx, key, n, now)
+	if err != nil {
+		return Allowance{}, err
+	}
+	m.stats.record(key, "", a.Allowed, now)
+	return a, nil
+}
+
+// AllowBatch calls AllowN for every item in order, for jobs processing
+// items of several keys in chunks. Items of the same key see the tokens
+// taken by the earlier ones. It stops at the first error, returning the
+// allowances of the items decided so far.
+func (m *Manager) AllowBatch(ctx context.Context, items []BatchItem) ([]Allowance, error) {
+	as := make([]Allowance, 0, len(items))
+	for _, it := range items {
+		a, err := m.AllowN(ctx, it.Key, it.N)
+		if err != nil {
+			return as, err
+		}
+		as = append(as, a)
+	}
+	return as, nil
+}
+
+// allowN takes n tokens from the buckets of key at now, choosing them as
+// serve does for a request without a route.
+func (m *Manager) allowN(ctx context.Context, key string, n int, now time.Time) (Allowance, error) {
+	r, burst := m.limitsFor(key)
+	levels := []Level{{Key: key, Rate: r, Burst: burst}}
+	switch {
+	case m.layered(key, nil):
+		levels = m.levels(key, r, burst, nil)
+		if m.opts.Algorithm == nil {
+			return m.chargeBuckets(levels, n, now), nil
+		}
+		fallthrough
+	case m.opts.Algorithm != nil && !m.eventual(nil):
+		a, _, err := takeLevels(ctx, m.opts.Algorithm, levels, n, now)
+		return a, err
+	}
+
+	if group := m.group(key, nil); group != "" {
+		l, allowed := m.groups.take(group, key, n, now)
+		return limiterAllowance(l, allowed, n, now), nil
+	}
+	a := m.chargeBuckets(levels, n, now)
+	if a.Allowed && m.eventual(nil) {
+		m.usage.add(m, key, r, burst, n)
+	}
+	return a, nil
+}
+
+// chargeBuckets takes n tokens at now from the token bucket of every
+// level, or from none of them, and returns the allowance of the most
+// restrictive level.
+func (m *Manager) chargeBuckets(levels []Level, n int, now time.Time) Allowance {
+	limiters, denied := m.reserveAll(levels, func(int) int { return n }, now)
+	if denied >= 0 {
+		return limiterAllowance(limiters[denied], false, n, now)
+	}
+	as := make([]Allowance, len(limiters))
+	for i, l := range limiters {
+		as[i] = limiterAllowance(l, true, n, now)
+	}
+	return as[MostRestrictive(as)]
+}
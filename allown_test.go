@@ -0,0 +1,99 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowN(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	for name, alg := range map[string]Algorithm{"Token bucket": nil, "GCRA": GCRA()} {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(Options{
+				Limits: []LimitSpec{
+					{Requests: 10, Window: time.Minute},
+					{Requests: 100, Window: time.Hour},
+				},
+				Algorithm: alg,
+				KeyFunc:   func(c *gin.Context) string { return "user:42" },
+			})
+			r := gin.New()
+			r.Use(m.Handler())
+			r.GET("/", func(c *gin.Context) {})
+
+			// Background jobs and requests share the budget of the key.
+			a, err := m.AllowN(ctx, "user:42", 8)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 2, a.Remaining)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			a, err = m.AllowN(ctx, "user:42", 2)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, 1, a.Remaining)
+			assert.InDelta(t, 6*time.Second, a.RetryAfter, float64(time.Second))
+
+			a, err = m.AllowN(ctx, "user:42", 11)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, time.Duration(-1), a.RetryAfter)
+
+			page, err := m.Keys(KeyQuery{Prefix: "user:42"})
+			require.NoError(t, err)
+			require.Len(t, page.Keys, 1)
+			assert.Equal(t, uint64(4), page.Keys[0].Requests)
+			assert.Equal(t, uint64(2), page.Keys[0].Denied)
+		})
+	}
+
+	t.Run("Banned", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1})
+		require.NoError(t, m.Ban(ctx, "user:42", 0))
+		a, err := m.AllowN(ctx, "user:42", 1)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, time.Duration(-1), a.RetryAfter)
+	})
+
+	t.Run("Algorithm error", func(t *testing.T) {
+		alg := InjectFaults(GCRA(), Faults{ErrorProbability: 1})
+		m := NewManager(Options{Rate: 1, Burst: 1, Algorithm: alg})
+		_, err := m.AllowN(ctx, "user:42", 1)
+		assert.ErrorIs(t, err, ErrInjectedFault)
+	})
+}
+
+func TestAllowBatch(t *testing.T) {
+	m := NewManager(Options{Rate: Per(3, time.Minute), Burst: 3})
+	as, err := m.AllowBatch(context.Background(), []BatchItem{
+		{Key: "user:1", N: 2},
+		{Key: "user:2", N: 3},
+		{Key: "user:1", N: 2},
+		{Key: "user:1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, as, 4)
+	assert.True(t, as[0].Allowed)
+	assert.True(t, as[1].Allowed)
+	assert.False(t, as[2].Allowed)
+	assert.True(t, as[3].Allowed)
+	assert.Zero(t, as[3].Remaining)
+}
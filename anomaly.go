@@ -0,0 +1,189 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Anomalies flags keys whose request rate deviates sharply from their own
+// baseline, even while they stay under their limit, to surface stolen
+// credentials or runaway clients early. Flagged keys are reported with
+// EventAnomalyDetected and in Stats.
+type Anomalies struct {
+	// Interval is the period over which request rates are measured. If
+	// zero, 10 seconds is used.
+	Interval time.Duration
+	// Smoothing is the weight of the latest interval in the baseline, an
+	// exponentially weighted moving average of the rate, between 0 and 1.
+	// If zero, 0.1 is used.
+	Smoothing float64
+	// Factor is how many times its baseline a key's rate must reach to be
+	// flagged. If zero, 10 is used.
+	Factor float64
+	// MinRate is the lowest rate flagged, so keys with a tiny baseline are
+	// not flagged for a handful of requests. If zero, 1 request per second
+	// is used.
+	MinRate rate.Limit
+	// Warmup is the number of intervals a key is measured for before it
+	// can be flagged. If zero, 6 is used.
+	Warmup int
+}
+
+// AnomalyStatus reports a key whose request rate is anomalous.
+type AnomalyStatus struct {
+	Key string `json:"key"`
+	// Rate is the key's request rate, per second.
+	Rate float64 `json:"rate"`
+	// Baseline is the key's usual request rate, per second.
+	Baseline float64 `json:"baseline"`
+	// Since is when the key was flagged.
+	Since time.Time `json:"since"`
+}
+
+// anomalyIdleIntervals is the number of intervals without requests after
+// which a key is forgotten.
+const anomalyIdleIntervals = 100
+
+// keyRate is the request rate of one key.
+type keyRate struct {
+	start    time.Time
+	count    int
+	baseline float64
+	samples  int
+	flagged  time.Time
+	// rate is the highest of the rates of the last closed interval and
+	// of the current one so far.
+	rate float64
+}
+
+// anomalyDetector tracks the request rates of all keys.
+type anomalyDetector struct {
+	cfg Anomalies
+
+	mu    sync.Mutex
+	keys  map[string]*keyRate
+	sweep time.Time
+}
+
+// newAnomalyDetector creates a detector, applying defaults.
+func newAnomalyDetector(cfg Anomalies) *anomalyDetector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = 0.1
+	}
+	if cfg.Factor <= 0 {
+		cfg.Factor = 10
+	}
+	if cfg.MinRate <= 0 {
+		cfg.MinRate = 1
+	}
+	if cfg.Warmup <= 0 {
+		cfg.Warmup = 6
+	}
+	return &anomalyDetector{cfg: cfg, keys: make(map[string]*keyRate)}
+}
+
+// observe counts a request of key at now, returning an event if the key
+// was flagged or recovered.
+func (d *anomalyDetector) observe(key string, now time.Time) (Event, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.After(d.sweep) {
+		d.forget(now)
+		d.sweep = now.Add(anomalyIdleIntervals * d.cfg.Interval)
+	}
+	kr, ok := d.keys[key]
+	if !ok {
+		kr = &keyRate{start: now}
+		d.keys[key] = kr
+	}
+
+	cleared := false
+	if elapsed := int(now.Sub(kr.start) / d.cfg.Interval); elapsed > 0 {
+		// Close the interval, and count the ones without requests since.
+		last := float64(kr.count) / d.cfg.Interval.Seconds()
+		alpha := d.cfg.Smoothing
+		if kr.samples == 0 {
+			kr.baseline = last
+		} else {
+			kr.baseline = alpha*last + (1-alpha)*kr.baseline
+		}
+		if elapsed > 1 {
+			kr.baseline *= math.Pow(1-alpha, float64(elapsed-1))
+			last = 0
+		}
+		kr.samples += elapsed
+		kr.start = kr.start.Add(time.Duration(elapsed) * d.cfg.Interval)
+		kr.count = 0
+		kr.rate = last
+
+		// Clear keys once a whole interval stays below the threshold.
+		if !kr.flagged.IsZero() && last <= d.threshold(kr) {
+			kr.flagged = time.Time{}
+			cleared = true
+		}
+	}
+	kr.count++
+	kr.rate = math.Max(kr.rate, float64(kr.count)/d.cfg.Interval.Seconds())
+
+	if cleared {
+		return Event{
+			Type:    EventAnomalyCleared,
+			Time:    now,
+			Key:     key,
+			Message: fmt.Sprintf("request rate is back near the baseline of %.1f/s", kr.baseline),
+		}, true
+	}
+	// Flag keys as soon as the current interval goes past the threshold.
+	if kr.flagged.IsZero() && kr.samples >= d.cfg.Warmup && kr.rate > d.threshold(kr) {
+		kr.flagged = now
+		return Event{
+			Type: EventAnomalyDetected,
+			Time: now,
+			Key:  key,
+			Message: fmt.Sprintf("request rate %.1f/s is %.1fx the baseline of %.1f/s",
+				kr.rate, kr.rate/math.Max(kr.baseline, 1e-9), kr.baseline),
+		}, true
+	}
+	return Event{}, false
+}
+
+// threshold returns the rate above which kr is anomalous.
+func (d *anomalyDetector) threshold(kr *keyRate) float64 {
+	return math.Max(d.cfg.Factor*kr.baseline, float64(d.cfg.MinRate))
+}
+
+// forget drops the keys idle for anomalyIdleIntervals.
+func (d *anomalyDetector) forget(now time.Time) {
+	for key, kr := range d.keys {
+		if now.Sub(kr.start) > anomalyIdleIntervals*d.cfg.Interval {
+			delete(d.keys, key)
+		}
+	}
+}
+
+// flagged returns the keys currently flagged, sorted by key.
+func (d *anomalyDetector) flagged() []AnomalyStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var list []AnomalyStatus
+	for key, kr := range d.keys {
+		if !kr.flagged.IsZero() {
+			list = append(list, AnomalyStatus{Key: key, Rate: kr.rate, Baseline: kr.baseline, Since: kr.flagged})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	start := time.Now()
+	cfg := Anomalies{Interval: time.Second, Factor: 5, MinRate: 2, Warmup: 3}
+
+	// steady sends perSecond requests a second for the given seconds,
+	// returning the events.
+	steady := func(d *anomalyDetector, from time.Time, seconds, perSecond int) []Event {
+		var events []Event
+		for s := range seconds {
+			for i := range perSecond {
+				at := from.Add(time.Duration(s)*time.Second + time.Duration(i)*time.Second/time.Duration(perSecond))
+				if e, ok := d.observe("k", at); ok {
+					events = append(events, e)
+				}
+			}
+		}
+		return events
+	}
+
+	t.Run("Spike", func(t *testing.T) {
+		d := newAnomalyDetector(cfg)
+		assert.Empty(t, steady(d, start, 10, 2))
+		assert.InDelta(t, 2, d.keys["k"].baseline, 0.01)
+
+		events := steady(d, start.Add(10*time.Second), 1, 50)
+		require.Len(t, events, 1)
+		assert.Equal(t, EventAnomalyDetected, events[0].Type)
+		assert.Equal(t, "k", events[0].Key)
+		flagged := d.flagged()
+		require.Len(t, flagged, 1)
+		assert.Equal(t, "k", flagged[0].Key)
+
+		events = steady(d, start.Add(11*time.Second), 2, 2)
+		require.Len(t, events, 1)
+		assert.Equal(t, EventAnomalyCleared, events[0].Type)
+		assert.Empty(t, d.flagged())
+	})
+
+	t.Run("Warmup and MinRate", func(t *testing.T) {
+		d := newAnomalyDetector(cfg)
+		// A new key is not flagged, however fast it starts.
+		assert.Empty(t, steady(d, start, 2, 50))
+
+		// A key with a tiny baseline is not flagged below MinRate.
+		d = newAnomalyDetector(cfg)
+		d.observe("k", start)
+		assert.Empty(t, steady(d, start.Add(5*time.Second), 1, 2))
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		d := newAnomalyDetector(cfg)
+		d.observe("old", start)
+		d.observe("k", start.Add(time.Second))
+		d.observe("k", start.Add(anomalyIdleIntervals*time.Second+2*time.Second))
+		assert.NotContains(t, d.keys, "old")
+	})
+}
+
+func TestAnomaliesMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var events []Event
+	m := NewManager(Options{
+		Rate:      rate.Inf,
+		Anomalies: &Anomalies{Warmup: 1, MinRate: 1, Interval: time.Hour},
+		OnEvent:   func(e Event) { events = append(events, e) },
+	})
+	// Pretend the key was quiet for an interval.
+	kr := &keyRate{start: time.Now().Add(-time.Hour), samples: 1, baseline: 0.01}
+	m.anomalies.keys["203.0.113.1"] = kr
+
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	for range 3700 {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.Len(t, events, 1)
+	assert.Equal(t, EventAnomalyDetected, events[0].Type)
+	assert.Len(t, m.Stats().Anomalies, 1)
+}
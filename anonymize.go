@@ -0,0 +1,62 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// anonymizedKeyPrefix marks keys replaced by an Anonymizer.
+const anonymizedKeyPrefix = "anon:"
+
+// Anonymizer replaces the keys the manager reports, in events, decisions,
+// key listings and statistics, with keyed hashes, so observability systems
+// get stable identifiers per client without storing raw IPs or user IDs.
+// Limits, controls, audit entries and the quotas of handlers keep using
+// the raw keys.
+type Anonymizer struct {
+	// Secret keys the hash, so identifiers cannot be linked back to keys
+	// by hashing candidates without it. Keep it out of the observability
+	// systems. NewManager panics if it is empty.
+	Secret []byte
+
+	// Rotation changes the identifiers of all keys every period, aligned
+	// to the Unix epoch, so identifiers of different periods cannot be
+	// linked. If zero, identifiers never change.
+	Rotation time.Duration
+}
+
+// Anonymize returns the identifier of key at t, such as
+// "anon:5f2b9c0d1e8a7b43", to find a known key in the reported data.
+func (a Anonymizer) Anonymize(key string, t time.Time) string {
+	mac := hmac.New(sha256.New, a.periodSecret(t))
+	mac.Write([]byte(key))
+	return anonymizedKeyPrefix + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// periodSecret derives the secret of the rotation period of t.
+func (a Anonymizer) periodSecret(t time.Time) []byte {
+	if a.Rotation <= 0 {
+		return a.Secret
+	}
+	var period [8]byte
+	binary.BigEndian.PutUint64(period[:], uint64(t.UnixNano()/int64(a.Rotation)))
+	mac := hmac.New(sha256.New, a.Secret)
+	mac.Write(period[:])
+	return mac.Sum(nil)
+}
+
+// anonymize returns the identifier key is reported under at t: key itself
+// without Options.Anonymize.
+func (m *Manager) anonymize(key string, t time.Time) string {
+	if m.opts.Anonymize == nil || key == "" {
+		return key
+	}
+	return m.opts.Anonymize.Anonymize(key, t)
+}
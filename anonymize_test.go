@@ -0,0 +1,90 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestAnonymizer(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := Anonymizer{Secret: []byte("s3cret")}
+
+	id := a.Anonymize("192.0.2.1", now)
+	assert.True(t, strings.HasPrefix(id, "anon:"))
+	assert.Len(t, id, len("anon:")+16)
+	assert.Equal(t, id, a.Anonymize("192.0.2.1", now.Add(365*24*time.Hour)))
+	assert.NotEqual(t, id, a.Anonymize("192.0.2.2", now))
+	assert.NotEqual(t, id, Anonymizer{Secret: []byte("other")}.Anonymize("192.0.2.1", now))
+
+	t.Run("Rotation", func(t *testing.T) {
+		a := Anonymizer{Secret: []byte("s3cret"), Rotation: 24 * time.Hour}
+		id := a.Anonymize("192.0.2.1", now)
+		assert.Equal(t, id, a.Anonymize("192.0.2.1", now.Add(11*time.Hour)))
+		assert.NotEqual(t, id, a.Anonymize("192.0.2.1", now.Add(12*time.Hour)))
+	})
+}
+
+func TestAnonymize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	anon := &Anonymizer{Secret: []byte("s3cret")}
+	var events []Event
+	m := NewManager(Options{
+		Rate:      rate.Every(time.Hour),
+		Burst:     1,
+		DryRun:    true,
+		Anonymize: anon,
+		OnEvent:   func(e Event) { events = append(events, e) },
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	decisions := m.WatchDecisions(ctx, 2)
+	for range 2 {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		r.ServeHTTP(w, req)
+	}
+
+	id := anon.Anonymize("192.0.2.1", time.Now())
+	for range 2 {
+		assert.Equal(t, id, (<-decisions).Key)
+	}
+	require.Len(t, events, 1)
+	assert.Equal(t, id, events[0].Key)
+	page, err := m.Keys(KeyQuery{Prefix: "192.0.2."})
+	require.NoError(t, err)
+	require.Len(t, page.Keys, 1)
+	assert.Equal(t, id, page.Keys[0].Key)
+
+	// Controls still take raw keys.
+	require.NoError(t, m.Ban(ctx, "192.0.2.1", 0))
+	assert.True(t, m.controls.banned("192.0.2.1", time.Now()))
+
+	b, err := m.ConfigJSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "s3cret")
+
+	assert.Panics(t, func() {
+		NewManager(Options{Rate: 1, Burst: 1, Anonymize: &Anonymizer{}})
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// AuditAction identifies an administrative change recorded in the audit
+// trail.
+type AuditAction string
+
+// Audited actions.
+const (
+	AuditReset         AuditAction = "reset"
+	AuditBan           AuditAction = "ban"
+	AuditUnban         AuditAction = "unban"
+	AuditOverride      AuditAction = "override"
+	AuditClearOverride AuditAction = "clear_override"
+	AuditForget        AuditAction = "forget"
+	AuditNote          AuditAction = "note"
+)
+
+// AuditEntry records an administrative change to the limiter.
+type AuditEntry struct {
+	// Time is when the change was made.
+	Time time.Time `json:"time"`
+	// Actor identifies who made the change; see WithActor.
+	Actor string `json:"actor"`
+	// Action is the kind of change.
+	Action AuditAction `json:"action"`
+	// Key is the rate limiting key that was changed, or empty for
+	// AuditForget.
+	Key string `json:"key"`
+	// Detail holds action-specific parameters, such as the new limits of
+	// an override or the fields of a note.
+	Detail map[string]string `json:"detail,omitempty"`
+}
+
+// AuditSink stores audit entries. Changes are only applied once their
+// entry has been recorded, so a failing sink blocks administrative changes
+// rather than letting them go unrecorded.
+type AuditSink interface {
+	Record(ctx context.Context, e AuditEntry) error
+}
+
+// actorKey is the context key holding the actor of administrative changes.
+type actorKey struct{}
+
+// WithActor returns a context that attributes the administrative changes
+// made with it to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx by WithActor, if any.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// audit records an administrative change with the configured sink.
+func (m *Manager) audit(ctx context.Context, action AuditAction, key string, detail map[string]string) error {
+	if m.opts.AuditSink == nil {
+		return nil
+	}
+	if len(detail) == 0 {
+		detail = nil
+	}
+	return m.opts.AuditSink.Record(ctx, AuditEntry{
+		Time:   time.Now(),
+		Actor:  ActorFrom(ctx),
+		Action: action,
+		Key:    key,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// DefaultBackpressureHeader is the response header used to advertise
+// backpressure when Backpressure.Header is empty.
+const DefaultBackpressureHeader = "X-Backpressure"
+
+// Backpressure configures an advisory response header that reports how close
+// the server is to its global capacity. Smart clients and gateways can use it
+// to reduce their send rate before hard denials start.
+type Backpressure struct {
+	// Header is the name of the response header.
+	// If empty, DefaultBackpressureHeader is used.
+	Header string
+
+	// Capacity is the total request rate the server is expected to sustain
+	// across all clients. Utilization is measured against it. It must be
+	// positive.
+	Capacity rate.Limit
+
+	// Burst is the number of requests above Capacity that can be absorbed
+	// before utilization is reported as full. If zero, Capacity rounded up
+	// to a whole number of requests is used.
+	Burst int
+
+	// Levels are the utilization thresholds and the header value sent once
+	// each threshold is crossed. Utilization ranges from 0 (idle) to 1 (at
+	// capacity). If nil, a "medium" level at 0.7 and a "high" level at 0.9
+	// are used.
+	Levels []BackpressureLevel
+}
+
+// BackpressureLevel is a utilization threshold and the header value
+// advertised once it is crossed.
+type BackpressureLevel struct {
+	// Threshold is the utilization, between 0 and 1, at which the level applies.
+	Threshold float64 `json:"threshold"`
+	// Value is the header value sent while the level applies.
+	Value string `json:"value"`
+}
+
+// backpressureMeter measures global utilization with a token bucket sized to
+// the configured capacity. Every request takes a token; the fraction of the
+// bucket that is empty is the current utilization.
+type backpressureMeter struct {
+	header string
+	levels []BackpressureLevel
+	meter  *rate.Limiter
+}
+
+// newBackpressureMeter creates a meter from the given configuration. It
+// panics if the capacity is not positive, which would report full
+// utilization from the first request on.
+func newBackpressureMeter(bp Backpressure) *backpressureMeter {
+	if bp.Capacity <= 0 {
+		panic(fmt.Sprintf("ratelimit: Backpressure capacity %v is not positive", bp.Capacity))
+	}
+	if bp.Header == "" {
+		bp.Header = DefaultBackpressureHeader
+	}
+	if bp.Burst <= 0 {
+		bp.Burst = int(bp.Capacity)
+		if rate.Limit(bp.Burst) < bp.Capacity {
+			bp.Burst++
+		}
+		if bp.Burst <= 0 {
+			bp.Burst = 1
+		}
+	}
+	levels := bp.Levels
+	if levels == nil {
+		levels = []BackpressureLevel{
+			{Threshold: 0.7, Value: "medium"},
+			{Threshold: 0.9, Value: "high"},
+		}
+	}
+	// Keep the highest threshold first so the first match wins.
+	levels = append([]BackpressureLevel(nil), levels...)
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Threshold > levels[j].Threshold
+	})
+
+	return &backpressureMeter{
+		header: bp.Header,
+		levels: levels,
+		meter:  rate.NewLimiter(bp.Capacity, bp.Burst),
+	}
+}
+
+// utilization records a request at t and returns the resulting utilization.
+func (m *backpressureMeter) utilization(t time.Time) float64 {
+	// The meter never rejects anything; an empty bucket simply means the
+	// server is at or above its capacity.
+	m.meter.AllowN(t, 1)
+	return m.current(t)
+}
+
+// current returns the utilization at t without recording a request.
+func (m *backpressureMeter) current(t time.Time) float64 {
+	burst := float64(m.meter.Burst())
+	tokens := m.meter.TokensAt(t)
+	if tokens < 0 {
+		tokens = 0
+	}
+	return 1 - tokens/burst
+}
+
+// annotate records the request and sets the backpressure header on the
+// response if a level has been crossed.
+func (m *backpressureMeter) annotate(c *gin.Context) {
+	u := m.utilization(time.Now())
+	for _, level := range m.levels {
+		if u >= level.Threshold {
+			c.Header(m.header, level.Value)
+			return
+		}
+	}
+}
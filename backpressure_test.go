@@ -0,0 +1,90 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestBackpressure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("HeaderFollowsUtilization", func(t *testing.T) {
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:  rate.Inf,
+			Burst: 1,
+			Backpressure: &Backpressure{
+				Capacity: rate.Every(time.Hour),
+				Burst:    10,
+				Levels: []BackpressureLevel{
+					{Threshold: 0.65, Value: "medium"},
+					{Threshold: 0.85, Value: "high"},
+				},
+			},
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+
+		var values []string
+		for i := 0; i < 10; i++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			values = append(values, w.Header().Get(DefaultBackpressureHeader))
+		}
+
+		assert.Equal(t, []string{
+			"", "", "", "", "", "", "medium", "medium", "high", "high",
+		}, values)
+	})
+
+	t.Run("SentOnDeniedResponses", func(t *testing.T) {
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:  rate.Every(time.Hour),
+			Burst: 1,
+			Backpressure: &Backpressure{
+				Header:   "X-Load",
+				Capacity: rate.Every(time.Hour),
+				Burst:    1,
+				Levels:   []BackpressureLevel{{Threshold: 0.99, Value: "full"}},
+			},
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "full", w.Header().Get("X-Load"))
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "full", w.Header().Get("X-Load"))
+	})
+
+	t.Run("RejectsNonPositiveCapacity", func(t *testing.T) {
+		assert.Panics(t, func() {
+			New(Options{Rate: rate.Inf, Burst: 1, Backpressure: &Backpressure{}})
+		})
+		assert.Panics(t, func() {
+			New(Options{Rate: rate.Inf, Burst: 1, Backpressure: &Backpressure{Capacity: -1}})
+		})
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// KeySelector selects the keys of a bulk operation.
+type KeySelector struct {
+	// Keys are selected individually.
+	Keys []string `json:"keys"`
+	// Prefixes select every key starting with one of them. Bans and
+	// overrides of a prefix also apply to keys first seen afterwards. With
+	// the default key, "203.0.113." selects the clients of 203.0.113.0/24;
+	// note the trailing dot, without which "10.0.0.1" would also select
+	// 10.0.0.10 to 10.0.0.199.
+	Prefixes []string `json:"prefixes"`
+}
+
+// validate rejects selectors that select nothing, and empty prefixes,
+// which would select every key.
+func (s KeySelector) validate() error {
+	if len(s.Keys) == 0 && len(s.Prefixes) == 0 {
+		return errors.New("ratelimit: no keys or prefixes selected")
+	}
+	for _, prefix := range s.Prefixes {
+		if prefix == "" {
+			return errors.New("ratelimit: empty key prefix")
+		}
+	}
+	return nil
+}
+
+// matchesPrefix reports whether key starts with one of prefixes.
+func matchesPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// detail describes the selection in an audit entry.
+func (s KeySelector) detail(detail map[string]string) map[string]string {
+	if detail == nil {
+		detail = map[string]string{}
+	}
+	if len(s.Keys) > 0 {
+		data, _ := json.Marshal(s.Keys)
+		detail["keys"] = string(data)
+	}
+	if len(s.Prefixes) > 0 {
+		data, _ := json.Marshal(s.Prefixes)
+		detail["prefixes"] = string(data)
+	}
+	return detail
+}
+
+// BulkReset refills the buckets of the selected keys. Prefixes select the
+// keys seen so far, as listed by Keys.
+func (m *Manager) BulkReset(ctx context.Context, sel KeySelector) error {
+	if err := sel.validate(); err != nil {
+		return err
+	}
+	if err := m.audit(ctx, AuditReset, "", sel.detail(nil)); err != nil {
+		return err
+	}
+
+	keys := sel.Keys
+	for _, prefix := range sel.Prefixes {
+		for _, info := range m.stats.matching(prefix) {
+			keys = append(keys, info.Key)
+		}
+	}
+	for _, key := range keys {
+		if m.opts.Algorithm != nil {
+			m.denials.forget(key)
+			if err := m.opts.Algorithm.Reset(ctx, key); err != nil {
+				return err
+			}
+			continue
+		}
+		r, burst := m.limitsFor(key)
+		m.limiters.Set(key, rate.NewLimiter(r, burst))
+	}
+	return nil
+}
+
+// BulkBan bans the selected keys for the given duration, or until they are
+// unbanned if d is zero.
+func (m *Manager) BulkBan(ctx context.Context, sel KeySelector, d time.Duration) error {
+	return m.bulkBan(ctx, sel, time.Time{}, d)
+}
+
+// bulkBan bans the selected keys from start, as ScheduleBan does.
+func (m *Manager) bulkBan(ctx context.Context, sel KeySelector, start time.Time, d time.Duration) error {
+	if err := sel.validate(); err != nil {
+		return err
+	}
+	b, detail := newBan(start, d, time.Now())
+	if err := m.audit(ctx, AuditBan, "", sel.detail(detail)); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		for _, key := range sel.Keys {
+			kc.bans[key] = b
+		}
+		for _, prefix := range sel.Prefixes {
+			kc.prefixBans[prefix] = b
+		}
+	})
+}
+
+// BulkUnban lifts the bans of the selected keys. A prefix lifts its own ban
+// and the bans of every key starting with it.
+func (m *Manager) BulkUnban(ctx context.Context, sel KeySelector) error {
+	if err := sel.validate(); err != nil {
+		return err
+	}
+	if err := m.audit(ctx, AuditUnban, "", sel.detail(nil)); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		for _, key := range sel.Keys {
+			delete(kc.bans, key)
+		}
+		for key := range kc.bans {
+			if matchesPrefix(key, sel.Prefixes) {
+				delete(kc.bans, key)
+			}
+		}
+		for prefix := range kc.prefixBans {
+			if matchesPrefix(prefix, sel.Prefixes) {
+				delete(kc.prefixBans, prefix)
+			}
+		}
+	})
+}
+
+// BulkSetOverride replaces the configured limits of the selected keys.
+func (m *Manager) BulkSetOverride(ctx context.Context, sel KeySelector, o Override) error {
+	if err := sel.validate(); err != nil {
+		return err
+	}
+	if err := m.audit(ctx, AuditOverride, "", sel.detail(overrideDetail(o))); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		for _, key := range sel.Keys {
+			kc.overrides[key] = o
+		}
+		for _, prefix := range sel.Prefixes {
+			kc.prefixOverrides[prefix] = o
+		}
+	})
+}
+
+// BulkClearOverride restores the configured limits of the selected keys. A
+// prefix clears its own override and the overrides of every key starting
+// with it.
+func (m *Manager) BulkClearOverride(ctx context.Context, sel KeySelector) error {
+	if err := sel.validate(); err != nil {
+		return err
+	}
+	if err := m.audit(ctx, AuditClearOverride, "", sel.detail(nil)); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		for _, key := range sel.Keys {
+			delete(kc.overrides, key)
+		}
+		for key := range kc.overrides {
+			if matchesPrefix(key, sel.Prefixes) {
+				delete(kc.overrides, key)
+			}
+		}
+		for prefix := range kc.prefixOverrides {
+			if matchesPrefix(prefix, sel.Prefixes) {
+				delete(kc.prefixOverrides, prefix)
+			}
+		}
+	})
+}
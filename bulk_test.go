@@ -0,0 +1,126 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestBulkOperations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(m *Manager) *gin.Engine {
+		r := gin.New()
+		m.RegisterAdmin(r.Group("/admin"))
+		r.GET("/", m.Handler(), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return r
+	}
+	serve := func(r *gin.Engine, ip string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	admin := func(r *gin.Engine, path, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/bulk"+path, strings.NewReader(body))
+		req.RemoteAddr = "198.51.100.1:1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("BanPrefix", func(t *testing.T) {
+		sink := &memoryAuditSink{}
+		m := NewManager(Options{Rate: rate.Inf, Burst: 1, AuditSink: sink})
+		r := newRouter(m)
+
+		assert.Equal(t, http.StatusNoContent, admin(r, "/ban", `{"prefixes": ["203.0.113."], "keys": ["192.0.2.1"]}`))
+		assert.Equal(t, http.StatusForbidden, serve(r, "203.0.113.7"))
+		assert.Equal(t, http.StatusForbidden, serve(r, "203.0.113.200"))
+		assert.Equal(t, http.StatusForbidden, serve(r, "192.0.2.1"))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.114.7"))
+
+		// Unbanning the /16 lifts the /24 ban it contains.
+		assert.Equal(t, http.StatusNoContent, admin(r, "/unban", `{"prefixes": ["203.0."]}`))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.113.7"))
+		assert.Equal(t, http.StatusForbidden, serve(r, "192.0.2.1"))
+
+		if assert.Len(t, sink.entries, 2) {
+			assert.Equal(t, AuditBan, sink.entries[0].Action)
+			assert.Equal(t, `["203.0.113."]`, sink.entries[0].Detail["prefixes"])
+			assert.Equal(t, `["192.0.2.1"]`, sink.entries[0].Detail["keys"])
+		}
+	})
+
+	t.Run("OverridePrefix", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		r := newRouter(m)
+
+		assert.Equal(t, http.StatusNoContent, admin(r, "/override", `{"prefixes": ["203.0."], "rate": 1, "burst": 2}`))
+		assert.Equal(t, http.StatusNoContent, admin(r, "/override", `{"prefixes": ["203.0.113."], "rate": 1, "burst": 3}`))
+
+		// The longest prefix wins.
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, serve(r, "203.0.113.7"))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "203.0.113.7"))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.5.5"))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.5.5"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "203.0.5.5"))
+
+		assert.Equal(t, http.StatusNoContent, admin(r, "/clear-override", `{"prefixes": ["203."]}`))
+		assert.Empty(t, m.controls.prefixOverrides)
+	})
+
+	t.Run("ResetPrefix", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		r := newRouter(m)
+
+		for _, ip := range []string{"203.0.113.7", "203.0.113.8", "192.0.2.1"} {
+			assert.Equal(t, http.StatusOK, serve(r, ip))
+			assert.Equal(t, http.StatusTooManyRequests, serve(r, ip))
+		}
+		assert.Equal(t, http.StatusNoContent, admin(r, "/reset", `{"prefixes": ["203.0.113."]}`))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.113.7"))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.113.8"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "192.0.2.1"))
+	})
+
+	t.Run("ResetCachedDenials", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:         rate.Every(time.Hour),
+			Burst:        1,
+			Algorithm:    GCRA(),
+			DenyCacheTTL: time.Hour,
+		})
+		r := newRouter(m)
+
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.113.7"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "203.0.113.7"))
+		assert.Equal(t, http.StatusNoContent, admin(r, "/reset", `{"prefixes": ["203.0.113."]}`))
+		assert.Equal(t, http.StatusOK, serve(r, "203.0.113.7"))
+	})
+
+	t.Run("InvalidSelector", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Inf, Burst: 1})
+		r := newRouter(m)
+
+		assert.Equal(t, http.StatusBadRequest, admin(r, "/ban", `{}`))
+		assert.Equal(t, http.StatusBadRequest, admin(r, "/ban", `{"prefixes": [""]}`))
+		assert.Error(t, m.BulkBan(context.Background(), KeySelector{}, 0))
+	})
+}
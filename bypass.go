@@ -0,0 +1,54 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BypassReason identifies why a request bypassed limiting.
+type BypassReason string
+
+// Reasons for bypassing limiting.
+const (
+	// BypassSkip is the reason of requests exempted by Options.Skip.
+	BypassSkip BypassReason = "skip"
+	// BypassAllowlist is the reason of requests from clients allowlisted
+	// by Options.IPLists.
+	BypassAllowlist BypassReason = "allowlist"
+	// BypassExempt is the reason of requests to routes marked with Exempt.
+	BypassExempt BypassReason = "exempt"
+)
+
+// bypass accounts for a request that bypasses limiting, so exemptions stay
+// visible: it is counted in Stats.Bypassed, published to the decision
+// watchers and, with Options.AuditBypasses, reported as an EventBypassed
+// event. Bypassed requests are not identified, so they are attributed to
+// the client IP.
+func (m *Manager) bypass(c *gin.Context, reason BypassReason) {
+	now := time.Now()
+	m.stats.bypass(reason)
+
+	ip := c.ClientIP()
+	if m.decisions.watched() {
+		m.decisions.publish(Decision{
+			Time:    now,
+			Key:     m.anonymize(ip, now),
+			Route:   c.FullPath(),
+			Allowed: true,
+			Bypass:  reason,
+		})
+	}
+	if m.opts.AuditBypasses {
+		m.emit(Event{
+			Type:    EventBypassed,
+			Time:    now,
+			Key:     ip,
+			Message: string(reason) + " " + c.Request.Method + " " + c.Request.URL.Path,
+		})
+	}
+}
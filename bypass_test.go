@@ -0,0 +1,83 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestBypassAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var events []Event
+	m := NewManager(Options{
+		Rate:  rate.Every(time.Hour),
+		Burst: 1,
+		Skip: func(c *gin.Context) bool {
+			return c.Request.URL.Path == "/healthz"
+		},
+		IPLists:       &IPLists{Allow: []string{"10.0.0.0/8"}},
+		AuditBypasses: true,
+		OnEvent:       func(e Event) { events = append(events, e) },
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	for _, path := range []string{"/", "/healthz"} {
+		r.GET(path, func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+	}
+	r.GET("/metrics", Exempt(), func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func(path, addr string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = addr + ":1234"
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	decisions := m.WatchDecisions(ctx, 8)
+
+	serve("/healthz", "203.0.113.1")
+	serve("/healthz", "203.0.113.1")
+	serve("/", "10.1.2.3")
+	serve("/metrics", "203.0.113.2")
+	serve("/", "203.0.113.3")
+
+	st := m.Stats()
+	assert.Equal(t, map[BypassReason]uint64{
+		BypassSkip:      2,
+		BypassAllowlist: 1,
+		BypassExempt:    1,
+	}, st.Bypassed)
+	assert.Equal(t, ClassStats{Requests: 1}, st.Total)
+
+	require.Len(t, events, 4)
+	assert.Equal(t, Event{
+		Type:    EventBypassed,
+		Time:    events[0].Time,
+		Key:     "203.0.113.1",
+		Message: "skip GET /healthz",
+	}, events[0])
+	assert.Equal(t, "allowlist GET /", events[2].Message)
+
+	d := <-decisions
+	assert.Equal(t, BypassSkip, d.Bypass)
+	assert.Equal(t, "203.0.113.1", d.Key)
+	assert.Equal(t, "/healthz", d.Route)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityCert is the identity type assigned by ClientCertResolver.
+const IdentityCert = "cert"
+
+// CertField selects which part of a client certificate identifies the
+// client.
+type CertField int
+
+const (
+	// CertSPKIHash identifies clients by the hex SHA-256 hash of their
+	// certificate's public key, which stays the same across certificate
+	// renewals that keep the key.
+	CertSPKIHash CertField = iota
+	// CertSubject identifies clients by their certificate's subject
+	// distinguished name, which stays the same across key rotations.
+	CertSubject
+)
+
+func (f CertField) id(cert *x509.Certificate) string {
+	if f == CertSubject {
+		return cert.Subject.String()
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
+
+// ClientCertResolver identifies requests by the client certificate
+// presented over mutual TLS. Only certificates the server verified are
+// used, so the tls.Config must set ClientAuth to VerifyClientCertIfGiven
+// or RequireAndVerifyClientCert; requests without one fall through to the
+// next resolver.
+func ClientCertResolver(field CertField) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		state := c.Request.TLS
+		if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+			return Identity{}, false
+		}
+		return Identity{Type: IdentityCert, ID: field.id(state.VerifiedChains[0][0])}, true
+	})
+}
+
+// ClientCertKey returns a KeyFunc that limits clients by their verified
+// client certificate, as ClientCertResolver, and clients without one by
+// their IP address.
+func ClientCertKey(field CertField) func(*gin.Context) string {
+	r := IdentityChain(ClientCertResolver(field), ClientIPResolver())
+	return func(c *gin.Context) string {
+		id, _ := r.Resolve(c)
+		return id.Key()
+	}
+}
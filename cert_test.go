@@ -0,0 +1,68 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCertKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "billing", Organization: []string{"Example"}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &priv.PublicKey, priv)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyFor := func(field CertField, state *tls.ConnectionState) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.RemoteAddr = "203.0.113.7:1234"
+		c.Request.TLS = state
+		return ClientCertKey(field)(c)
+	}
+	verified := &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+
+	t.Run("Verified", func(t *testing.T) {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		assert.Equal(t, "cert:"+hex.EncodeToString(sum[:]), keyFor(CertSPKIHash, verified))
+		assert.Equal(t, "cert:CN=billing,O=Example", keyFor(CertSubject, verified))
+	})
+
+	t.Run("Unverified", func(t *testing.T) {
+		assert.Equal(t, "ip:203.0.113.7", keyFor(CertSPKIHash, nil))
+		assert.Equal(t, "ip:203.0.113.7", keyFor(CertSPKIHash, &tls.ConnectionState{}))
+		// Certificates the server did not verify could be self-signed by anyone.
+		assert.Equal(t, "ip:203.0.113.7", keyFor(CertSubject, &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		}))
+	})
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// classificationKey is the gin context key holding the Classification of
+// the current request.
+const classificationKey = "github.com/gin-contrib/ratelimit/classification"
+
+// Classification is the category of traffic a request belongs to.
+type Classification struct {
+	// Class is the name of the category, for example "api" or "bot".
+	Class string `json:"class"`
+	// Labels holds additional dimensions of the category.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Classifier assigns requests to traffic classes. The middleware classifies
+// every request once, so all subsystems agree on how it is categorized.
+type Classifier interface {
+	Classify(c *gin.Context) Classification
+}
+
+// ClassifierFunc adapts a function to the Classifier interface.
+type ClassifierFunc func(c *gin.Context) Classification
+
+// Classify implements Classifier.
+func (f ClassifierFunc) Classify(c *gin.Context) Classification {
+	return f(c)
+}
+
+// ClassificationFrom returns the classification the middleware assigned to
+// the request, and whether there is one.
+func ClassificationFrom(c *gin.Context) (Classification, bool) {
+	v, ok := c.Get(classificationKey)
+	if !ok {
+		return Classification{}, false
+	}
+	cl, ok := v.(Classification)
+	return cl, ok
+}
+
+// classify runs the configured classifier, if any, and records the result
+// in the context.
+func (m *Manager) classify(c *gin.Context) Classification {
+	if m.opts.Classifier == nil {
+		return Classification{}
+	}
+	cl := m.opts.Classifier.Classify(c)
+	c.Set(classificationKey, cl)
+	return cl
+}
+
+// MethodClassifier classifies requests by HTTP method, such as "GET".
+func MethodClassifier() Classifier {
+	return ClassifierFunc(func(c *gin.Context) Classification {
+		return Classification{
+			Class:  c.Request.Method,
+			Labels: map[string]string{"method": c.Request.Method},
+		}
+	})
+}
+
+// PathGroupClassifier classifies requests by the longest path prefix in
+// groups that matches the request path. Requests that match no prefix are
+// assigned the fallback class.
+func PathGroupClassifier(groups map[string]string, fallback string) Classifier {
+	return ClassifierFunc(func(c *gin.Context) Classification {
+		class, longest := fallback, -1
+		path := c.Request.URL.Path
+		for prefix, group := range groups {
+			if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+				class, longest = group, len(prefix)
+			}
+		}
+		return Classification{
+			Class:  class,
+			Labels: map[string]string{"path_group": class},
+		}
+	})
+}
+
+// botMarkers are User-Agent fragments, in lower case, that identify
+// automated clients.
+var botMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "curl/", "wget/", "python-requests",
+	"python-urllib", "go-http-client", "java/", "okhttp", "libwww", "httpclient",
+	"headless",
+}
+
+// BotClassifier classifies requests as "bot" or "human" with a User-Agent
+// heuristic: requests without a User-Agent, or whose User-Agent names a
+// crawler, HTTP library or headless browser, are considered bots. It is a
+// cheap first-pass signal, not a defense against clients that lie.
+func BotClassifier() Classifier {
+	return ClassifierFunc(func(c *gin.Context) Classification {
+		agent := "human"
+		if isBot(c.Request.UserAgent()) {
+			agent = "bot"
+		}
+		return Classification{
+			Class:  agent,
+			Labels: map[string]string{"agent": agent},
+		}
+	})
+}
+
+// isBot reports whether the User-Agent looks automated.
+func isBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+	ua := strings.ToLower(userAgent)
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// CombineClassifiers runs several classifiers and merges their results.
+// The classes are joined with "/" in order, skipping empty ones, and the
+// labels are merged, later classifiers taking precedence.
+func CombineClassifiers(classifiers ...Classifier) Classifier {
+	return ClassifierFunc(func(c *gin.Context) Classification {
+		var (
+			classes []string
+			labels  = make(map[string]string)
+		)
+		for _, classifier := range classifiers {
+			cl := classifier.Classify(c)
+			if cl.Class != "" {
+				classes = append(classes, cl.Class)
+			}
+			for name, value := range cl.Labels {
+				labels[name] = value
+			}
+		}
+		return Classification{
+			Class:  strings.Join(classes, "/"),
+			Labels: labels,
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestClassifier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classify := func(cl Classifier, method, path, userAgent string) Classification {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest(method, path, nil)
+		if userAgent != "" {
+			c.Request.Header.Set("User-Agent", userAgent)
+		}
+		return cl.Classify(c)
+	}
+
+	t.Run("Method", func(t *testing.T) {
+		cl := classify(MethodClassifier(), "POST", "/", "")
+		assert.Equal(t, "POST", cl.Class)
+		assert.Equal(t, map[string]string{"method": "POST"}, cl.Labels)
+	})
+
+	t.Run("PathGroup", func(t *testing.T) {
+		groups := PathGroupClassifier(map[string]string{
+			"/api":       "api",
+			"/api/admin": "admin",
+		}, "web")
+
+		assert.Equal(t, "api", classify(groups, "GET", "/api/users", "").Class)
+		assert.Equal(t, "admin", classify(groups, "GET", "/api/admin/keys", "").Class)
+		assert.Equal(t, "web", classify(groups, "GET", "/index.html", "").Class)
+	})
+
+	t.Run("Bot", func(t *testing.T) {
+		bots := BotClassifier()
+
+		assert.Equal(t, "bot", classify(bots, "GET", "/", "").Class)
+		assert.Equal(t, "bot", classify(bots, "GET", "/", "Googlebot/2.1").Class)
+		assert.Equal(t, "bot", classify(bots, "GET", "/", "curl/8.4.0").Class)
+		assert.Equal(t, "human", classify(bots, "GET", "/",
+			"Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/120.0").Class)
+	})
+
+	t.Run("Combine", func(t *testing.T) {
+		cl := classify(CombineClassifiers(
+			PathGroupClassifier(map[string]string{"/api": "api"}, ""),
+			BotClassifier(),
+		), "GET", "/api/users", "python-requests/2.31")
+
+		assert.Equal(t, "api/bot", cl.Class)
+		assert.Equal(t, map[string]string{"path_group": "api", "agent": "bot"}, cl.Labels)
+	})
+
+	t.Run("SharedWithKeyFuncAndHandlers", func(t *testing.T) {
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:       rate.Every(time.Hour),
+			Burst:      1,
+			Classifier: BotClassifier(),
+			KeyFunc: func(c *gin.Context) string {
+				cl, _ := ClassificationFrom(c)
+				return cl.Class
+			},
+		}))
+		r.GET("/", func(c *gin.Context) {
+			cl, ok := ClassificationFrom(c)
+			assert.True(t, ok)
+			c.String(http.StatusOK, cl.Class)
+		})
+
+		serve := func(userAgent string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("User-Agent", userAgent)
+			r.ServeHTTP(w, req)
+			return w
+		}
+
+		// All bots share one bucket, separate from humans.
+		assert.Equal(t, "bot", serve("curl/8.4.0").Body.String())
+		assert.Equal(t, http.StatusTooManyRequests, serve("Wget/1.21").Code)
+		assert.Equal(t, "human", serve("Mozilla/5.0").Body.String())
+	})
+}
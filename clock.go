@@ -0,0 +1,155 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClockJumpThreshold is the smallest drift between the wall clock and the
+// monotonic clock reported as a clock jump.
+const ClockJumpThreshold = time.Second
+
+// clockCheckInterval is how often the clocks are compared.
+const clockCheckInterval = time.Second
+
+// ClockStats reports the wall-clock jumps the manager detected, as caused
+// by VM migrations or NTP corrections, and the skew its Algorithm observed.
+// In-process limiters measure time with the monotonic clock and are
+// unaffected, but timestamps shared with other processes, such as those
+// kept by distributed stores, are wall-clock times and shift with every
+// jump.
+type ClockStats struct {
+	// Jumps is the number of jumps detected.
+	Jumps uint64 `json:"jumps"`
+	// LastJump is how far the wall clock moved on its own in the last
+	// jump, such as "-1h0m0s" for a correction one hour back.
+	LastJump string `json:"lastJump"`
+	// LastJumpAt is when the last jump was detected.
+	LastJumpAt time.Time `json:"lastJumpAt"`
+	// Skew is the skew observed by the Algorithm, if it is a
+	// SkewReporter observing it.
+	Skew *SkewStats `json:"skew,omitempty"`
+}
+
+// SkewStats reports the skew between the clock of the process and the
+// clock of a distributed store.
+type SkewStats struct {
+	// Tolerance is the skew tolerated before requests are timed by the
+	// store's clock instead.
+	Tolerance string `json:"tolerance"`
+	// Last is the last skew observed: how far the clock of the process is
+	// ahead of the store's, negative if it is behind, including the
+	// latency to the store.
+	Last string `json:"last"`
+	// Max is the largest skew observed, ahead or behind.
+	Max string `json:"max"`
+	// Samples is the number of requests the skew was observed on.
+	Samples uint64 `json:"samples"`
+	// Corrected is the number of requests timed by the store's clock
+	// because the skew exceeded the tolerance.
+	Corrected uint64 `json:"corrected"`
+}
+
+// SkewReporter is implemented by Algorithms observing the skew between the
+// clock of the process and that of their store, which Manager.Stats then
+// reports under Clock.
+type SkewReporter interface {
+	// Skew returns the skew observed so far, or nil if the algorithm does
+	// not observe it.
+	Skew() *SkewStats
+}
+
+// clockWatch detects wall-clock jumps by comparing the time elapsed on the
+// wall clock with the time elapsed on the monotonic clock.
+type clockWatch struct {
+	base time.Time
+	// next is the monotonic offset from base of the next comparison.
+	next atomic.Int64
+
+	mu    sync.Mutex
+	wall  time.Time
+	mono  time.Duration
+	stats ClockStats
+}
+
+func newClockWatch(now time.Time) *clockWatch {
+	w := &clockWatch{base: now, wall: now.Round(0)}
+	w.next.Store(int64(clockCheckInterval))
+	return w
+}
+
+// observe compares the clocks at most once per clockCheckInterval and
+// returns the drift since the last comparison if it is a jump.
+func (w *clockWatch) observe(now time.Time) (time.Duration, bool) {
+	mono := now.Sub(w.base)
+	if int64(mono) < w.next.Load() {
+		return 0, false
+	}
+	// Round(0) strips the monotonic reading, leaving the wall clock.
+	return w.observeAt(now.Round(0), mono)
+}
+
+func (w *clockWatch) observeAt(wall time.Time, mono time.Duration) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if mono-w.mono < clockCheckInterval {
+		// Another request compared the clocks first.
+		return 0, false
+	}
+	drift := wall.Sub(w.wall) - (mono - w.mono)
+	w.wall, w.mono = wall, mono
+	w.next.Store(int64(mono + clockCheckInterval))
+	if drift.Abs() < ClockJumpThreshold {
+		return 0, false
+	}
+	w.stats.Jumps++
+	w.stats.LastJump = drift.String()
+	w.stats.LastJumpAt = wall
+	return drift, true
+}
+
+// snapshot returns the jumps detected so far, or nil if there were none.
+func (w *clockWatch) snapshot() *ClockStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stats.Jumps == 0 {
+		return nil
+	}
+	st := w.stats
+	return &st
+}
+
+// clockStats returns the jumps detected so far and the skew observed by
+// the Algorithm, or nil if there is neither.
+func (m *Manager) clockStats() *ClockStats {
+	st := m.clock.snapshot()
+	sr, ok := m.opts.Algorithm.(SkewReporter)
+	if !ok {
+		return st
+	}
+	skew := sr.Skew()
+	if skew == nil {
+		return st
+	}
+	if st == nil {
+		st = &ClockStats{}
+	}
+	st.Skew = skew
+	return st
+}
+
+// watchClock reports wall-clock jumps as EventClockJump events.
+func (m *Manager) watchClock(now time.Time) {
+	if drift, ok := m.clock.observe(now); ok {
+		m.emit(Event{
+			Type:    EventClockJump,
+			Time:    now,
+			Message: "wall clock jumped by " + drift.String(),
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockWatch(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := newClockWatch(start)
+	w.wall = start
+
+	t.Run("Steady", func(t *testing.T) {
+		_, ok := w.observeAt(start.Add(2*time.Second), 2*time.Second)
+		assert.False(t, ok)
+		// Small drift, as from NTP slewing, is not a jump.
+		_, ok = w.observeAt(start.Add(4100*time.Millisecond), 4*time.Second)
+		assert.False(t, ok)
+		assert.Nil(t, w.snapshot())
+	})
+
+	t.Run("Backwards", func(t *testing.T) {
+		drift, ok := w.observeAt(start.Add(-time.Hour), 6*time.Second)
+		assert.True(t, ok)
+		assert.Equal(t, -time.Hour-6100*time.Millisecond, drift)
+
+		st := w.snapshot()
+		require.NotNil(t, st)
+		assert.Equal(t, uint64(1), st.Jumps)
+		assert.Equal(t, drift.String(), st.LastJump)
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		// Comparisons closer together than the check interval are skipped.
+		_, ok := w.observeAt(start.Add(time.Hour), 6500*time.Millisecond)
+		assert.False(t, ok)
+	})
+
+	t.Run("Event", func(t *testing.T) {
+		var events []Event
+		m := NewManager(Options{OnEvent: func(e Event) { events = append(events, e) }})
+		now := time.Now()
+		m.clock.wall = m.clock.wall.Add(-time.Minute)
+		m.watchClock(now.Add(clockCheckInterval))
+
+		require.Len(t, events, 1)
+		assert.Equal(t, EventClockJump, events[0].Type)
+		assert.Equal(t, uint64(1), m.Stats().Clock.Jumps)
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Command ratelimitctl checks rate limiting configuration files:
+//
+//	ratelimitctl validate config.yaml
+//
+// It prints every finding of ratelimit.ValidateConfig and exits with
+// status 1 if one is an error. Only the in-memory store is built in; build
+// the command with the import of another backend, such as redisstore, to
+// check that its DSNs are reachable.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+)
+
+// storeTimeout bounds the check that the store is reachable.
+const storeTimeout = 5 * time.Second
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 || args[0] != "validate" {
+		fmt.Fprintln(stderr, "usage: ratelimitctl validate <config.yaml>")
+		return 2
+	}
+	cfg, err := ratelimit.LoadConfig(args[1])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	status := 0
+	for _, f := range ratelimit.ValidateConfig(ctx, cfg) {
+		fmt.Fprintln(stdout, f)
+		if f.Severity == ratelimit.SeverityError {
+			status = 1
+		}
+	}
+	if status == 0 {
+		fmt.Fprintf(stdout, "%s: ok\n", args[1])
+	}
+	return status
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ColdStart makes buckets created shortly after the manager starts begin
+// partially filled. Without it every key gets a full bucket at once after
+// a deploy, letting all clients burst against downstreams in lockstep.
+type ColdStart struct {
+	// Window is how long after the manager is created buckets start
+	// partially filled. The fill grows linearly to a full bucket over the
+	// window.
+	Window time.Duration
+
+	// Fill is the fraction of the burst, between 0 and 1, that buckets
+	// created when the manager starts hold.
+	Fill float64
+
+	// Stagger gives every key its own starting fill, spread evenly between
+	// Fill and a full bucket, so keys do not run dry or refill together.
+	Stagger bool
+}
+
+// coldStart applies a ColdStart to new buckets.
+type coldStart struct {
+	cfg   ColdStart
+	start time.Time
+}
+
+// newColdStart creates a cold start beginning at start.
+func newColdStart(cfg ColdStart, start time.Time) *coldStart {
+	cfg.Fill = math.Max(0, math.Min(cfg.Fill, 1))
+	return &coldStart{cfg: cfg, start: start}
+}
+
+// fill returns the fraction of its burst the new bucket of key starts with.
+func (s *coldStart) fill(key string, now time.Time) float64 {
+	elapsed := now.Sub(s.start)
+	if elapsed >= s.cfg.Window {
+		return 1
+	}
+	f := s.cfg.Fill
+	if s.cfg.Stagger {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		f += (1 - f) * float64(h.Sum64()) / math.MaxUint64
+	}
+	return f + (1-f)*float64(max(0, elapsed))/float64(s.cfg.Window)
+}
+
+// newLimiter creates the limiter of key, partially filled while the cold
+// start lasts.
+func (s *coldStart) newLimiter(key string, r rate.Limit, burst int, now time.Time) *rate.Limiter {
+	limiter := rate.NewLimiter(r, burst)
+	if spent := burst - int(math.Round(s.fill(key, now)*float64(burst))); spent > 0 {
+		limiter.AllowN(now, spent)
+	}
+	return limiter
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestColdStart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	start := time.Now()
+
+	t.Run("Fill", func(t *testing.T) {
+		cs := newColdStart(ColdStart{Window: time.Minute, Fill: 0.2}, start)
+		assert.InDelta(t, 0.2, cs.fill("a", start), 1e-9)
+		assert.InDelta(t, 0.6, cs.fill("a", start.Add(30*time.Second)), 1e-9)
+		assert.InDelta(t, 1, cs.fill("a", start.Add(time.Minute)), 1e-9)
+
+		l := cs.newLimiter("a", rate.Every(time.Hour), 10, start)
+		assert.InDelta(t, 2, l.TokensAt(start), 1e-9)
+		l = cs.newLimiter("a", rate.Every(time.Hour), 10, start.Add(2*time.Minute))
+		assert.InDelta(t, 10, l.TokensAt(start.Add(2*time.Minute)), 1e-9)
+	})
+
+	t.Run("Stagger", func(t *testing.T) {
+		cs := newColdStart(ColdStart{Window: time.Minute, Fill: 0.5, Stagger: true}, start)
+		fills := map[float64]bool{}
+		for i := range 20 {
+			key := fmt.Sprintf("client-%d", i)
+			f := cs.fill(key, start)
+			assert.GreaterOrEqual(t, f, 0.5)
+			assert.LessOrEqual(t, f, 1.0)
+			assert.Equal(t, f, cs.fill(key, start), "fill is stable per key")
+			fills[f] = true
+		}
+		assert.Greater(t, len(fills), 10)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:      rate.Every(time.Hour),
+			Burst:     10,
+			ColdStart: &ColdStart{Window: time.Hour, Fill: 0.3},
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+
+		allowed := 0
+		for range 10 {
+			req, _ := http.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code == http.StatusOK {
+				allowed++
+			}
+		}
+		assert.Equal(t, 3, allowed)
+	})
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const (
+		goroutines = 2000
+		requests   = 10
+	)
+
+	newRouter := func(opts Options) *gin.Engine {
+		r := gin.New()
+		r.Use(New(opts))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return r
+	}
+
+	// hammer sends requests from many goroutines at once, letting keyOf
+	// pick the key of each goroutine, and returns the number of allowed
+	// requests per key along with the elapsed time.
+	hammer := func(r *gin.Engine, keyOf func(g int) string) (map[string]int, time.Duration) {
+		var (
+			mu      sync.Mutex
+			allowed = make(map[string]int)
+			wg      sync.WaitGroup
+			start   = make(chan struct{})
+		)
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				<-start
+				for i := 0; i < requests; i++ {
+					w := httptest.NewRecorder()
+					req, _ := http.NewRequest("GET", "/", nil)
+					req.Header.Set("X-API-KEY", key)
+					r.ServeHTTP(w, req)
+					if w.Code == http.StatusOK {
+						mu.Lock()
+						allowed[key]++
+						mu.Unlock()
+					}
+				}
+			}(keyOf(g))
+		}
+		begin := time.Now()
+		close(start)
+		wg.Wait()
+		return allowed, time.Since(begin)
+	}
+
+	headerKey := func(c *gin.Context) string {
+		return c.GetHeader("X-API-KEY")
+	}
+
+	t.Run("SharedKeyNeverExceedsBudget", func(t *testing.T) {
+		limit, burst := rate.Limit(1000), 50
+		// The limiter is created up front: this test checks token
+		// accounting, not concurrent limiter creation.
+		store := newMemoryStore()
+		store.Set("shared", rate.NewLimiter(limit, burst))
+		r := newRouter(Options{
+			Rate:    limit,
+			Burst:   burst,
+			KeyFunc: headerKey,
+			Store:   store,
+		})
+
+		allowed, elapsed := hammer(r, func(int) string { return "shared" })
+
+		budget := float64(burst) + float64(limit)*elapsed.Seconds()
+		assert.LessOrEqual(t, float64(allowed["shared"]), budget)
+		assert.GreaterOrEqual(t, allowed["shared"], burst)
+	})
+
+	t.Run("NewKeyCreatedOnce", func(t *testing.T) {
+		burst := 50
+		r := newRouter(Options{
+			Rate:    rate.Every(time.Hour),
+			Burst:   burst,
+			KeyFunc: headerKey,
+		})
+
+		// Every goroutine races to create the limiter of the key.
+		allowed, _ := hammer(r, func(int) string { return "new" })
+		assert.Equal(t, burst, allowed["new"])
+	})
+
+	t.Run("DistinctKeysAreIsolated", func(t *testing.T) {
+		burst := 3
+		r := newRouter(Options{
+			Rate:    rate.Every(time.Hour),
+			Burst:   burst,
+			KeyFunc: headerKey,
+		})
+
+		allowed, _ := hammer(r, func(g int) string { return fmt.Sprintf("key-%d", g) })
+
+		assert.Len(t, allowed, goroutines)
+		for key, n := range allowed {
+			if !assert.Equal(t, burst, n, key) {
+				break
+			}
+		}
+	})
+
+	t.Run("MemoryStoreGetSet", func(t *testing.T) {
+		store := newMemoryStore()
+		var (
+			wg    sync.WaitGroup
+			found atomic.Int64
+		)
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				key := fmt.Sprintf("key-%d", g%100)
+				for i := 0; i < requests; i++ {
+					if _, ok := store.Get(key); ok {
+						found.Add(1)
+						continue
+					}
+					store.Set(key, rate.NewLimiter(rate.Inf, 1))
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		assert.Len(t, store.limiters, 100)
+		assert.Positive(t, found.Load())
+	})
+}
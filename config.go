@@ -0,0 +1,451 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/time/rate"
+)
+
+// Values reported for function-valued options in the effective configuration.
+const (
+	configDefault = "default"
+	configCustom  = "custom"
+)
+
+// effectiveConfig is the serializable form of a manager's resolved options.
+type effectiveConfig struct {
+	Rate            jsonLimit           `json:"rate"`
+	Burst           int                 `json:"burst"`
+	Limits          []string            `json:"limits,omitempty"`
+	Global          string              `json:"global,omitempty"`
+	MaxDelay        string              `json:"maxDelay"`
+	Allowance       string              `json:"handlerAllowance,omitempty"`
+	MaxQueue        int                 `json:"maxQueue,omitempty"`
+	FastPath        bool                `json:"fastPath,omitempty"`
+	Serverless      bool                `json:"serverless,omitempty"`
+	DryRun          bool                `json:"dryRun,omitempty"`
+	Tuning          *tuningConfig       `json:"tuning,omitempty"`
+	AuditBypasses   bool                `json:"auditBypasses,omitempty"`
+	Anonymize       *anonymizeConfig    `json:"anonymize,omitempty"`
+	QuotaNotices    *quotaNoticesConfig `json:"quotaNotifications,omitempty"`
+	Identity        string              `json:"identity,omitempty"`
+	Classifier      string              `json:"classifier,omitempty"`
+	Signatures      *signaturesConfig   `json:"signatures,omitempty"`
+	KeyFunc         string              `json:"keyFunc"`
+	Skip            string              `json:"skip,omitempty"`
+	IPLists         *ipListsConfig      `json:"ipLists,omitempty"`
+	MaxKeyLength    int                 `json:"maxKeyLength"`
+	IdleTTL         string              `json:"idleTTL,omitempty"`
+	MaxKeys         int                 `json:"maxKeys,omitempty"`
+	CostFunc        string              `json:"costFunc,omitempty"`
+	Store           string              `json:"store"`
+	Algorithm       string              `json:"algorithm,omitempty"`
+	Faults          *faultsConfig       `json:"faults,omitempty"`
+	DenyCacheTTL    string              `json:"denyCacheTTL,omitempty"`
+	Consistency     string              `json:"consistency,omitempty"`
+	SyncInterval    string              `json:"syncInterval,omitempty"`
+	OnLimitExceeded string              `json:"onLimitExceeded"`
+	Headers         string              `json:"headers"`
+	RetryAfterDate  bool                `json:"retryAfterDate,omitempty"`
+	Backpressure    *backpressureConfig `json:"backpressure,omitempty"`
+	Duplicates      *duplicatesConfig   `json:"duplicates,omitempty"`
+	Retries         *retriesConfig      `json:"retries,omitempty"`
+	ColdStart       *coldStartConfig    `json:"coldStart,omitempty"`
+	Groups          *groupsConfig       `json:"groups,omitempty"`
+	Organization    *organizationConfig `json:"organization,omitempty"`
+	Hierarchy       string              `json:"hierarchy,omitempty"`
+	Regions         *regionsConfig      `json:"regions,omitempty"`
+	Guardrails      []guardrailConfig   `json:"guardrails,omitempty"`
+	Anomalies       *anomaliesConfig    `json:"anomalies,omitempty"`
+	MemoryStats     bool                `json:"memoryStats,omitempty"`
+	OnEvent         string              `json:"onEvent,omitempty"`
+	AuditSink       string              `json:"auditSink,omitempty"`
+	Retention       *retentionConfig    `json:"retention,omitempty"`
+	SharedControls  string              `json:"sharedControls,omitempty"`
+	AdminAuth       *adminAuthConfig    `json:"adminAuth,omitempty"`
+}
+
+// adminAuthConfig is the serializable form of the AdminAuth options.
+type adminAuthConfig struct {
+	ValidateToken string                       `json:"validateToken"`
+	Authorize     string                       `json:"authorize"`
+	Roles         map[string][]AdminPermission `json:"roles,omitempty"`
+}
+
+// tuningConfig is the serializable form of the resolved Tuning options.
+type tuningConfig struct {
+	TargetDenial float64 `json:"targetDenial"`
+	MaxKeys      int     `json:"maxKeys"`
+	MaxSamples   int     `json:"maxSamples"`
+}
+
+// retentionConfig is the serializable form of the Retention options.
+type retentionConfig struct {
+	Keys     string `json:"keys"`
+	Controls string `json:"controls"`
+	Audit    string `json:"audit"`
+}
+
+// anonymizeConfig is the serializable form of the Anonymize options,
+// without the secret.
+type anonymizeConfig struct {
+	Rotation string `json:"rotation"`
+}
+
+// quotaNoticesConfig is the serializable form of the resolved
+// QuotaNotifications options.
+type quotaNoticesConfig struct {
+	Notifier   string    `json:"notifier"`
+	Thresholds []float64 `json:"thresholds"`
+	Period     string    `json:"period,omitempty"`
+}
+
+// ipListsConfig is the serializable form of the resolved IPLists options.
+// The lists can be long, so only their sizes are reported.
+type ipListsConfig struct {
+	Allow      int `json:"allow"`
+	Deny       int `json:"deny"`
+	DenyStatus int `json:"denyStatus"`
+}
+
+// signaturesConfig is the serializable form of the resolved Signatures
+// options.
+type signaturesConfig struct {
+	Rate    jsonLimit `json:"rate"`
+	Burst   int       `json:"burst"`
+	MaxSkew string    `json:"maxSkew"`
+	MaxBody int64     `json:"maxBody"`
+}
+
+// guardrailConfig is the serializable form of a resolved Guardrail.
+type guardrailConfig struct {
+	Class       string   `json:"class"`
+	MaxLatency  string   `json:"maxLatency"`
+	Objective   float64  `json:"objective"`
+	BurnRate    float64  `json:"burnRate"`
+	Window      string   `json:"window"`
+	MinRequests int      `json:"minRequests"`
+	Tighten     []string `json:"tighten"`
+	Factor      float64  `json:"factor"`
+}
+
+// faultsConfig is the serializable form of the Faults options.
+type faultsConfig struct {
+	Latency            string  `json:"latency"`
+	LatencyProbability float64 `json:"latencyProbability"`
+	Error              string  `json:"error"`
+	ErrorProbability   float64 `json:"errorProbability"`
+	ClockSkew          string  `json:"clockSkew"`
+	SkewProbability    float64 `json:"skewProbability"`
+}
+
+// duplicatesConfig is the serializable form of the resolved Duplicates
+// options.
+type duplicatesConfig struct {
+	Rate       jsonLimit `json:"rate"`
+	Burst      int       `json:"burst"`
+	BodyPrefix int       `json:"bodyPrefix"`
+}
+
+// retriesConfig is the serializable form of the Retries options.
+type retriesConfig struct {
+	Header string    `json:"header"`
+	Cost   int       `json:"cost,omitempty"`
+	Rate   jsonLimit `json:"rate,omitempty"`
+	Burst  int       `json:"burst,omitempty"`
+}
+
+// coldStartConfig is the serializable form of the resolved ColdStart
+// options.
+type coldStartConfig struct {
+	Window  string  `json:"window"`
+	Fill    float64 `json:"fill"`
+	Stagger bool    `json:"stagger"`
+}
+
+// groupsConfig is the serializable form of the resolved Groups options.
+type groupsConfig struct {
+	Rate     jsonLimit `json:"rate"`
+	Burst    int       `json:"burst"`
+	MinRate  jsonLimit `json:"minRate"`
+	MinBurst int       `json:"minBurst"`
+	Idle     string    `json:"idle"`
+}
+
+// organizationConfig is the serializable form of the Organization options.
+type organizationConfig struct {
+	Key   string    `json:"key"`
+	Rate  jsonLimit `json:"rate"`
+	Burst int       `json:"burst"`
+}
+
+// anomaliesConfig is the serializable form of the resolved Anomalies
+// options.
+type anomaliesConfig struct {
+	Interval  string    `json:"interval"`
+	Smoothing float64   `json:"smoothing"`
+	Factor    float64   `json:"factor"`
+	MinRate   jsonLimit `json:"minRate"`
+	Warmup    int       `json:"warmup"`
+}
+
+// regionsConfig is the serializable form of the resolved Regions options.
+type regionsConfig struct {
+	Local  string             `json:"local"`
+	Shares map[string]float64 `json:"shares"`
+	Ledger string             `json:"ledger,omitempty"`
+}
+
+// backpressureConfig is the serializable form of the resolved Backpressure
+// options.
+type backpressureConfig struct {
+	Header   string              `json:"header"`
+	Capacity jsonLimit           `json:"capacity"`
+	Burst    int                 `json:"burst"`
+	Levels   []BackpressureLevel `json:"levels"`
+}
+
+// jsonLimit encodes a rate.Limit as a number of events per second, or as
+// the string "Inf" for an unlimited rate, which JSON numbers cannot hold.
+type jsonLimit rate.Limit
+
+// MarshalJSON implements json.Marshaler.
+func (l jsonLimit) MarshalJSON() ([]byte, error) {
+	if rate.Limit(l) == rate.Inf {
+		return []byte(`"Inf"`), nil
+	}
+	return json.Marshal(float64(l))
+}
+
+// newEffectiveConfig captures the options as given by the caller, before
+// defaults are applied, so customized function options can be told apart.
+func newEffectiveConfig(opts Options) effectiveConfig {
+	return effectiveConfig{
+		Rate:            jsonLimit(opts.Rate),
+		Burst:           opts.Burst,
+		Limits:          specStrings(opts.Limits),
+		MaxDelay:        opts.MaxDelay.String(),
+		MaxQueue:        opts.MaxQueue,
+		MaxKeys:         opts.MaxKeys,
+		FastPath:        opts.FastPath,
+		Serverless:      opts.Serverless,
+		DryRun:          opts.DryRun,
+		AuditBypasses:   opts.AuditBypasses,
+		KeyFunc:         describeFunc(opts.KeyFunc != nil),
+		OnLimitExceeded: describeFunc(opts.OnLimitExceeded != nil),
+		Headers:         opts.Headers.String(),
+		RetryAfterDate:  opts.RetryAfterDate,
+	}
+}
+
+// resolve fills in the parts of the configuration that are only known once
+// the manager has applied its defaults.
+func (c *effectiveConfig) resolve(m *Manager) {
+	if a := m.opts.HandlerAllowance; a > 0 {
+		c.Allowance = a.String()
+	}
+	if ttl := m.opts.IdleTTL; ttl > 0 {
+		c.IdleTTL = ttl.String()
+	}
+	if a := m.opts.Anonymize; a != nil {
+		// The secret is never reported.
+		c.Anonymize = &anonymizeConfig{Rotation: a.Rotation.String()}
+	}
+	if m.opts.Global != nil {
+		c.Global = m.opts.Global.String()
+	}
+	if m.notices != nil {
+		cfg := m.notices.cfg
+		c.QuotaNotices = &quotaNoticesConfig{
+			Notifier:   fmt.Sprintf("%T", cfg.Notifier),
+			Thresholds: cfg.Thresholds,
+		}
+		if cfg.Period > 0 {
+			c.QuotaNotices.Period = cfg.Period.String()
+		}
+	}
+	if m.opts.Skip != nil {
+		c.Skip = configCustom
+	}
+	if m.opts.CostFunc != nil {
+		c.CostFunc = configCustom
+	}
+	if m.opts.Identity != nil {
+		c.Identity = configCustom
+	}
+	if m.opts.Classifier != nil {
+		c.Classifier = configCustom
+	}
+	if v := m.signatures; v != nil {
+		c.Signatures = &signaturesConfig{
+			Rate:    jsonLimit(v.cfg.Rate),
+			Burst:   v.cfg.Burst,
+			MaxSkew: v.cfg.MaxSkew.String(),
+			MaxBody: v.cfg.MaxBody,
+		}
+	}
+	if t := m.tuner; t != nil {
+		c.Tuning = &tuningConfig{
+			TargetDenial: t.cfg.TargetDenial,
+			MaxKeys:      t.cfg.MaxKeys,
+			MaxSamples:   t.cfg.MaxSamples,
+		}
+	}
+	if l := m.ipLists; l != nil {
+		c.IPLists = &ipListsConfig{
+			Allow:      len(l.cfg.Allow),
+			Deny:       len(l.cfg.Deny),
+			DenyStatus: l.cfg.DenyStatus,
+		}
+	}
+	c.MaxKeyLength = m.opts.MaxKeyLength
+	c.Store = fmt.Sprintf("%T", m.opts.Store)
+	alg := m.opts.Algorithm
+	if f, ok := alg.(*faultAlgorithm); ok {
+		c.Faults = &faultsConfig{
+			Latency:            f.cfg.Latency.String(),
+			LatencyProbability: f.cfg.LatencyProbability,
+			Error:              f.cfg.Error.Error(),
+			ErrorProbability:   f.cfg.ErrorProbability,
+			ClockSkew:          f.cfg.ClockSkew.String(),
+			SkewProbability:    f.cfg.SkewProbability,
+		}
+		alg = f.alg
+	}
+	if _, allow := alg.(allowAlgorithm); alg != nil && !allow {
+		c.Algorithm = fmt.Sprintf("%T", alg)
+	}
+	if d := m.denials; d != nil && alg != nil {
+		c.DenyCacheTTL = d.maxTTL.String()
+	}
+	if alg != nil {
+		c.Consistency = m.opts.Consistency.String()
+		if !m.opts.Serverless {
+			c.SyncInterval = m.usage.interval.String()
+		}
+	}
+	if bp := m.backpressure; bp != nil {
+		c.Backpressure = &backpressureConfig{
+			Header:   bp.header,
+			Capacity: jsonLimit(bp.meter.Limit()),
+			Burst:    bp.meter.Burst(),
+			Levels:   bp.levels,
+		}
+	}
+	if d := m.duplicates; d != nil {
+		c.Duplicates = &duplicatesConfig{
+			Rate:       jsonLimit(d.cfg.Rate),
+			Burst:      d.cfg.Burst,
+			BodyPrefix: d.cfg.BodyPrefix,
+		}
+	}
+	if rt := m.opts.Retries; rt != nil {
+		c.Retries = &retriesConfig{
+			Header: cmp.Or(rt.Header, DefaultRetryHeader),
+			Cost:   rt.Cost,
+			Rate:   jsonLimit(rt.Rate),
+			Burst:  rt.Burst,
+		}
+	}
+	if cs := m.coldStart; cs != nil {
+		c.ColdStart = &coldStartConfig{
+			Window:  cs.cfg.Window.String(),
+			Fill:    cs.cfg.Fill,
+			Stagger: cs.cfg.Stagger,
+		}
+	}
+	if g := m.groups; g != nil {
+		c.Groups = &groupsConfig{
+			Rate:     jsonLimit(g.cfg.Rate),
+			Burst:    g.cfg.Burst,
+			MinRate:  jsonLimit(g.cfg.MinRate),
+			MinBurst: g.cfg.MinBurst,
+			Idle:     g.cfg.Idle.String(),
+		}
+	}
+	if m.opts.Hierarchy != nil {
+		c.Hierarchy = configCustom
+	}
+	if o := m.opts.Organization; o != nil {
+		c.Organization = &organizationConfig{
+			Key:   describeFunc(o.Key != nil),
+			Rate:  jsonLimit(o.Rate),
+			Burst: o.Burst,
+		}
+	}
+	for _, g := range m.guardrails {
+		c.Guardrails = append(c.Guardrails, guardrailConfig{
+			Class:       g.cfg.Class,
+			MaxLatency:  g.cfg.MaxLatency.String(),
+			Objective:   g.cfg.Objective,
+			BurnRate:    g.cfg.BurnRate,
+			Window:      g.cfg.Window.String(),
+			MinRequests: g.cfg.MinRequests,
+			Tighten:     g.cfg.Tighten,
+			Factor:      g.cfg.Factor,
+		})
+	}
+	if d := m.anomalies; d != nil {
+		c.Anomalies = &anomaliesConfig{
+			Interval:  d.cfg.Interval.String(),
+			Smoothing: d.cfg.Smoothing,
+			Factor:    d.cfg.Factor,
+			MinRate:   jsonLimit(d.cfg.MinRate),
+			Warmup:    d.cfg.Warmup,
+		}
+	}
+	c.MemoryStats = m.opts.MemoryStats
+	if m.opts.OnEvent != nil {
+		c.OnEvent = configCustom
+	}
+	if rt := m.opts.Retention; rt != nil {
+		c.Retention = &retentionConfig{
+			Keys:     rt.Keys.String(),
+			Controls: rt.Controls.String(),
+			Audit:    rt.Audit.String(),
+		}
+	}
+	if m.opts.AuditSink != nil {
+		c.AuditSink = fmt.Sprintf("%T", m.opts.AuditSink)
+	}
+	if sc := m.opts.SharedControls; sc != nil {
+		c.SharedControls = fmt.Sprintf("%T", sc.Sync)
+	}
+	if a := m.opts.AdminAuth; a != nil {
+		c.AdminAuth = &adminAuthConfig{
+			ValidateToken: describeFunc(a.ValidateToken != nil),
+			Authorize:     describeFunc(a.Authorize != nil),
+			Roles:         a.Roles,
+		}
+	}
+	if rb := m.regions; rb != nil {
+		c.Regions = &regionsConfig{
+			Local:  rb.cfg.Local,
+			Shares: rb.shares,
+		}
+		if rb.cfg.Ledger != nil {
+			c.Regions.Ledger = fmt.Sprintf("%T", rb.cfg.Ledger)
+		}
+	}
+}
+
+// describeFunc reports whether a function-valued option was customized.
+func describeFunc(custom bool) string {
+	if custom {
+		return configCustom
+	}
+	return configDefault
+}
+
+// ConfigJSON returns the effective configuration enforced by the manager as
+// indented JSON, with all defaults applied. Function-valued options are
+// reported as "default" or "custom", and the store by its type name.
+func (m *Manager) ConfigJSON() ([]byte, error) {
+	return json.MarshalIndent(m.config, "", "  ")
+}
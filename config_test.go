@@ -0,0 +1,70 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestConfigJSON(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:  rate.Every(100 * time.Millisecond),
+			Burst: 5,
+		})
+
+		data, err := m.ConfigJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"rate": 10,
+			"burst": 5,
+			"maxDelay": "0s",
+			"keyFunc": "default",
+			"maxKeyLength": 256,
+			"store": "*ratelimit.shardedStore",
+			"onLimitExceeded": "default",
+			"headers": "x-ratelimit"
+		}`, string(data))
+	})
+
+	t.Run("Customized", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:     rate.Inf,
+			Burst:    1,
+			MaxDelay: time.Second,
+			KeyFunc: func(c *gin.Context) string {
+				return c.GetHeader("X-API-KEY")
+			},
+			Backpressure: &Backpressure{Capacity: 2.5},
+		})
+
+		data, err := m.ConfigJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"rate": "Inf",
+			"burst": 1,
+			"maxDelay": "1s",
+			"keyFunc": "custom",
+			"maxKeyLength": 256,
+			"store": "*ratelimit.shardedStore",
+			"onLimitExceeded": "default",
+			"headers": "x-ratelimit",
+			"backpressure": {
+				"header": "X-Backpressure",
+				"capacity": 2.5,
+				"burst": 3,
+				"levels": [
+					{"threshold": 0.9, "value": "high"},
+					{"threshold": 0.7, "value": "medium"}
+				]
+			}
+		}`, string(data))
+	})
+}
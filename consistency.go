@@ -0,0 +1,135 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// DefaultSyncInterval is how often eventually consistent limits report
+// their usage to the store when Options.SyncInterval is zero.
+const DefaultSyncInterval = time.Second
+
+// Consistency selects how a limit is enforced across the instances sharing
+// a store through an Algorithm.
+type Consistency uint8
+
+const (
+	// ConsistencyStrict checks every request against the shared store,
+	// so the limit holds exactly across instances at the cost of a store
+	// round trip per request.
+	ConsistencyStrict Consistency = iota
+	// ConsistencyEventual checks requests against local buckets, which
+	// report their usage to the shared store every Options.SyncInterval
+	// and are drained when the shared budget is spent. Instances can
+	// together let up to one interval's worth of excess requests
+	// through, but requests never wait for the store.
+	ConsistencyEventual
+)
+
+// String returns the name of the consistency mode.
+func (c Consistency) String() string {
+	if c == ConsistencyEventual {
+		return "eventual"
+	}
+	return "strict"
+}
+
+// RouteLimitWith is like RouteLimit, with the given consistency instead of
+// Options.Consistency, so for example login routes can be strictly
+// enforced while high-volume reads use eventual consistency:
+//
+//	r.POST("/login", m.RouteLimitWith(spec, ratelimit.ConsistencyStrict), login)
+func (m *Manager) RouteLimitWith(spec LimitSpec, consistency Consistency) gin.HandlerFunc {
+	if err := spec.Validate(); err != nil {
+		panic(err)
+	}
+	route := &routeLimit{rate: spec.Rate(), burst: spec.BurstSize(), consistency: consistency}
+	return func(c *gin.Context) {
+		if m.routes.annotation(c) == routeExempt {
+			return
+		}
+		m.routes.limited(c, route)
+		m.serve(c, route)
+	}
+}
+
+// eventual reports whether requests to route are checked against local
+// buckets synced with the store. Without an Algorithm, all buckets are
+// local already; serverless managers have no time to sync them.
+func (m *Manager) eventual(route *routeLimit) bool {
+	if m.opts.Algorithm == nil || m.opts.Serverless {
+		return false
+	}
+	if route != nil {
+		return route.consistency == ConsistencyEventual
+	}
+	return m.opts.Consistency == ConsistencyEventual
+}
+
+// pendingUsage is the usage of a local bucket not yet reported to the
+// store.
+type pendingUsage struct {
+	r     rate.Limit
+	burst int
+	n     int
+}
+
+// usageSync collects the usage of eventually consistent buckets and
+// reports it to the store in the background.
+type usageSync struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]pendingUsage
+}
+
+// newUsageSync creates a sync reporting usage every interval.
+func newUsageSync(interval time.Duration) *usageSync {
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+	return &usageSync{interval: interval, pending: make(map[string]pendingUsage)}
+}
+
+// add records n tokens taken from the local bucket of key, scheduling a
+// report if none is pending.
+func (s *usageSync) add(m *Manager, key string, r rate.Limit, burst, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		time.AfterFunc(s.interval, func() { s.flush(m) })
+	}
+	u := s.pending[key]
+	s.pending[key] = pendingUsage{r: r, burst: burst, n: u.n + n}
+}
+
+// flush reports the pending usage to the store, draining the local
+// buckets of keys whose shared budget is spent.
+func (s *usageSync) flush(m *Manager) {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]pendingUsage)
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	now := time.Now()
+	for key, u := range pending {
+		// Usage beyond the burst could never be allowed at once.
+		a, err := m.opts.Algorithm.Take(ctx, key, u.r, u.burst, min(u.n, u.burst), now)
+		if err != nil || a.Allowed {
+			// Failing stores do not hold back local decisions.
+			continue
+		}
+		if limiter, ok := m.limiters.Get(key); ok {
+			limiter.AllowN(now, int(limiter.TokensAt(now)))
+		}
+	}
+}
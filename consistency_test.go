@@ -0,0 +1,158 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsistency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Both instances share the state of the algorithm, as they would
+	// share a Redis store.
+	shared := GCRA()
+	newInstance := func() (*Manager, *gin.Engine) {
+		m := NewManager(Options{
+			Rate:         1,
+			Burst:        1,
+			Algorithm:    shared,
+			Consistency:  ConsistencyEventual,
+			SyncInterval: time.Hour,
+		})
+		r := gin.New()
+		r.Use(m.Handler())
+		login := LimitSpec{Requests: 1, Window: time.Hour}
+		r.POST("/login", m.RouteLimitWith(login, ConsistencyStrict), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		r.GET("/items", m.RouteLimit(LimitSpec{Requests: 4, Window: time.Hour}), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return m, r
+	}
+	a, ra := newInstance()
+	b, rb := newInstance()
+	serve := func(r *gin.Engine, method, path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(ra, http.MethodPost, "/login"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(rb, http.MethodPost, "/login"))
+	})
+
+	t.Run("Eventual", func(t *testing.T) {
+		// Until they sync, each instance enforces the whole limit.
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(ra, http.MethodGet, "/items"))
+			assert.Equal(t, http.StatusOK, serve(rb, http.MethodGet, "/items"))
+		}
+
+		// a's usage fits the shared budget, but b's does not, so its
+		// local bucket is drained.
+		a.usage.flush(a)
+		b.usage.flush(b)
+		assert.Equal(t, http.StatusOK, serve(ra, http.MethodGet, "/items"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(rb, http.MethodGet, "/items"))
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		data, err := b.ConfigJSON()
+		require.NoError(t, err)
+		var config struct {
+			Consistency  string `json:"consistency"`
+			SyncInterval string `json:"syncInterval"`
+		}
+		require.NoError(t, json.Unmarshal(data, &config))
+		assert.Equal(t, "eventual", config.Consistency)
+		assert.Equal(t, "1h0m0s", config.SyncInterval)
+	})
+}
+
+func TestServerless(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	shared := GCRA()
+	var notified []float64
+	newInstance := func() (*Manager, *gin.Engine) {
+		m := NewManager(Options{
+			Rate:        1,
+			Burst:       2,
+			Algorithm:   shared,
+			Consistency: ConsistencyEventual,
+			IdleTTL:     time.Minute,
+			Serverless:  true,
+			QuotaNotifications: &QuotaNotifications{
+				Notifier: QuotaNotifierFunc(func(_ context.Context, n QuotaNotification) error {
+					notified = append(notified, n.Threshold)
+					return nil
+				}),
+				Thresholds: []float64{0.5},
+			},
+		})
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		items := LimitSpec{Requests: 2, Window: time.Hour}
+		r.GET("/items", m.RouteLimitWith(items, ConsistencyEventual), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return m, r
+	}
+	a, ra := newInstance()
+	_, rb := newInstance()
+	serve := func(r *gin.Engine, path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Every limit is checked against the store, so the instances share
+	// it at once.
+	for _, path := range []string{"/", "/items"} {
+		assert.Equal(t, http.StatusOK, serve(ra, path))
+		assert.Equal(t, http.StatusOK, serve(rb, path))
+		assert.Equal(t, http.StatusTooManyRequests, serve(ra, path))
+	}
+	assert.Empty(t, a.usage.pending)
+
+	// Nothing runs in the background: each instance notified the
+	// crossing before responding, and idle keys are not evicted.
+	assert.Equal(t, []float64{0.5, 0.5}, notified)
+	assert.Nil(t, a.owned.done)
+
+	raw, err := a.ConfigJSON()
+	require.NoError(t, err)
+	var cfg map[string]any
+	require.NoError(t, json.Unmarshal(raw, &cfg))
+	assert.Equal(t, true, cfg["serverless"])
+	assert.Equal(t, "strict", cfg["consistency"])
+	assert.NotContains(t, cfg, "syncInterval")
+
+	// Limits kept in process memory are rejected.
+	assert.Panics(t, func() { NewManager(Options{Rate: 1, Burst: 1, Serverless: true}) })
+	assert.Panics(t, func() {
+		NewManager(Options{Rate: 1, Burst: 1, Serverless: true, Store: NewMemoryStore()})
+	})
+	assert.NotPanics(t, func() {
+		NewManager(Options{Rate: 1, Burst: 1, Serverless: true, Store: NewMemoryStore(), Algorithm: shared})
+	})
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Override replaces the configured limits for a single key.
+type Override struct {
+	// Rate is the token generation rate for the key.
+	Rate rate.Limit `json:"rate"`
+	// Burst is the bucket size for the key.
+	Burst int `json:"burst"`
+	// Start is when the override takes effect, and End when it lapses,
+	// restoring the configured limits. Zero times leave the override
+	// unbounded on that side.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// activeAt reports whether the override applies at t.
+func (o Override) activeAt(t time.Time) bool {
+	return within(t, o.Start, o.End)
+}
+
+// Ban rejects every request of a key, or of every key starting with a
+// prefix.
+type Ban struct {
+	// Start is when the ban takes effect, and Until when it is lifted.
+	// Zero times leave the ban unbounded on that side.
+	Start time.Time `json:"start"`
+	Until time.Time `json:"until"`
+}
+
+// activeAt reports whether the ban applies at t.
+func (b Ban) activeAt(t time.Time) bool {
+	return within(t, b.Start, b.Until)
+}
+
+// within reports whether t is in [start, end), zero times being unbounded.
+func within(t, start, end time.Time) bool {
+	return (start.IsZero() || !t.Before(start)) && (end.IsZero() || t.Before(end))
+}
+
+// keyControls holds the operator decisions applied to individual keys and
+// to every key starting with a prefix.
+type keyControls struct {
+	mu              sync.RWMutex
+	bans            map[string]Ban
+	overrides       map[string]Override
+	prefixBans      map[string]Ban
+	prefixOverrides map[string]Override
+	notes           map[string]KeyNote
+	// version is the version of the shared controls last synced or
+	// published.
+	version int64
+}
+
+// newKeyControls creates an empty set of controls.
+func newKeyControls() *keyControls {
+	return &keyControls{
+		bans:            make(map[string]Ban),
+		overrides:       make(map[string]Override),
+		prefixBans:      make(map[string]Ban),
+		prefixOverrides: make(map[string]Override),
+		notes:           make(map[string]KeyNote),
+	}
+}
+
+// banned reports whether key, or a prefix of it, is banned at t. Expired
+// and scheduled bans are ignored.
+func (kc *keyControls) banned(key string, t time.Time) bool {
+	_, ok := kc.activeBan(key, t)
+	return ok
+}
+
+// activeBan returns the ban of key, or of a prefix of it, active at t.
+func (kc *keyControls) activeBan(key string, t time.Time) (Ban, bool) {
+	kc.mu.RLock()
+	defer kc.mu.RUnlock()
+	if b, ok := kc.bans[key]; ok && b.activeAt(t) {
+		return b, true
+	}
+	for prefix, b := range kc.prefixBans {
+		if strings.HasPrefix(key, prefix) && b.activeAt(t) {
+			return b, true
+		}
+	}
+	return Ban{}, false
+}
+
+// override returns the override for key at t, if any. An override of the
+// key itself wins over prefix overrides, and longer prefixes win over
+// shorter ones. Overrides that are not active at t are ignored.
+func (kc *keyControls) override(key string, t time.Time) (Override, bool) {
+	kc.mu.RLock()
+	defer kc.mu.RUnlock()
+	if o, ok := kc.overrides[key]; ok && o.activeAt(t) {
+		return o, true
+	}
+	var (
+		best  Override
+		found bool
+		size  = -1
+	)
+	for prefix, o := range kc.prefixOverrides {
+		if len(prefix) > size && strings.HasPrefix(key, prefix) && o.activeAt(t) {
+			best, found, size = o, true, len(prefix)
+		}
+	}
+	return best, found
+}
+
+// Reset refills the bucket of key, as if the client had never been seen.
+func (m *Manager) Reset(ctx context.Context, key string) error {
+	if err := m.audit(ctx, AuditReset, key, nil); err != nil {
+		return err
+	}
+	if m.opts.Algorithm != nil {
+		m.denials.forget(key)
+		return m.opts.Algorithm.Reset(ctx, key)
+	}
+	r, burst := m.limitsFor(key)
+	m.limiters.Set(key, rate.NewLimiter(r, burst))
+	return nil
+}
+
+// Ban rejects every request of key for the given duration, or until Unban
+// is called if d is zero.
+func (m *Manager) Ban(ctx context.Context, key string, d time.Duration) error {
+	return m.ScheduleBan(ctx, key, time.Time{}, d)
+}
+
+// ScheduleBan bans key from start, or from now if start is zero, for the
+// given duration, or until Unban is called if d is zero. A key has one
+// ban, so scheduling a ban replaces the current one.
+func (m *Manager) ScheduleBan(ctx context.Context, key string, start time.Time, d time.Duration) error {
+	b, detail := newBan(start, d, time.Now())
+	if err := m.audit(ctx, AuditBan, key, detail); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		kc.bans[key] = b
+	})
+}
+
+// newBan returns a ban from start for d, and its audit detail.
+func newBan(start time.Time, d time.Duration, now time.Time) (Ban, map[string]string) {
+	b := Ban{Start: start}
+	detail := map[string]string{}
+	if !start.IsZero() {
+		detail["start"] = start.UTC().Format(time.RFC3339)
+	} else {
+		start = now
+	}
+	if d > 0 {
+		b.Until = start.Add(d)
+		detail["until"] = b.Until.UTC().Format(time.RFC3339)
+	}
+	return b, detail
+}
+
+// Unban lifts the ban of key.
+func (m *Manager) Unban(ctx context.Context, key string) error {
+	if err := m.audit(ctx, AuditUnban, key, nil); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		delete(kc.bans, key)
+	})
+}
+
+// SetOverride replaces the configured limits of key. The new limits apply
+// from the key's next request, or its first request after o.Start, until
+// o.End. A key has one override, so setting one replaces the current one,
+// scheduled or not.
+func (m *Manager) SetOverride(ctx context.Context, key string, o Override) error {
+	if err := m.audit(ctx, AuditOverride, key, overrideDetail(o)); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		kc.overrides[key] = o
+	})
+}
+
+// overrideDetail describes an override in an audit entry.
+func overrideDetail(o Override) map[string]string {
+	detail := map[string]string{
+		"rate":  strconv.FormatFloat(float64(o.Rate), 'g', -1, 64),
+		"burst": strconv.Itoa(o.Burst),
+	}
+	if !o.Start.IsZero() {
+		detail["start"] = o.Start.UTC().Format(time.RFC3339)
+	}
+	if !o.End.IsZero() {
+		detail["end"] = o.End.UTC().Format(time.RFC3339)
+	}
+	return detail
+}
+
+// ClearOverride restores the configured limits of key.
+func (m *Manager) ClearOverride(ctx context.Context, key string) error {
+	if err := m.audit(ctx, AuditClearOverride, key, nil); err != nil {
+		return err
+	}
+
+	return m.changeControls(ctx, func(kc *keyControls) {
+		delete(kc.overrides, key)
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+// memoryAuditSink collects audit entries for inspection in tests.
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+	err     error
+}
+
+func (s *memoryAuditSink) Record(_ context.Context, e AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func TestKeyControls(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(m *Manager) *gin.Engine {
+		r := gin.New()
+		m.RegisterAdmin(r.Group("/admin", gin.BasicAuth(gin.Accounts{"alice": "secret"})))
+		r.GET("/", m.Handler(), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return r
+	}
+	serve := func(r *gin.Engine) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	admin := func(r *gin.Engine, path, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin"+path, strings.NewReader(body))
+		req.SetBasicAuth("alice", "secret")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("ResetBanOverride", func(t *testing.T) {
+		sink := &memoryAuditSink{}
+		m := NewManager(Options{
+			Rate:      rate.Every(time.Hour),
+			Burst:     1,
+			AuditSink: sink,
+		})
+		r := newRouter(m)
+
+		assert.Equal(t, http.StatusOK, serve(r))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+
+		assert.Equal(t, http.StatusNoContent, admin(r, "/reset", `{"key": "203.0.113.7"}`))
+		assert.Equal(t, http.StatusBadRequest, admin(r, "/reset", `{}`))
+		assert.Equal(t, http.StatusOK, serve(r))
+
+		assert.Equal(t, http.StatusNoContent, admin(r, "/ban", `{"key": "203.0.113.7", "duration": "1h"}`))
+		assert.Equal(t, http.StatusForbidden, serve(r))
+		assert.Equal(t, http.StatusNoContent, admin(r, "/unban", `{"key": "203.0.113.7"}`))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+
+		assert.Equal(t, http.StatusNoContent, admin(r, "/override", `{"key": "203.0.113.7", "rate": 1, "burst": 3}`))
+		assert.Equal(t, http.StatusOK, serve(r))
+		assert.Equal(t, http.StatusOK, serve(r))
+		assert.Equal(t, http.StatusNoContent, admin(r, "/clear-override", `{"key": "203.0.113.7"}`))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+
+		var actions []AuditAction
+		for _, e := range sink.entries {
+			assert.Equal(t, "alice", e.Actor)
+			actions = append(actions, e.Action)
+		}
+		assert.Equal(t, []AuditAction{
+			AuditReset, AuditBan, AuditUnban, AuditOverride, AuditClearOverride,
+		}, actions)
+		assert.Equal(t, map[string]string{"rate": "1", "burst": "3"}, sink.entries[3].Detail)
+	})
+
+	t.Run("BanExpires", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Inf, Burst: 1})
+		assert.NoError(t, m.Ban(context.Background(), "k", time.Millisecond))
+		assert.True(t, m.controls.banned("k", time.Now()))
+		assert.False(t, m.controls.banned("k", time.Now().Add(time.Second)))
+	})
+
+	t.Run("Scheduled", func(t *testing.T) {
+		ctx := context.Background()
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		now := time.Now()
+		start, end := now.Add(time.Hour), now.Add(49*time.Hour)
+
+		assert.NoError(t, m.ScheduleBan(ctx, "k", start, time.Hour))
+		assert.False(t, m.controls.banned("k", now))
+		assert.True(t, m.controls.banned("k", start))
+		assert.False(t, m.controls.banned("k", start.Add(time.Hour)))
+
+		// Raise the key to 500/s for 48 hours from start.
+		o := Override{Rate: 500, Burst: 1000, Start: start, End: end}
+		assert.NoError(t, m.SetOverride(ctx, "k", o))
+		_, ok := m.controls.override("k", now)
+		assert.False(t, ok)
+		got, ok := m.controls.override("k", start)
+		assert.True(t, ok)
+		assert.Equal(t, o, got)
+		_, ok = m.controls.override("k", end)
+		assert.False(t, ok)
+
+		// Prefix overrides apply while the key's own one is inactive.
+		assert.NoError(t, m.BulkSetOverride(ctx, KeySelector{Prefixes: []string{"k"}}, Override{Rate: 1, Burst: 2}))
+		got, ok = m.controls.override("k", now)
+		assert.True(t, ok)
+		assert.Equal(t, 2, got.Burst)
+
+		r := newRouter(m)
+		body := `{"key": "203.0.113.7", "start": "` + start.Format(time.RFC3339) + `", "duration": "1h"}`
+		assert.Equal(t, http.StatusNoContent, admin(r, "/ban", body))
+		assert.Equal(t, http.StatusOK, serve(r))
+		assert.True(t, m.controls.banned("203.0.113.7", start.Add(time.Minute)))
+
+		body = `{"key": "203.0.113.7", "rate": 1, "burst": 5, "end": "` + now.Add(-time.Minute).Format(time.RFC3339) + `"}`
+		assert.Equal(t, http.StatusNoContent, admin(r, "/override", body))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+	})
+
+	t.Run("FailingSinkBlocksChanges", func(t *testing.T) {
+		sink := &memoryAuditSink{err: errors.New("sink down")}
+		m := NewManager(Options{
+			Rate:      rate.Inf,
+			Burst:     1,
+			AuditSink: sink,
+		})
+
+		assert.Error(t, m.Ban(context.Background(), "k", 0))
+		assert.False(t, m.controls.banned("k", time.Now()))
+		assert.Equal(t, http.StatusInternalServerError, admin(newRouter(m), "/ban", `{"key": "k"}`))
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// SharedControls shares the bans and overrides set by operators between
+// all instances of a fleet. Every change is published through Sync, and
+// instances pick up the changes of others on SyncControls, which
+// RunControlSync calls periodically, so changes take effect fleet-wide
+// within one interval.
+type SharedControls struct {
+	// Sync stores the latest controls.
+	Sync ControlSync
+
+	// OnError is called when RunControlSync fails to sync. The current
+	// controls are kept until the next successful sync. If nil, errors are
+	// ignored.
+	OnError func(error)
+}
+
+// ControlSnapshot is the state of the bans, overrides and notes of a
+// manager, including scheduled and expired bans and overrides.
+type ControlSnapshot struct {
+	// Version increases with every published change.
+	Version         int64
+	Bans            map[string]Ban
+	Overrides       map[string]Override
+	PrefixBans      map[string]Ban
+	PrefixOverrides map[string]Override
+	Notes           map[string]KeyNote
+}
+
+// ErrControlsChanged is returned by ControlSync.Publish when the shared
+// controls were changed by another instance since the version the
+// published snapshot is based on.
+var ErrControlsChanged = errors.New("ratelimit: shared controls changed concurrently")
+
+// maxPublishAttempts bounds how often a change is synced and published
+// again after losing a race with the changes of other instances.
+const maxPublishAttempts = 10
+
+// ControlSync stores the controls shared between instances, typically in
+// the store the instances already share. A version counter lets instances
+// check for changes cheaply.
+type ControlSync interface {
+	// Version returns the version of the latest controls, or zero if none
+	// were published.
+	Version(ctx context.Context) (int64, error)
+	// Load returns the latest controls.
+	Load(ctx context.Context) (ControlSnapshot, error)
+	// Publish stores s as the latest controls under a new version, which
+	// it returns, if the latest version is still s.Version. Otherwise it
+	// stores nothing and returns ErrControlsChanged.
+	Publish(ctx context.Context, s ControlSnapshot) (int64, error)
+}
+
+// snapshot returns a copy of the controls. The caller holds kc.mu.
+func (kc *keyControls) snapshot() ControlSnapshot {
+	return ControlSnapshot{
+		Version:         kc.version,
+		Bans:            maps.Clone(kc.bans),
+		Overrides:       maps.Clone(kc.overrides),
+		PrefixBans:      maps.Clone(kc.prefixBans),
+		PrefixOverrides: maps.Clone(kc.prefixOverrides),
+		Notes:           maps.Clone(kc.notes),
+	}
+}
+
+// restore replaces the controls with s, unless they are already as recent.
+func (kc *keyControls) restore(s ControlSnapshot) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	if s.Version <= kc.version {
+		return
+	}
+	kc.set(s)
+}
+
+// set replaces the controls with a copy of s. The caller holds kc.mu, or
+// owns kc.
+func (kc *keyControls) set(s ControlSnapshot) {
+	kc.version = s.Version
+	kc.bans = cloneControls(s.Bans)
+	kc.overrides = cloneControls(s.Overrides)
+	kc.prefixBans = cloneControls(s.PrefixBans)
+	kc.prefixOverrides = cloneControls(s.PrefixOverrides)
+	kc.notes = cloneControls(s.Notes)
+}
+
+// cloneControls copies a map of controls, which may be nil.
+func cloneControls[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return make(map[string]V)
+	}
+	return maps.Clone(m)
+}
+
+// changeControls applies a change to the controls and, with
+// SharedControls, publishes it. The change is applied to the latest shared
+// controls and published only if no other instance published in between,
+// so it does not undo the changes of others; a change that loses such a
+// race is synced and applied again.
+func (m *Manager) changeControls(ctx context.Context, change func(kc *keyControls)) error {
+	kc := m.controls
+	shared := m.opts.SharedControls
+	if shared == nil {
+		kc.mu.Lock()
+		defer kc.mu.Unlock()
+		change(kc)
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := m.SyncControls(ctx); err != nil {
+			return err
+		}
+		kc.mu.RLock()
+		next := &keyControls{}
+		next.set(kc.snapshot())
+		kc.mu.RUnlock()
+
+		change(next)
+		s := next.snapshot()
+		version, err := shared.Sync.Publish(ctx, s)
+		if errors.Is(err, ErrControlsChanged) && attempt < maxPublishAttempts {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ratelimit: publishing controls: %w", err)
+		}
+		s.Version = version
+		kc.restore(s)
+		return nil
+	}
+}
+
+// SyncControls replaces the bans and overrides with the latest shared
+// controls, if they changed. It does nothing if SharedControls is not
+// configured.
+func (m *Manager) SyncControls(ctx context.Context) error {
+	shared := m.opts.SharedControls
+	if shared == nil {
+		return nil
+	}
+	version, err := shared.Sync.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("ratelimit: syncing controls: %w", err)
+	}
+	m.controls.mu.RLock()
+	current := m.controls.version
+	m.controls.mu.RUnlock()
+	if version <= current {
+		return nil
+	}
+	s, err := shared.Sync.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("ratelimit: syncing controls: %w", err)
+	}
+	m.controls.restore(s)
+	return nil
+}
+
+// RunControlSync syncs the shared controls every interval until ctx is
+// done. Run it in its own goroutine.
+func (m *Manager) RunControlSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.SyncControls(ctx); err != nil && m.opts.SharedControls.OnError != nil {
+				m.opts.SharedControls.OnError(err)
+			}
+		}
+	}
+}
+
+// memoryControlSync is an in-process ControlSync.
+type memoryControlSync struct {
+	mu sync.Mutex
+	s  ControlSnapshot
+}
+
+// NewMemoryControlSync creates a ControlSync that keeps the controls in
+// memory. It is useful for tests, or when all instances are managers in
+// the same process.
+func NewMemoryControlSync() ControlSync {
+	return &memoryControlSync{}
+}
+
+// Version implements ControlSync.
+func (s *memoryControlSync) Version(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.s.Version, nil
+}
+
+// Load implements ControlSync.
+func (s *memoryControlSync) Load(context.Context) (ControlSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.s, nil
+}
+
+// Publish implements ControlSync.
+func (s *memoryControlSync) Publish(_ context.Context, snap ControlSnapshot) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap.Version != s.s.Version {
+		return 0, ErrControlsChanged
+	}
+	snap.Version = s.s.Version + 1
+	s.s = snap
+	return snap.Version, nil
+}
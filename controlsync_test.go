@@ -0,0 +1,129 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// failingControlSync is a ControlSync whose store is unreachable.
+type failingControlSync struct{}
+
+func (failingControlSync) Version(context.Context) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (failingControlSync) Load(context.Context) (ControlSnapshot, error) {
+	return ControlSnapshot{}, errors.New("connection refused")
+}
+
+func (failingControlSync) Publish(context.Context, ControlSnapshot) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestSharedControls(t *testing.T) {
+	ctx := context.Background()
+	newManagerWith := func(shared ControlSync) *Manager {
+		return NewManager(Options{
+			Rate:           rate.Every(time.Second),
+			Burst:          1,
+			SharedControls: &SharedControls{Sync: shared},
+		})
+	}
+	shared := NewMemoryControlSync()
+	a, b := newManagerWith(shared), newManagerWith(shared)
+
+	t.Run("Sync", func(t *testing.T) {
+		require.NoError(t, a.Ban(ctx, "mallory", 0))
+		assert.False(t, b.controls.banned("mallory", time.Now()))
+
+		require.NoError(t, b.SyncControls(ctx))
+		assert.True(t, b.controls.banned("mallory", time.Now()))
+	})
+
+	t.Run("Changes of others are kept", func(t *testing.T) {
+		// b has not synced the override when it lifts the ban.
+		require.NoError(t, a.SetOverride(ctx, "alice", Override{Rate: 10, Burst: 20}))
+		require.NoError(t, b.Unban(ctx, "mallory"))
+
+		require.NoError(t, a.SyncControls(ctx))
+		for _, m := range []*Manager{a, b} {
+			assert.False(t, m.controls.banned("mallory", time.Now()))
+			o, ok := m.controls.override("alice", time.Now())
+			assert.True(t, ok)
+			assert.Equal(t, Override{Rate: 10, Burst: 20}, o)
+		}
+	})
+
+	t.Run("Bulk", func(t *testing.T) {
+		require.NoError(t, a.BulkBan(ctx, KeySelector{Prefixes: []string{"bot:"}}, time.Hour))
+		require.NoError(t, b.SyncControls(ctx))
+		assert.True(t, b.controls.banned("bot:crawler", time.Now()))
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go b.RunControlSync(runCtx, 10*time.Millisecond)
+
+		require.NoError(t, a.ClearOverride(ctx, "alice"))
+		assert.Eventually(t, func() bool {
+			_, ok := b.controls.override("alice", time.Now())
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Concurrent changes", func(t *testing.T) {
+		shared := NewMemoryControlSync()
+		a, b := newManagerWith(shared), newManagerWith(shared)
+		var wg sync.WaitGroup
+		for i, m := range []*Manager{a, b} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 10 {
+					assert.NoError(t, m.Ban(ctx, fmt.Sprintf("m%d:k%d", i, j), 0))
+				}
+			}()
+		}
+		wg.Wait()
+
+		for _, m := range []*Manager{a, b} {
+			require.NoError(t, m.SyncControls(ctx))
+			for i := range 2 {
+				for j := range 10 {
+					assert.True(t, m.controls.banned(fmt.Sprintf("m%d:k%d", i, j), time.Now()))
+				}
+			}
+		}
+	})
+
+	t.Run("Stale publish", func(t *testing.T) {
+		shared := NewMemoryControlSync()
+		_, err := shared.Publish(ctx, ControlSnapshot{})
+		require.NoError(t, err)
+		_, err = shared.Publish(ctx, ControlSnapshot{})
+		require.ErrorIs(t, err, ErrControlsChanged)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:           rate.Every(time.Second),
+			Burst:          1,
+			SharedControls: &SharedControls{Sync: failingControlSync{}},
+		})
+		require.Error(t, m.Ban(ctx, "mallory", 0))
+		require.Error(t, m.SyncControls(ctx))
+	})
+}
@@ -0,0 +1,16 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import "github.com/gin-gonic/gin"
+
+// requestCost returns the number of tokens a request takes from its
+// bucket, before guardrails and retry policies apply.
+func (m *Manager) requestCost(c *gin.Context) int {
+	if m.opts.CostFunc == nil {
+		return 1
+	}
+	return max(m.opts.CostFunc(c), 1)
+}
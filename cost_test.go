@@ -0,0 +1,61 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestCostFunc(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for name, alg := range map[string]Algorithm{"Token bucket": nil, "GCRA": GCRA()} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(New(Options{
+				Rate:      rate.Every(time.Hour),
+				Burst:     60,
+				Algorithm: alg,
+				CostFunc: func(c *gin.Context) int {
+					switch c.Request.URL.Path {
+					case "/export":
+						return 50
+					case "/free":
+						return 0
+					default:
+						return 1
+					}
+				},
+			}))
+			for _, path := range []string{"/export", "/ping", "/free"} {
+				r.GET(path, func(c *gin.Context) {
+					c.String(http.StatusOK, "OK")
+				})
+			}
+			serve := func(path string) int {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, path, nil)
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			assert.Equal(t, http.StatusOK, serve("/export"))
+			assert.Equal(t, http.StatusTooManyRequests, serve("/export"))
+			for range 9 {
+				assert.Equal(t, http.StatusOK, serve("/ping"))
+			}
+			// Costs below one count as one.
+			assert.Equal(t, http.StatusOK, serve("/free"))
+			assert.Equal(t, http.StatusTooManyRequests, serve("/free"))
+		})
+	}
+}
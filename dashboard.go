@@ -0,0 +1,47 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !ratelimit_nodashboard
+
+package ratelimit
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// DashboardHandler returns a handler serving a single-page dashboard of
+// allow and deny rates, per-class counters, guardrails, the most denied
+// keys and live decisions. The page reads the /stats, /keys and /decisions
+// admin endpoints one level up from its own location, so mount it next to them with a wildcard route:
+//
+//	admin := r.Group("/ratelimit")
+//	m.RegisterAdmin(admin)
+//	admin.GET("/dashboard/*filepath", ratelimit.DashboardHandler())
+//
+// When Options.AdminAuth validates tokens, the page asks for one and sends
+// it with every request; the page itself contains no data.
+func DashboardHandler() gin.HandlerFunc {
+	sub, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FS(sub)
+
+	return func(c *gin.Context) {
+		path := c.Param("filepath")
+		if path == "" {
+			path = "/"
+		}
+		c.Header("Content-Security-Policy", "default-src 'self'")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.FileFromFS(path, files)
+	}
+}
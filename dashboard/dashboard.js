@@ -0,0 +1,208 @@
+// The dashboard is served next to the admin endpoints, so the API is one
+// level up from the page.
+(function () {
+  "use strict";
+
+  const api = new URL("../", window.location.href);
+  const interval = 2000;
+  const history = 60;
+  const liveRows = 20;
+  const liveRate = 5;
+
+  const $ = (id) => document.getElementById(id);
+  const samples = [];
+  let previous = null;
+  let token = sessionStorage.getItem("ratelimit-token") || "";
+
+  $("auth").addEventListener("submit", (e) => {
+    e.preventDefault();
+    token = $("token").value;
+    sessionStorage.setItem("ratelimit-token", token);
+    $("auth").hidden = true;
+    poll();
+    watch();
+  });
+
+  async function request(path, options) {
+    const headers = token ? { Authorization: "Bearer " + token } : {};
+    const resp = await fetch(new URL(path, api), Object.assign({ headers }, options));
+    if (resp.status === 401) {
+      $("auth").hidden = false;
+      throw new Error("admin token required");
+    }
+    if (!resp.ok) {
+      throw new Error(path + ": " + resp.status);
+    }
+    return resp;
+  }
+
+  async function get(path) {
+    return (await request(path)).json();
+  }
+
+  // rate returns the per-second growth of a counter between two snapshots.
+  function rate(now, before, seconds) {
+    if (!before || seconds <= 0) {
+      return 0;
+    }
+    return Math.max(0, now - before) / seconds;
+  }
+
+  function cell(row, text, className) {
+    const td = document.createElement("td");
+    td.textContent = text;
+    if (className) {
+      td.className = className;
+    }
+    row.appendChild(td);
+  }
+
+  function fixed(n) {
+    return n.toFixed(n < 10 ? 1 : 0);
+  }
+
+  function renderStats(stats) {
+    const seconds = previous ? (new Date(stats.time) - new Date(previous.time)) / 1000 : 0;
+    const before = previous ? previous.total : null;
+    const allowed = rate(stats.total.requests - stats.total.denied,
+      before && before.requests - before.denied, seconds);
+    const denied = rate(stats.total.denied, before && before.denied, seconds);
+
+    $("allowed-rate").textContent = fixed(allowed);
+    $("denied-rate").textContent = fixed(denied);
+    $("keys").textContent = stats.keys;
+
+    if (stats.utilization !== undefined) {
+      $("utilization-card").hidden = false;
+      $("utilization").textContent = Math.round(stats.utilization * 100) + "%";
+      $("utilization-bar").style.width = (stats.utilization * 100) + "%";
+    }
+
+    if (previous) {
+      samples.push({ allowed, denied });
+      if (samples.length > history) {
+        samples.shift();
+      }
+      renderChart();
+    }
+
+    const tripped = {};
+    (stats.guardrails || []).forEach((g) => { tripped[g.class] = g.tripped; });
+    const body = $("classes");
+    body.replaceChildren();
+    Object.keys(stats.classes || {}).sort().forEach((name) => {
+      const cs = stats.classes[name];
+      const old = previous && previous.classes && previous.classes[name];
+      const row = document.createElement("tr");
+      cell(row, name);
+      cell(row, fixed(rate(cs.requests - cs.denied, old && old.requests - old.denied, seconds)));
+      cell(row, fixed(rate(cs.denied, old && old.denied, seconds)));
+      cell(row, cs.denied);
+      if (name in tripped) {
+        cell(row, tripped[name] ? "tripped" : "ok", tripped[name] ? "tripped" : "");
+      } else {
+        cell(row, "");
+      }
+      body.appendChild(row);
+    });
+
+    previous = stats;
+  }
+
+  function renderChart() {
+    const max = Math.max(1, ...samples.map((s) => Math.max(s.allowed, s.denied)));
+    const points = (field) => samples.map((s, i) =>
+      (i * 600 / (history - 1)).toFixed(1) + "," + (120 - s[field] / max * 115).toFixed(1)).join(" ");
+    $("chart-allowed").setAttribute("points", points("allowed"));
+    $("chart-denied").setAttribute("points", points("denied"));
+  }
+
+  function renderKeys(page) {
+    const body = $("top-keys");
+    body.replaceChildren();
+    page.keys.filter((k) => k.denied > 0).forEach((k) => {
+      const row = document.createElement("tr");
+      cell(row, k.key, "key");
+      cell(row, k.requests);
+      cell(row, k.denied);
+      cell(row, new Date(k.lastSeen).toLocaleTimeString());
+      body.appendChild(row);
+    });
+  }
+
+  function renderDecision(d) {
+    const body = $("decisions");
+    const row = document.createElement("tr");
+    cell(row, new Date(d.time).toLocaleTimeString());
+    cell(row, d.keyHash, "key");
+    cell(row, d.route);
+    cell(row, d.class || "");
+    cell(row, d.decision, d.decision === "allowed" ? "" : d.decision);
+    body.insertBefore(row, body.firstChild);
+    while (body.children.length > liveRows) {
+      body.removeChild(body.lastChild);
+    }
+    $("sampled").textContent = d.skipped ? "(sampled, " + d.skipped + " skipped)" : "";
+  }
+
+  // watch follows the decision stream. EventSource cannot send the admin
+  // token, so the stream is read with fetch and parsed here.
+  let watching = false;
+  async function watch() {
+    if (watching) {
+      return;
+    }
+    watching = true;
+    try {
+      const resp = await request("decisions?rate=" + liveRate, { cache: "no-store" });
+      const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += value;
+        let end;
+        while ((end = buffer.indexOf("\n\n")) >= 0) {
+          const message = buffer.slice(0, end);
+          buffer = buffer.slice(end + 2);
+          const data = message.split("\n")
+            .filter((line) => line.startsWith("data:"))
+            .map((line) => line.slice(5))
+            .join("\n");
+          if (data) {
+            renderDecision(JSON.parse(data));
+          }
+        }
+      }
+    } catch (err) {
+      $("status").textContent = err.message;
+    } finally {
+      watching = false;
+    }
+    if ($("auth").hidden) {
+      setTimeout(watch, interval);
+    }
+  }
+
+  let timer = null;
+  async function poll() {
+    clearTimeout(timer);
+    try {
+      const [stats, keys] = await Promise.all([get("stats"), get("keys?sort=denied&limit=10")]);
+      renderStats(stats);
+      renderKeys(keys);
+      $("status").textContent = "updated " + new Date().toLocaleTimeString();
+    } catch (err) {
+      $("status").textContent = err.message;
+      if (!$("auth").hidden) {
+        return;
+      }
+    }
+    timer = setTimeout(poll, interval);
+  }
+
+  poll();
+  watch();
+})();
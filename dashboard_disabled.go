@@ -0,0 +1,22 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build ratelimit_nodashboard
+
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DashboardHandler returns a handler answering 404 Not Found, since the
+// dashboard assets were left out of this build with the
+// ratelimit_nodashboard build tag.
+func DashboardHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.String(http.StatusNotFound, "ratelimit: dashboard not included in this build")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !ratelimit_nodashboard
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestDashboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{
+		Rate:       rate.Every(time.Hour),
+		Burst:      1,
+		Classifier: MethodClassifier(),
+		Guardrails: []Guardrail{{Class: "GET"}},
+		Backpressure: &Backpressure{
+			Capacity: 1,
+			Burst:    10,
+		},
+	})
+	r := gin.New()
+	admin := r.Group("/ratelimit")
+	m.RegisterAdmin(admin)
+	admin.GET("/dashboard/*filepath", DashboardHandler())
+	r.Any("/", m.Handler(), func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Files", func(t *testing.T) {
+		w := get("/ratelimit/dashboard/")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `<script src="dashboard.js">`)
+		assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+
+		w = get("/ratelimit/dashboard/dashboard.js")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+
+		assert.Equal(t, http.StatusNotFound, get("/ratelimit/dashboard/missing.js").Code)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		get("/")
+		get("/")
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", nil)
+		r.ServeHTTP(w, req)
+
+		w = get("/ratelimit/stats")
+		require.Equal(t, http.StatusOK, w.Code)
+		var st Stats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &st))
+		assert.Equal(t, ClassStats{Requests: 3, Denied: 2}, st.Total)
+		assert.Equal(t, ClassStats{Requests: 2, Denied: 1}, st.Classes["GET"])
+		assert.Equal(t, ClassStats{Requests: 1, Denied: 1}, st.Classes["POST"])
+		assert.Equal(t, 1, st.Keys)
+		if assert.NotNil(t, st.Utilization) {
+			assert.InDelta(t, 0.3, *st.Utilization, 0.01)
+		}
+		assert.Equal(t, []GuardrailStatus{{Class: "GET"}}, st.Guardrails)
+	})
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// decision is how a request is handled, as decided by decide for both the
+// middleware and Evaluate.
+type decision struct {
+	// bypass is why the request bypasses limiting, if it does.
+	bypass BypassReason
+	// denylisted reports whether Options.IPLists denies the client.
+	denylisted bool
+	cl         Classification
+	key        string
+	banned     bool
+	// duplicate reports whether the request was throttled as a replayed
+	// payload, limiter being the throttle's bucket.
+	duplicate bool
+
+	// cost is the number of tokens the request takes.
+	cost int
+	// limiter is the bucket of limit, or one in the state of its
+	// allowance with Options.Algorithm, for headers and OnLimitExceeded.
+	// Probes leave it unset.
+	limiter *rate.Limiter
+	allowed bool
+	// limit is the limit deciding the request: the one denying it, or
+	// the most restrictive of those allowing it.
+	limit EffectiveLimit
+	// allowance is the state of limit. It is only set by probes.
+	allowance Allowance
+	// err is the error of the Algorithm. Only probes report it; the
+	// middleware records it in the context and lets the request through.
+	err error
+}
+
+// decide runs the checks of the middleware on a request limited by the
+// route's own buckets if route is set: Skip, the address lists, bans,
+// duplicates and the limits. Probes, for Evaluate, take no tokens and
+// leave every limit, cache and throttle as they were.
+func (m *Manager) decide(c *gin.Context, route *routeLimit, probe bool) decision {
+	var d decision
+	if m.opts.Skip != nil && m.opts.Skip(c) {
+		d.bypass = BypassSkip
+		return d
+	}
+	if m.ipLists != nil {
+		switch m.ipLists.action(c.ClientIP()) {
+		case ipListAllow:
+			d.bypass = BypassAllowlist
+			return d
+		case ipListDeny:
+			d.denylisted = true
+			return d
+		}
+	}
+	now := time.Now()
+	if !probe {
+		m.watchClock(now)
+	}
+
+	// Identify and classify the request so every subsystem sees the
+	// same identity and class.
+	m.identify(c)
+	d.cl = m.classify(c)
+	d.key = m.key(c)
+	if m.controls.banned(d.key, now) {
+		d.banned = true
+		return d
+	}
+
+	// Throttle replayed payloads before they draw on the sender's budget.
+	if m.duplicates != nil && !probe {
+		if dup, ok := m.duplicates.allow(c); !ok && !m.shadowDeny(d.cl, d.key) {
+			d.duplicate, d.limiter = true, dup
+			return d
+		}
+	}
+
+	m.decideLimits(c, route, &d, probe)
+	return d
+}
+
+// decideLimits checks the request of d against its limits: the bucket of
+// its key or route, or of retries, along with the global, organization
+// and extra limits, or the key's group.
+func (m *Manager) decideLimits(c *gin.Context, route *routeLimit, d *decision, probe bool) {
+	scope := ScopeKey
+	if route != nil {
+		scope = c.Request.Method + " " + c.FullPath()
+	}
+	own, r, burst := m.bucket(c, route, d.key)
+	bucket, r, burst, cost := m.retry(c, own, r, burst, m.requestCost(c)*m.cost(d.cl.Class))
+	if bucket != own {
+		scope = ScopeRetry
+	}
+	d.cost = cost
+
+	var levels []Level
+	if org := m.organization(c, route); org != "" {
+		levels = m.orgLevels(org, Level{Key: bucket, Rate: r, Burst: burst})
+	} else if m.layered(d.key, route) {
+		levels = m.levels(bucket, r, burst, route)
+	}
+	if levels != nil {
+		var i int
+		if probe {
+			d.allowance, i, d.err = m.peekLevels(c.Request.Context(), levels, cost, time.Now())
+			d.allowed = d.allowance.Allowed
+		} else {
+			d.limiter, d.allowed, i = m.takeAll(c, levels, cost)
+		}
+		lv := levels[i]
+		d.limit = effectiveLimit(levelScope(lv, bucket, scope), lv.Rate, lv.Burst)
+		return
+	}
+
+	d.limit = effectiveLimit(scope, r, burst)
+	lv := Level{Key: bucket, Rate: r, Burst: burst}
+	switch group := m.group(d.key, route); {
+	case m.opts.Algorithm != nil && !m.eventual(route):
+		if probe {
+			d.allowance, d.err = m.peekAlgorithm(c.Request.Context(), lv, cost, time.Now())
+		} else {
+			d.limiter, d.allowed = m.takeAlgorithm(c, bucket, r, burst, cost)
+		}
+	case group != "":
+		var limiter *rate.Limiter
+		if probe {
+			limiter, d.allowed = m.groups.peek(group, d.key, cost, time.Now())
+			d.allowance = limiterAllowance(limiter, d.allowed, cost, time.Now())
+		} else {
+			limiter, d.allowed = m.groups.take(group, d.key, cost, time.Now())
+			d.limiter = limiter
+		}
+		d.limit = effectiveLimit(ScopeGroup, limiter.Limit(), limiter.Burst())
+	default:
+		if probe {
+			d.allowance = peekBucket(m.peekLimiter(lv, time.Now()), cost, m.opts.MaxDelay, time.Now())
+		} else {
+			d.limiter = m.storedLimiter(bucket, r, burst)
+			d.allowed = take(c, d.limiter, cost, m.waitBudget(c.Request.Context()), m.queue)
+			if d.allowed && m.eventual(route) {
+				m.usage.add(m, bucket, r, burst, cost)
+			}
+		}
+	}
+	if probe {
+		d.allowed = d.allowance.Allowed
+	}
+}
+
+// levelScope returns the scope of a level checked along the bucket own of
+// the given scope.
+func levelScope(lv Level, own, scope string) string {
+	switch {
+	case lv.Key == own:
+		return scope
+	case lv.Key == globalKey:
+		return ScopeGlobal
+	case strings.HasPrefix(lv.Key, own+limitKeySeparator):
+		return strings.TrimPrefix(lv.Key, own+limitKeySeparator)
+	default:
+		return ScopeOrganization
+	}
+}
+
+// peekLevels returns the allowance of n tokens from every level at now,
+// without taking them, and the index of the level deciding it: the first
+// denying them, or else the most restrictive.
+func (m *Manager) peekLevels(ctx context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	as := make([]Allowance, 0, len(levels))
+	for i, lv := range levels {
+		var (
+			a   Allowance
+			err error
+		)
+		if m.opts.Algorithm != nil {
+			a, err = m.peekAlgorithm(ctx, lv, n, now)
+		} else {
+			a = peekBucket(m.peekLimiter(lv, now), n, 0, now)
+		}
+		if err != nil || !a.Allowed {
+			return a, i, err
+		}
+		as = append(as, a)
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// peekAlgorithm returns the allowance of n tokens from the bucket of lv
+// with Options.Algorithm at now, without taking them. Cached denials are
+// reported as they are.
+func (m *Manager) peekAlgorithm(ctx context.Context, lv Level, n int, now time.Time) (Allowance, error) {
+	if wait, ok := m.denials.denied(lv.Key, lv.Rate, lv.Burst, n, now); ok {
+		return Allowance{RetryAfter: wait}, nil
+	}
+	a, err := m.opts.Algorithm.Take(ctx, lv.Key, lv.Rate, lv.Burst, 0, now)
+	if err != nil {
+		return a, err
+	}
+	a.Allowed = a.Remaining >= n || lv.Rate == rate.Inf
+	if a.Allowed {
+		a.RetryAfter = 0
+	} else {
+		a.Delay = 0
+	}
+	return a, nil
+}
+
+// peekLimiter returns a copy of the bucket of lv, in the state the next
+// request would find it in.
+func (m *Manager) peekLimiter(lv Level, now time.Time) *rate.Limiter {
+	if l, ok := m.limiters.Get(lv.Key); ok {
+		return resizeLimiter(l, lv.Rate, lv.Burst, now)
+	}
+	if m.coldStart != nil {
+		return m.coldStart.newLimiter(lv.Key, lv.Rate, lv.Burst, now)
+	}
+	return rate.NewLimiter(lv.Rate, lv.Burst)
+}
+
+// peekBucket returns the allowance of n tokens from l at now, without
+// taking them. Requests that would wait up to maxDelay for their tokens
+// are allowed with a Delay.
+func peekBucket(l *rate.Limiter, n int, maxDelay time.Duration, now time.Time) Allowance {
+	allowed := l.TokensAt(now) >= float64(n) || l.Limit() == rate.Inf
+	a := limiterAllowance(l, allowed, n, now)
+	if !allowed && a.RetryAfter >= 0 && a.RetryAfter <= maxDelay {
+		a.Allowed, a.Delay, a.RetryAfter = true, a.RetryAfter, 0
+	}
+	return a
+}
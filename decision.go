@@ -0,0 +1,141 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Caps on the decision events sent per second to each client of the admin
+// decision stream.
+const (
+	// DefaultDecisionStreamRate is used when the client asks for no rate.
+	DefaultDecisionStreamRate = 10
+	// MaxDecisionStreamRate is the highest rate a client may ask for.
+	MaxDecisionStreamRate = 100
+)
+
+// Decision is the outcome of the rate limiting check of one request.
+type Decision struct {
+	Time time.Time `json:"time"`
+	// Key is the rate limiting key of the request.
+	Key string `json:"key"`
+	// Class is the traffic class assigned by Options.Classifier.
+	Class string `json:"class,omitempty"`
+	// Route is the matched route pattern, such as "/users/:id", or empty if
+	// no route matched.
+	Route string `json:"route"`
+	// Allowed reports whether the request was let through.
+	Allowed bool `json:"allowed"`
+	// Banned reports whether the request was rejected because its key is
+	// banned.
+	Banned bool `json:"banned,omitempty"`
+	// DryRun reports whether the request was let through by
+	// Options.DryRun although it was denied.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Bypass is the reason the request bypassed limiting, if it did. Key
+	// is then the client IP.
+	Bypass BypassReason `json:"bypass,omitempty"`
+}
+
+// decisionHub fans decisions out to their watchers.
+type decisionHub struct {
+	// watching counts the watchers, so requests skip the lock when there
+	// are none.
+	watching atomic.Int32
+	mu       sync.Mutex
+	watchers map[chan Decision]struct{}
+}
+
+// newDecisionHub creates a hub without watchers.
+func newDecisionHub() *decisionHub {
+	return &decisionHub{watchers: make(map[chan Decision]struct{})}
+}
+
+// watched reports whether there are watchers, so callers can skip
+// building decisions, and anonymizing their keys, when there are none.
+func (h *decisionHub) watched() bool {
+	return h.watching.Load() > 0
+}
+
+// publish delivers d to every watcher with room for it.
+func (h *decisionHub) publish(d Decision) {
+	if !h.watched() {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.watchers {
+		select {
+		case ch <- d:
+		default:
+			// The watcher is behind; drop rather than slow down requests.
+		}
+	}
+}
+
+// WatchDecisions returns a channel receiving the decisions made by the
+// manager's middleware until ctx is done, when the channel is closed.
+// Watchers that fall more than buffer decisions behind miss decisions
+// rather than delaying requests.
+func (m *Manager) WatchDecisions(ctx context.Context, buffer int) <-chan Decision {
+	h := m.decisions
+	ch := make(chan Decision, max(buffer, 0))
+
+	h.mu.Lock()
+	h.watchers[ch] = struct{}{}
+	h.watching.Add(1)
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.watchers, ch)
+		h.watching.Add(-1)
+		close(ch)
+	}()
+	return ch
+}
+
+// decisionEvent is a decision as sent on the admin decision stream.
+type decisionEvent struct {
+	Time time.Time `json:"time"`
+	// KeyHash identifies the key without writing it verbatim to terminals
+	// and logs.
+	KeyHash string `json:"keyHash"`
+	Class   string `json:"class,omitempty"`
+	Route   string `json:"route"`
+	// Decision is "allowed", "denied" or "banned".
+	Decision string `json:"decision"`
+	// Skipped is the number of decisions left out since the previous event
+	// to stay within the stream's rate.
+	Skipped uint64 `json:"skipped,omitempty"`
+}
+
+// newDecisionEvent converts d for the decision stream.
+func newDecisionEvent(d Decision, skipped uint64) decisionEvent {
+	sum := sha256.Sum256([]byte(d.Key))
+	e := decisionEvent{
+		Time:     d.Time,
+		KeyHash:  hex.EncodeToString(sum[:8]),
+		Class:    d.Class,
+		Route:    d.Route,
+		Decision: "allowed",
+		Skipped:  skipped,
+	}
+	switch {
+	case d.Banned:
+		e.Decision = "banned"
+	case !d.Allowed:
+		e.Decision = "denied"
+	}
+	return e
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestWatchDecisions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+	r := gin.New()
+	r.GET("/users/:id", m.Handler(), func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func() {
+		req, _ := http.NewRequest("GET", "/users/42", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := m.WatchDecisions(ctx, 2)
+	for i := 0; i < 3; i++ {
+		serve()
+	}
+
+	// The third decision did not fit in the buffer and was dropped.
+	first, second := <-ch, <-ch
+	assert.Equal(t, "203.0.113.7", first.Key)
+	assert.Equal(t, "/users/:id", first.Route)
+	assert.True(t, first.Allowed)
+	assert.False(t, second.Allowed)
+	assert.Empty(t, ch)
+
+	cancel()
+	_, open := <-ch
+	assert.False(t, open)
+	assert.Eventually(t, func() bool {
+		return m.decisions.watching.Load() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestDecisionStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+	r := gin.New()
+	m.RegisterAdmin(r.Group("/admin"))
+	r.GET("/users/:id", m.Handler(), func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/admin/decisions?rate=1", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Keep sending requests: the stream subscribes asynchronously, and with
+	// a rate of one event per second most decisions are skipped.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+			req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/users/42", nil)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	var events []decisionEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < 2 && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var e decisionEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &e))
+		events = append(events, e)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, newDecisionEvent(Decision{Key: "127.0.0.1"}, 0).KeyHash, events[0].KeyHash)
+	assert.NotContains(t, events[0].KeyHash, "127.0.0.1")
+	assert.Equal(t, "/users/:id", events[0].Route)
+	assert.Equal(t, "denied", events[1].Decision)
+	assert.Positive(t, events[1].Skipped)
+}
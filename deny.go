@@ -0,0 +1,148 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// limitKeyContextKey is the gin context key holding the key of a denied
+// request while OnLimitExceeded runs.
+const limitKeyContextKey = "github.com/gin-contrib/ratelimit/key"
+
+// DenyTemplate describes a deny response rendered from a text/template,
+// executed with a DenyData, for example:
+//
+//	Too many requests for {{.Key}}: the limit is {{.Limit}}.
+//	Try again in {{.RetryAfter}} seconds, see {{.DocsURL}}.
+type DenyTemplate struct {
+	// Status is the response status. If zero, 429 Too Many Requests is
+	// used.
+	Status int
+	// ContentType is the response content type. If empty,
+	// "text/plain; charset=utf-8" is used.
+	ContentType string
+	// Body is the template source of the response body.
+	Body string
+	// DocsURL and SupportEmail are passed to the template as is.
+	DocsURL      string
+	SupportEmail string
+}
+
+// DenyData holds the variables available to deny templates.
+type DenyData struct {
+	// Key is the client's rate limiting key.
+	Key string
+	// Class is the request's traffic class, if classified.
+	Class string
+	// Limit is the client's limit, such as "100/minute burst 20".
+	Limit string
+	// Rate is the client's limit in requests per second, and Burst its
+	// burst size.
+	Rate  float64
+	Burst int
+	// Remaining is the number of requests the client may send right now.
+	Remaining int
+	// RetryAfter is the number of seconds until the next request is
+	// allowed, and Reset the number of seconds until the full burst is
+	// available again.
+	RetryAfter int
+	Reset      int
+	// ResetAt is when the full burst is available again.
+	ResetAt time.Time
+	// DocsURL and SupportEmail come from the DenyTemplate.
+	DocsURL      string
+	SupportEmail string
+}
+
+// newDenyData describes the state of limiter at now for a deny response.
+func newDenyData(c *gin.Context, limiter *rate.Limiter, now time.Time) DenyData {
+	d := DenyData{
+		Key:   c.GetString(limitKeyContextKey),
+		Limit: FormatLimit(limiter.Limit(), limiter.Burst()),
+		Rate:  float64(limiter.Limit()),
+		Burst: limiter.Burst(),
+	}
+	if cl, ok := ClassificationFrom(c); ok {
+		d.Class = cl.Class
+	}
+	tokens := limiter.TokensAt(now)
+	d.Remaining = max(0, int(tokens))
+	if r := limiter.Limit(); r > 0 && r != rate.Inf {
+		d.RetryAfter = int(math.Ceil(max(0, 1-tokens) / float64(r)))
+		reset := max(0, float64(limiter.Burst())-tokens) / float64(r)
+		d.Reset = int(math.Ceil(reset))
+		d.ResetAt = now.Add(time.Duration(reset * float64(time.Second)))
+	}
+	return d
+}
+
+// compiledDeny is a parsed DenyTemplate.
+type compiledDeny struct {
+	DenyTemplate
+	body *template.Template
+}
+
+func compileDeny(name string, t DenyTemplate) (*compiledDeny, error) {
+	if t.Status == 0 {
+		t.Status = http.StatusTooManyRequests
+	}
+	if t.ContentType == "" {
+		t.ContentType = "text/plain; charset=utf-8"
+	}
+	body, err := template.New(name).Option("missingkey=error").Parse(t.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: invalid deny template: %w", err)
+	}
+	return &compiledDeny{DenyTemplate: t, body: body}, nil
+}
+
+func (d *compiledDeny) render(c *gin.Context, limiter *rate.Limiter) {
+	data := newDenyData(c, limiter, time.Now())
+	data.DocsURL, data.SupportEmail = d.DocsURL, d.SupportEmail
+
+	var buf bytes.Buffer
+	if err := d.body.Execute(&buf, data); err != nil {
+		// Never fail the response over a template bug.
+		_ = c.Error(err)
+		c.String(d.Status, http.StatusText(d.Status))
+		return
+	}
+	c.Data(d.Status, d.ContentType, buf.Bytes())
+}
+
+// DenyResponse returns an OnLimitExceeded handler rendering deny responses
+// from templates, so the message can be customized without handler code.
+// Requests whose traffic class has an entry in classes get that template,
+// all others def. It fails if a template cannot be parsed.
+func DenyResponse(def DenyTemplate, classes map[string]DenyTemplate) (func(*gin.Context, *rate.Limiter), error) {
+	fallback, err := compileDeny("deny", def)
+	if err != nil {
+		return nil, err
+	}
+	byClass := make(map[string]*compiledDeny, len(classes))
+	for class, t := range classes {
+		if byClass[class], err = compileDeny("deny-"+class, t); err != nil {
+			return nil, fmt.Errorf("%w (class %q)", err, class)
+		}
+	}
+	return func(c *gin.Context, limiter *rate.Limiter) {
+		d := fallback
+		if cl, ok := ClassificationFrom(c); ok {
+			if t, ok := byClass[cl.Class]; ok {
+				d = t
+			}
+		}
+		d.render(c, limiter)
+	}, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenyResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	deny, err := DenyResponse(DenyTemplate{
+		Body:    "{{.Key}} exceeded {{.Limit}}; retry in {{.RetryAfter}}s, full in {{.Reset}}s. {{.DocsURL}}",
+		DocsURL: "https://example.com/limits",
+	}, map[string]DenyTemplate{
+		"api": {
+			Status:       http.StatusServiceUnavailable,
+			ContentType:  "application/json",
+			Body:         `{"error":"rate_limited","remaining":{{.Remaining}},"support":"{{.SupportEmail}}"}`,
+			SupportEmail: "support@example.com",
+		},
+	})
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(New(Options{
+		Limit:           &LimitSpec{Requests: 2, Window: time.Minute},
+		Classifier:      PathGroupClassifier(map[string]string{"/api": "api"}, "web"),
+		OnLimitExceeded: deny,
+	}))
+	r.GET("/*path", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		serve("/")
+		serve("/")
+		w := serve("/")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "203.0.113.7 exceeded 2/minute; retry in 30s, full in 60s. https://example.com/limits",
+			w.Body.String())
+	})
+
+	t.Run("Class", func(t *testing.T) {
+		w := serve("/api/users")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"error":"rate_limited","remaining":0,"support":"support@example.com"}`, w.Body.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := DenyResponse(DenyTemplate{Body: "{{.Key"}, nil)
+		assert.Error(t, err)
+		_, err = DenyResponse(DenyTemplate{}, map[string]DenyTemplate{"api": {Body: "{{end}}"}})
+		assert.ErrorContains(t, err, `class "api"`)
+	})
+
+	t.Run("ExecuteError", func(t *testing.T) {
+		deny, err := DenyResponse(DenyTemplate{Body: "{{.Missing}}"}, nil)
+		require.NoError(t, err)
+		r := gin.New()
+		r.Use(New(Options{Limit: &LimitSpec{Requests: 1, Window: time.Hour}, OnLimitExceeded: deny}))
+		r.GET("/", func(c *gin.Context) {})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "Too Many Requests", w.Body.String())
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minDenySweep is the fewest cached denials that trigger a sweep of the
+// expired ones.
+const minDenySweep = 1024
+
+// cachedDenial is a denial of the algorithm remembered for a bucket.
+type cachedDenial struct {
+	r     rate.Limit
+	burst int
+	// n is the cost of the denied request; cheaper requests may be
+	// allowed earlier.
+	n     int
+	until time.Time
+}
+
+// denyCache remembers the denials of the algorithm until the denied
+// requests would be allowed, so requests of clients known to be over
+// their limit do not reach the store.
+type denyCache struct {
+	maxTTL time.Duration
+
+	mu      sync.Mutex
+	denials map[string]cachedDenial
+	sweepAt int
+}
+
+// newDenyCache creates a cache keeping denials up to maxTTL, or returns
+// nil if maxTTL is not positive.
+func newDenyCache(maxTTL time.Duration) *denyCache {
+	if maxTTL <= 0 {
+		return nil
+	}
+	return &denyCache{
+		maxTTL:  maxTTL,
+		denials: make(map[string]cachedDenial),
+		sweepAt: minDenySweep,
+	}
+}
+
+// denied returns how long a request of cost n to the bucket of key with
+// the given limits would still be denied, according to the cache.
+func (d *denyCache) denied(key string, r rate.Limit, burst, n int, now time.Time) (time.Duration, bool) {
+	if d == nil {
+		return 0, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.denials[key]
+	if !ok || e.r != r || e.burst != burst || n < e.n || !now.Before(e.until) {
+		return 0, false
+	}
+	return e.until.Sub(now), true
+}
+
+// add caches a denial of the bucket of key for the time until its request
+// would be allowed, capped to the cache's TTL. Denials without a retry
+// time are not cached.
+func (d *denyCache) add(key string, r rate.Limit, burst, n int, a Allowance, now time.Time) {
+	if d == nil || a.RetryAfter <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.denials) >= d.sweepAt {
+		for k, e := range d.denials {
+			if !now.Before(e.until) {
+				delete(d.denials, k)
+			}
+		}
+		d.sweepAt = max(minDenySweep, 2*len(d.denials))
+	}
+	d.denials[key] = cachedDenial{
+		r:     r,
+		burst: burst,
+		n:     n,
+		until: now.Add(min(a.RetryAfter, d.maxTTL)),
+	}
+}
+
+// forget drops the cached denials of the buckets of key.
+func (d *denyCache) forget(key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for bucket := range d.denials {
+		if bucketOf(bucket, key) {
+			delete(d.denials, bucket)
+		}
+	}
+}
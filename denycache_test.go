@@ -0,0 +1,99 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+// countingAlgorithm is an Algorithm counting the requests reaching it.
+type countingAlgorithm struct {
+	Algorithm
+	takes atomic.Int32
+}
+
+func (a *countingAlgorithm) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	a.takes.Add(1)
+	return a.Algorithm.Take(ctx, key, r, burst, n, now)
+}
+
+func TestDenyCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(r rate.Limit, ttl time.Duration) (*gin.Engine, *countingAlgorithm) {
+		alg := &countingAlgorithm{Algorithm: GCRA()}
+		e := gin.New()
+		e.Use(New(Options{
+			Rate:         r,
+			Burst:        1,
+			Algorithm:    alg,
+			DenyCacheTTL: ttl,
+		}))
+		e.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return e, alg
+	}
+	serve := func(e *gin.Engine) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Until the retry time", func(t *testing.T) {
+		e, alg := newRouter(rate.Every(50*time.Millisecond), time.Hour)
+		assert.Equal(t, http.StatusOK, serve(e).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(e).Code)
+		w := serve(e)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, int32(2), alg.takes.Load())
+
+		// The denial expires as the request would be allowed.
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, http.StatusOK, serve(e).Code)
+		assert.Equal(t, int32(3), alg.takes.Load())
+	})
+
+	t.Run("Capped", func(t *testing.T) {
+		e, alg := newRouter(rate.Every(time.Hour), 10*time.Millisecond)
+		assert.Equal(t, http.StatusOK, serve(e).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(e).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(e).Code)
+		assert.Equal(t, int32(2), alg.takes.Load())
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, http.StatusTooManyRequests, serve(e).Code)
+		assert.Equal(t, int32(3), alg.takes.Load())
+	})
+
+	t.Run("Cost", func(t *testing.T) {
+		d := newDenyCache(time.Hour)
+		now := time.Now()
+		d.add("k", 1, 5, 3, Allowance{RetryAfter: time.Second}, now)
+		_, ok := d.denied("k", 1, 5, 3, now)
+		assert.True(t, ok)
+		// Cheaper requests and changed limits go to the store.
+		_, ok = d.denied("k", 1, 5, 1, now)
+		assert.False(t, ok)
+		_, ok = d.denied("k", 2, 5, 3, now)
+		assert.False(t, ok)
+		d.forget("k")
+		_, ok = d.denied("k", 1, 5, 3, now)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// FormatRetryAfter describes a delay in English words, using its two
+// largest units rounded up to the second, such as "2 minutes 5 seconds" or
+// "1 hour". It is the default formatter of HTMLDenyPage.
+func FormatRetryAfter(d time.Duration) string {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds <= 0 {
+		return "a moment"
+	}
+	units := []struct {
+		name    string
+		seconds int64
+	}{{"day", 86400}, {"hour", 3600}, {"minute", 60}, {"second", 1}}
+	var parts []string
+	for _, u := range units {
+		if n := seconds / u.seconds; n > 0 && len(parts) < 2 {
+			name := u.name
+			if n > 1 {
+				name += "s"
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", n, name))
+			seconds %= u.seconds
+		} else if len(parts) > 0 {
+			// Only adjacent units, so "1 hour 5 seconds" reads "1 hour".
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// defaultDenyPage is the page rendered by HTMLDenyResponse without a
+// custom template. The countdown degrades to the static text where inline
+// scripts are not allowed.
+const defaultDenyPage = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+{{if .RetryAfter}}<meta http-equiv="refresh" content="{{.RetryAfter}}">{{end}}
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 32rem; margin: 15vh auto; padding: 0 1rem; color: #1d2330; }
+#countdown { font-variant-numeric: tabular-nums; color: #6b7385; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .RetryAfter}}<p id="countdown" data-seconds="{{.RetryAfter}}"></p>
+<script>
+(function () {
+  var el = document.getElementById("countdown");
+  var left = Number(el.dataset.seconds);
+  function tick() {
+    var m = Math.floor(left / 60), s = left % 60;
+    el.textContent = m + ":" + (s < 10 ? "0" : "") + s;
+    if (left-- > 0) { setTimeout(tick, 1000); }
+  }
+  tick();
+})();
+</script>{{end}}
+{{if .DocsURL}}<p><a href="{{.DocsURL}}">Learn more about rate limits</a></p>{{end}}
+{{if .SupportEmail}}<p>Need help? <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>{{end}}
+</body>
+</html>
+`
+
+// HTMLDenyPage describes a friendly "slow down" page for server-rendered
+// applications.
+type HTMLDenyPage struct {
+	// Lang is the language of the page. If empty, "en" is used.
+	Lang string
+	// Title is the page title. If empty, "Slow down" is used.
+	Title string
+	// Message is a text/template for the message shown to the user,
+	// executed with the HTMLDenyData. If empty,
+	// "You are sending requests too quickly. Please try again in {{.RetryIn}}."
+	// is used.
+	Message string
+	// FormatRetryAfter describes the delay until the next request is
+	// allowed, typically in the page's language. If nil, FormatRetryAfter
+	// is used.
+	FormatRetryAfter func(time.Duration) string
+	// Template replaces the whole page with an html/template executed with
+	// the HTMLDenyData. If empty, a built-in page with a countdown is used.
+	Template string
+	// DocsURL and SupportEmail are linked from the page if set.
+	DocsURL      string
+	SupportEmail string
+}
+
+// HTMLDenyData holds the variables available to deny pages.
+type HTMLDenyData struct {
+	DenyData
+	Lang  string
+	Title string
+	// RetryIn is the delay until the next request is allowed, in words.
+	RetryIn string
+	// Message is the rendered message.
+	Message string
+}
+
+// HTMLDenyResponse returns an OnLimitExceeded handler that answers clients
+// preferring text/html, such as browsers, with a page built from page, and
+// all other clients with fallback. If fallback is nil, they get the
+// default plain text response. It fails if a template cannot be parsed.
+func HTMLDenyResponse(page HTMLDenyPage, fallback func(*gin.Context, *rate.Limiter)) (
+	func(*gin.Context, *rate.Limiter), error,
+) {
+	if page.Lang == "" {
+		page.Lang = "en"
+	}
+	if page.Title == "" {
+		page.Title = "Slow down"
+	}
+	if page.Message == "" {
+		page.Message = "You are sending requests too quickly. Please try again in {{.RetryIn}}."
+	}
+	if page.FormatRetryAfter == nil {
+		page.FormatRetryAfter = FormatRetryAfter
+	}
+	if page.Template == "" {
+		page.Template = defaultDenyPage
+	}
+	message, err := compileDeny("deny-message", DenyTemplate{Body: page.Message})
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("deny-page").Parse(page.Template)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: invalid deny page: %w", err)
+	}
+	if fallback == nil {
+		fallback = func(c *gin.Context, _ *rate.Limiter) {
+			c.String(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+		}
+	}
+
+	return func(c *gin.Context, limiter *rate.Limiter) {
+		if c.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+			fallback(c, limiter)
+			return
+		}
+		data := HTMLDenyData{
+			DenyData: newDenyData(c, limiter, time.Now()),
+			Lang:     page.Lang,
+			Title:    page.Title,
+		}
+		data.DocsURL, data.SupportEmail = page.DocsURL, page.SupportEmail
+		data.RetryIn = page.FormatRetryAfter(time.Duration(data.RetryAfter) * time.Second)
+
+		var msg, buf bytes.Buffer
+		err := message.body.Execute(&msg, data)
+		if err == nil {
+			data.Message = msg.String()
+			err = tmpl.Execute(&buf, data)
+		}
+		if err != nil {
+			_ = c.Error(err)
+			fallback(c, limiter)
+			return
+		}
+		if data.RetryAfter > 0 && c.Writer.Header().Get("Retry-After") == "" {
+			c.Header("Retry-After", fmt.Sprint(data.RetryAfter))
+		}
+		c.Data(http.StatusTooManyRequests, "text/html; charset=utf-8", buf.Bytes())
+	}, nil
+}
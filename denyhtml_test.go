@@ -0,0 +1,138 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestFormatRetryAfter(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                              "a moment",
+		300 * time.Millisecond:         "1 second",
+		45 * time.Second:               "45 seconds",
+		time.Minute:                    "1 minute",
+		2*time.Minute + 5*time.Second:  "2 minutes 5 seconds",
+		time.Hour + 5*time.Second:      "1 hour",
+		26*time.Hour + 30*time.Minute:  "1 day 2 hours",
+		90*time.Minute + 1*time.Second: "1 hour 30 minutes",
+	}
+	for d, want := range tests {
+		assert.Equal(t, want, FormatRetryAfter(d), d.String())
+	}
+}
+
+func TestHTMLDenyResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(deny func(*gin.Context, *rate.Limiter), accept string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(New(Options{
+			Limit:           &LimitSpec{Requests: 1, Window: 2 * time.Minute},
+			OnLimitExceeded: deny,
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		var w *httptest.ResponseRecorder
+		for range 2 {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", accept)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+		}
+		return w
+	}
+	const browser = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	t.Run("Browser", func(t *testing.T) {
+		deny, err := HTMLDenyResponse(HTMLDenyPage{DocsURL: "https://example.com/limits"}, nil)
+		require.NoError(t, err)
+
+		w := serve(deny, browser)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "120", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), `<html lang="en">`)
+		assert.Contains(t, w.Body.String(), "<title>Slow down</title>")
+		assert.Contains(t, w.Body.String(), "Please try again in 2 minutes.")
+		assert.Contains(t, w.Body.String(), `data-seconds="120"`)
+		assert.Contains(t, w.Body.String(), `href="https://example.com/limits"`)
+	})
+
+	t.Run("APIClient", func(t *testing.T) {
+		deny, err := HTMLDenyResponse(HTMLDenyPage{}, nil)
+		require.NoError(t, err)
+
+		for _, accept := range []string{"", "application/json", "*/*", "text/plain, text/html"} {
+			w := serve(deny, accept)
+			assert.Equal(t, http.StatusTooManyRequests, w.Code, accept)
+			assert.Equal(t, "Too Many Requests", w.Body.String(), accept)
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		deny, err := HTMLDenyResponse(HTMLDenyPage{}, func(c *gin.Context, _ *rate.Limiter) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate_limited"})
+		})
+		require.NoError(t, err)
+
+		w := serve(deny, "application/json")
+		assert.JSONEq(t, `{"error":"rate_limited"}`, w.Body.String())
+	})
+
+	t.Run("Localized", func(t *testing.T) {
+		deny, err := HTMLDenyResponse(HTMLDenyPage{
+			Lang:    "de",
+			Title:   "Langsamer, bitte",
+			Message: "Bitte versuchen Sie es in {{.RetryIn}} erneut.",
+			FormatRetryAfter: func(d time.Duration) string {
+				return fmt.Sprintf("%.0f Minuten", d.Minutes())
+			},
+		}, nil)
+		require.NoError(t, err)
+
+		w := serve(deny, browser)
+		assert.Contains(t, w.Body.String(), `<html lang="de">`)
+		assert.Contains(t, w.Body.String(), "<title>Langsamer, bitte</title>")
+		assert.Contains(t, w.Body.String(), "Bitte versuchen Sie es in 2 Minuten erneut.")
+	})
+
+	t.Run("Escaping", func(t *testing.T) {
+		deny, err := HTMLDenyResponse(HTMLDenyPage{Title: "<script>alert(1)</script>"}, nil)
+		require.NoError(t, err)
+
+		w := serve(deny, browser)
+		assert.NotContains(t, w.Body.String(), "<script>alert(1)")
+		assert.Contains(t, w.Body.String(), "&lt;script&gt;alert(1)&lt;/script&gt;")
+	})
+
+	t.Run("CustomTemplate", func(t *testing.T) {
+		deny, err := HTMLDenyResponse(HTMLDenyPage{
+			Template: `<p>{{.Message}} ({{.Limit}})</p>`,
+		}, nil)
+		require.NoError(t, err)
+
+		w := serve(deny, browser)
+		assert.Equal(t, "<p>You are sending requests too quickly. Please try again in 2 minutes. (30/hour burst 1)</p>",
+			w.Body.String())
+	})
+
+	t.Run("InvalidTemplate", func(t *testing.T) {
+		_, err := HTMLDenyResponse(HTMLDenyPage{Template: "{{.Message"}, nil)
+		assert.Error(t, err)
+		_, err = HTMLDenyResponse(HTMLDenyPage{Message: "{{"}, nil)
+		assert.Error(t, err)
+	})
+}
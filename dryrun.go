@@ -0,0 +1,23 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import "time"
+
+// shadowDeny handles a denied request in dry-run mode, reporting whether
+// it is let through. The denial is reported as an EventDryRunDenied event.
+func (m *Manager) shadowDeny(cl Classification, key string) bool {
+	if !m.opts.DryRun {
+		return false
+	}
+	m.emit(Event{
+		Type:    EventDryRunDenied,
+		Time:    time.Now(),
+		Key:     key,
+		Class:   cl.Class,
+		Message: "request would have been limited",
+	})
+	return true
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var events []Event
+	m := NewManager(Options{
+		Rate:    rate.Every(time.Hour),
+		Burst:   1,
+		DryRun:  true,
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	decisions := m.WatchDecisions(ctx, 4)
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+	assert.Empty(t, events)
+
+	w := serve()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	require.Len(t, events, 1)
+	assert.Equal(t, EventDryRunDenied, events[0].Type)
+	assert.Equal(t, "203.0.113.1", events[0].Key)
+
+	assert.True(t, (<-decisions).Allowed)
+	d := <-decisions
+	assert.False(t, d.Allowed)
+	assert.True(t, d.DryRun)
+	assert.Equal(t, ClassStats{Requests: 2, Denied: 1}, m.Stats().Total)
+
+	// Bans are still enforced.
+	require.NoError(t, m.Ban(ctx, "203.0.113.1", time.Minute))
+	assert.Equal(t, http.StatusForbidden, serve().Code)
+}
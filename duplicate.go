@@ -0,0 +1,127 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// DefaultDuplicateBodyPrefix is the number of body bytes hashed to detect
+// duplicates when Duplicates.BodyPrefix is zero.
+const DefaultDuplicateBodyPrefix = 4096
+
+// Duplicates configures a limit on identical requests, shared by all
+// senders, so floods replaying the same payload, such as replayed webhooks,
+// are throttled independently of each sender's own budget. Requests are
+// identical when they have the same method, path and body prefix; requests
+// without a body are not checked.
+type Duplicates struct {
+	// Rate is the rate at which identical requests are allowed.
+	Rate rate.Limit
+
+	// Burst is the number of identical requests allowed at once.
+	// If zero, one is used.
+	Burst int
+
+	// BodyPrefix is the number of leading body bytes compared. If zero,
+	// DefaultDuplicateBodyPrefix is used.
+	BodyPrefix int
+}
+
+// duplicateLimiter keeps a token bucket per request fingerprint.
+type duplicateLimiter struct {
+	cfg Duplicates
+
+	mu       sync.Mutex
+	limiters map[[sha256.Size]byte]*rate.Limiter
+	// sweepAt is the number of fingerprints at which full buckets, which
+	// are indistinguishable from new ones, are next dropped.
+	sweepAt int
+}
+
+// minDuplicateSweep is the fewest fingerprints that trigger a sweep.
+const minDuplicateSweep = 1024
+
+// newDuplicateLimiter creates a limiter from the given configuration.
+func newDuplicateLimiter(cfg Duplicates) *duplicateLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.BodyPrefix <= 0 {
+		cfg.BodyPrefix = DefaultDuplicateBodyPrefix
+	}
+	return &duplicateLimiter{
+		cfg:      cfg,
+		limiters: make(map[[sha256.Size]byte]*rate.Limiter),
+		sweepAt:  minDuplicateSweep,
+	}
+}
+
+// fingerprint hashes the method, path and body prefix of the request,
+// leaving the body intact for later handlers. It reports false for
+// requests without a body.
+func (d *duplicateLimiter) fingerprint(r *http.Request) ([sha256.Size]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return [sha256.Size]byte{}, false
+	}
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, int64(d.cfg.BodyPrefix)))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), r.Body), r.Body}
+	if err != nil || len(prefix) == 0 {
+		return [sha256.Size]byte{}, false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write(prefix)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum, true
+}
+
+// limiter returns the bucket for a fingerprint.
+func (d *duplicateLimiter) limiter(sum [sha256.Size]byte, now time.Time) *rate.Limiter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if l, ok := d.limiters[sum]; ok {
+		return l
+	}
+	if len(d.limiters) >= d.sweepAt {
+		for k, l := range d.limiters {
+			if l.TokensAt(now) >= float64(d.cfg.Burst) {
+				delete(d.limiters, k)
+			}
+		}
+		d.sweepAt = max(minDuplicateSweep, 2*len(d.limiters))
+	}
+	l := rate.NewLimiter(d.cfg.Rate, d.cfg.Burst)
+	d.limiters[sum] = l
+	return l
+}
+
+// allow reports whether the request may proceed, and the bucket of its
+// fingerprint if it has one.
+func (d *duplicateLimiter) allow(c *gin.Context) (*rate.Limiter, bool) {
+	sum, ok := d.fingerprint(c.Request)
+	if !ok {
+		return nil, true
+	}
+	now := time.Now()
+	l := d.limiter(sum, now)
+	return l, l.AllowN(now, 1)
+}
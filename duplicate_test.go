@@ -0,0 +1,83 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestDuplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(New(Options{
+		Rate:       rate.Inf,
+		Duplicates: &Duplicates{Rate: rate.Every(time.Hour), Burst: 2, BodyPrefix: 8},
+	}))
+	r.Any("/hooks/:name", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	serve := func(method, path, ip, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		payload := `{"event":"paid","id":1}`
+		w := serve("POST", "/hooks/a", "203.0.113.1", payload)
+		assert.Equal(t, http.StatusOK, w.Code)
+		// The body is passed on in full after its prefix has been hashed.
+		assert.Equal(t, payload, w.Body.String())
+
+		// Replays are throttled whoever sends them.
+		assert.Equal(t, http.StatusOK, serve("POST", "/hooks/a", "203.0.113.2", payload).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve("POST", "/hooks/a", "203.0.113.3", payload).Code)
+		// Only the prefix is compared.
+		assert.Equal(t, http.StatusTooManyRequests,
+			serve("POST", "/hooks/a", "203.0.113.3", `{"event":"refunded"}`).Code)
+	})
+
+	t.Run("Distinct", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, serve("POST", "/hooks/b", "203.0.113.1", "payload").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve("POST", "/hooks/b", "203.0.113.1", "payload").Code)
+		assert.Equal(t, http.StatusOK, serve("PUT", "/hooks/b", "203.0.113.1", "payload").Code)
+		assert.Equal(t, http.StatusOK, serve("POST", "/hooks/c", "203.0.113.1", "payload").Code)
+		assert.Equal(t, http.StatusOK, serve("POST", "/hooks/b", "203.0.113.1", "other").Code)
+	})
+
+	t.Run("NoBody", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, serve("GET", "/hooks/a", "203.0.113.1", "").Code)
+		}
+	})
+}
+
+func TestDuplicateSweep(t *testing.T) {
+	d := newDuplicateLimiter(Duplicates{Rate: rate.Every(time.Millisecond)})
+	now := time.Now()
+	for i := 0; i < minDuplicateSweep; i++ {
+		d.limiter([32]byte{byte(i), byte(i >> 8)}, now).AllowN(now, 1)
+	}
+	assert.Len(t, d.limiters, minDuplicateSweep)
+
+	// Once their buckets have refilled, old fingerprints are forgotten.
+	d.limiter([32]byte{0xff, 0xff}, now.Add(time.Second))
+	assert.Len(t, d.limiters, 1)
+}
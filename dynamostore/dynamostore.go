@@ -0,0 +1,248 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package dynamostore provides a DynamoDB store for the ratelimit
+// middleware, so serverless deployments such as Gin applications on AWS
+// Lambda can share limits without running Redis. It is a module of its
+// own, so applications using other stores do not depend on the AWS SDK.
+//
+// Unlike the other store modules, it registers no scheme with
+// ratelimit.NewStoreFromDSN: DynamoDB clients are configured by the AWS
+// SDK, from its credential and region chain, which a DSN cannot carry.
+// Pass a Store created with New in ratelimit.Options instead.
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// Attributes of the items of the table.
+const (
+	// KeyAttribute is the partition key of the table, a string.
+	KeyAttribute = "id"
+	// TTLAttribute holds when an item may be deleted, in Unix seconds.
+	// Enable Time to Live on it so idle keys do not accumulate.
+	TTLAttribute = "expires"
+	// tatAttribute holds the theoretical arrival time of a key, in Unix
+	// microseconds.
+	tatAttribute = "tat"
+)
+
+// gcraPrefix prefixes the partition keys of the GCRA arrival times, so
+// the table can hold other items.
+const gcraPrefix = "ratelimit:gcra:"
+
+// maxAttempts is how many updates a request makes at most while other
+// instances change its key.
+const maxAttempts = 4
+
+// ErrContention is returned when the item of a key kept changing while a
+// request was being decided.
+var ErrContention = errors.New("dynamostore: too much contention on key")
+
+// Update expressions. A key idle since its bucket was full, or never
+// seen, restarts from now; a busy key's arrival time is advanced in place
+// as long as it allows the requests.
+const (
+	idleCondition = "attribute_not_exists(#tat) OR #tat < :now"
+	idleUpdate    = "SET #tat = :tat, #ttl = :ttl"
+	busyCondition = "#tat BETWEEN :now AND :last"
+	busyUpdate    = "SET #tat = #tat + :inc, #ttl = :ttl"
+)
+
+// Client is the subset of the DynamoDB API used by the store. It is
+// implemented by *dynamodb.Client.
+type Client interface {
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (
+		*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (
+		*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (
+		*dynamodb.DeleteItemOutput, error)
+}
+
+// store is a ratelimit.Store and ratelimit.Algorithm implementing the
+// generic cell rate algorithm with conditional updates.
+type store struct {
+	client Client
+	table  string
+}
+
+// New creates a DynamoDB-based store with the token bucket semantics of
+// redisstore.New, keeping the arrival time of every key in table. The
+// table needs a string partition key named KeyAttribute, and Time to Live
+// enabled on TTLAttribute.
+//
+// Requests are decided with conditional updates, which DynamoDB applies
+// atomically: requests of idle and denied keys take one write, and those
+// of busy keys two. Updates raced by other instances are retried.
+func New(client Client, table string) ratelimit.Store {
+	return &store{client: client, table: table}
+}
+
+// NewGCRA returns the store of New as a ratelimit.Algorithm. It does not
+// implement ratelimit.LevelAlgorithm, so levels are taken one at a time.
+func NewGCRA(client Client, table string) ratelimit.Algorithm {
+	return &store{client: client, table: table}
+}
+
+// Allow implements ratelimit.Store.
+func (s *store) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return s.Take(ctx, key, limit.Rate, limit.Burst, limit.N, time.Now())
+}
+
+// Take implements ratelimit.Algorithm. A non-positive rate denies every
+// request.
+func (s *store) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (ratelimit.Allowance, error) {
+	if r == rate.Inf {
+		return ratelimit.Allowance{Allowed: true, Remaining: burst}, nil
+	}
+	if r <= 0 {
+		return ratelimit.Allowance{RetryAfter: -1}, nil
+	}
+	interval := max(1, int64(1e6/float64(r)))
+	g := gcra{now: now.UnixMicro(), interval: interval, tolerance: interval * int64(burst), n: int64(n)}
+	id := itemKey(key)
+
+	// Requests that could never be allowed, and peeks, only read the key.
+	if n <= 0 || g.n*g.interval > g.tolerance {
+		out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(s.table),
+			Key:            id,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return ratelimit.Allowance{}, err
+		}
+		tat, err := tatOf(out.Item)
+		if err != nil {
+			return ratelimit.Allowance{}, err
+		}
+		return g.decide(tat), nil
+	}
+
+	idle := true
+	for range maxAttempts {
+		in := &dynamodb.UpdateItemInput{
+			TableName:                           aws.String(s.table),
+			Key:                                 id,
+			ExpressionAttributeNames:            map[string]string{"#tat": tatAttribute, "#ttl": TTLAttribute},
+			ReturnValues:                        types.ReturnValueUpdatedNew,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		}
+		// The item may be deleted once the bucket is full again, at
+		// now+tolerance at the latest.
+		ttl := number((g.now + g.tolerance + 999_999) / 1e6)
+		if idle {
+			in.ConditionExpression = aws.String(idleCondition)
+			in.UpdateExpression = aws.String(idleUpdate)
+			in.ExpressionAttributeValues = map[string]types.AttributeValue{
+				":now": number(g.now), ":tat": number(g.now + g.n*g.interval), ":ttl": ttl,
+			}
+		} else {
+			in.ConditionExpression = aws.String(busyCondition)
+			in.UpdateExpression = aws.String(busyUpdate)
+			in.ExpressionAttributeValues = map[string]types.AttributeValue{
+				":now": number(g.now), ":last": number(g.last()), ":inc": number(g.n * g.interval), ":ttl": ttl,
+			}
+		}
+
+		out, err := s.client.UpdateItem(ctx, in)
+		var failed *types.ConditionalCheckFailedException
+		if errors.As(err, &failed) {
+			tat, err := tatOf(failed.Item)
+			if err != nil {
+				return ratelimit.Allowance{}, err
+			}
+			if a := g.decide(tat); !a.Allowed {
+				return a, nil
+			}
+			idle = tat < g.now
+			continue
+		}
+		if err != nil {
+			return ratelimit.Allowance{}, err
+		}
+		tat, err := tatOf(out.Attributes)
+		if err != nil {
+			return ratelimit.Allowance{}, err
+		}
+		return ratelimit.Allowance{Allowed: true, Remaining: g.remaining(tat)}, nil
+	}
+	return ratelimit.Allowance{}, ErrContention
+}
+
+// Reset implements ratelimit.Algorithm.
+func (s *store) Reset(ctx context.Context, key string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       itemKey(key),
+	})
+	return err
+}
+
+// gcra holds the parameters of a request, in microseconds.
+type gcra struct {
+	now       int64
+	interval  int64
+	tolerance int64
+	n         int64
+}
+
+// last returns the latest arrival time that allows the requests.
+func (g gcra) last() int64 {
+	return g.now + g.tolerance - g.n*g.interval
+}
+
+// remaining returns the requests the arrival time tat leaves.
+func (g gcra) remaining(tat int64) int {
+	return int(max(0, (g.tolerance-(max(tat, g.now)-g.now))/g.interval))
+}
+
+// decide decides the requests from the arrival time tat without taking
+// them, as the script of redisstore.NewGCRA does. A zero tat is a key
+// never seen.
+func (g gcra) decide(tat int64) ratelimit.Allowance {
+	tat = max(tat, g.now)
+	a := ratelimit.Allowance{Remaining: g.remaining(tat), RetryAfter: -1}
+	need := max(g.n, 1)
+	wait := tat + need*g.interval - g.now - g.tolerance
+	switch {
+	case wait <= 0:
+		a.Allowed, a.RetryAfter = true, 0
+	case need*g.interval <= g.tolerance:
+		a.RetryAfter = time.Duration(wait) * time.Microsecond
+	}
+	return a
+}
+
+// itemKey returns the primary key of the item of key.
+func itemKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{KeyAttribute: &types.AttributeValueMemberS{Value: gcraPrefix + key}}
+}
+
+// number returns a DynamoDB number.
+func number(v int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(v, 10)}
+}
+
+// tatOf returns the arrival time of an item, or zero if it has none.
+func tatOf(item map[string]types.AttributeValue) (int64, error) {
+	v, ok := item[tatAttribute].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(v.Value, 10, 64)
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// fakeTable is an in-memory table evaluating the expressions of the store.
+type fakeTable struct {
+	mu     sync.Mutex
+	items  map[string]map[string]types.AttributeValue
+	writes int
+	// race, if set, is called before every update, so tests can change
+	// the item concurrently.
+	race func(items map[string]map[string]types.AttributeValue)
+}
+
+func newFakeTable() *fakeTable {
+	return &fakeTable{items: make(map[string]map[string]types.AttributeValue)}
+}
+
+func (f *fakeTable) id(key map[string]types.AttributeValue) string {
+	return key[KeyAttribute].(*types.AttributeValueMemberS).Value
+}
+
+func intOf(v types.AttributeValue) int64 {
+	n, _ := strconv.ParseInt(v.(*types.AttributeValueMemberN).Value, 10, 64)
+	return n
+}
+
+func (f *fakeTable) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (
+	*dynamodb.GetItemOutput, error,
+) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: maps.Clone(f.items[f.id(in.Key)])}, nil
+}
+
+func (f *fakeTable) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (
+	*dynamodb.UpdateItemOutput, error,
+) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.race != nil {
+		f.race(f.items)
+	}
+	f.writes++
+	id := f.id(in.Key)
+	item := f.items[id]
+	v := in.ExpressionAttributeValues
+	tat, exists := item[tatAttribute]
+
+	var ok bool
+	var next int64
+	switch *in.ConditionExpression {
+	case idleCondition:
+		ok = !exists || intOf(tat) < intOf(v[":now"])
+		next = intOf(v[":tat"])
+	case busyCondition:
+		ok = exists && intOf(tat) >= intOf(v[":now"]) && intOf(tat) <= intOf(v[":last"])
+		if ok {
+			next = intOf(tat) + intOf(v[":inc"])
+		}
+	default:
+		return nil, errors.New("unexpected condition " + *in.ConditionExpression)
+	}
+	if !ok {
+		return nil, &types.ConditionalCheckFailedException{Item: maps.Clone(item)}
+	}
+	updated := map[string]types.AttributeValue{tatAttribute: number(next), TTLAttribute: v[":ttl"]}
+	f.items[id] = map[string]types.AttributeValue{KeyAttribute: in.Key[KeyAttribute]}
+	maps.Copy(f.items[id], updated)
+	return &dynamodb.UpdateItemOutput{Attributes: updated}, nil
+}
+
+func (f *fakeTable) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (
+	*dynamodb.DeleteItemOutput, error,
+) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.items, f.id(in.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestGCRA(t *testing.T) {
+	ctx := context.Background()
+	table := newFakeTable()
+	start := time.Now()
+	r := rate.Every(10 * time.Second)
+
+	// Two instances sharing the table share the limit.
+	a, b := NewGCRA(table, "limits"), NewGCRA(table, "limits")
+	for i, alg := range []ratelimit.Algorithm{a, b, a} {
+		allowance, err := alg.Take(ctx, "k", r, 3, 1, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed, i)
+		assert.Equal(t, 2-i, allowance.Remaining, i)
+	}
+	// The first request restarts the idle key, the others advance it.
+	assert.Equal(t, 5, table.writes)
+
+	table.writes = 0
+	allowance, err := b.Take(ctx, "k", r, 3, 1, start)
+	require.NoError(t, err)
+	assert.False(t, allowance.Allowed)
+	assert.Zero(t, allowance.Remaining)
+	assert.Equal(t, 10*time.Second, allowance.RetryAfter)
+	assert.Equal(t, 1, table.writes)
+
+	allowance, err = b.Take(ctx, "k", r, 3, 1, start.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowance.Allowed)
+
+	item := table.items[gcraPrefix+"k"]
+	assert.Equal(t, (start.Add(40*time.Second).UnixMicro()+999_999)/1e6, intOf(item[TTLAttribute]))
+
+	t.Run("Peek", func(t *testing.T) {
+		allowance, err := a.Take(ctx, "k", r, 3, 0, start.Add(10*time.Second))
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, 10*time.Second, allowance.RetryAfter)
+
+		allowance, err = a.Take(ctx, "new", r, 3, 0, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed)
+		assert.Equal(t, 3, allowance.Remaining)
+		assert.NotContains(t, table.items, gcraPrefix+"new")
+	})
+
+	t.Run("Never allowed", func(t *testing.T) {
+		allowance, err := a.Take(ctx, "k", r, 3, 4, start)
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, time.Duration(-1), allowance.RetryAfter)
+
+		allowance, err = a.Take(ctx, "zero", 0, 3, 1, start)
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, time.Duration(-1), allowance.RetryAfter)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		require.NoError(t, a.Reset(ctx, "k"))
+		allowance, err := a.Take(ctx, "k", r, 3, 3, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed)
+	})
+}
+
+func TestContention(t *testing.T) {
+	ctx := context.Background()
+	table := newFakeTable()
+	start := time.Now()
+	alg := NewGCRA(table, "limits")
+
+	// Other instances keep moving the key between idle and busy before
+	// every update.
+	busy := false
+	table.race = func(items map[string]map[string]types.AttributeValue) {
+		busy = !busy
+		tat := start.UnixMicro() - 1
+		if busy {
+			tat += 2
+		}
+		items[gcraPrefix+"k"] = map[string]types.AttributeValue{tatAttribute: number(tat)}
+	}
+	_, err := alg.Take(ctx, "k", rate.Every(time.Second), 100, 1, start)
+	require.ErrorIs(t, err, ErrContention)
+	assert.Equal(t, maxAttempts, table.writes)
+
+	// Raced updates are retried.
+	table.writes = 0
+	table.race = func(items map[string]map[string]types.AttributeValue) {
+		items[gcraPrefix+"k"] = map[string]types.AttributeValue{tatAttribute: number(start.UnixMicro() + 1e6)}
+		table.race = nil
+	}
+	allowance, err := alg.Take(ctx, "k", rate.Every(time.Second), 3, 1, start)
+	require.NoError(t, err)
+	assert.True(t, allowance.Allowed)
+	assert.Equal(t, 1, allowance.Remaining)
+	assert.Equal(t, 2, table.writes)
+}
+
+func TestStore(t *testing.T) {
+	s := New(newFakeTable(), "limits")
+	limit := ratelimit.Limit{Rate: rate.Every(time.Hour), Burst: 1, N: 1}
+
+	res, err := s.Allow(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = s.Allow(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+}
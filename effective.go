@@ -0,0 +1,126 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// LimitSource is what set the limit of a key.
+type LimitSource string
+
+// Limit sources.
+const (
+	// LimitFromDefault is the limit of Options.
+	LimitFromDefault LimitSource = "default"
+	// LimitFromSignature is the limit of Options.Signatures.
+	LimitFromSignature LimitSource = "signature"
+	// LimitFromHierarchy is set along the key's path in Options.Hierarchy.
+	LimitFromHierarchy LimitSource = "hierarchy"
+	// LimitFromOverride is an override set by an operator.
+	LimitFromOverride LimitSource = "override"
+	// LimitFromLearned is learned from upstream responses, which lowered
+	// the key's configured limit.
+	LimitFromLearned LimitSource = "learned"
+)
+
+// Scopes of EffectiveLimits.
+const (
+	// ScopeKey is the limit of the key's own bucket.
+	ScopeKey = "key"
+	// ScopeGlobal is the limit shared by every key.
+	ScopeGlobal = "global"
+	// ScopeOrganization is the limit of the key's organization.
+	ScopeOrganization = "organization"
+	// ScopeGroup is the limit of the key's group: the minimum of the key,
+	// or the pool shared by the group.
+	ScopeGroup = "group"
+	// ScopeRetry is the limit of the key's retries, Options.Retries.
+	ScopeRetry = "retry"
+)
+
+// EffectiveLimit is one of the limits applying to a key.
+type EffectiveLimit struct {
+	// Scope is one of the scopes above, the spec of one of Options.Limits,
+	// or the "METHOD /path" of a route with a limit of its own.
+	Scope string `json:"scope"`
+	// Limit is the limit in the form of FormatLimit.
+	Limit string     `json:"limit"`
+	Rate  rate.Limit `json:"-"`
+	Burst int        `json:"burst"`
+}
+
+// AppliedLimits describes how the requests of a key are limited.
+type AppliedLimits struct {
+	// Key is the rate limiting key.
+	Key string `json:"key"`
+	// Source is what set the limit of the key's own bucket.
+	Source LimitSource `json:"source"`
+	// Limits are checked together on every request, the key's own bucket
+	// first, followed by the global limit and Options.Limits.
+	Limits []EffectiveLimit `json:"limits"`
+	// Routes are the routes set up by LimitEndpoints or ApplyRules, which
+	// limit the key with buckets of their own instead.
+	Routes []EffectiveLimit `json:"routes,omitempty"`
+	// Ban is the ban rejecting every request of the key, if any.
+	Ban *Ban `json:"ban,omitempty"`
+	// Note is the note of the key, if any.
+	Note *KeyNote `json:"note,omitempty"`
+}
+
+// EffectiveLimits reports the limits applying to id without consuming
+// tokens, for support tooling and customer dashboards showing clients
+// their limits. The key of id is id.Key(), the key of the default KeyFunc
+// with Options.Identity; use LimitsOf with other KeyFuncs.
+func (m *Manager) EffectiveLimits(id Identity) AppliedLimits {
+	return m.LimitsOf(id.Key())
+}
+
+// LimitsOf reports the limits applying to key, resolving its tenant
+// hierarchy, overrides, learned limits and route rules, and its ban.
+// Organization and group limits depend on the request, and are not
+// included.
+func (m *Manager) LimitsOf(key string) AppliedLimits {
+	now := time.Now()
+	r, burst, src := m.resolveLimits(key, now)
+	if lr, lburst := m.learned.capLimits(key, r, burst, now); lr != r || lburst != burst {
+		r, burst, src = lr, lburst, LimitFromLearned
+	}
+	al := AppliedLimits{Key: key, Source: src, Limits: []EffectiveLimit{effectiveLimit(ScopeKey, r, burst)}}
+	for _, lv := range m.globalLevels() {
+		al.Limits = append(al.Limits, effectiveLimit(ScopeGlobal, lv.Rate, lv.Burst))
+	}
+	for _, lv := range m.limits {
+		lr, lburst := m.scale(lv.Rate, lv.Burst)
+		al.Limits = append(al.Limits, effectiveLimit(strings.TrimPrefix(lv.Key, limitKeySeparator), lr, lburst))
+	}
+
+	m.routes.endpoints.Range(func(k, v any) bool {
+		route := v.(*routeLimit)
+		rr, rburst := m.scale(route.rate, route.burst)
+		al.Routes = append(al.Routes, effectiveLimit(k.(string), rr, rburst))
+		return true
+	})
+	sort.Slice(al.Routes, func(i, j int) bool {
+		return al.Routes[i].Scope < al.Routes[j].Scope
+	})
+
+	if b, ok := m.controls.activeBan(key, now); ok {
+		al.Ban = &b
+	}
+	if n, ok := m.Note(key); ok {
+		al.Note = &n
+	}
+	return al
+}
+
+// effectiveLimit returns the limit of scope.
+func effectiveLimit(scope string, r rate.Limit, burst int) EffectiveLimit {
+	return EffectiveLimit{Scope: scope, Limit: FormatLimit(r, burst), Rate: r, Burst: burst}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveLimits(t *testing.T) {
+	ctx := context.Background()
+	h := NewTenantHierarchy(func(key string) []string {
+		return []string{"acme", key}
+	})
+	m := NewManager(Options{
+		Limits: []LimitSpec{
+			{Requests: 10, Window: time.Second},
+			{Requests: 1000, Window: time.Hour},
+		},
+		Global:    &LimitSpec{Requests: 5000, Window: time.Second},
+		Hierarchy: h,
+	})
+	alice := Identity{Type: IdentityUser, ID: "alice"}
+
+	t.Run("Default", func(t *testing.T) {
+		al := m.EffectiveLimits(alice)
+		assert.Equal(t, "user:alice", al.Key)
+		assert.Equal(t, LimitFromDefault, al.Source)
+		assert.Equal(t, []EffectiveLimit{
+			{Scope: ScopeKey, Limit: "10/second", Rate: 10, Burst: 10},
+			{Scope: ScopeGlobal, Limit: "5000/second", Rate: 5000, Burst: 5000},
+			{Scope: "1000/hour", Limit: "1000/hour", Rate: Per(1000, time.Hour), Burst: 1000},
+		}, al.Limits)
+		assert.Nil(t, al.Ban)
+	})
+
+	t.Run("Hierarchy", func(t *testing.T) {
+		h.Set([]string{"acme"}, TenantLimit{Burst: 50})
+		defer h.Delete([]string{"acme"})
+		al := m.EffectiveLimits(alice)
+		assert.Equal(t, LimitFromHierarchy, al.Source)
+		assert.Equal(t, 50, al.Limits[0].Burst)
+	})
+
+	t.Run("Override and ban", func(t *testing.T) {
+		require.NoError(t, m.SetOverride(ctx, "user:alice", Override{Rate: 100, Burst: 200}))
+		require.NoError(t, m.Ban(ctx, "user:alice", time.Hour))
+		require.NoError(t, m.SetNote(ctx, "user:alice", map[string]string{"ticket": "OPS-1"}))
+		al := m.EffectiveLimits(alice)
+		assert.Equal(t, LimitFromOverride, al.Source)
+		assert.Equal(t, "100/second burst 200", al.Limits[0].Limit)
+		require.NotNil(t, al.Ban)
+		assert.False(t, al.Ban.Until.IsZero())
+		require.NotNil(t, al.Note)
+
+		// Scheduled bans do not apply yet.
+		require.NoError(t, m.ScheduleBan(ctx, "user:alice", time.Now().Add(time.Hour), 0))
+		assert.Nil(t, m.EffectiveLimits(alice).Ban)
+	})
+
+	t.Run("Learned", func(t *testing.T) {
+		m.learned.set(LearnedLimit{Key: "user:bob", Rate: 1, Burst: 1, Expires: time.Now().Add(time.Hour)})
+		al := m.LimitsOf("user:bob")
+		assert.Equal(t, LimitFromLearned, al.Source)
+		assert.Equal(t, "1/second", al.Limits[0].Limit)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.GET("/login", func(c *gin.Context) {})
+		m.LimitEndpoints(r, LimitSpec{Requests: 5, Window: time.Minute})
+		m.RegisterAdmin(r)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/limits?type=user&id=carol", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var al AppliedLimits
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &al))
+		assert.Equal(t, "user:carol", al.Key)
+		assert.Len(t, al.Limits, 3)
+		require.Len(t, al.Routes, 1)
+		assert.Equal(t, EffectiveLimit{Scope: "GET /login", Limit: "5/minute", Burst: 5}, al.Routes[0])
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/limits?key=user:carol", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/limits?type=user", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// OptionsFromEnv builds options from environment variables, for
+// deployments configured entirely through the environment. With the
+// prefix "RATELIMIT", the variables are:
+//
+//	RATELIMIT_LIMIT           limit spec, as in "100/minute burst 20"
+//	RATELIMIT_RATE            requests per second, or "inf"
+//	RATELIMIT_BURST           burst size
+//	RATELIMIT_MAX_DELAY       longest wait for a token, as a Go duration
+//	RATELIMIT_MAX_KEY_LENGTH  longest key stored verbatim
+//	RATELIMIT_KEY             "ip", "header:<name>" or "cookie:<name>"
+//	RATELIMIT_SESSION_SECRET  HMAC secret of signed session cookies
+//	RATELIMIT_STORE           store DSN, as accepted by NewStoreFromDSN
+//
+// RATELIMIT_LIMIT and RATELIMIT_RATE are mutually exclusive. Clients are
+// keyed by IP unless RATELIMIT_KEY names a header, such as an API key, or
+// a session cookie signed as by SignSessionCookie with
+// RATELIMIT_SESSION_SECRET; clients without one fall back to their IP.
+// Unset variables keep the option's default. Every invalid variable is
+// reported, by name.
+func OptionsFromEnv(prefix string) (Options, error) {
+	e := envReader{prefix: strings.TrimSuffix(prefix, "_") + "_"}
+	var opts Options
+
+	if v, ok := e.lookup("LIMIT"); ok {
+		spec, err := ParseLimitSpec(v)
+		e.check("LIMIT", err)
+		opts.Limit = &spec
+	}
+	if v, ok := e.lookup("RATE"); ok {
+		if opts.Limit != nil {
+			e.fail("RATE", errors.New("cannot be combined with "+e.name("LIMIT")))
+		}
+		opts.Rate = e.rate("RATE", v)
+	}
+	if v, ok := e.lookup("BURST"); ok {
+		if opts.Limit != nil {
+			e.fail("BURST", errors.New("cannot be combined with "+e.name("LIMIT")))
+		}
+		opts.Burst = e.int("BURST", v)
+	}
+	if v, ok := e.lookup("MAX_DELAY"); ok {
+		d, err := time.ParseDuration(v)
+		if err == nil && d < 0 {
+			err = errors.New("must not be negative")
+		}
+		e.check("MAX_DELAY", err)
+		opts.MaxDelay = d
+	}
+	if v, ok := e.lookup("MAX_KEY_LENGTH"); ok {
+		opts.MaxKeyLength = e.int("MAX_KEY_LENGTH", v)
+	}
+	if v, ok := e.lookup("KEY"); ok {
+		secret, _ := e.lookup("SESSION_SECRET")
+		opts.Identity = e.identity("KEY", v, secret)
+	}
+	if v, ok := e.lookup("STORE"); ok {
+		opts.Store = e.store("STORE", v)
+	}
+	return opts, errors.Join(e.errs...)
+}
+
+// envReader reads prefixed variables and collects their errors.
+type envReader struct {
+	prefix string
+	errs   []error
+}
+
+func (e *envReader) name(key string) string {
+	return e.prefix + key
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	v, ok := os.LookupEnv(e.name(key))
+	v = strings.TrimSpace(v)
+	return v, ok && v != ""
+}
+
+func (e *envReader) fail(key string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("ratelimit: %s: %w", e.name(key), err))
+}
+
+func (e *envReader) check(key string, err error) {
+	if err != nil {
+		e.fail(key, err)
+	}
+}
+
+func (e *envReader) int(key, v string) int {
+	n, err := strconv.Atoi(v)
+	if err == nil && n < 0 {
+		err = errors.New("must not be negative")
+	}
+	if err != nil {
+		e.fail(key, fmt.Errorf("invalid number %q", v))
+	}
+	return n
+}
+
+func (e *envReader) rate(key, v string) rate.Limit {
+	if strings.EqualFold(v, "inf") {
+		return rate.Inf
+	}
+	r, err := strconv.ParseFloat(v, 64)
+	if err != nil || r < 0 || math.IsNaN(r) {
+		e.fail(key, fmt.Errorf("invalid rate %q", v))
+	}
+	return rate.Limit(r)
+}
+
+func (e *envReader) identity(key, v, secret string) IdentityResolver {
+	kind, name, _ := strings.Cut(v, ":")
+	switch {
+	case kind == "ip" && name == "":
+		return nil
+	case kind == "header" && name != "":
+		return APIKeyResolver(name)
+	case kind == "cookie" && name != "":
+		if secret == "" {
+			e.fail(key, fmt.Errorf("cookie keys need %s, since unsigned cookies can be forged", e.name("SESSION_SECRET")))
+			return nil
+		}
+		return SignedSessionCookieResolver(name, []byte(secret))
+	}
+	e.fail(key, fmt.Errorf(`invalid key %q, want "ip", "header:<name>" or "cookie:<name>"`, v))
+	return nil
+}
+
+func (e *envReader) store(key, dsn string) Store {
+	store, err := NewStoreFromDSN(dsn)
+	e.check(key, err)
+	return store
+}
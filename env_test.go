@@ -0,0 +1,81 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestOptionsFromEnv(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Empty", func(t *testing.T) {
+		opts, err := OptionsFromEnv("APP_RATELIMIT")
+		require.NoError(t, err)
+		assert.Equal(t, Options{}, opts)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		t.Setenv("APP_RATELIMIT_LIMIT", "100/minute burst 20")
+		t.Setenv("APP_RATELIMIT_MAX_DELAY", "250ms")
+		t.Setenv("APP_RATELIMIT_MAX_KEY_LENGTH", "64")
+		t.Setenv("APP_RATELIMIT_STORE", "memory://")
+
+		opts, err := OptionsFromEnv("APP_RATELIMIT_")
+		require.NoError(t, err)
+		assert.Equal(t, &LimitSpec{Requests: 100, Window: time.Minute, Burst: 20}, opts.Limit)
+		assert.Equal(t, 250*time.Millisecond, opts.MaxDelay)
+		assert.Equal(t, 64, opts.MaxKeyLength)
+		assert.IsType(t, &shardedStore{}, opts.Store)
+	})
+
+	t.Run("Rate", func(t *testing.T) {
+		t.Setenv("APP_RATELIMIT_RATE", "2.5")
+		t.Setenv("APP_RATELIMIT_BURST", "5")
+
+		opts, err := OptionsFromEnv("APP_RATELIMIT")
+		require.NoError(t, err)
+		assert.Equal(t, rate.Limit(2.5), opts.Rate)
+		assert.Equal(t, 5, opts.Burst)
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		t.Setenv("APP_RATELIMIT_RATE", "inf")
+		t.Setenv("APP_RATELIMIT_KEY", "cookie:session")
+		t.Setenv("APP_RATELIMIT_SESSION_SECRET", "secret")
+
+		opts, err := OptionsFromEnv("APP_RATELIMIT")
+		require.NoError(t, err)
+		m := NewManager(opts)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.AddCookie(&http.Cookie{Name: "session", Value: SignSessionCookie([]byte("secret"), "abc")})
+		m.identify(c)
+		assert.Equal(t, "session:abc", m.key(c))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Setenv("APP_RATELIMIT_LIMIT", "100/fortnight")
+		t.Setenv("APP_RATELIMIT_BURST", "-1")
+		t.Setenv("APP_RATELIMIT_MAX_DELAY", "soon")
+		t.Setenv("APP_RATELIMIT_KEY", "cookie:session")
+		t.Setenv("APP_RATELIMIT_STORE", "carrier-pigeon://")
+
+		_, err := OptionsFromEnv("APP_RATELIMIT")
+		require.Error(t, err)
+		for _, name := range []string{"LIMIT", "BURST", "MAX_DELAY", "KEY", "STORE"} {
+			assert.Contains(t, err.Error(), "APP_RATELIMIT_"+name+":")
+		}
+		assert.Contains(t, err.Error(), "APP_RATELIMIT_SESSION_SECRET")
+	})
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package etcdstore
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+	"golang.org/x/time/rate"
+)
+
+// startServer starts an embedded etcd server and returns its address.
+func startServer(t *testing.T) string {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	local := url.URL{Scheme: "http", Host: "127.0.0.1:0"}
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{local}, []url.URL{local}
+	peer := url.URL{Scheme: "http", Host: "127.0.0.1:0"}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.InitialCluster = cfg.Name + "=" + peer.String()
+
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd did not start")
+	}
+	return e.Clients[0].Addr().String()
+}
+
+// newClient starts an embedded etcd server and returns a client of it.
+func newClient(t *testing.T) *clientv3.Client {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{startServer(t)},
+		DialTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestGCRA(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	start := time.Now()
+	r := rate.Every(10 * time.Second)
+
+	// Two instances sharing the cluster share the limit.
+	a, b := NewGCRA(client), NewGCRA(client)
+	for i, alg := range []ratelimit.Algorithm{a, b, a} {
+		allowance, err := alg.Take(ctx, "k", r, 3, 1, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed, i)
+		assert.Equal(t, 2-i, allowance.Remaining, i)
+	}
+
+	allowance, err := b.Take(ctx, "k", r, 3, 1, start)
+	require.NoError(t, err)
+	assert.False(t, allowance.Allowed)
+	assert.Zero(t, allowance.Remaining)
+	assert.Equal(t, 10*time.Second, allowance.RetryAfter)
+
+	allowance, err = b.Take(ctx, "k", r, 3, 1, start.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowance.Allowed)
+
+	resp, err := client.Get(ctx, Prefix+"k")
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	ttl, err := client.TimeToLive(ctx, clientv3.LeaseID(resp.Kvs[0].Lease))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Duration(ttl.TTL)*time.Second, 39*time.Second)
+
+	t.Run("Peek", func(t *testing.T) {
+		allowance, err := a.Take(ctx, "k", r, 3, 0, start.Add(10*time.Second))
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, 10*time.Second, allowance.RetryAfter)
+
+		allowance, err = a.Take(ctx, "new", r, 3, 0, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed)
+		assert.Equal(t, 3, allowance.Remaining)
+		resp, err := client.Get(ctx, Prefix+"new")
+		require.NoError(t, err)
+		assert.Empty(t, resp.Kvs)
+	})
+
+	t.Run("Never allowed", func(t *testing.T) {
+		allowance, err := a.Take(ctx, "k", r, 3, 4, start)
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, time.Duration(-1), allowance.RetryAfter)
+
+		allowance, err = a.Take(ctx, "zero", 0, 3, 1, start)
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, time.Duration(-1), allowance.RetryAfter)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		require.NoError(t, a.Reset(ctx, "k"))
+		allowance, err := a.Take(ctx, "k", r, 3, 3, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed)
+	})
+
+	t.Run("Expired lease", func(t *testing.T) {
+		s := newStore(client)
+		_, err := s.Take(ctx, "lease", r, 3, 1, start)
+		require.NoError(t, err)
+		for _, id := range s.leases {
+			_, err := client.Revoke(ctx, id)
+			require.NoError(t, err)
+		}
+		allowance, err := s.Take(ctx, "lease", r, 3, 1, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed)
+		assert.Equal(t, 2, allowance.Remaining)
+	})
+}
+
+func TestTakeCosts(t *testing.T) {
+	ctx := context.Background()
+	alg := NewGCRA(newClient(t)).(ratelimit.CostAlgorithm)
+	start := time.Now()
+	levels := []ratelimit.Level{
+		{Key: "user", Rate: rate.Every(time.Second), Burst: 5},
+		{Key: "org", Rate: rate.Every(time.Second), Burst: 10},
+		{Key: "global", Rate: rate.Inf, Burst: 100},
+	}
+
+	allowance, i, err := alg.TakeCosts(ctx, levels, []int{4, 8, 1}, start)
+	require.NoError(t, err)
+	assert.True(t, allowance.Allowed)
+	assert.Equal(t, 1, allowance.Remaining)
+	assert.Equal(t, 0, i)
+
+	// The organization denies, so the user is not charged either.
+	allowance, i, err = alg.TakeCosts(ctx, levels, []int{1, 3, 1}, start)
+	require.NoError(t, err)
+	assert.False(t, allowance.Allowed)
+	assert.Equal(t, 1, i)
+	allowance, _, err = alg.TakeLevels(ctx, levels[:1], 0, start)
+	require.NoError(t, err)
+	assert.Equal(t, 1, allowance.Remaining)
+
+	// Concurrent requests are serialized by the transactions.
+	results := make(chan bool, 10)
+	for range 10 {
+		go func() {
+			allowance, _, err := alg.TakeCosts(ctx, levels[1:2], []int{1}, start.Add(5*time.Second))
+			results <- err == nil && allowance.Allowed
+		}()
+	}
+	allowed := 0
+	for range 10 {
+		if <-results {
+			allowed++
+		}
+	}
+	assert.LessOrEqual(t, allowed, 7)
+}
+
+func TestStore(t *testing.T) {
+	s := New(newClient(t))
+	limit := ratelimit.Limit{Rate: rate.Every(time.Hour), Burst: 1, N: 1}
+
+	res, err := s.Allow(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = s.Allow(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+}
+
+func TestNewStoreFromDSN(t *testing.T) {
+	assert.Contains(t, ratelimit.StoreSchemes(), "etcd")
+
+	t.Run("Etcd", func(t *testing.T) {
+		s, err := ratelimit.NewStoreFromDSN("etcd://" + startServer(t) + ",127.0.0.1:1?dial_timeout=5s")
+		require.NoError(t, err)
+		client := s.(*store).client
+		t.Cleanup(func() { client.Close() })
+		assert.Len(t, client.Endpoints(), 2)
+
+		limit := ratelimit.Limit{Rate: rate.Every(time.Minute), Burst: 1, N: 1}
+		res, err := s.Allow(context.Background(), "k", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	})
+
+	for _, dsn := range []string{
+		"etcd://",
+		"etcd://localhost:2379?dial_timeout=soon",
+		"etcd://localhost:2379?tls=1",
+	} {
+		t.Run(dsn, func(t *testing.T) {
+			_, err := ratelimit.NewStoreFromDSN(dsn)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyntheticRequest describes a hypothetical request to evaluate against the
+// limiter without sending it.
+type SyntheticRequest struct {
+	// Method is the HTTP method. If empty, GET is used.
+	Method string `json:"method"`
+	// Path is the request target, including any query string.
+	// If empty, "/" is used.
+	Path string `json:"path"`
+	// Route is the pattern of the route the request is matched to, such
+	// as "/users/:id", which Path must match. Routes marked with Exempt
+	// or RouteLimit are recognized once they served a request. If empty,
+	// the request matches no route.
+	Route string `json:"route"`
+	// IP is the client address the request appears to come from.
+	IP string `json:"ip"`
+	// Header holds the request headers.
+	Header map[string]string `json:"header"`
+}
+
+// Evaluation is the outcome of evaluating a synthetic request.
+type Evaluation struct {
+	// Key is the rate limiting key the request maps to.
+	Key string
+	// Identity is the identity resolved by Options.Identity, if any.
+	Identity Identity
+	// Classification is the traffic class assigned by Options.Classifier.
+	Classification Classification
+	// Allowed reports whether the request would currently be allowed.
+	Allowed bool
+	// Bypass is why the request would bypass limiting, if it would. Such
+	// requests are allowed without being keyed.
+	Bypass BypassReason
+	// Denylisted reports whether Options.IPLists denies the client.
+	Denylisted bool
+	// Banned reports whether the key is banned.
+	Banned bool
+	// Cost is the number of tokens the request would take, from
+	// Options.CostFunc, the class costs and Options.Retries.
+	Cost int
+	// Limit is the limit deciding the request: the one that would deny
+	// it, or the most restrictive of those allowing it. It is nil for
+	// requests bypassing limiting, denylisted or banned.
+	Limit *EffectiveLimit
+	// Tokens is the number of tokens currently available in Limit, or
+	// the requests remaining in its window with Options.Algorithm.
+	Tokens float64
+	// Delay is how long the request would be held before being served.
+	// It is only set for allowed requests when MaxDelay is configured or
+	// the algorithm paces requests.
+	Delay time.Duration
+	// RetryAfter is how long until the request would be allowed.
+	// It is only set for requests that would be rejected.
+	RetryAfter time.Duration
+}
+
+// Evaluate reports how the middleware would handle the given request,
+// without consuming any tokens. It is meant for answering "why was I
+// limited" questions and for testing KeyFunc logic. The request goes
+// through the same checks as real ones, except for the duplicate payload
+// throttle, which needs the request body.
+func (m *Manager) Evaluate(sr SyntheticRequest) (Evaluation, error) {
+	var ev Evaluation
+	err := sr.serve(func(c *gin.Context) error {
+		var err error
+		ev, err = m.evaluate(c)
+		return err
+	})
+	return ev, err
+}
+
+// evaluate evaluates the request of c.
+func (m *Manager) evaluate(c *gin.Context) (Evaluation, error) {
+	route, exempt := m.routes.lookup(c)
+	if exempt {
+		return Evaluation{Allowed: true, Bypass: BypassExempt}, nil
+	}
+	d := m.decide(c, route, true)
+	ev := Evaluation{
+		Key:            d.key,
+		Classification: d.cl,
+		Bypass:         d.bypass,
+		Denylisted:     d.denylisted,
+		Banned:         d.banned,
+	}
+	ev.Identity, _ = IdentityFrom(c)
+	switch {
+	case d.bypass != "":
+		ev.Allowed = true
+		return ev, nil
+	case d.denylisted || d.banned:
+		return ev, nil
+	case d.err != nil:
+		return ev, d.err
+	}
+
+	ev.Allowed, ev.Cost, ev.Limit = d.allowed, d.cost, &d.limit
+	ev.Tokens = float64(d.allowance.Remaining)
+	if ev.Allowed {
+		ev.Delay = d.allowance.Delay
+	} else if d.allowance.RetryAfter > 0 {
+		ev.RetryAfter = d.allowance.RetryAfter
+	}
+	return ev, nil
+}
+
+// serve passes a gin context for the synthetic request to f, so the
+// configured KeyFunc sees it exactly as it would a real one. With a Route,
+// the context is matched to it by an engine of its own.
+func (sr SyntheticRequest) serve(f func(c *gin.Context) error) error {
+	method := sr.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := sr.Path
+	if path == "" {
+		path = "/"
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, path, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range sr.Header {
+		req.Header.Set(name, value)
+	}
+	if sr.IP != "" {
+		req.RemoteAddr = net.JoinHostPort(sr.IP, "0")
+	}
+
+	if sr.Route == "" {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		return f(c)
+	}
+
+	var (
+		e       = gin.New()
+		matched bool
+		ferr    error
+	)
+	err = handle(e, method, sr.Route, func(c *gin.Context) {
+		matched, ferr = true, f(c)
+	})
+	if err != nil {
+		return err
+	}
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if !matched {
+		return fmt.Errorf("ratelimit: path %q does not match route %q", path, sr.Route)
+	}
+	return ferr
+}
+
+// handle registers h for method and route on e, returning gin's panic on
+// invalid routes as an error.
+func handle(e *gin.Engine, method, route string, h gin.HandlerFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ratelimit: invalid route %q: %v", route, r)
+		}
+	}()
+	e.Handle(method, route, h)
+	return nil
+}
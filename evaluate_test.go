@@ -0,0 +1,280 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestEvaluate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newManager := func() *Manager {
+		return NewManager(Options{
+			Rate:  rate.Every(time.Hour),
+			Burst: 1,
+			KeyFunc: func(c *gin.Context) string {
+				return c.ClientIP() + "|" + c.GetHeader("X-API-KEY")
+			},
+		})
+	}
+
+	t.Run("DoesNotConsumeTokens", func(t *testing.T) {
+		m := newManager()
+		sr := SyntheticRequest{IP: "203.0.113.7", Header: map[string]string{"X-API-KEY": "k"}}
+
+		for i := 0; i < 3; i++ {
+			ev, err := m.Evaluate(sr)
+			assert.NoError(t, err)
+			assert.Equal(t, "203.0.113.7|k", ev.Key)
+			assert.True(t, ev.Allowed)
+			assert.Equal(t, float64(1), ev.Tokens)
+		}
+	})
+
+	t.Run("ReportsDenial", func(t *testing.T) {
+		m := newManager()
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.Equal(t, "203.0.113.7|", ev.Key)
+		assert.False(t, ev.Allowed)
+		assert.InDelta(t, time.Hour.Seconds(), ev.RetryAfter.Seconds(), 1)
+
+		// Another client is unaffected.
+		ev, err = m.Evaluate(SyntheticRequest{IP: "203.0.113.8"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+	})
+
+	t.Run("AdminEndpoint", func(t *testing.T) {
+		m := newManager()
+		r := gin.New()
+		m.RegisterAdmin(r.Group("/admin"))
+
+		w := httptest.NewRecorder()
+		body := `{"ip": "203.0.113.7", "header": {"X-API-KEY": "k"}}`
+		req, _ := http.NewRequest("POST", "/admin/evaluate", strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var got map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "203.0.113.7|k", got["key"])
+		assert.Equal(t, true, got["allowed"])
+		assert.Equal(t, map[string]any{"scope": ScopeKey, "limit": "1/hour", "burst": float64(1)}, got["limit"])
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/admin/config", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"keyFunc": "custom"`)
+	})
+}
+
+func TestEvaluateSharesDecision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// serve sends a request from ip to m's middleware on an engine whose
+	// routes are set up by routes.
+	serve := func(m *Manager, routes func(r *gin.Engine), method, path, ip string) int {
+		r := gin.New()
+		r.Use(m.Handler())
+		routes(r)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	ok := func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	}
+	root := func(r *gin.Engine) {
+		r.GET("/", ok)
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1, Skip: func(c *gin.Context) bool {
+			return c.GetHeader("X-Internal") != ""
+		}})
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.7", Header: map[string]string{"X-Internal": "1"}})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		assert.Equal(t, BypassSkip, ev.Bypass)
+		assert.Nil(t, ev.Limit)
+	})
+
+	t.Run("IPLists", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1, IPLists: &IPLists{
+			Allow: []string{"10.0.0.0/8"},
+			Deny:  []string{"203.0.113.7"},
+		}})
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		assert.True(t, ev.Denylisted)
+		assert.Equal(t, http.StatusForbidden, serve(m, root, "GET", "/", "203.0.113.7"))
+
+		ev, err = m.Evaluate(SyntheticRequest{IP: "10.1.2.3"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		assert.Equal(t, BypassAllowlist, ev.Bypass)
+	})
+
+	t.Run("Routes", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 10})
+		routes := func(r *gin.Engine) {
+			r.GET("/healthz", Exempt(), ok)
+			r.POST("/login", m.RouteLimit(LimitSpec{Requests: 1, Window: time.Hour}), ok)
+			r.GET("/users/:id", ok)
+		}
+		assert.Equal(t, http.StatusOK, serve(m, routes, "GET", "/healthz", "203.0.113.7"))
+		assert.Equal(t, http.StatusOK, serve(m, routes, "POST", "/login", "203.0.113.7"))
+
+		ev, err := m.Evaluate(SyntheticRequest{Path: "/healthz", Route: "/healthz", IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		assert.Equal(t, BypassExempt, ev.Bypass)
+
+		ev, err = m.Evaluate(SyntheticRequest{Method: "POST", Path: "/login", Route: "/login", IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, "POST /login", ev.Limit.Scope)
+			assert.Equal(t, 1, ev.Limit.Burst)
+		}
+		assert.InDelta(t, time.Hour.Seconds(), ev.RetryAfter.Seconds(), 1)
+		assert.Equal(t, http.StatusTooManyRequests, serve(m, routes, "POST", "/login", "203.0.113.7"))
+
+		// LimitEndpoints gives routes buckets of their own.
+		e := gin.New()
+		routes(e)
+		m.LimitEndpoints(e, LimitSpec{Requests: 5, Window: time.Minute})
+		ev, err = m.Evaluate(SyntheticRequest{Path: "/users/42", Route: "/users/:id", IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, "GET /users/:id", ev.Limit.Scope)
+			assert.Equal(t, 5, ev.Limit.Burst)
+		}
+
+		_, err = m.Evaluate(SyntheticRequest{Path: "/orders/42", Route: "/users/:id"})
+		assert.Error(t, err)
+		_, err = m.Evaluate(SyntheticRequest{Path: "/", Route: "users"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		spec := LimitSpec{Requests: 2, Window: time.Hour}
+		m := NewManager(Options{
+			Limits: []LimitSpec{{Requests: 10, Window: time.Second}, spec},
+			Global: &LimitSpec{Requests: 100, Window: time.Second},
+		})
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, spec.String(), ev.Limit.Scope)
+		}
+		assert.Equal(t, float64(2), ev.Tokens)
+
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, serve(m, root, "GET", "/", "203.0.113.7"))
+		}
+		ev, err = m.Evaluate(SyntheticRequest{IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, spec.String(), ev.Limit.Scope)
+		}
+		assert.InDelta(t, (30 * time.Minute).Seconds(), ev.RetryAfter.Seconds(), 1)
+		assert.Equal(t, http.StatusTooManyRequests, serve(m, root, "GET", "/", "203.0.113.7"))
+	})
+
+	t.Run("Global", func(t *testing.T) {
+		m := NewManager(Options{Rate: 10, Burst: 10, Global: &LimitSpec{Requests: 1, Window: time.Hour}})
+		assert.Equal(t, http.StatusOK, serve(m, root, "GET", "/", "203.0.113.7"))
+
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.8"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, ScopeGlobal, ev.Limit.Scope)
+		}
+	})
+
+	t.Run("CostFunc", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 2, CostFunc: func(c *gin.Context) int {
+			return len(c.QueryArray("id"))
+		}})
+		ev, err := m.Evaluate(SyntheticRequest{Path: "/?id=1&id=2&id=3", IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		assert.Equal(t, 3, ev.Cost)
+		assert.Equal(t, http.StatusTooManyRequests, serve(m, root, "GET", "/?id=1&id=2&id=3", "203.0.113.7"))
+
+		ev, err = m.Evaluate(SyntheticRequest{Path: "/?id=1&id=2", IP: "203.0.113.7"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		assert.Equal(t, 2, ev.Cost)
+	})
+
+	t.Run("Organization", func(t *testing.T) {
+		m := NewManager(Options{Rate: 10, Burst: 10, Organization: &Organization{
+			Key:   func(c *gin.Context) string { return "acme" },
+			Rate:  rate.Every(time.Hour),
+			Burst: 1,
+		}})
+		assert.Equal(t, http.StatusOK, serve(m, root, "GET", "/", "203.0.113.7"))
+
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.8"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, ScopeOrganization, ev.Limit.Scope)
+		}
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		m := NewManager(Options{Rate: 10, Burst: 10, Groups: &KeyGroups{
+			Group: func(string) string { return "acme" },
+			Rate:  rate.Every(time.Hour),
+			Burst: 1,
+		}})
+		ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.8"})
+		assert.NoError(t, err)
+		assert.True(t, ev.Allowed)
+		assert.Equal(t, http.StatusOK, serve(m, root, "GET", "/", "203.0.113.7"))
+
+		ev, err = m.Evaluate(SyntheticRequest{IP: "203.0.113.8"})
+		assert.NoError(t, err)
+		assert.False(t, ev.Allowed)
+		if assert.NotNil(t, ev.Limit) {
+			assert.Equal(t, ScopeGroup, ev.Limit.Scope)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(m, root, "GET", "/", "203.0.113.8"))
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import "time"
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Event types emitted by the manager.
+const (
+	// EventGuardrailTripped is emitted when a guardrail's SLO burns too fast
+	// and the limits of its low-priority classes are tightened.
+	EventGuardrailTripped EventType = "guardrail_tripped"
+	// EventGuardrailCleared is emitted when a tripped guardrail recovers.
+	EventGuardrailCleared EventType = "guardrail_cleared"
+	// EventClockJump is emitted when the wall clock jumps relative to the
+	// monotonic clock; see ClockStats.
+	EventClockJump EventType = "clock_jump"
+	// EventAnomalyDetected is emitted when a key's request rate deviates
+	// sharply from its baseline; see Options.Anomalies.
+	EventAnomalyDetected EventType = "anomaly_detected"
+	// EventAnomalyCleared is emitted when a flagged key's request rate is
+	// back near its baseline.
+	EventAnomalyCleared EventType = "anomaly_cleared"
+	// EventDryRunDenied is emitted for every request that would have been
+	// denied, and was let through by Options.DryRun.
+	EventDryRunDenied EventType = "dry_run_denied"
+	// EventBypassed is emitted for every request that bypassed limiting,
+	// with Options.AuditBypasses. The message holds the reason, method and
+	// path of the request.
+	EventBypassed EventType = "bypassed"
+	// EventKeysEvicted is emitted when the in-memory store drops token
+	// buckets to stay within Options.MaxKeys. The message holds the number
+	// dropped.
+	EventKeysEvicted EventType = "keys_evicted"
+)
+
+// Event describes a noteworthy change in the limiter's behavior.
+type Event struct {
+	// Type is the kind of event.
+	Type EventType `json:"type"`
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+	// Key is the rate limiting key the event is about, if any.
+	Key string `json:"key,omitempty"`
+	// Class is the traffic class the event is about, if any.
+	Class string `json:"class,omitempty"`
+	// Message is a human-readable description of the event.
+	Message string `json:"message,omitempty"`
+}
+
+// emit delivers an event to the OnEvent hook, if one is configured.
+func (m *Manager) emit(e Event) {
+	if m.opts.OnEvent == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Key = m.anonymize(e.Key, e.Time)
+	m.opts.OnEvent(e)
+}
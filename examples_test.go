@@ -0,0 +1,208 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exampleResponse is the outcome of a request sent to a running example.
+type exampleResponse struct {
+	code   int
+	header http.Header
+	body   string
+}
+
+// exampleClient sends requests to a running example program.
+type exampleClient struct {
+	t    *testing.T
+	base string
+}
+
+// do sends a request and returns the response.
+func (c exampleClient) do(method, path string, header map[string]string, body string) exampleResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, strings.NewReader(body))
+	require.NoError(c.t, err)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	return exampleResponse{code: resp.StatusCode, header: resp.Header, body: string(data)}
+}
+
+// get sends a GET request and returns the response.
+func (c exampleClient) get(path string, header map[string]string) exampleResponse {
+	return c.do(http.MethodGet, path, header, "")
+}
+
+// TestExamples builds every program under _examples, runs it and checks
+// its behavior over HTTP, so the examples cannot silently rot.
+func TestExamples(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping example programs in short mode")
+	}
+
+	examples := []struct {
+		name string
+		// vars are passed to the example on top of the test's environment.
+		vars  []string
+		check func(t *testing.T, c exampleClient)
+	}{
+		{
+			name: "basic",
+			check: func(t *testing.T, c exampleClient) {
+				assert.Equal(t, http.StatusOK, c.get("/", nil).code)
+				assert.Equal(t, http.StatusTooManyRequests, c.get("/", nil).code)
+
+				for i := 0; i < 3; i++ {
+					assert.Equal(t, http.StatusOK, c.get("/unlimited", nil).code)
+				}
+
+				key := map[string]string{"X-API-KEY": "example"}
+				for i := 0; i < 5; i++ {
+					assert.Equal(t, http.StatusOK, c.get("/custom", key).code)
+				}
+				resp := c.get("/custom", key)
+				assert.Equal(t, http.StatusTooManyRequests, resp.code)
+				assert.JSONEq(t, `{"message": "Too many requests"}`, resp.body)
+			},
+		},
+		{
+			name: "tiered-plans",
+			check: func(t *testing.T, c exampleClient) {
+				assert.Equal(t, http.StatusUnauthorized, c.get("/", nil).code)
+
+				free := map[string]string{"X-API-KEY": "free-key"}
+				assert.Equal(t, http.StatusOK, c.get("/", free).code)
+				assert.Equal(t, http.StatusOK, c.get("/", free).code)
+				assert.Equal(t, http.StatusTooManyRequests, c.get("/", free).code)
+
+				pro := map[string]string{"X-API-KEY": "pro-key"}
+				for i := 0; i < 20; i++ {
+					assert.Equal(t, http.StatusOK, c.get("/", pro).code)
+				}
+			},
+		},
+		{
+			name: "admin-api",
+			vars: []string{"ADMIN_TOKEN=example-token"},
+			check: func(t *testing.T, c exampleClient) {
+				assert.Equal(t, http.StatusOK, c.get("/", nil).code)
+
+				assert.Equal(t, http.StatusUnauthorized, c.get("/admin/config", nil).code)
+
+				auth := map[string]string{"Authorization": "Bearer example-token"}
+				resp := c.get("/admin/config", auth)
+				assert.Equal(t, http.StatusOK, resp.code)
+				assert.Contains(t, resp.body, `"burst": 5`)
+
+				resp = c.do(http.MethodPost, "/admin/evaluate", auth, `{"ip": "127.0.0.1"}`)
+				assert.Equal(t, http.StatusOK, resp.code)
+				assert.Contains(t, resp.body, `"allowed":true`)
+
+				assert.Equal(t, http.StatusOK, c.get("/admin/dashboard/", nil).code)
+			},
+		},
+		{
+			name: "adaptive",
+			check: func(t *testing.T, c exampleClient) {
+				assert.Equal(t, http.StatusOK, c.get("/", nil).code)
+
+				var (
+					mu        sync.Mutex
+					codes     = make(map[int]int)
+					pressured bool
+					wg        sync.WaitGroup
+				)
+				for i := 0; i < 19; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						resp := c.get("/", nil)
+						mu.Lock()
+						defer mu.Unlock()
+						codes[resp.code]++
+						if resp.header.Get("X-Backpressure") != "" {
+							pressured = true
+						}
+					}()
+				}
+				wg.Wait()
+
+				assert.Positive(t, codes[http.StatusOK])
+				assert.Positive(t, codes[http.StatusServiceUnavailable])
+				assert.True(t, pressured)
+			},
+		},
+	}
+
+	bin := t.TempDir()
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			path := filepath.Join(bin, ex.name)
+			buildExample(t, path, "./_examples/"+ex.name)
+			ex.check(t, startExample(t, path, ex.vars...))
+		})
+	}
+}
+
+// buildExample compiles the example in dir to bin.
+func buildExample(t *testing.T, bin, dir string) {
+	build := exec.Command("go", "build", "-o", bin, dir)
+	out, err := build.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// startExample starts the example binary on a free local port and waits
+// until it accepts requests, passing it the extra environment variables.
+// The process is stopped when the test ends.
+func startExample(t *testing.T, bin string, env ...string) exampleClient {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	cmd := exec.Command(bin)
+	cmd.Env = append(os.Environ(), "ADDR="+addr, "GIN_MODE=release")
+	cmd.Env = append(cmd.Env, env...)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("example %s did not start: %v", bin, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return exampleClient{t: t, base: "http://" + addr}
+}
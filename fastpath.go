@@ -0,0 +1,27 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// fastPathHandler returns the handler of Options.FastPath. The bucket is
+// shared by all requests and checked without allocating, so the handler
+// costs little more than the limiter's lock.
+func fastPathHandler(r rate.Limit, burst int) gin.HandlerFunc {
+	if r == rate.Inf {
+		return func(*gin.Context) {}
+	}
+	limiter := rate.NewLimiter(r, burst)
+	return func(c *gin.Context) {
+		if !limiter.Allow() {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		}
+	}
+}
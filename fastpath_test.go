@@ -0,0 +1,75 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestFastPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(New(Options{Rate: rate.Every(time.Hour), Burst: 2, FastPath: true}))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	// All clients share the bucket, and no headers are set.
+	addrs := []string{"203.0.113.1:1234", "203.0.113.2:1234", "203.0.113.3:1234"}
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = addrs[i]
+		r.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		h := NewManager(Options{Rate: rate.Inf, FastPath: true}).Handler()
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		h(c)
+		assert.False(t, c.IsAborted())
+	})
+}
+
+// benchmarkHandler measures a limiting middleware serving requests that
+// are always allowed, on top of the cost of routing them.
+func benchmarkHandler(b *testing.B, h gin.HandlerFunc) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(h)
+	r.GET("/", func(*gin.Context) {})
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.1:1234"
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		w := httptest.NewRecorder()
+		for pb.Next() {
+			r.ServeHTTP(w, req)
+		}
+	})
+}
+
+func BenchmarkRouting(b *testing.B) {
+	benchmarkHandler(b, func(*gin.Context) {})
+}
+
+func BenchmarkHandler(b *testing.B) {
+	benchmarkHandler(b, New(Options{Rate: rate.Limit(1e9), Burst: 1e9, Headers: HeadersNone}))
+}
+
+func BenchmarkFastPath(b *testing.B) {
+	benchmarkHandler(b, New(Options{Rate: rate.Limit(1e9), Burst: 1e9, FastPath: true}))
+}
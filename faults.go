@@ -0,0 +1,146 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrInjectedFault is the error injected by InjectFaults unless
+// Faults.Error is set.
+var ErrInjectedFault = errors.New("ratelimit: injected fault")
+
+// Faults configures the faults InjectFaults adds to an algorithm, so a
+// staging deployment can verify that failure policies and alerting behave
+// as intended before a real outage. Every fault is injected on its own
+// with its probability, between 0 and 1.
+type Faults struct {
+	// Latency is added to calls with probability LatencyProbability, as
+	// a slow store would. Waiting stops when the request is canceled.
+	Latency            time.Duration
+	LatencyProbability float64
+
+	// Error is returned instead of calling the algorithm with probability
+	// ErrorProbability. If nil, ErrInjectedFault is returned.
+	Error            error
+	ErrorProbability float64
+
+	// ClockSkew is added to the time of calls with probability
+	// SkewProbability, as if the clock of this instance were off.
+	ClockSkew       time.Duration
+	SkewProbability float64
+
+	// Rand returns the numbers in [0, 1) that decide whether faults are
+	// injected. If nil, math/rand/v2 is used.
+	Rand func() float64
+}
+
+// faultAlgorithm is an Algorithm injecting faults into another.
+type faultAlgorithm struct {
+	alg Algorithm
+	cfg Faults
+}
+
+// InjectFaults returns an Algorithm calling alg with the given faults. It
+// is meant for resilience testing, for example of the backends of a
+// TenantRouter, and should not be used in production.
+func InjectFaults(alg Algorithm, f Faults) Algorithm {
+	if f.Error == nil {
+		f.Error = ErrInjectedFault
+	}
+	if f.Rand == nil {
+		f.Rand = rand.Float64
+	}
+	return &faultAlgorithm{alg: alg, cfg: f}
+}
+
+// inject injects the latency and error faults, reporting the error the
+// call fails with, if any.
+func (f *faultAlgorithm) inject(ctx context.Context) error {
+	if f.cfg.Latency > 0 && f.cfg.Rand() < f.cfg.LatencyProbability {
+		timer := time.NewTimer(f.cfg.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if f.cfg.Rand() < f.cfg.ErrorProbability {
+		return f.cfg.Error
+	}
+	return nil
+}
+
+// skew returns now with the clock skew fault injected.
+func (f *faultAlgorithm) skew(now time.Time) time.Time {
+	if f.cfg.ClockSkew != 0 && f.cfg.Rand() < f.cfg.SkewProbability {
+		return now.Add(f.cfg.ClockSkew)
+	}
+	return now
+}
+
+// Take implements Algorithm.
+func (f *faultAlgorithm) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	if err := f.inject(ctx); err != nil {
+		return Allowance{}, err
+	}
+	return f.alg.Take(ctx, key, r, burst, n, f.skew(now))
+}
+
+// TakeLevels implements LevelAlgorithm.
+func (f *faultAlgorithm) TakeLevels(ctx context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	if err := f.inject(ctx); err != nil {
+		return Allowance{}, 0, err
+	}
+	return takeLevels(ctx, f.alg, levels, n, f.skew(now))
+}
+
+// TakeCosts implements CostAlgorithm.
+func (f *faultAlgorithm) TakeCosts(ctx context.Context, levels []Level, costs []int, now time.Time) (
+	Allowance, int, error,
+) {
+	if err := f.inject(ctx); err != nil {
+		return Allowance{}, 0, err
+	}
+	return takeCosts(ctx, f.alg, levels, costs, f.skew(now))
+}
+
+// Reset implements Algorithm.
+func (f *faultAlgorithm) Reset(ctx context.Context, key string) error {
+	if err := f.inject(ctx); err != nil {
+		return err
+	}
+	return f.alg.Reset(ctx, key)
+}
+
+// Skew implements SkewReporter, reporting the skew observed by the wrapped
+// algorithm, if it observes it.
+func (f *faultAlgorithm) Skew() *SkewStats {
+	if sr, ok := f.alg.(SkewReporter); ok {
+		return sr.Skew()
+	}
+	return nil
+}
+
+// HotKeys implements HotKeyReporter for wrapped algorithms reporting hot
+// keys, and fails with ErrHotKeysUnsupported otherwise.
+func (f *faultAlgorithm) HotKeys(ctx context.Context, n int) ([]HotKey, error) {
+	hk, ok := f.alg.(HotKeyReporter)
+	if !ok {
+		return nil, ErrHotKeysUnsupported
+	}
+	if err := f.inject(ctx); err != nil {
+		return nil, err
+	}
+	return hk.HotKeys(ctx, n)
+}
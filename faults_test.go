@@ -0,0 +1,133 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// always is a Faults.Rand injecting every fault with a non-zero
+// probability.
+func always() float64 { return 0 }
+
+func TestInjectFaults(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	r := rate.Every(time.Hour)
+
+	t.Run("Errors", func(t *testing.T) {
+		alg := InjectFaults(GCRA(), Faults{ErrorProbability: 1, Rand: always})
+		_, err := alg.Take(ctx, "k", r, 1, 1, now)
+		require.ErrorIs(t, err, ErrInjectedFault)
+		require.ErrorIs(t, alg.Reset(ctx, "k"), ErrInjectedFault)
+
+		timeout := errors.New("i/o timeout")
+		alg = InjectFaults(GCRA(), Faults{Error: timeout, ErrorProbability: 1, Rand: always})
+		_, _, err = alg.(LevelAlgorithm).TakeLevels(ctx, []Level{{Key: "k", Rate: r, Burst: 1}}, 1, now)
+		require.ErrorIs(t, err, timeout)
+
+		// Faults without a probability are never injected.
+		alg = InjectFaults(GCRA(), Faults{Rand: always})
+		a, err := alg.Take(ctx, "k", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		alg := InjectFaults(GCRA(), Faults{Latency: 20 * time.Millisecond, LatencyProbability: 1, Rand: always})
+		start := time.Now()
+		a, err := alg.Take(ctx, "k", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = alg.Take(canceled, "k", r, 1, 1, now)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Clock skew", func(t *testing.T) {
+		inner := GCRA()
+		alg := InjectFaults(inner, Faults{ClockSkew: time.Hour, SkewProbability: 1, Rand: always})
+		a, err := alg.Take(ctx, "k", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+
+		// The request was recorded an hour ahead.
+		a, err = inner.Take(ctx, "k", r, 1, 0, now)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 2*time.Hour, a.RetryAfter)
+	})
+}
+
+func TestFaultsOption(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{
+		Rate:      rate.Every(time.Hour),
+		Burst:     1,
+		Algorithm: GCRA(),
+		Faults:    &Faults{ErrorProbability: 1, Rand: always},
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	// Failing algorithms let requests through.
+	for range 2 {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	b, err := m.ConfigJSON()
+	require.NoError(t, err)
+	var config struct {
+		Algorithm string `json:"algorithm"`
+		Faults    struct {
+			Error            string  `json:"error"`
+			ErrorProbability float64 `json:"errorProbability"`
+		} `json:"faults"`
+	}
+	require.NoError(t, json.Unmarshal(b, &config))
+	assert.Equal(t, "*ratelimit.gcra", config.Algorithm)
+	assert.Equal(t, ErrInjectedFault.Error(), config.Faults.Error)
+	assert.InDelta(t, 1.0, config.Faults.ErrorProbability, 0)
+}
+
+// skewAlgorithm reports a fixed skew.
+type skewAlgorithm struct {
+	Algorithm
+	skew SkewStats
+}
+
+func (a skewAlgorithm) Skew() *SkewStats {
+	return &a.skew
+}
+
+func TestFaultsSkew(t *testing.T) {
+	skew := SkewStats{Tolerance: "1s", Last: "2s", Max: "2s", Samples: 1, Corrected: 1}
+	m := NewManager(Options{Rate: 1, Burst: 1, Algorithm: skewAlgorithm{GCRA(), skew}, Faults: &Faults{}})
+	assert.Equal(t, &skew, m.Stats().Clock.Skew)
+
+	m = NewManager(Options{Rate: 1, Burst: 1, Algorithm: GCRA(), Faults: &Faults{}})
+	assert.Nil(t, m.Stats().Clock)
+}
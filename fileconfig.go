@@ -0,0 +1,142 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of a configuration file, in YAML or JSON:
+//
+//	store: redis://localhost:6379/0
+//	limits: [10/second, 1000/hour]
+//	rules:
+//	  - name: login
+//	    method: POST
+//	    path: /login
+//	    limit: 5/minute
+//	  - name: api
+//	    path: /api/*
+//	    limit: 100/minute burst 20
+//
+// Options builds the options of the default limits, and ApplyRules gives
+// the routes matched by the rules limits of their own. ValidateConfig
+// checks the file before deploying it, as does the validate command of
+// ratelimitctl.
+type Config struct {
+	// Store is the DSN of the store, as accepted by NewStoreFromDSN. If
+	// empty, the in-memory store is used.
+	Store string `json:"store,omitempty" yaml:"store,omitempty"`
+	// Limits are the default limits; see Options.Limits.
+	Limits []LimitSpec `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Rules give the routes they match limits of their own. The first
+	// matching rule applies.
+	Rules []Rule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// Rule is a limit for the routes matching a method and path.
+type Rule struct {
+	// Name identifies the rule in validation findings.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Method is the HTTP method matched. If empty, every method is.
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Path is the route pattern matched, such as "/users/:id". A trailing
+	// "*" matches every pattern starting with the rest of it.
+	Path string `json:"path" yaml:"path"`
+	// Limit is the limit of every route matched, with buckets of its own.
+	Limit LimitSpec `json:"limit" yaml:"limit"`
+}
+
+// matches reports whether the rule matches a route.
+func (r Rule) matches(method, path string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.Path, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return r.Path == path
+}
+
+// covers reports whether the rule matches every route other matches.
+func (r Rule) covers(other Rule) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, other.Method) {
+		return false
+	}
+	prefix, wildcard := strings.CutSuffix(r.Path, "*")
+	if !wildcard {
+		return r.Path == other.Path
+	}
+	return strings.HasPrefix(strings.TrimSuffix(other.Path, "*"), prefix)
+}
+
+// ParseConfig decodes a configuration file in YAML or JSON. Unknown fields
+// and invalid limits are errors.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("ratelimit: invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads and decodes the configuration file at path.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return ParseConfig(data)
+}
+
+// Options returns the options of the default limits and store of the
+// configuration.
+func (cfg Config) Options() (Options, error) {
+	opts := Options{Limits: cfg.Limits}
+	if cfg.Store != "" {
+		store, err := NewStoreFromDSN(cfg.Store)
+		if err != nil {
+			return Options{}, err
+		}
+		opts.Store = store
+	}
+	return opts, nil
+}
+
+// ApplyRules gives every route of e matched by a rule the limit of the
+// first rule matching it, with buckets of its own, as LimitEndpoints
+// does. The manager's middleware must already be in the routes' handler
+// chains. It panics if a limit is invalid.
+func (m *Manager) ApplyRules(e *gin.Engine, rules []Rule) {
+	limits := make([]*routeLimit, len(rules))
+	for i, rule := range rules {
+		if err := rule.Limit.Validate(); err != nil {
+			panic(err)
+		}
+		limits[i] = &routeLimit{
+			rate:        rule.Limit.Rate(),
+			burst:       rule.Limit.BurstSize(),
+			consistency: m.opts.Consistency,
+		}
+	}
+	for _, ri := range e.Routes() {
+		for i, rule := range rules {
+			if rule.matches(ri.Method, ri.Path) {
+				m.routes.endpoints.Store(ri.Method+" "+ri.Path, limits[i])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := ParseConfig([]byte(`
+store: memory://
+limits: [2/second, 1000/hour]
+rules:
+  - name: login
+    method: POST
+    path: /login
+    limit: 1/minute
+  - name: api
+    path: /api/*
+    limit: {requests: 3, window: 1m}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []LimitSpec{
+		{Requests: 2, Window: time.Second},
+		{Requests: 1000, Window: time.Hour},
+	}, cfg.Limits)
+	assert.Equal(t, Rule{
+		Name:   "login",
+		Method: "POST",
+		Path:   "/login",
+		Limit:  LimitSpec{Requests: 1, Window: time.Minute},
+	}, cfg.Rules[0])
+
+	t.Run("Apply", func(t *testing.T) {
+		opts, err := cfg.Options()
+		require.NoError(t, err)
+		m := NewManager(opts)
+		r := gin.New()
+		r.Use(m.Handler())
+		for _, route := range []string{"/login", "/api/items", "/"} {
+			r.Handle(http.MethodGet, route, func(c *gin.Context) { c.Status(http.StatusOK) })
+			r.Handle(http.MethodPost, route, func(c *gin.Context) { c.Status(http.StatusOK) })
+		}
+		m.ApplyRules(r, cfg.Rules)
+		serve := func(method, path string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, path, nil)
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/login"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/login"))
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/items"))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/api/items"))
+		// Other routes keep the default limits.
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/login"))
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/login"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/login"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := ParseConfig([]byte("limits: [5/fortnight]\n"))
+		require.Error(t, err)
+		_, err = ParseConfig([]byte("limit: 5/minute\n"))
+		require.Error(t, err)
+		_, err = Config{Store: "nosuch://"}.Options()
+		require.Error(t, err)
+	})
+}
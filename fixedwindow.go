@@ -0,0 +1,150 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// windowCount is the number of requests of one key in its current window.
+type windowCount struct {
+	start, end time.Time
+	count      int
+}
+
+// fixedWindow is the in-memory fixed window counter algorithm.
+type fixedWindow struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	counts map[string]*windowCount
+	// sweepAt is the number of counters at which stale ones are next
+	// dropped.
+	sweepAt int
+}
+
+// FixedWindow returns an Algorithm counting the requests of every key in
+// fixed windows of the given size, in memory. Windows are aligned to the
+// clock, so a window of a minute resets on the minute, and each allows
+// rate×window requests, rounded. If window is zero, windows are burst/rate long and
+// allow burst requests. A fixed window is the cheapest algorithm and the
+// easiest to reason about for quotas, but admits up to twice the limit
+// around the end of a window. The Redis-backed variant is
+// redisstore.NewFixedWindow.
+func FixedWindow(window time.Duration) Algorithm {
+	return &fixedWindow{
+		window:  window,
+		counts:  make(map[string]*windowCount),
+		sweepAt: minLogSweep,
+	}
+}
+
+// Take implements Algorithm.
+func (f *fixedWindow) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	a, _, err := f.TakeLevels(ctx, []Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements LevelAlgorithm.
+func (f *fixedWindow) TakeLevels(_ context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sweep(now)
+
+	as := make([]Allowance, len(levels))
+	counts := make([]*windowCount, len(levels))
+	allowed := true
+	for i, lv := range levels {
+		if lv.Rate == rate.Inf {
+			as[i] = Allowance{Allowed: true, Remaining: lv.Burst}
+			continue
+		}
+		window, limit := windowLimit(f.window, lv.Rate, lv.Burst)
+		counts[i] = f.count(lv.Key, window, now)
+		as[i] = counts[i].allowance(limit, n, now)
+		allowed = allowed && as[i].Allowed
+	}
+	if allowed {
+		for i, wc := range counts {
+			if wc != nil {
+				wc.count += n
+				as[i].Remaining -= n
+			}
+		}
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// count returns the counter of key for the window holding now, creating
+// or restarting it as needed.
+func (f *fixedWindow) count(key string, window time.Duration, now time.Time) *windowCount {
+	wc, ok := f.counts[key]
+	if !ok {
+		wc = &windowCount{}
+		f.counts[key] = wc
+	}
+	var start, end time.Time
+	if window > 0 {
+		start = now.Truncate(window)
+		end = start.Add(window)
+	}
+	if !wc.start.Equal(start) || !wc.end.Equal(end) {
+		wc.start, wc.end, wc.count = start, end, 0
+	}
+	return wc
+}
+
+// allowance reports whether n more requests fit in the window's limit,
+// without counting them.
+func (wc *windowCount) allowance(limit, n int, now time.Time) Allowance {
+	need := max(n, 1)
+	if wc.count+need <= limit {
+		return Allowance{Allowed: true, Remaining: limit - wc.count}
+	}
+	a := Allowance{Remaining: max(0, limit-wc.count), RetryAfter: -1}
+	if need <= limit && !wc.end.IsZero() {
+		a.RetryAfter = wc.end.Sub(now)
+	}
+	return a
+}
+
+// sweep drops the counters of past windows, once there are sweepAt
+// counters.
+func (f *fixedWindow) sweep(now time.Time) {
+	if len(f.counts) < f.sweepAt {
+		return
+	}
+	for key, wc := range f.counts {
+		if !wc.end.IsZero() && !now.Before(wc.end) {
+			delete(f.counts, key)
+		}
+	}
+	f.sweepAt = max(minLogSweep, 2*len(f.counts))
+}
+
+// Reset implements Algorithm.
+func (f *fixedWindow) Reset(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.counts, key)
+	return nil
+}
+
+// windowLimit returns the window and the requests allowed in it for a
+// fixed window of the given size, or of burst/rate if size is zero.
+func windowLimit(size time.Duration, r rate.Limit, burst int) (time.Duration, int) {
+	if size <= 0 {
+		return logWindow(r, burst), burst
+	}
+	return size, int(math.Round(float64(r) * size.Seconds()))
+}
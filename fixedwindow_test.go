@@ -0,0 +1,91 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedWindow(t *testing.T) {
+	ctx := context.Background()
+	// The start of a minute.
+	start := time.Now().Truncate(time.Minute)
+	r := Per(100, time.Minute)
+
+	t.Run("Window", func(t *testing.T) {
+		alg := FixedWindow(time.Minute)
+		for i := range 100 {
+			a, err := alg.Take(ctx, "k", r, 1, 1, start.Add(10*time.Second))
+			require.NoError(t, err)
+			require.True(t, a.Allowed)
+			assert.Equal(t, 99-i, a.Remaining)
+		}
+
+		a, err := alg.Take(ctx, "k", r, 1, 1, start.Add(45*time.Second))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 15*time.Second, a.RetryAfter)
+
+		// The count resets on the minute.
+		a, err = alg.Take(ctx, "k", r, 1, 1, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 99, a.Remaining)
+	})
+
+	t.Run("Default window", func(t *testing.T) {
+		// 3 requests per 30s window.
+		alg := FixedWindow(0)
+		r := Per(3, 30*time.Second)
+		for range 3 {
+			a, err := alg.Take(ctx, "k", r, 3, 1, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+		}
+		a, err := alg.Take(ctx, "k", r, 3, 0, start.Add(20*time.Second))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 10*time.Second, a.RetryAfter)
+	})
+
+	t.Run("Cost", func(t *testing.T) {
+		alg := FixedWindow(time.Minute)
+		a, err := alg.Take(ctx, "k", r, 1, 60, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+
+		a, err = alg.Take(ctx, "k", r, 1, 50, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 40, a.Remaining)
+
+		a, err = alg.Take(ctx, "k", r, 1, 101, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Negative(t, a.RetryAfter)
+
+		require.NoError(t, alg.Reset(ctx, "k"))
+		a, err = alg.Take(ctx, "k", r, 1, 0, start)
+		require.NoError(t, err)
+		assert.Equal(t, 100, a.Remaining)
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		alg := FixedWindow(time.Minute).(*fixedWindow)
+		for i := range minLogSweep {
+			_, err := alg.Take(ctx, fmt.Sprint(i), r, 1, 1, start)
+			require.NoError(t, err)
+		}
+		_, err := alg.Take(ctx, "late", r, 1, 1, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Len(t, alg.counts, 1)
+	})
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ForgetReport describes the data Forget removed for a key.
+type ForgetReport struct {
+	// Limiters is the number of token buckets removed from the in-memory
+	// store, including those of route limits, retries and Limits.
+	Limiters int `json:"limiters"`
+	// AlgorithmReset reports whether the key was reset in the store of
+	// Options.Algorithm.
+	AlgorithmReset bool `json:"algorithmReset"`
+	// Stats reports whether the statistics of the key were removed.
+	Stats bool `json:"stats"`
+	// Ban, Override and Note report whether a ban, override or note of
+	// the key was removed.
+	Ban      bool `json:"ban"`
+	Override bool `json:"override"`
+	Note     bool `json:"note"`
+	// Samples reports whether tuning, anomaly, group or quota notification
+	// state of the key was removed.
+	Samples bool `json:"samples"`
+	// AuditEntries is the number of audit entries of the key removed by an
+	// AuditSink implementing AuditEraser.
+	AuditEntries int `json:"auditEntries"`
+}
+
+// LimiterDeleter is implemented by LimiterStores that can delete
+// limiters, such as the in-memory store.
+type LimiterDeleter interface {
+	// DeleteFunc deletes the limiters whose key matches, and returns the
+	// number deleted.
+	DeleteFunc(match func(key string) bool) int
+}
+
+// AuditEraser is implemented by AuditSinks that can delete the entries of
+// a key.
+type AuditEraser interface {
+	// Erase deletes the entries of key, and returns the number deleted.
+	Erase(ctx context.Context, key string) (int, error)
+}
+
+// Forget deletes the data kept about key, as needed to honor erasure
+// requests when keys are derived from personal data: its token buckets,
+// statistics, bans, overrides, notes, samples and audit entries. The erasure is
+// audited as AuditForget without the key, so the trail keeps no trace of
+// it.
+//
+// With Options.Algorithm, the key and its retry and Limits buckets are
+// reset; the buckets of route limits in the algorithm's store cannot be
+// listed, and are left to expire as they refill. Custom LimiterStores are
+// only cleared if they implement LimiterDeleter.
+func (m *Manager) Forget(ctx context.Context, key string) (ForgetReport, error) {
+	var report ForgetReport
+	if err := m.audit(ctx, AuditForget, "", nil); err != nil {
+		return report, err
+	}
+
+	if d, ok := m.limiters.(LimiterDeleter); ok {
+		report.Limiters = d.DeleteFunc(func(bucket string) bool {
+			return bucketOf(bucket, key)
+		})
+	}
+	m.usage.forget(key)
+	m.denials.forget(key)
+	if alg := m.opts.Algorithm; alg != nil {
+		buckets := []string{key, key + retryKeySuffix}
+		for _, lv := range m.limits {
+			buckets = append(buckets, key+lv.Key)
+		}
+		for _, bucket := range buckets {
+			if err := alg.Reset(ctx, bucket); err != nil {
+				return report, fmt.Errorf("ratelimit: forgetting key: %w", err)
+			}
+		}
+		report.AlgorithmReset = true
+	}
+
+	report.Stats = m.stats.forget(key)
+	if m.tuner != nil && m.tuner.forget(key) {
+		report.Samples = true
+	}
+	if m.anomalies != nil && m.anomalies.erase(key) {
+		report.Samples = true
+	}
+	if m.groups != nil && m.groups.forget(key) {
+		report.Samples = true
+	}
+	if m.notices != nil && m.notices.forget(key) {
+		report.Samples = true
+	}
+
+	m.controls.mu.RLock()
+	_, report.Ban = m.controls.bans[key]
+	_, report.Override = m.controls.overrides[key]
+	_, report.Note = m.controls.notes[key]
+	m.controls.mu.RUnlock()
+	if report.Ban || report.Override || report.Note {
+		err := m.changeControls(ctx, func(kc *keyControls) {
+			delete(kc.bans, key)
+			delete(kc.overrides, key)
+			delete(kc.notes, key)
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if e, ok := m.opts.AuditSink.(AuditEraser); ok {
+		n, err := e.Erase(ctx, key)
+		if err != nil {
+			return report, fmt.Errorf("ratelimit: erasing audit entries: %w", err)
+		}
+		report.AuditEntries = n
+	}
+	return report, nil
+}
+
+// bucketOf reports whether bucket holds tokens of key: the key's own
+// bucket, or one of its route, retry or Limits buckets.
+func bucketOf(bucket, key string) bool {
+	rest, ok := strings.CutPrefix(bucket, key)
+	if !ok {
+		return false
+	}
+	return rest == "" || routeBucketSuffix(rest) || strings.HasPrefix(rest, limitKeySeparator) ||
+		strings.HasPrefix(rest, retryKeySuffix)
+}
+
+// DeleteFunc implements LimiterDeleter.
+func (s *memoryStore) DeleteFunc(match func(key string) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.limiters)
+	for key := range s.limiters {
+		if match(key) {
+			delete(s.limiters, key)
+		}
+	}
+	return n - len(s.limiters)
+}
+
+// DeleteFunc implements LimiterDeleter.
+func (s *shardedStore) DeleteFunc(match func(key string) bool) int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.DeleteFunc(match)
+	}
+	return n
+}
+
+// forget deletes the pending usage of the buckets of key.
+func (s *usageSync) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for bucket := range s.pending {
+		if bucketOf(bucket, key) {
+			delete(s.pending, bucket)
+		}
+	}
+}
+
+// forget deletes the statistics of key, reporting whether it had any.
+func (s *keyStats) forget(key string) bool {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	_, ok := shard.keys[key]
+	delete(shard.keys, key)
+	return ok
+}
+
+// forget deletes the samples of key, reporting whether it had any.
+func (t *tuner) forget(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	found := false
+	for _, keys := range t.groups {
+		if _, ok := keys[key]; ok {
+			delete(keys, key)
+			found = true
+		}
+	}
+	return found
+}
+
+// erase deletes the rate of key, reporting whether it had one.
+func (d *anomalyDetector) erase(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.keys[key]
+	delete(d.keys, key)
+	return ok
+}
+
+// forget removes key from its groups, returning its minimum and the
+// tokens left in it to the pool, and reports whether it was a member.
+func (g *groupLimiter) forget(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	found := false
+	for _, kg := range g.groups {
+		member, ok := kg.members[key]
+		if !ok {
+			continue
+		}
+		delete(kg.members, key)
+		if member.reserve != nil {
+			tokens := kg.pool.TokensAt(now) + member.reserve.TokensAt(now)
+			kg.reserved--
+			g.resizePool(kg, tokens, now)
+		}
+		found = true
+	}
+	return found
+}
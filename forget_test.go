@@ -0,0 +1,169 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// erasingAuditSink is a memoryAuditSink that deletes the entries of keys.
+type erasingAuditSink struct {
+	memoryAuditSink
+}
+
+func (s *erasingAuditSink) Erase(_ context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.entries[:0]
+	for _, e := range s.entries {
+		if e.Key != key {
+			kept = append(kept, e)
+		}
+	}
+	n := len(s.entries) - len(kept)
+	s.entries = kept
+	return n, nil
+}
+
+func TestForget(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("Memory store", func(t *testing.T) {
+		sink := &erasingAuditSink{}
+		m := NewManager(Options{
+			Rate:      rate.Every(time.Second),
+			Burst:     1,
+			AuditSink: sink,
+			Tuning:    &Tuning{},
+		})
+		for _, bucket := range []string{
+			"alice", "alice@GET /", "alice" + retryKeySuffix, "alicia", "alice@example.com", "bob",
+		} {
+			m.limiters.Set(bucket, rate.NewLimiter(1, 1))
+		}
+		m.stats.record("alice", "", true, now)
+		m.stats.record("bob", "", true, now)
+		m.tuner.observe("/", "", "alice", now)
+		require.NoError(t, m.Ban(ctx, "alice", time.Hour))
+		require.NoError(t, m.SetOverride(ctx, "alice", Override{Rate: 10, Burst: 20}))
+		require.NoError(t, m.Ban(ctx, "bob", time.Hour))
+		require.NoError(t, m.Ban(ctx, "alice@example.com", time.Hour))
+
+		report, err := m.Forget(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, ForgetReport{
+			Limiters:     3,
+			Stats:        true,
+			Ban:          true,
+			Override:     true,
+			Samples:      true,
+			AuditEntries: 2,
+		}, report)
+
+		for _, bucket := range []string{"alice", "alice@GET /", "alice" + retryKeySuffix} {
+			_, ok := m.limiters.Get(bucket)
+			assert.False(t, ok, bucket)
+		}
+		for _, bucket := range []string{"alicia", "alice@example.com", "bob"} {
+			_, ok := m.limiters.Get(bucket)
+			assert.True(t, ok, bucket)
+		}
+		page, err := m.Keys(KeyQuery{})
+		require.NoError(t, err)
+		require.Len(t, page.Keys, 1)
+		assert.Equal(t, "bob", page.Keys[0].Key)
+		assert.False(t, m.controls.banned("alice", now))
+		assert.True(t, m.controls.banned("bob", now))
+		assert.True(t, m.controls.banned("alice@example.com", now))
+
+		// The erasure is audited without the key.
+		require.Len(t, sink.entries, 3)
+		assert.Equal(t, "bob", sink.entries[0].Key)
+		assert.Equal(t, "alice@example.com", sink.entries[1].Key)
+		assert.Equal(t, AuditEntry{Time: sink.entries[2].Time, Action: AuditForget}, sink.entries[2])
+
+		// Forgetting an unknown key removes nothing.
+		report, err = m.Forget(ctx, "carol")
+		require.NoError(t, err)
+		assert.Equal(t, ForgetReport{}, report)
+	})
+
+	t.Run("Algorithm", func(t *testing.T) {
+		alg := GCRA()
+		m := NewManager(Options{
+			Rate:         rate.Every(time.Hour),
+			Burst:        1,
+			Algorithm:    alg,
+			DenyCacheTTL: time.Minute,
+		})
+		_, err := alg.Take(ctx, "alice", rate.Every(time.Hour), 1, 1, now)
+		require.NoError(t, err)
+
+		report, err := m.Forget(ctx, "alice")
+		require.NoError(t, err)
+		assert.True(t, report.AlgorithmReset)
+
+		a, err := alg.Take(ctx, "alice", rate.Every(time.Hour), 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		// Cached denials of the key's buckets go too, but not those of
+		// keys merely starting with it.
+		denied := Allowance{RetryAfter: time.Minute}
+		for _, bucket := range []string{"alice@GET /", "alice@example.com"} {
+			m.denials.add(bucket, rate.Every(time.Hour), 1, 1, denied, now)
+		}
+		_, err = m.Forget(ctx, "alice")
+		require.NoError(t, err)
+		_, ok := m.denials.denied("alice@GET /", rate.Every(time.Hour), 1, 1, now)
+		assert.False(t, ok)
+		_, ok = m.denials.denied("alice@example.com", rate.Every(time.Hour), 1, 1, now)
+		assert.True(t, ok)
+	})
+
+	t.Run("Audit failure", func(t *testing.T) {
+		sink := &erasingAuditSink{}
+		m := NewManager(Options{Rate: rate.Every(time.Second), Burst: 1, AuditSink: sink})
+		m.stats.record("alice", "", true, now)
+		sink.err = assert.AnError
+
+		_, err := m.Forget(ctx, "alice")
+		require.ErrorIs(t, err, assert.AnError)
+		assert.Len(t, m.stats.matching("alice"), 1)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		m := NewManager(Options{Rate: rate.Every(time.Second), Burst: 1})
+		m.stats.record("alice", "", true, now)
+		r := gin.New()
+		m.RegisterAdmin(r)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/forget", strings.NewReader(`{"key": "alice"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var report ForgetReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.True(t, report.Stats)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodPost, "/forget", strings.NewReader(`{}`))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
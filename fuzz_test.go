@@ -0,0 +1,129 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func FuzzNormalizeKey(f *testing.F) {
+	f.Add("203.0.113.7", 256)
+	f.Add(strings.Repeat("k", 1<<16), 256)
+	f.Add("\xff\xfe", 256)
+	f.Add("", -1)
+	f.Fuzz(func(t *testing.T, key string, maxLen int) {
+		got := normalizeKey(key, maxLen)
+		if !utf8.ValidString(got) {
+			t.Fatalf("normalized key %q is not valid UTF-8", got)
+		}
+		if got != key && len(got) != len(hashedKeyPrefix)+64 {
+			t.Fatalf("hashed key %q has unexpected length", got)
+		}
+		if maxLen >= len(hashedKeyPrefix)+64 && len(got) > maxLen {
+			t.Fatalf("normalized key is %d bytes, limit is %d", len(got), maxLen)
+		}
+		if normalizeKey(key, maxLen) != got {
+			t.Fatal("normalization is not deterministic")
+		}
+	})
+}
+
+func FuzzHeaderKey(f *testing.F) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(New(Options{
+		Rate:  rate.Every(time.Millisecond),
+		Burst: 1,
+		KeyFunc: func(c *gin.Context) string {
+			return c.GetHeader("X-API-KEY")
+		},
+	}))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	f.Add("test-key", "203.0.113.7:1234")
+	f.Add(strings.Repeat("\xff", 1<<12), "[::1]:80")
+	f.Add("", "garbage")
+	f.Fuzz(func(t *testing.T, header, remoteAddr string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header["X-Api-Key"] = []string{header}
+		req.RemoteAddr = remoteAddr
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK && w.Code != http.StatusTooManyRequests {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+	})
+}
+
+func FuzzEvaluate(f *testing.F) {
+	m := NewManager(Options{
+		Rate:  rate.Every(time.Second),
+		Burst: 1,
+		KeyFunc: func(c *gin.Context) string {
+			return c.ClientIP() + c.Request.URL.Path + c.GetHeader("X-API-KEY")
+		},
+	})
+
+	f.Add("GET", "/", "203.0.113.7", "X-API-KEY", "k")
+	f.Add("BAD METHOD", "%zz", "not-an-ip", "X-Forwarded-For", "\xff, 10.0.0.1")
+	f.Add("", "", "", "", "")
+	f.Fuzz(func(t *testing.T, method, path, ip, name, value string) {
+		ev, err := m.Evaluate(SyntheticRequest{
+			Method: method,
+			Path:   path,
+			IP:     ip,
+			Header: map[string]string{name: value},
+		})
+		if err == nil && !utf8.ValidString(ev.Key) {
+			t.Fatalf("key %q is not valid UTF-8", ev.Key)
+		}
+	})
+}
+
+func FuzzIPList(f *testing.F) {
+	f.Add("10.0.0.0/8", "10.1.2.3")
+	f.Add("192.0.2.1", "192.0.2.2")
+	f.Add("2001:db8::/32", "2001:db8::1")
+	f.Add("::ffff:0:0/96", "203.0.113.7")
+	f.Add("fe80::1%eth0", "fe80::1%eth1")
+	f.Add(" 0.0.0.0/0 ", "::1")
+	f.Add("1.2.3.4/33", "1.2.3.4")
+	f.Fuzz(func(t *testing.T, entry, ip string) {
+		p, err := parsePrefix(entry)
+		if err != nil {
+			return
+		}
+		if !p.IsValid() || p != p.Masked() {
+			t.Fatalf("entry %q parsed to %v", entry, p)
+		}
+		tree := &ipTrie{}
+		tree.insert(p)
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return
+		}
+		// The tree maps IPv4 into IPv6, so compare in the mapped form.
+		bits := p.Bits()
+		if p.Addr().Is4() {
+			bits += 96
+		}
+		mapped := netip.PrefixFrom(netip.AddrFrom16(p.Addr().As16()), bits)
+		if want := mapped.Contains(netip.AddrFrom16(addr.As16())); tree.contains(addr) != want {
+			t.Fatalf("range %v contains %v: got %v, want %v", p, addr, !want, want)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// gcra is the in-memory generic cell rate algorithm.
+type gcra struct {
+	mu sync.Mutex
+	// tats holds the theoretical arrival time of every key: when its
+	// bucket would be full again.
+	tats map[string]time.Time
+	// sweepAt is the number of keys at which full ones are next dropped.
+	sweepAt int
+}
+
+// GCRA returns an Algorithm implementing the generic cell rate algorithm,
+// in memory. It behaves like a token bucket of the same rate and burst,
+// but its whole state per key is a single timestamp, which makes it cheap
+// to keep and, with redisstore.NewGCRA, to update atomically in Redis. A
+// zero rate denies every request.
+func GCRA() Algorithm {
+	return &gcra{tats: make(map[string]time.Time), sweepAt: minLogSweep}
+}
+
+// Take implements Algorithm.
+func (g *gcra) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	a, _, err := g.TakeLevels(ctx, []Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements LevelAlgorithm.
+func (g *gcra) TakeLevels(ctx context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	costs := make([]int, len(levels))
+	for i := range costs {
+		costs[i] = n
+	}
+	return g.TakeCosts(ctx, levels, costs, now)
+}
+
+// TakeCosts implements CostAlgorithm.
+func (g *gcra) TakeCosts(_ context.Context, levels []Level, costs []int, now time.Time) (Allowance, int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sweep(now)
+
+	as := make([]Allowance, len(levels))
+	tats := make([]time.Time, len(levels))
+	allowed := true
+	for i, lv := range levels {
+		as[i], tats[i] = gcraAllowance(g.tats[lv.Key], lv.Rate, lv.Burst, costs[i], now)
+		allowed = allowed && as[i].Allowed
+	}
+	if allowed {
+		for i, lv := range levels {
+			if costs[i] > 0 && !tats[i].IsZero() {
+				g.tats[lv.Key] = tats[i]
+				as[i].Remaining = max(0, as[i].Remaining-costs[i])
+			}
+		}
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// gcraAllowance reports whether n requests fit in a limit of rate r and
+// burst with the theoretical arrival time tat, and returns the arrival
+// time to record if they do. Unlimited rates have no arrival time.
+func gcraAllowance(tat time.Time, r rate.Limit, burst, n int, now time.Time) (Allowance, time.Time) {
+	switch {
+	case r == rate.Inf:
+		return Allowance{Allowed: true, Remaining: burst}, time.Time{}
+	case r <= 0:
+		return Allowance{RetryAfter: -1}, time.Time{}
+	}
+	interval := time.Duration(float64(time.Second) / float64(r))
+	tolerance := time.Duration(burst) * interval
+	if tat.Before(now) {
+		tat = now
+	}
+	remaining := func(tat time.Time) int {
+		return max(0, int((tolerance-tat.Sub(now))/interval))
+	}
+
+	need := max(n, 1)
+	next := tat.Add(time.Duration(need) * interval)
+	if wait := next.Sub(now) - tolerance; wait > 0 {
+		a := Allowance{Remaining: remaining(tat), RetryAfter: -1}
+		if need <= burst {
+			a.RetryAfter = wait
+		}
+		return a, time.Time{}
+	}
+	next = tat.Add(time.Duration(n) * interval)
+	return Allowance{Allowed: true, Remaining: remaining(tat)}, next
+}
+
+// sweep drops the keys whose bucket is full again, once there are sweepAt
+// keys.
+func (g *gcra) sweep(now time.Time) {
+	if len(g.tats) < g.sweepAt {
+		return
+	}
+	for key, tat := range g.tats {
+		if !tat.After(now) {
+			delete(g.tats, key)
+		}
+	}
+	g.sweepAt = max(minLogSweep, 2*len(g.tats))
+}
+
+// Reset implements Algorithm.
+func (g *gcra) Reset(_ context.Context, key string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.tats, key)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestGCRA(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	// One request every 10s, bursts of 3.
+	r := rate.Every(10 * time.Second)
+
+	t.Run("Burst", func(t *testing.T) {
+		alg := GCRA()
+		for i := range 3 {
+			a, err := alg.Take(ctx, "k", r, 3, 1, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 2-i, a.Remaining)
+		}
+
+		a, err := alg.Take(ctx, "k", r, 3, 1, start.Add(4*time.Second))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 6*time.Second, a.RetryAfter)
+
+		// One request is allowed again every 10s.
+		a, err = alg.Take(ctx, "k", r, 3, 1, start.Add(10*time.Second))
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 0, a.Remaining)
+	})
+
+	t.Run("Matches token bucket", func(t *testing.T) {
+		alg := GCRA()
+		tb := rate.NewLimiter(r, 3)
+		for at := time.Duration(0); at < 2*time.Minute; at += 3 * time.Second {
+			now := start.Add(at)
+			a, err := alg.Take(ctx, "k", r, 3, 1, now)
+			require.NoError(t, err)
+			assert.Equal(t, tb.AllowN(now, 1), a.Allowed, at.String())
+		}
+	})
+
+	t.Run("Cost and Peek", func(t *testing.T) {
+		alg := GCRA()
+		a, err := alg.Take(ctx, "k", r, 3, 2, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 1, a.Remaining)
+
+		a, err = alg.Take(ctx, "k", r, 3, 2, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 1, a.Remaining)
+		assert.Equal(t, 10*time.Second, a.RetryAfter)
+
+		a, err = alg.Take(ctx, "k", r, 3, 0, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 1, a.Remaining)
+
+		a, err = alg.Take(ctx, "k", r, 3, 4, start)
+		require.NoError(t, err)
+		assert.Negative(t, a.RetryAfter)
+
+		a, err = alg.Take(ctx, "k", 0, 3, 1, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+
+		require.NoError(t, alg.Reset(ctx, "k"))
+		a, err = alg.Take(ctx, "k", r, 3, 0, start)
+		require.NoError(t, err)
+		assert.Equal(t, 3, a.Remaining)
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		alg := GCRA().(*gcra)
+		for i := range minLogSweep {
+			_, err := alg.Take(ctx, fmt.Sprint(i), r, 3, 1, start)
+			require.NoError(t, err)
+		}
+		_, err := alg.Take(ctx, "late", r, 3, 1, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Len(t, alg.tats, 1)
+	})
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+// globalKey is the bucket key of the global limit, kept apart from the
+// keys of clients and organizations.
+const globalKey = "global:"
+
+// newGlobalLevel returns the level of the global limit. It panics if spec
+// is invalid.
+func newGlobalLevel(spec LimitSpec) *Level {
+	if err := spec.Validate(); err != nil {
+		panic(err)
+	}
+	return &Level{Key: globalKey, Rate: spec.Rate(), Burst: spec.BurstSize()}
+}
+
+// globalLevels returns the levels of the global limit, scaled to the share
+// of the local region, or none without one.
+func (m *Manager) globalLevels() []Level {
+	if m.global == nil {
+		return nil
+	}
+	lv := *m.global
+	if m.regions != nil {
+		lv.Rate, lv.Burst = m.regions.scale(lv.Rate, lv.Burst)
+	}
+	return []Level{lv}
+}
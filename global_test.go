@@ -0,0 +1,79 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestGlobal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	global := &LimitSpec{Requests: 5, Window: time.Hour}
+	for name, alg := range map[string]Algorithm{"Token bucket": nil, "GCRA": GCRA()} {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(Options{
+				Rate:      rate.Every(time.Hour),
+				Burst:     2,
+				Global:    global,
+				Algorithm: alg,
+			})
+			r := gin.New()
+			r.Use(m.Handler())
+			r.GET("/", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			serve := func(client int) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = "10.0.0." + strconv.Itoa(client) + ":1234"
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			// Every client keeps its own limit.
+			assert.Equal(t, http.StatusOK, serve(1).Code)
+			assert.Equal(t, http.StatusOK, serve(1).Code)
+			w := serve(1)
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+
+			// The global limit caps all of them together.
+			for client := 2; client <= 4; client++ {
+				assert.Equal(t, http.StatusOK, serve(client).Code)
+			}
+			w = serve(5)
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"))
+		})
+	}
+
+	t.Run("Config", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1, Global: global})
+		b, err := m.ConfigJSON()
+		require.NoError(t, err)
+		var config struct {
+			Global string `json:"global"`
+		}
+		require.NoError(t, json.Unmarshal(b, &config))
+		assert.Equal(t, global.String(), config.Global)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewManager(Options{Rate: 1, Burst: 1, Global: &LimitSpec{Requests: -1}})
+		})
+	})
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultGroupIdle is how long a key stays a member of its group without
+// requests when KeyGroups.Idle is zero.
+const DefaultGroupIdle = time.Minute
+
+// KeyGroups pools the budget of related keys, such as the users of one
+// organization, so capacity quiet members leave unused can serve busy
+// ones without the group exceeding its total. Every member is guaranteed
+// a minimum of its own, drawn on first; the rest of the group's total is
+// a pool shared by all members. Minimums are only granted while they fit
+// in the total, so later members may have to rely on the pool alone.
+type KeyGroups struct {
+	// Group returns the group of a key, or "" if the key is limited on
+	// its own.
+	Group func(key string) string
+
+	// Rate and Burst are the total limit of every group.
+	Rate  rate.Limit
+	Burst int
+
+	// MinRate and MinBurst are the limit every member is guaranteed.
+	MinRate  rate.Limit
+	MinBurst int
+
+	// Idle is how long a key stays a member, holding on to its minimum,
+	// without requests. If zero, DefaultGroupIdle is used.
+	Idle time.Duration
+}
+
+// keyGroup is the state of one group.
+type keyGroup struct {
+	pool    *rate.Limiter
+	members map[string]*groupMember
+	// reserved is the number of members holding a minimum.
+	reserved int
+	// sweepAt is when idle members are next dropped.
+	sweepAt time.Time
+}
+
+// groupMember is a key in a group.
+type groupMember struct {
+	// reserve is the member's minimum, or nil if the total had no room
+	// for it when the key joined.
+	reserve *rate.Limiter
+	seen    time.Time
+}
+
+// groupLimiter enforces KeyGroups. Each decision is made under one lock,
+// so members never overdraw the group.
+type groupLimiter struct {
+	cfg KeyGroups
+
+	mu     sync.Mutex
+	groups map[string]*keyGroup
+}
+
+// newGroupLimiter creates a limiter from the given configuration.
+func newGroupLimiter(cfg KeyGroups) *groupLimiter {
+	if cfg.Idle <= 0 {
+		cfg.Idle = DefaultGroupIdle
+	}
+	return &groupLimiter{cfg: cfg, groups: make(map[string]*keyGroup)}
+}
+
+// take takes n tokens for key in group, from the key's minimum if it can,
+// or else from the group's pool. It returns the bucket the tokens were
+// taken from, or the one the key would wait for.
+func (g *groupLimiter) take(group, key string, n int, now time.Time) (*rate.Limiter, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	kg, ok := g.groups[group]
+	if !ok {
+		kg = g.newGroup()
+		g.groups[group] = kg
+	}
+	if !now.Before(kg.sweepAt) {
+		g.sweep(kg, now)
+		kg.sweepAt = now.Add(g.cfg.Idle / 4)
+	}
+
+	m := g.join(kg, key, now)
+	m.seen = now
+	return draw(kg, m, n, now, true)
+}
+
+// peek reports what take would return without taking tokens or changing
+// the group, checking copies of the buckets take would draw from.
+func (g *groupLimiter) peek(group, key string, n int, now time.Time) (*rate.Limiter, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	scratch := g.newGroup()
+	if kg, ok := g.groups[group]; ok {
+		scratch.pool = resizeLimiter(kg.pool, kg.pool.Limit(), kg.pool.Burst(), now)
+		scratch.reserved = kg.reserved
+		if m, ok := kg.members[key]; ok {
+			member := &groupMember{}
+			if m.reserve != nil {
+				member.reserve = resizeLimiter(m.reserve, m.reserve.Limit(), m.reserve.Burst(), now)
+			}
+			scratch.members[key] = member
+		}
+	}
+	return draw(scratch, g.join(scratch, key, now), n, now, false)
+}
+
+// newGroup returns a group without members.
+func (g *groupLimiter) newGroup() *keyGroup {
+	return &keyGroup{
+		pool:    rate.NewLimiter(g.cfg.Rate, g.cfg.Burst),
+		members: make(map[string]*groupMember),
+	}
+}
+
+// join returns the member key of kg, adding it if needed.
+func (g *groupLimiter) join(kg *keyGroup, key string, now time.Time) *groupMember {
+	if m, ok := kg.members[key]; ok {
+		return m
+	}
+	m := &groupMember{}
+	if g.fits(kg.reserved + 1) {
+		// The minimum starts with the tokens it takes from the pool,
+		// which may already have been spent by other members.
+		pool := kg.pool.TokensAt(now)
+		grant := max(0, min(g.cfg.MinBurst, int(pool)))
+		m.reserve = rate.NewLimiter(g.cfg.MinRate, g.cfg.MinBurst)
+		if spent := g.cfg.MinBurst - grant; spent > 0 {
+			m.reserve.AllowN(now, spent)
+		}
+		kg.reserved++
+		g.resizePool(kg, pool-float64(grant), now)
+	}
+	kg.members[key] = m
+	return m
+}
+
+// draw takes n tokens for member m of kg, from its minimum if it can, or
+// else from the pool. Unless take is set, the tokens are only checked.
+func draw(kg *keyGroup, m *groupMember, n int, now time.Time, take bool) (*rate.Limiter, bool) {
+	has := func(l *rate.Limiter) bool {
+		if take {
+			return l.AllowN(now, n)
+		}
+		return l.TokensAt(now) >= float64(n) || l.Limit() == rate.Inf
+	}
+	if m.reserve != nil && has(m.reserve) {
+		return m.reserve, true
+	}
+	if has(kg.pool) {
+		return kg.pool, true
+	}
+	if m.reserve != nil {
+		return m.reserve, false
+	}
+	return kg.pool, false
+}
+
+// fits reports whether the minimums of reserved members fit in the total.
+func (g *groupLimiter) fits(reserved int) bool {
+	return g.cfg.MinRate*rate.Limit(reserved) <= g.cfg.Rate && g.cfg.MinBurst*reserved <= g.cfg.Burst
+}
+
+// resizePool gives the pool what the members' minimums leave of the total,
+// holding the given tokens.
+func (g *groupLimiter) resizePool(kg *keyGroup, tokens float64, now time.Time) {
+	r := g.cfg.Rate - g.cfg.MinRate*rate.Limit(kg.reserved)
+	burst := g.cfg.Burst - g.cfg.MinBurst*kg.reserved
+	tokens = math.Max(0, math.Min(tokens, float64(burst)))
+
+	kg.pool = rate.NewLimiter(r, burst)
+	if spent := burst - int(tokens); spent > 0 {
+		kg.pool.AllowN(now, spent)
+	}
+}
+
+// sweep drops the members idle for longer than Idle, returning their
+// minimums and the tokens left in them to the pool.
+func (g *groupLimiter) sweep(kg *keyGroup, now time.Time) {
+	released := false
+	tokens := kg.pool.TokensAt(now)
+	for key, m := range kg.members {
+		if now.Sub(m.seen) > g.cfg.Idle {
+			if m.reserve != nil {
+				tokens += m.reserve.TokensAt(now)
+				kg.reserved--
+				released = true
+			}
+			delete(kg.members, key)
+		}
+	}
+	if released {
+		g.resizePool(kg, tokens, now)
+	}
+}
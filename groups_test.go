@@ -0,0 +1,128 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestKeyGroups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	cfg := KeyGroups{
+		Rate:     rate.Every(time.Minute),
+		Burst:    10,
+		MinRate:  rate.Every(10 * time.Minute),
+		MinBurst: 2,
+	}
+
+	t.Run("Borrowing", func(t *testing.T) {
+		g := newGroupLimiter(cfg)
+		// A quiet member joins, then a busy one uses the rest of the total.
+		_, ok := g.take("org", "quiet", 1, now)
+		assert.True(t, ok)
+		allowed := 1
+		for range 20 {
+			if _, ok := g.take("org", "busy", 1, now); ok {
+				allowed++
+			}
+		}
+		assert.Equal(t, 9, allowed)
+
+		// The quiet member still has the rest of its minimum, which brings
+		// the group to its total.
+		_, ok = g.take("org", "quiet", 1, now)
+		assert.True(t, ok)
+		_, ok = g.take("org", "quiet", 1, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Separate groups", func(t *testing.T) {
+		g := newGroupLimiter(cfg)
+		for range 10 {
+			g.take("a", "user", 1, now)
+		}
+		_, ok := g.take("a", "user", 1, now)
+		assert.False(t, ok)
+		_, ok = g.take("b", "user", 1, now)
+		assert.True(t, ok)
+	})
+
+	t.Run("Minimums fit the total", func(t *testing.T) {
+		g := newGroupLimiter(cfg)
+		for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+			g.take("org", key, 0, now)
+		}
+		kg := g.groups["org"]
+		assert.Equal(t, 5, kg.reserved)
+		assert.Nil(t, kg.members["f"].reserve)
+		assert.Equal(t, 0, kg.pool.Burst())
+
+		// Without a minimum or a pool, the last member is denied.
+		_, ok := g.take("org", "f", 1, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Idle members", func(t *testing.T) {
+		g := newGroupLimiter(cfg)
+		g.take("org", "a", 0, now)
+		g.take("org", "b", 0, now)
+		assert.Equal(t, 6, g.groups["org"].pool.Burst())
+
+		later := now.Add(2 * DefaultGroupIdle)
+		g.take("org", "b", 0, later)
+		kg := g.groups["org"]
+		assert.NotContains(t, kg.members, "a")
+		assert.Equal(t, 1, kg.reserved)
+		assert.Equal(t, 8, kg.pool.Burst())
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		groups := cfg
+		groups.Group = func(key string) string {
+			if org, _, ok := strings.Cut(key, "/"); ok {
+				return org
+			}
+			return ""
+		}
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:    rate.Every(time.Hour),
+			Burst:   1,
+			KeyFunc: func(c *gin.Context) string { return c.GetHeader("X-User") },
+			Groups:  &groups,
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+
+		get := func(user string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-User", user)
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+		allowed := 0
+		for range 15 {
+			if get("acme/alice") == http.StatusOK {
+				allowed++
+			}
+		}
+		assert.Equal(t, 10, allowed)
+		// A member joining an exhausted group has nothing to take either.
+		assert.Equal(t, http.StatusTooManyRequests, get("acme/bob"))
+		assert.Equal(t, http.StatusOK, get("solo"))
+		assert.Equal(t, http.StatusTooManyRequests, get("solo"))
+	})
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package grpcadmin exposes the administration operations of a
+// ratelimit.Manager as a gRPC service, for managing a fleet of instances
+// from internal tooling. The service mirrors the HTTP endpoints mounted by
+// Manager.RegisterAdmin; its definition is in adminpb/admin.proto.
+//
+// Callers authenticate with an "authorization: Bearer <token>" metadata
+// entry, checked against Options.AdminAuth of the manager.
+package grpcadmin
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-contrib/ratelimit/grpcadmin/adminpb"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultWatchBuffer is the decision buffer of WatchDecisions streams that
+// do not ask for one.
+const DefaultWatchBuffer = 256
+
+// Server implements the RateLimitAdmin service for a manager.
+type Server struct {
+	adminpb.UnimplementedRateLimitAdminServer
+	m *ratelimit.Manager
+}
+
+// NewServer creates a service operating on m.
+func NewServer(m *ratelimit.Manager) *Server {
+	return &Server{m: m}
+}
+
+// Register registers the service for m on s.
+func Register(s grpc.ServiceRegistrar, m *ratelimit.Manager) {
+	adminpb.RegisterRateLimitAdminServer(s, NewServer(m))
+}
+
+// authorize checks the caller's token for perm and returns a context that
+// attributes changes to the caller.
+func (s *Server) authorize(ctx context.Context, perm ratelimit.AdminPermission) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = bearerToken(values[0])
+		}
+	}
+
+	p, err := s.m.AuthorizeAdmin(ctx, token, perm)
+	switch {
+	case errors.Is(err, ratelimit.ErrPermissionDenied):
+		return nil, status.Errorf(codes.PermissionDenied, "missing permission %s", perm)
+	case err != nil:
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return ratelimit.WithActor(ctx, actor(ctx, p)), nil
+}
+
+// bearerToken extracts the token of a "Bearer <token>" value.
+func bearerToken(value string) string {
+	scheme, token, _ := strings.Cut(value, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// actor identifies the caller in the audit trail: the principal's name or,
+// without one, the peer's address.
+func actor(ctx context.Context, p ratelimit.AdminPrincipal) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		if host, _, err := net.SplitHostPort(pr.Addr.String()); err == nil {
+			return host
+		}
+		return pr.Addr.String()
+	}
+	return ""
+}
+
+// selector converts a key selector, rejecting empty ones.
+func selector(sel *adminpb.KeySelector) (ratelimit.KeySelector, error) {
+	ks := ratelimit.KeySelector{
+		Keys:     sel.GetKeys(),
+		Prefixes: sel.GetPrefixes(),
+	}
+	if len(ks.Keys) == 0 && len(ks.Prefixes) == 0 {
+		return ks, status.Error(codes.InvalidArgument, "no keys or prefixes selected")
+	}
+	for _, prefix := range ks.Prefixes {
+		if prefix == "" {
+			return ks, status.Error(codes.InvalidArgument, "empty key prefix")
+		}
+	}
+	return ks, nil
+}
+
+// mutate authorizes a change to the selected keys and applies it with
+// one, for a selector naming exactly one key so the change is audited like
+// its HTTP counterpart, or with bulk otherwise.
+func (s *Server) mutate(ctx context.Context, perm ratelimit.AdminPermission, sel *adminpb.KeySelector,
+	one func(context.Context, string) error, bulk func(context.Context, ratelimit.KeySelector) error,
+) error {
+	ctx, err := s.authorize(ctx, perm)
+	if err != nil {
+		return err
+	}
+	ks, err := selector(sel)
+	if err != nil {
+		return err
+	}
+	if len(ks.Keys) == 1 && len(ks.Prefixes) == 0 {
+		err = one(ctx, ks.Keys[0])
+	} else {
+		err = bulk(ctx, ks)
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// GetConfig implements adminpb.RateLimitAdminServer.
+func (s *Server) GetConfig(ctx context.Context, _ *adminpb.GetConfigRequest) (*adminpb.GetConfigResponse, error) {
+	if _, err := s.authorize(ctx, ratelimit.AdminRead); err != nil {
+		return nil, err
+	}
+	data, err := s.m.ConfigJSON()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &adminpb.GetConfigResponse{Json: string(data)}, nil
+}
+
+// Evaluate implements adminpb.RateLimitAdminServer.
+func (s *Server) Evaluate(ctx context.Context, req *adminpb.EvaluateRequest) (*adminpb.EvaluateResponse, error) {
+	if _, err := s.authorize(ctx, ratelimit.AdminRead); err != nil {
+		return nil, err
+	}
+	ev, err := s.m.Evaluate(ratelimit.SyntheticRequest{
+		Method: req.GetMethod(),
+		Path:   req.GetPath(),
+		IP:     req.GetIp(),
+		Header: req.GetHeader(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &adminpb.EvaluateResponse{
+		Key:        ev.Key,
+		Class:      ev.Classification.Class,
+		Labels:     ev.Classification.Labels,
+		Allowed:    ev.Allowed,
+		Banned:     ev.Banned,
+		Tokens:     ev.Tokens,
+		Delay:      durationpb.New(ev.Delay),
+		RetryAfter: durationpb.New(ev.RetryAfter),
+	}, nil
+}
+
+// ListKeys implements adminpb.RateLimitAdminServer.
+func (s *Server) ListKeys(ctx context.Context, req *adminpb.ListKeysRequest) (*adminpb.ListKeysResponse, error) {
+	if _, err := s.authorize(ctx, ratelimit.AdminRead); err != nil {
+		return nil, err
+	}
+	page, err := s.m.Keys(ratelimit.KeyQuery{
+		Prefix: req.GetPrefix(),
+		Sort:   ratelimit.KeySort(req.GetSort()),
+		Limit:  int(req.GetLimit()),
+		Cursor: req.GetCursor(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &adminpb.ListKeysResponse{NextCursor: page.Next}
+	for _, info := range page.Keys {
+		resp.Keys = append(resp.Keys, &adminpb.KeyInfo{
+			Key:      info.Key,
+			Requests: info.Requests,
+			Denied:   info.Denied,
+			LastSeen: timestamppb.New(info.LastSeen),
+		})
+	}
+	return resp, nil
+}
+
+// Reset implements adminpb.RateLimitAdminServer.
+func (s *Server) Reset(ctx context.Context, req *adminpb.ResetRequest) (*adminpb.ResetResponse, error) {
+	if err := s.mutate(ctx, ratelimit.AdminReset, req.GetSelector(), s.m.Reset, s.m.BulkReset); err != nil {
+		return nil, err
+	}
+	return &adminpb.ResetResponse{}, nil
+}
+
+// Ban implements adminpb.RateLimitAdminServer.
+func (s *Server) Ban(ctx context.Context, req *adminpb.BanRequest) (*adminpb.BanResponse, error) {
+	var d time.Duration
+	if req.GetDuration() != nil {
+		d = req.GetDuration().AsDuration()
+	}
+	err := s.mutate(ctx, ratelimit.AdminBan, req.GetSelector(),
+		func(ctx context.Context, key string) error {
+			return s.m.Ban(ctx, key, d)
+		},
+		func(ctx context.Context, ks ratelimit.KeySelector) error {
+			return s.m.BulkBan(ctx, ks, d)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &adminpb.BanResponse{}, nil
+}
+
+// Unban implements adminpb.RateLimitAdminServer.
+func (s *Server) Unban(ctx context.Context, req *adminpb.UnbanRequest) (*adminpb.UnbanResponse, error) {
+	if err := s.mutate(ctx, ratelimit.AdminBan, req.GetSelector(), s.m.Unban, s.m.BulkUnban); err != nil {
+		return nil, err
+	}
+	return &adminpb.UnbanResponse{}, nil
+}
+
+// SetOverride implements adminpb.RateLimitAdminServer.
+func (s *Server) SetOverride(
+	ctx context.Context, req *adminpb.SetOverrideRequest,
+) (*adminpb.SetOverrideResponse, error) {
+	o := ratelimit.Override{Rate: rate.Limit(req.GetRate()), Burst: int(req.GetBurst())}
+	err := s.mutate(ctx, ratelimit.AdminOverride, req.GetSelector(),
+		func(ctx context.Context, key string) error {
+			return s.m.SetOverride(ctx, key, o)
+		},
+		func(ctx context.Context, ks ratelimit.KeySelector) error {
+			return s.m.BulkSetOverride(ctx, ks, o)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &adminpb.SetOverrideResponse{}, nil
+}
+
+// ClearOverride implements adminpb.RateLimitAdminServer.
+func (s *Server) ClearOverride(
+	ctx context.Context, req *adminpb.ClearOverrideRequest,
+) (*adminpb.ClearOverrideResponse, error) {
+	err := s.mutate(ctx, ratelimit.AdminOverride, req.GetSelector(), s.m.ClearOverride, s.m.BulkClearOverride)
+	if err != nil {
+		return nil, err
+	}
+	return &adminpb.ClearOverrideResponse{}, nil
+}
+
+// WatchDecisions implements adminpb.RateLimitAdminServer.
+func (s *Server) WatchDecisions(
+	req *adminpb.WatchDecisionsRequest, stream adminpb.RateLimitAdmin_WatchDecisionsServer,
+) error {
+	ctx := stream.Context()
+	if _, err := s.authorize(ctx, ratelimit.AdminRead); err != nil {
+		return err
+	}
+	buffer := int(req.GetBuffer())
+	if buffer <= 0 {
+		buffer = DefaultWatchBuffer
+	}
+
+	for d := range s.m.WatchDecisions(ctx, buffer) {
+		err := stream.Send(&adminpb.Decision{
+			Time:    timestamppb.New(d.Time),
+			Key:     d.Key,
+			Class:   d.Class,
+			Route:   d.Route,
+			Allowed: d.Allowed,
+			Banned:  d.Banned,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
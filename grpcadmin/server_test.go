@@ -0,0 +1,183 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package grpcadmin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-contrib/ratelimit/grpcadmin/adminpb"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newClient serves the admin service of m in memory and returns a client.
+func newClient(t *testing.T, m *ratelimit.Manager) adminpb.RateLimitAdminClient {
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	Register(s, m)
+	go func() {
+		_ = s.Serve(l)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return adminpb.NewRateLimitAdminClient(conn)
+}
+
+// withToken attaches a bearer token to ctx.
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Operations", func(t *testing.T) {
+		m := ratelimit.NewManager(ratelimit.Options{Rate: rate.Every(time.Hour), Burst: 1})
+		client := newClient(t, m)
+		ctx := context.Background()
+
+		cfg, err := client.GetConfig(ctx, &adminpb.GetConfigRequest{})
+		require.NoError(t, err)
+		assert.Contains(t, cfg.GetJson(), `"burst": 1`)
+
+		_, err = client.Ban(ctx, &adminpb.BanRequest{
+			Selector: &adminpb.KeySelector{Prefixes: []string{"203.0.113."}},
+			Duration: durationpb.New(time.Hour),
+		})
+		require.NoError(t, err)
+		ev, err := client.Evaluate(ctx, &adminpb.EvaluateRequest{Ip: "203.0.113.7"})
+		require.NoError(t, err)
+		assert.Equal(t, "203.0.113.7", ev.GetKey())
+		assert.True(t, ev.GetBanned())
+
+		_, err = client.Unban(ctx, &adminpb.UnbanRequest{
+			Selector: &adminpb.KeySelector{Prefixes: []string{"203.0.113."}},
+		})
+		require.NoError(t, err)
+		_, err = client.SetOverride(ctx, &adminpb.SetOverrideRequest{
+			Selector: &adminpb.KeySelector{Keys: []string{"203.0.113.7"}},
+			Rate:     1,
+			Burst:    5,
+		})
+		require.NoError(t, err)
+		ev, err = client.Evaluate(ctx, &adminpb.EvaluateRequest{Ip: "203.0.113.7"})
+		require.NoError(t, err)
+		assert.False(t, ev.GetBanned())
+		assert.InDelta(t, 5, ev.GetTokens(), 0.01)
+
+		_, err = client.Reset(ctx, &adminpb.ResetRequest{Selector: &adminpb.KeySelector{}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("ListKeys", func(t *testing.T) {
+		m := ratelimit.NewManager(ratelimit.Options{Rate: rate.Every(time.Hour), Burst: 1})
+		r := gin.New()
+		r.GET("/", m.Handler())
+		for _, ip := range []string{"203.0.113.7", "203.0.113.7", "192.0.2.1"} {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = ip + ":1234"
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		resp, err := newClient(t, m).ListKeys(context.Background(), &adminpb.ListKeysRequest{Sort: "denied", Limit: 1})
+		require.NoError(t, err)
+		if assert.Len(t, resp.GetKeys(), 1) {
+			assert.Equal(t, "203.0.113.7", resp.GetKeys()[0].GetKey())
+			assert.Equal(t, uint64(1), resp.GetKeys()[0].GetDenied())
+		}
+		assert.NotEmpty(t, resp.GetNextCursor())
+	})
+
+	t.Run("Auth", func(t *testing.T) {
+		m := ratelimit.NewManager(ratelimit.Options{
+			Rate:  rate.Inf,
+			Burst: 1,
+			AdminAuth: &ratelimit.AdminAuth{
+				ValidateToken: ratelimit.StaticTokens(map[string]ratelimit.AdminPrincipal{
+					"viewer": {Name: "viewer", Roles: []string{"viewer"}},
+				}),
+				Roles: map[string][]ratelimit.AdminPermission{
+					"viewer": {ratelimit.AdminRead},
+				},
+			},
+		})
+		client := newClient(t, m)
+		ctx := context.Background()
+
+		_, err := client.GetConfig(ctx, &adminpb.GetConfigRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		_, err = client.GetConfig(withToken(ctx, "viewer"), &adminpb.GetConfigRequest{})
+		assert.NoError(t, err)
+		_, err = client.Ban(withToken(ctx, "viewer"), &adminpb.BanRequest{
+			Selector: &adminpb.KeySelector{Keys: []string{"k"}},
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("WatchDecisions", func(t *testing.T) {
+		m := ratelimit.NewManager(ratelimit.Options{Rate: rate.Every(time.Hour), Burst: 1})
+		client := newClient(t, m)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.WatchDecisions(ctx, &adminpb.WatchDecisionsRequest{})
+		require.NoError(t, err)
+		// The server subscribes asynchronously, so send requests until one
+		// is seen.
+		r := gin.New()
+		r.GET("/users/:id", m.Handler())
+		decisions := make(chan *adminpb.Decision, 16)
+		go func() {
+			for {
+				d, err := stream.Recv()
+				if err != nil {
+					close(decisions)
+					return
+				}
+				decisions <- d
+			}
+		}()
+
+		var d *adminpb.Decision
+		require.Eventually(t, func() bool {
+			req, _ := http.NewRequest("GET", "/users/42", nil)
+			req.RemoteAddr = "203.0.113.7:1234"
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			select {
+			case d = <-decisions:
+				return true
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, time.Millisecond)
+		assert.Equal(t, "203.0.113.7", d.GetKey())
+		assert.Equal(t, "/users/:id", d.GetRoute())
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Defaults applied to unset Guardrail fields.
+const (
+	DefaultGuardrailObjective   = 0.99
+	DefaultGuardrailBurnRate    = 10
+	DefaultGuardrailWindow      = time.Minute
+	DefaultGuardrailMinRequests = 20
+	DefaultGuardrailFactor      = 0.5
+)
+
+// Guardrail watches the latency and error SLO of a traffic class and
+// tightens the limits of lower-priority classes while the SLO's error
+// budget burns too fast, shedding their load to protect the watched class.
+//
+// A request of the watched class is bad if its response status is 5xx or,
+// when MaxLatency is set, if it took longer than MaxLatency. The guardrail
+// trips when the fraction of bad requests over the last Window exceeds
+// BurnRate times the error budget (1 - Objective), and clears once it falls
+// below half of that.
+type Guardrail struct {
+	// Class is the traffic class whose SLO is watched, as assigned by
+	// Options.Classifier.
+	Class string
+
+	// MaxLatency is the slowest a request may be to count as good.
+	// If zero, only errors count as bad requests.
+	MaxLatency time.Duration
+
+	// Objective is the fraction of requests that must be good.
+	// If zero, DefaultGuardrailObjective is used.
+	Objective float64
+
+	// BurnRate is how many times faster than sustainable the error budget
+	// may be spent before the guardrail trips.
+	// If zero, DefaultGuardrailBurnRate is used.
+	BurnRate float64
+
+	// Window is the period over which the fraction of bad requests is
+	// measured. If zero, DefaultGuardrailWindow is used.
+	Window time.Duration
+
+	// MinRequests is the number of requests the window must hold before the
+	// guardrail can trip. If zero, DefaultGuardrailMinRequests is used.
+	MinRequests int
+
+	// Tighten lists the low-priority classes whose limits are tightened
+	// while the guardrail is tripped.
+	Tighten []string
+
+	// Factor scales the limits of the tightened classes, between 0 and 1.
+	// Tightened requests consume 1/Factor tokens, rounded up, so a class
+	// gets Factor of its usual rate; when the cost exceeds the burst, the
+	// class is shed entirely. If zero, DefaultGuardrailFactor is used.
+	Factor float64
+}
+
+// guardrail is the runtime state of a Guardrail.
+type guardrail struct {
+	cfg       Guardrail
+	threshold float64
+	cost      int
+
+	mu      sync.Mutex
+	start   time.Time
+	current sloCounts
+	prev    sloCounts
+	tripped bool
+}
+
+// sloCounts counts the requests of one window.
+type sloCounts struct {
+	total, bad int
+}
+
+// newGuardrail applies defaults to the configuration.
+func newGuardrail(cfg Guardrail, now time.Time) *guardrail {
+	if cfg.Objective <= 0 {
+		cfg.Objective = DefaultGuardrailObjective
+	}
+	if cfg.BurnRate <= 0 {
+		cfg.BurnRate = DefaultGuardrailBurnRate
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultGuardrailWindow
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultGuardrailMinRequests
+	}
+	if cfg.Factor <= 0 || cfg.Factor > 1 {
+		cfg.Factor = DefaultGuardrailFactor
+	}
+	return &guardrail{
+		cfg:       cfg,
+		threshold: cfg.BurnRate * (1 - cfg.Objective),
+		cost:      int(math.Ceil(1 / cfg.Factor)),
+		start:     now,
+	}
+}
+
+// isTripped reports whether the guardrail currently tightens limits.
+func (g *guardrail) isTripped() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.tripped
+}
+
+// record counts a finished request of the watched class. It returns the
+// event to emit if the guardrail changed state.
+func (g *guardrail) record(status int, latency time.Duration, now time.Time) (Event, bool) {
+	bad := status >= http.StatusInternalServerError ||
+		(g.cfg.MaxLatency > 0 && latency > g.cfg.MaxLatency)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Roll the window over, dropping counts that are more than a full
+	// window old.
+	if elapsed := now.Sub(g.start); elapsed >= g.cfg.Window {
+		g.prev = g.current
+		if elapsed >= 2*g.cfg.Window {
+			g.prev = sloCounts{}
+		}
+		g.current = sloCounts{}
+		g.start = now.Add(-(elapsed % g.cfg.Window))
+	}
+	g.current.total++
+	if bad {
+		g.current.bad++
+	}
+
+	// Weight the previous window by how much of it still overlaps the
+	// sliding window ending now.
+	weight := 1 - float64(now.Sub(g.start))/float64(g.cfg.Window)
+	total := float64(g.current.total) + weight*float64(g.prev.total)
+	if total < float64(g.cfg.MinRequests) {
+		return Event{}, false
+	}
+	burn := (float64(g.current.bad) + weight*float64(g.prev.bad)) / total
+
+	switch {
+	case !g.tripped && burn > g.threshold:
+		g.tripped = true
+		return Event{
+			Type:    EventGuardrailTripped,
+			Time:    now,
+			Class:   g.cfg.Class,
+			Message: fmt.Sprintf("%.1f%% bad requests, tightening %v", burn*100, g.cfg.Tighten),
+		}, true
+	case g.tripped && burn < g.threshold/2:
+		g.tripped = false
+		return Event{
+			Type:    EventGuardrailCleared,
+			Time:    now,
+			Class:   g.cfg.Class,
+			Message: fmt.Sprintf("%.1f%% bad requests, restoring %v", burn*100, g.cfg.Tighten),
+		}, true
+	}
+	return Event{}, false
+}
+
+// cost returns the number of tokens a request of the given class consumes
+// under the currently tripped guardrails.
+func (m *Manager) cost(class string) int {
+	n := 1
+	for _, g := range m.guardrails {
+		if g.cost > n && slices.Contains(g.cfg.Tighten, class) && g.isTripped() {
+			n = g.cost
+		}
+	}
+	return n
+}
+
+// observe feeds a finished request to the guardrails watching its class.
+func (m *Manager) observe(class string, status int, latency time.Duration) {
+	now := time.Now()
+	for _, g := range m.guardrails {
+		if g.cfg.Class != class {
+			continue
+		}
+		if e, changed := g.record(status, latency, now); changed {
+			m.emit(e)
+		}
+	}
+}
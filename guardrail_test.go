@@ -0,0 +1,109 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestGuardrails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("TightensLowPriorityClasses", func(t *testing.T) {
+		var events []Event
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:  rate.Every(time.Hour),
+			Burst: 100,
+			Classifier: PathGroupClassifier(map[string]string{
+				"/checkout": "checkout",
+				"/export":   "export",
+			}, ""),
+			KeyFunc: func(c *gin.Context) string {
+				cl, _ := ClassificationFrom(c)
+				return cl.Class
+			},
+			Guardrails: []Guardrail{{
+				Class:       "checkout",
+				MinRequests: 4,
+				Window:      time.Hour,
+				Tighten:     []string{"export"},
+				Factor:      0.01,
+			}},
+			OnEvent: func(e Event) {
+				events = append(events, e)
+			},
+		}))
+		r.GET("/checkout", func(c *gin.Context) {
+			status, _ := strconv.Atoi(c.Query("status"))
+			c.Status(status)
+		})
+		r.GET("/export", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		serve := func(path string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, serve("/export"))
+
+		for i := 0; i < 4; i++ {
+			serve("/checkout?status=500")
+		}
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, EventGuardrailTripped, events[0].Type)
+			assert.Equal(t, "checkout", events[0].Class)
+		}
+
+		// Tightened export requests now cost 100 tokens, more than left.
+		assert.Equal(t, http.StatusTooManyRequests, serve("/export"))
+
+		// Enough good requests bring the bad fraction under half the
+		// threshold of 10%.
+		for i := 0; i < 80; i++ {
+			serve("/checkout?status=200")
+		}
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, EventGuardrailCleared, events[1].Type)
+		}
+		assert.Equal(t, http.StatusOK, serve("/export"))
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		g := newGuardrail(Guardrail{MaxLatency: time.Second, MinRequests: 1}, time.Now())
+
+		_, changed := g.record(http.StatusOK, time.Millisecond, time.Now())
+		assert.False(t, changed)
+		e, changed := g.record(http.StatusOK, 2*time.Second, time.Now())
+		assert.True(t, changed)
+		assert.Equal(t, EventGuardrailTripped, e.Type)
+	})
+
+	t.Run("WindowRollover", func(t *testing.T) {
+		start := time.Now()
+		g := newGuardrail(Guardrail{Window: time.Minute, MinRequests: 2}, start)
+
+		_, changed := g.record(http.StatusInternalServerError, 0, start)
+		assert.False(t, changed)
+
+		// Two windows later the old failure no longer counts.
+		_, changed = g.record(http.StatusOK, 0, start.Add(3*time.Minute))
+		assert.False(t, changed)
+		_, changed = g.record(http.StatusOK, 0, start.Add(3*time.Minute))
+		assert.False(t, changed)
+		assert.False(t, g.isTripped())
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// HeaderFormat selects the rate limit headers set on responses.
+type HeaderFormat int
+
+// Header formats.
+const (
+	// HeadersXRateLimit sets X-RateLimit-Limit to the burst,
+	// X-RateLimit-Remaining to the requests the client may still send
+	// right now, and X-RateLimit-Reset to the seconds until the full
+	// burst is available again.
+	HeadersXRateLimit HeaderFormat = iota
+	// HeadersNone sets no rate limit headers.
+	HeadersNone
+	// HeadersIETF sets the RateLimit and RateLimit-Policy headers of the
+	// IETF draft draft-ietf-httpapi-ratelimit-headers, such as
+	// "limit=100, remaining=50, reset=23" and "100;w=60", the window
+	// being the seconds it takes to refill the whole burst.
+	HeadersIETF
+)
+
+// String returns the name of the format, as reported by ConfigJSON.
+func (f HeaderFormat) String() string {
+	switch f {
+	case HeadersXRateLimit:
+		return "x-ratelimit"
+	case HeadersNone:
+		return "none"
+	case HeadersIETF:
+		return "ietf"
+	default:
+		return "HeaderFormat(" + strconv.Itoa(int(f)) + ")"
+	}
+}
+
+// setHeaders sets the rate limit headers describing limiter, the state of
+// the client's limit after the decision on the request.
+func (m *Manager) setHeaders(c *gin.Context, limiter *rate.Limiter) {
+	if m.opts.Headers == HeadersNone || limiter == nil {
+		return
+	}
+	d := newDenyData(c, limiter, time.Now())
+	h := c.Writer.Header()
+	if m.opts.Headers == HeadersIETF {
+		h.Set("RateLimit", fmt.Sprintf("limit=%d, remaining=%d, reset=%d", d.Burst, d.Remaining, d.Reset))
+		policy := strconv.Itoa(d.Burst)
+		if w := logWindow(limiter.Limit(), limiter.Burst()); w > 0 && limiter.Limit() != rate.Inf {
+			policy += ";w=" + strconv.FormatInt(int64(math.Ceil(w.Seconds())), 10)
+		}
+		h.Set("RateLimit-Policy", policy)
+		return
+	}
+	h.Set("X-RateLimit-Limit", strconv.Itoa(d.Burst))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(d.Reset))
+}
+
+// setRetryAfter sets the Retry-After header of a denied request to the
+// time until limiter has a token available, in whole seconds or, with
+// Options.RetryAfterDate, as an HTTP-date. Nothing is set if the limiter
+// never allows a request.
+func (m *Manager) setRetryAfter(c *gin.Context, limiter *rate.Limiter) {
+	if limiter == nil {
+		return
+	}
+	now := time.Now()
+	res := limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return
+	}
+	delay := res.DelayFrom(now)
+	res.CancelAt(now)
+	if delay == rate.InfDuration {
+		return
+	}
+	seconds := max(1, int(math.Ceil(delay.Seconds())))
+	if m.opts.RetryAfterDate {
+		c.Header("Retry-After", now.Add(time.Duration(seconds)*time.Second).UTC().Format(http.TimeFormat))
+		return
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(opts Options) func() *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(New(opts))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.ServeHTTP(w, req)
+			return w
+		}
+	}
+
+	t.Run("XRateLimit", func(t *testing.T) {
+		get := serve(Options{Rate: rate.Every(time.Second), Burst: 2})
+		for _, want := range []struct {
+			code      int
+			remaining string
+			reset     string
+		}{
+			{http.StatusOK, "1", "1"},
+			{http.StatusOK, "0", "2"},
+			{http.StatusTooManyRequests, "0", "2"},
+		} {
+			w := get()
+			assert.Equal(t, want.code, w.Code)
+			assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+			assert.Equal(t, want.remaining, w.Header().Get("X-RateLimit-Remaining"))
+			assert.Equal(t, want.reset, w.Header().Get("X-RateLimit-Reset"))
+		}
+	})
+
+	t.Run("Algorithm", func(t *testing.T) {
+		get := serve(Options{Rate: rate.Every(time.Minute), Burst: 3, Algorithm: SlidingWindowLog()})
+		w := get()
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Remaining"))
+	})
+
+	t.Run("IETF", func(t *testing.T) {
+		get := serve(Options{Rate: rate.Every(time.Second), Burst: 60, Headers: HeadersIETF})
+		w := get()
+		assert.Equal(t, "limit=60, remaining=59, reset=1", w.Header().Get("RateLimit"))
+		assert.Equal(t, "60;w=60", w.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+
+		get = serve(Options{Rate: rate.Inf, Burst: 5, Headers: HeadersIETF})
+		w = get()
+		assert.Equal(t, "limit=5, remaining=5, reset=0", w.Header().Get("RateLimit"))
+		assert.Equal(t, "5", w.Header().Get("RateLimit-Policy"))
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		get := serve(Options{Rate: rate.Every(time.Minute), Burst: 1})
+		assert.Empty(t, get().Header().Get("Retry-After"))
+		w := get()
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		get = serve(Options{Rate: rate.Every(time.Minute), Burst: 1, RetryAfterDate: true})
+		get()
+		at, err := http.ParseTime(get().Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), at, 2*time.Second)
+
+		// Limits that never allow a request have no time to retry at.
+		get = serve(Options{Rate: 0, Burst: 0})
+		w = get()
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("None", func(t *testing.T) {
+		get := serve(Options{Rate: rate.Every(time.Second), Burst: 2, Headers: HeadersNone})
+		w := get()
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+		assert.Empty(t, w.Header().Get("X-RateLimit-Remaining"))
+		assert.Empty(t, w.Header().Get("X-RateLimit-Reset"))
+	})
+}
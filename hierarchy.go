@@ -0,0 +1,122 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// maxHierarchyCache is the most keys a TenantHierarchy caches the limits
+// of before starting over.
+const maxHierarchyCache = 1 << 16
+
+// TenantLimit is the limit set at one node of a TenantHierarchy. Zero
+// fields are inherited from the node's parent, so a project can raise its
+// burst while keeping the rate of its organization.
+type TenantLimit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// TenantHierarchy resolves the limits of keys that belong to a hierarchy
+// of tenants, such as organization, project and API key. Every node can
+// set a limit, which applies to the keys below it unless a lower node
+// overrides it; at the top, the limits of Options apply. Resolved limits
+// are cached per key until the hierarchy changes.
+type TenantHierarchy struct {
+	path func(key string) []string
+
+	mu     sync.RWMutex
+	limits map[string]TenantLimit
+	// cache holds the merged limits of the nodes above each key.
+	cache map[string]TenantLimit
+}
+
+// NewTenantHierarchy creates a hierarchy in which path returns the nodes
+// above a key, from the top, such as []string{"acme", "billing", key}.
+func NewTenantHierarchy(path func(key string) []string) *TenantHierarchy {
+	return &TenantHierarchy{
+		path:   path,
+		limits: make(map[string]TenantLimit),
+		cache:  make(map[string]TenantLimit),
+	}
+}
+
+// nodeID returns the identifier of the node at path.
+func nodeID(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// Set sets the limit of the node at path.
+func (h *TenantHierarchy) Set(path []string, limit TenantLimit) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.limits[nodeID(path)] = limit
+	clear(h.cache)
+}
+
+// Delete removes the limit of the node at path, which then inherits its
+// parent's again.
+func (h *TenantHierarchy) Delete(path []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.limits, nodeID(path))
+	clear(h.cache)
+}
+
+// Get returns the limit set at the node at path, if any.
+func (h *TenantHierarchy) Get(path []string) (TenantLimit, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	l, ok := h.limits[nodeID(path)]
+	return l, ok
+}
+
+// Resolve returns the limits of key, starting from the given ones and
+// applying the limits set along its path, from the top down.
+func (h *TenantHierarchy) Resolve(key string, r rate.Limit, burst int) (rate.Limit, int) {
+	h.mu.RLock()
+	merged, ok := h.cache[key]
+	h.mu.RUnlock()
+	if !ok {
+		merged = h.merge(key)
+	}
+	if merged.Rate != 0 {
+		r = merged.Rate
+	}
+	if merged.Burst != 0 {
+		burst = merged.Burst
+	}
+	return r, burst
+}
+
+// merge merges the limits along the path of key and caches the result.
+func (h *TenantHierarchy) merge(key string) TenantLimit {
+	path := h.path(key)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var merged TenantLimit
+	for i := range path {
+		l, ok := h.limits[nodeID(path[:i+1])]
+		if !ok {
+			continue
+		}
+		if l.Rate != 0 {
+			merged.Rate = l.Rate
+		}
+		if l.Burst != 0 {
+			merged.Burst = l.Burst
+		}
+	}
+	if len(h.cache) >= maxHierarchyCache {
+		clear(h.cache)
+	}
+	h.cache[key] = merged
+	return merged
+}
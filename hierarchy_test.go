@@ -0,0 +1,109 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestTenantHierarchy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Keys are "<org>/<project>/<key>".
+	newHierarchy := func() *TenantHierarchy {
+		return NewTenantHierarchy(func(key string) []string {
+			return strings.Split(key, "/")
+		})
+	}
+	base := rate.Every(time.Second)
+
+	t.Run("Inheritance", func(t *testing.T) {
+		h := newHierarchy()
+		h.Set([]string{"acme"}, TenantLimit{Rate: 10, Burst: 20})
+		h.Set([]string{"acme", "billing"}, TenantLimit{Burst: 50})
+		h.Set([]string{"acme", "billing", "k1"}, TenantLimit{Rate: 100})
+
+		r, burst := h.Resolve("acme/web/k2", base, 5)
+		assert.Equal(t, rate.Limit(10), r)
+		assert.Equal(t, 20, burst)
+
+		r, burst = h.Resolve("acme/billing/k2", base, 5)
+		assert.Equal(t, rate.Limit(10), r, "the project keeps the organization's rate")
+		assert.Equal(t, 50, burst)
+
+		r, burst = h.Resolve("acme/billing/k1", base, 5)
+		assert.Equal(t, rate.Limit(100), r)
+		assert.Equal(t, 50, burst)
+
+		r, burst = h.Resolve("initech/web/k1", base, 5)
+		assert.Equal(t, base, r)
+		assert.Equal(t, 5, burst)
+	})
+
+	t.Run("Changes invalidate the cache", func(t *testing.T) {
+		h := newHierarchy()
+		h.Set([]string{"acme"}, TenantLimit{Burst: 20})
+		_, burst := h.Resolve("acme/web/k", base, 5)
+		assert.Equal(t, 20, burst)
+		assert.Contains(t, h.cache, "acme/web/k")
+
+		h.Set([]string{"acme", "web"}, TenantLimit{Burst: 30})
+		_, burst = h.Resolve("acme/web/k", base, 5)
+		assert.Equal(t, 30, burst)
+
+		h.Delete([]string{"acme", "web"})
+		_, burst = h.Resolve("acme/web/k", base, 5)
+		assert.Equal(t, 20, burst)
+
+		l, ok := h.Get([]string{"acme"})
+		assert.True(t, ok)
+		assert.Equal(t, 20, l.Burst)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		h := newHierarchy()
+		h.Set([]string{"acme"}, TenantLimit{Burst: 3})
+		m := NewManager(Options{
+			Rate:      rate.Every(time.Hour),
+			Burst:     1,
+			KeyFunc:   func(c *gin.Context) string { return c.GetHeader("X-Key") },
+			Hierarchy: h,
+		})
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		allowed := func(key string) int {
+			n := 0
+			for range 5 {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Key", key)
+				r.ServeHTTP(w, req)
+				if w.Code == http.StatusOK {
+					n++
+				}
+			}
+			return n
+		}
+		assert.Equal(t, 3, allowed("acme/web/k"))
+		assert.Equal(t, 1, allowed("initech/web/k"))
+
+		// Overrides still win over the hierarchy.
+		o := Override{Rate: rate.Every(time.Hour), Burst: 2}
+		require.NoError(t, m.SetOverride(context.Background(), "acme/web/k2", o))
+		assert.Equal(t, 2, allowed("acme/web/k2"))
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrHotKeysUnsupported is returned by Manager.Hydrate when the Algorithm
+// does not record the activity of its keys.
+var ErrHotKeysUnsupported = errors.New("ratelimit: algorithm does not report hot keys")
+
+// HotKey is a key an Algorithm recently saw requests for.
+type HotKey struct {
+	// Key is the key of the bucket.
+	Key string
+	// Rate and Burst are the limits the key was last checked against.
+	Rate  rate.Limit
+	Burst int
+	// Requests is the number of requests recently seen for the key.
+	Requests int64
+}
+
+// HotKeyReporter is implemented by Algorithms sharing state between
+// instances that record which keys are busiest, for Manager.Hydrate.
+type HotKeyReporter interface {
+	// HotKeys returns up to n of the keys with the most recent requests,
+	// busiest first.
+	HotKeys(ctx context.Context, n int) ([]HotKey, error)
+}
+
+// Hydrate fills the local buckets of up to n of the busiest keys of the
+// Algorithm with the state of the shared store, returning how many it
+// filled. Without it, a new instance starts every key with a full local
+// bucket, so with ConsistencyEventual heavy clients get a burst past their
+// shared budget in the first seconds after a deploy. Call it once after
+// NewManager, before serving requests; keys that already have a local
+// bucket are left as they are.
+func (m *Manager) Hydrate(ctx context.Context, n int) (int, error) {
+	hk, ok := m.opts.Algorithm.(HotKeyReporter)
+	if !ok {
+		return 0, ErrHotKeysUnsupported
+	}
+	keys, err := hk.HotKeys(ctx, n)
+	if err != nil {
+		return 0, fmt.Errorf("ratelimit: listing hot keys: %w", err)
+	}
+
+	hydrated := 0
+	now := time.Now()
+	for _, k := range keys {
+		if _, ok := m.limiters.Get(k.Key); ok {
+			continue
+		}
+		a, err := m.opts.Algorithm.Take(ctx, k.Key, k.Rate, k.Burst, 0, now)
+		if err != nil {
+			return hydrated, fmt.Errorf("ratelimit: hydrating key: %w", err)
+		}
+		limiter := rate.NewLimiter(k.Rate, k.Burst)
+		if spent := k.Burst - a.Remaining; spent > 0 {
+			limiter.AllowN(now, spent)
+		}
+		m.limiters.Set(k.Key, limiter)
+		hydrated++
+	}
+	return hydrated, nil
+}
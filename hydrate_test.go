@@ -0,0 +1,108 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hotAlgorithm reports fixed hot keys.
+type hotAlgorithm struct {
+	Algorithm
+	hot []HotKey
+}
+
+func (a hotAlgorithm) HotKeys(_ context.Context, n int) ([]HotKey, error) {
+	return a.hot[:min(n, len(a.hot))], nil
+}
+
+func TestHydrate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	// Another instance spent the budget of the heavy client.
+	shared := hotAlgorithm{GCRA(), []HotKey{
+		{Key: "192.0.2.1", Rate: 1, Burst: 3, Requests: 9},
+		{Key: "192.0.2.2", Rate: 1, Burst: 3, Requests: 1},
+	}}
+	_, err := shared.Take(ctx, "192.0.2.1", 1, 3, 3, time.Now())
+	require.NoError(t, err)
+
+	newInstance := func() (*Manager, *gin.Engine) {
+		m := NewManager(Options{
+			Rate:         1,
+			Burst:        3,
+			Algorithm:    shared,
+			Consistency:  ConsistencyEventual,
+			SyncInterval: time.Hour,
+		})
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+		return m, r
+	}
+	serve := func(r *gin.Engine, addr string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = addr + ":1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Cold", func(t *testing.T) {
+		// Without hydration, the local bucket starts full.
+		_, r := newInstance()
+		assert.Equal(t, http.StatusOK, serve(r, "192.0.2.1"))
+	})
+
+	t.Run("Hydrated", func(t *testing.T) {
+		m, r := newInstance()
+		n, err := m.Hydrate(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "192.0.2.1"))
+		assert.Equal(t, http.StatusOK, serve(r, "192.0.2.2"))
+
+		// Buckets already in use are kept.
+		n, err = m.Hydrate(ctx, 10)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("Faults", func(t *testing.T) {
+		// Injecting faults keeps the hot keys of the algorithm.
+		m := NewManager(Options{Rate: 1, Burst: 3, Algorithm: shared, Faults: &Faults{}})
+		n, err := m.Hydrate(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		m = NewManager(Options{
+			Rate:      1,
+			Burst:     3,
+			Algorithm: shared,
+			Faults:    &Faults{ErrorProbability: 1, Rand: always},
+		})
+		_, err = m.Hydrate(ctx, 10)
+		assert.ErrorIs(t, err, ErrInjectedFault)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 3, Algorithm: GCRA()})
+		_, err := m.Hydrate(ctx, 10)
+		assert.ErrorIs(t, err, ErrHotKeysUnsupported)
+
+		m = NewManager(Options{Rate: 1, Burst: 3, Algorithm: GCRA(), Faults: &Faults{}})
+		_, err = m.Hydrate(ctx, 10)
+		assert.ErrorIs(t, err, ErrHotKeysUnsupported)
+	})
+}
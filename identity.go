@@ -0,0 +1,175 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// identityKey is the gin context key holding the request's identity.
+const identityKey = "github.com/gin-contrib/ratelimit/identity"
+
+// Identity types assigned by the built-in resolvers.
+const (
+	IdentityUser    = "user"
+	IdentityAPIKey  = "api_key"
+	IdentitySession = "session"
+	IdentityIP      = "ip"
+)
+
+// Identity is who a request is attributed to.
+type Identity struct {
+	// Type is the kind of identity, such as IdentityUser or IdentityIP.
+	Type string `json:"type"`
+	// ID identifies the client among identities of the same type.
+	ID string `json:"id"`
+}
+
+// Key returns the rate limiting key of the identity, "<type>:<id>", so
+// identities of different types never share a bucket.
+func (id Identity) Key() string {
+	return id.Type + ":" + id.ID
+}
+
+// IdentityResolver determines the identity of a request, reporting false
+// if the request carries no identity of the kind it resolves.
+type IdentityResolver interface {
+	Resolve(c *gin.Context) (Identity, bool)
+}
+
+// IdentityResolverFunc adapts a function to the IdentityResolver interface.
+type IdentityResolverFunc func(c *gin.Context) (Identity, bool)
+
+// Resolve implements IdentityResolver.
+func (f IdentityResolverFunc) Resolve(c *gin.Context) (Identity, bool) {
+	return f(c)
+}
+
+// IdentityFrom returns the identity the middleware resolved for the
+// request, and whether there is one.
+func IdentityFrom(c *gin.Context) (Identity, bool) {
+	v, ok := c.Get(identityKey)
+	if !ok {
+		return Identity{}, false
+	}
+	id, ok := v.(Identity)
+	return id, ok
+}
+
+// identify verifies the request's signature and runs the configured
+// resolver, if any, and records the result in the context. Requests no
+// resolver recognizes are identified by their IP.
+func (m *Manager) identify(c *gin.Context) {
+	if m.opts.Identity == nil && m.signatures == nil {
+		return
+	}
+	var (
+		id Identity
+		ok bool
+	)
+	if m.signatures != nil {
+		id, ok = m.signatures.Resolve(c)
+	}
+	if !ok && m.opts.Identity != nil {
+		id, ok = m.opts.Identity.Resolve(c)
+	}
+	if !ok {
+		id = Identity{Type: IdentityIP, ID: c.ClientIP()}
+	}
+	c.Set(identityKey, id)
+}
+
+// identityKeyFunc is the default KeyFunc when Options.Identity is set.
+func identityKeyFunc(c *gin.Context) string {
+	id, _ := IdentityFrom(c)
+	return id.Key()
+}
+
+// IdentityChain tries each resolver in order and returns the first
+// identity found, for example:
+//
+//	IdentityChain(
+//		JWTClaimResolver("sub", verify),
+//		APIKeyResolver("X-API-Key"),
+//		SessionCookieResolver("session"),
+//		ClientIPResolver(),
+//	)
+func IdentityChain(resolvers ...IdentityResolver) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		for _, r := range resolvers {
+			if id, ok := r.Resolve(c); ok {
+				return id, true
+			}
+		}
+		return Identity{}, false
+	})
+}
+
+// JWTVerifier verifies the signature and validity of a JSON Web Token and
+// returns its claims.
+type JWTVerifier func(token string) (map[string]any, error)
+
+// JWTClaimResolver identifies requests bearing a valid JWT in their
+// "Authorization: Bearer" header as users, by the given claim, typically
+// "sub". Tokens are only trusted once verify accepts them, since anyone
+// can mint unsigned tokens and with them fresh buckets; requests whose
+// token is rejected or lacks the claim fall through to the next resolver.
+func JWTClaimResolver(claim string, verify JWTVerifier) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		token := bearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			return Identity{}, false
+		}
+		claims, err := verify(token)
+		if err != nil {
+			return Identity{}, false
+		}
+		var id string
+		switch v := claims[claim].(type) {
+		case nil:
+			return Identity{}, false
+		case string:
+			id = v
+		default:
+			id = fmt.Sprint(v)
+		}
+		if id == "" {
+			return Identity{}, false
+		}
+		return Identity{Type: IdentityUser, ID: id}, true
+	})
+}
+
+// APIKeyResolver identifies requests by the API key in the given header.
+func APIKeyResolver(header string) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		key := c.GetHeader(header)
+		return Identity{Type: IdentityAPIKey, ID: key}, key != ""
+	})
+}
+
+// SessionCookieResolver identifies requests by the value of the named
+// session cookie. The value is trusted as is, so clients can pick new
+// identities by changing it; prefer a resolver that validates the cookie
+// where that matters.
+func SessionCookieResolver(name string) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		value, err := c.Cookie(name)
+		if err != nil || value == "" {
+			return Identity{}, false
+		}
+		return Identity{Type: IdentitySession, ID: value}, true
+	})
+}
+
+// ClientIPResolver identifies every request by its client IP, as returned
+// by gin.Context.ClientIP.
+func ClientIPResolver() IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		return Identity{Type: IdentityIP, ID: c.ClientIP()}, true
+	})
+}
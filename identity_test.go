@@ -0,0 +1,128 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestIdentityResolver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verify := func(token string) (map[string]any, error) {
+		switch token {
+		case "alice":
+			return map[string]any{"sub": "alice"}, nil
+		case "numeric":
+			return map[string]any{"sub": float64(42)}, nil
+		case "anonymous":
+			return map[string]any{}, nil
+		}
+		return nil, errors.New("invalid token")
+	}
+	chain := IdentityChain(
+		JWTClaimResolver("sub", verify),
+		APIKeyResolver("X-API-Key"),
+		SessionCookieResolver("session"),
+		ClientIPResolver(),
+	)
+	resolve := func(header map[string]string) (Identity, bool) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.RemoteAddr = "203.0.113.7:1234"
+		for k, v := range header {
+			c.Request.Header.Set(k, v)
+		}
+		return chain.Resolve(c)
+	}
+
+	t.Run("JWT", func(t *testing.T) {
+		id, ok := resolve(map[string]string{"Authorization": "Bearer alice", "X-API-Key": "k1"})
+		assert.True(t, ok)
+		assert.Equal(t, Identity{Type: IdentityUser, ID: "alice"}, id)
+
+		id, _ = resolve(map[string]string{"Authorization": "Bearer numeric"})
+		assert.Equal(t, Identity{Type: IdentityUser, ID: "42"}, id)
+	})
+
+	t.Run("FallThrough", func(t *testing.T) {
+		// Forged tokens and tokens without the claim are not trusted.
+		id, _ := resolve(map[string]string{"Authorization": "Bearer forged", "X-API-Key": "k1"})
+		assert.Equal(t, Identity{Type: IdentityAPIKey, ID: "k1"}, id)
+
+		id, _ = resolve(map[string]string{"Authorization": "Bearer anonymous", "Cookie": "session=s1"})
+		assert.Equal(t, Identity{Type: IdentitySession, ID: "s1"}, id)
+
+		id, _ = resolve(nil)
+		assert.Equal(t, Identity{Type: IdentityIP, ID: "203.0.113.7"}, id)
+		assert.Equal(t, "ip:203.0.113.7", id.Key())
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		_, ok := IdentityChain(APIKeyResolver("X-API-Key")).Resolve(c)
+		assert.False(t, ok)
+	})
+}
+
+func TestIdentityMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{
+		Rate:     rate.Every(time.Hour),
+		Burst:    1,
+		Identity: APIKeyResolver("X-API-Key"),
+		Classifier: ClassifierFunc(func(c *gin.Context) Classification {
+			id, _ := IdentityFrom(c)
+			return Classification{Class: id.Type}
+		}),
+	})
+	r := gin.New()
+	r.GET("/", m.Handler(), func(c *gin.Context) {
+		id, _ := IdentityFrom(c)
+		c.String(http.StatusOK, id.Key())
+	})
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Key", func(t *testing.T) {
+		w := serve("k1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "api_key:k1", w.Body.String())
+		assert.Equal(t, http.StatusTooManyRequests, serve("k1").Code)
+
+		// Other keys and clients without one have their own buckets.
+		assert.Equal(t, http.StatusOK, serve("k2").Code)
+		w = serve("")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ip:203.0.113.7", w.Body.String())
+	})
+
+	t.Run("Evaluate", func(t *testing.T) {
+		ev, err := m.Evaluate(SyntheticRequest{Header: map[string]string{"X-API-Key": "k3"}})
+		require.NoError(t, err)
+		assert.Equal(t, Identity{Type: IdentityAPIKey, ID: "k3"}, ev.Identity)
+		assert.Equal(t, "api_key:k3", ev.Key)
+		assert.Equal(t, IdentityAPIKey, ev.Classification.Class)
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPLists configures lists of client addresses that bypass limiting or are
+// rejected outright. Entries are IP addresses, such as "192.0.2.1", or CIDR
+// ranges, such as "10.0.0.0/8" or "2001:db8::/32". Clients are matched by
+// gin's ClientIP, so configure the engine's trusted proxies accordingly.
+type IPLists struct {
+	// Allow lists the clients that bypass limiting, as Skip would.
+	Allow []string
+
+	// Deny lists the clients rejected before limiting. Deny takes
+	// precedence over Allow.
+	Deny []string
+
+	// DenyStatus is the status of rejected requests. If zero,
+	// http.StatusForbidden is used.
+	DenyStatus int
+}
+
+// ipListAction is what an address list does to a request.
+type ipListAction int
+
+const (
+	ipListNone ipListAction = iota
+	ipListAllow
+	ipListDeny
+)
+
+// ipLists holds the parsed address lists.
+type ipLists struct {
+	cfg   IPLists
+	allow *ipTrie
+	deny  *ipTrie
+}
+
+// newIPLists parses the given lists. It panics on invalid entries, which
+// are configuration errors.
+func newIPLists(cfg IPLists) *ipLists {
+	if cfg.DenyStatus == 0 {
+		cfg.DenyStatus = http.StatusForbidden
+	}
+	l := &ipLists{cfg: cfg, allow: &ipTrie{}, deny: &ipTrie{}}
+	for _, entry := range cfg.Allow {
+		l.allow.insert(mustParsePrefix(entry))
+	}
+	for _, entry := range cfg.Deny {
+		l.deny.insert(mustParsePrefix(entry))
+	}
+	return l
+}
+
+// mustParsePrefix parses an address or CIDR range, panicking if it is
+// invalid.
+func mustParsePrefix(entry string) netip.Prefix {
+	p, err := parsePrefix(entry)
+	if err != nil {
+		panic(fmt.Sprintf("ratelimit: invalid IP list entry %q: %v", strings.TrimSpace(entry), err))
+	}
+	return p
+}
+
+// parsePrefix parses an address or CIDR range. Addresses are ranges of a
+// single address, and the host bits of ranges are cleared.
+func parsePrefix(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		p, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.WithZone(""), addr.BitLen()), nil
+}
+
+// action returns what the lists do to requests from ip.
+func (l *ipLists) action(ip string) ipListAction {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ipListNone
+	}
+	switch {
+	case l.deny.contains(addr):
+		return ipListDeny
+	case l.allow.contains(addr):
+		return ipListAllow
+	default:
+		return ipListNone
+	}
+}
+
+// ipTrie is a binary radix tree of address ranges, so lookups take at
+// most one step per address bit however many ranges it holds. IPv4
+// ranges are stored as IPv4-mapped IPv6 ranges, sharing the tree with
+// IPv6 ones.
+type ipTrie struct {
+	root ipTrieNode
+}
+
+// ipTrieNode is a node of an ipTrie.
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	// end marks the last bit of a range: every address below it matches.
+	end bool
+}
+
+// bit returns bit i of key, counting from the most significant.
+func bit(key [16]byte, i int) int {
+	return int(key[i/8]>>(7-i%8)) & 1
+}
+
+// insert adds a range to the tree.
+func (t *ipTrie) insert(p netip.Prefix) {
+	// As16 maps IPv4 addresses into IPv6, below 96 fixed bits.
+	key, bits := p.Addr().As16(), p.Bits()
+	if p.Addr().Is4() {
+		bits += 96
+	}
+	n := &t.root
+	for i := range bits {
+		if n.end {
+			// A wider range already covers this one.
+			return
+		}
+		b := bit(key, i)
+		if n.children[b] == nil {
+			n.children[b] = &ipTrieNode{}
+		}
+		n = n.children[b]
+	}
+	n.end = true
+	n.children = [2]*ipTrieNode{}
+}
+
+// contains reports whether a range of the tree contains addr.
+func (t *ipTrie) contains(addr netip.Addr) bool {
+	key := addr.As16()
+	n := &t.root
+	for i := 0; n != nil; i++ {
+		if n.end {
+			return true
+		}
+		if i == 128 {
+			return false
+		}
+		n = n.children[bit(key, i)]
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestIPLists(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Matching", func(t *testing.T) {
+		l := newIPLists(IPLists{
+			Allow: []string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"},
+			Deny:  []string{"10.1.0.0/16", "::ffff:198.51.100.0/120"},
+		})
+		for ip, want := range map[string]ipListAction{
+			"10.2.3.4":        ipListAllow,
+			"10.1.2.3":        ipListDeny,
+			"192.0.2.1":       ipListAllow,
+			"192.0.2.2":       ipListNone,
+			"198.51.100.7":    ipListDeny,
+			"::ffff:10.2.3.4": ipListAllow,
+			"2001:db8::1":     ipListAllow,
+			"2001:db9::1":     ipListNone,
+			"not an address":  ipListNone,
+		} {
+			assert.Equal(t, want, l.action(ip), ip)
+		}
+	})
+
+	t.Run("Many entries", func(t *testing.T) {
+		var deny []string
+		for i := range 4096 {
+			deny = append(deny, fmt.Sprintf("10.%d.%d.0/24", i/256, i%256))
+		}
+		l := newIPLists(IPLists{Deny: deny})
+		assert.Equal(t, ipListDeny, l.action("10.15.255.1"))
+		assert.Equal(t, ipListNone, l.action("10.16.0.1"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Panics(t, func() { newIPLists(IPLists{Allow: []string{"10.0.0.0/33"}}) })
+		assert.Panics(t, func() { newIPLists(IPLists{Deny: []string{"localhost"}}) })
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:  rate.Every(time.Hour),
+			Burst: 1,
+			IPLists: &IPLists{
+				Allow:      []string{"192.0.2.0/24"},
+				Deny:       []string{"198.51.100.0/24"},
+				DenyStatus: http.StatusUnauthorized,
+			},
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		serve := func(addr string) int {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = addr + ":1234"
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve("192.0.2.10"))
+			assert.Equal(t, http.StatusUnauthorized, serve("198.51.100.10"))
+		}
+		assert.Equal(t, http.StatusOK, serve("203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("203.0.113.1"))
+	})
+}
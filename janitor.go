@@ -0,0 +1,74 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// touch records that e was used. The caller holds s.mu.
+func (s *memoryStore) touch(e *memoryEntry) {
+	if s.ttl > 0 || s.max > 0 {
+		e.used.Store(time.Now().UnixNano())
+	}
+}
+
+// janitor sweeps every shard every interval until the store is closed.
+func (s *shardedStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			for _, shard := range s.shards {
+				shard.sweep(now)
+			}
+		}
+	}
+}
+
+// sweep evicts the limiters unused since before now minus the TTL.
+func (s *memoryStore) sweep(now time.Time) {
+	cutoff := now.Add(-s.ttl).UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, e := range s.limiters {
+		if e.used.Load() < cutoff {
+			delete(s.limiters, key)
+		}
+	}
+}
+
+// Close stops evicting unused limiters. It implements io.Closer, so
+// stores created with a TTL can be closed when they are no longer needed.
+// The store keeps working, but keeps unused limiters.
+func (s *shardedStore) Close() error {
+	if s.done != nil {
+		s.closeOnce.Do(func() { close(s.done) })
+	}
+	return nil
+}
+
+// Close stops evicting unused token buckets from the in-memory store the
+// manager created for IdleTTL. Stores passed in Options are left to the
+// caller. The manager keeps working after Close.
+func (m *Manager) Close() error {
+	if m.owned == nil {
+		return nil
+	}
+	return m.owned.Close()
+}
+
+// evicted reports that n token buckets were dropped from the in-memory
+// store to stay within MaxKeys.
+func (m *Manager) evicted(n int) {
+	m.emit(Event{
+		Type:    EventKeysEvicted,
+		Message: fmt.Sprintf("evicted %d keys to stay within %d", n, m.owned.max),
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestIdleTTL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Sweep", func(t *testing.T) {
+		s := newMemoryStore()
+		s.ttl = time.Minute
+		s.Set("old", rate.NewLimiter(1, 1))
+		s.Set("new", rate.NewLimiter(1, 1))
+
+		// Used limiters are kept.
+		s.sweep(time.Now().Add(30 * time.Second))
+		assert.Len(t, s.limiters, 2)
+		s.limiters["new"].used.Add(int64(time.Minute))
+		s.sweep(time.Now().Add(90 * time.Second))
+		assert.Len(t, s.limiters, 1)
+		_, ok := s.Get("new")
+		assert.True(t, ok)
+	})
+
+	t.Run("Janitor", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1, IdleTTL: 20 * time.Millisecond})
+		defer m.Close()
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		for i := range 10 {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0." + strconv.Itoa(i) + ":1234"
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		assert.Equal(t, 10, m.owned.Size().Entries)
+		assert.Eventually(t, func() bool {
+			return m.owned.Size().Entries == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1, IdleTTL: 10 * time.Millisecond})
+		require.NoError(t, m.Close())
+		require.NoError(t, m.Close())
+		m.owned.Set("k", rate.NewLimiter(1, 1))
+		time.Sleep(30 * time.Millisecond)
+		_, ok := m.owned.Get("k")
+		assert.True(t, ok)
+
+		// Managers with a store of the caller have nothing to close.
+		m = NewManager(Options{Rate: 1, Burst: 1, Store: FromLimiterStore(newMemoryStore())})
+		require.NoError(t, m.Close())
+	})
+
+	t.Run("DSN", func(t *testing.T) {
+		s, err := NewStoreFromDSN("memory://?ttl=10m")
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Minute, s.(*shardedStore).ttl)
+		require.NoError(t, s.(*shardedStore).Close())
+
+		_, err = NewStoreFromDSN("memory://?ttl=soon")
+		require.Error(t, err)
+	})
+}
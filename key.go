@@ -0,0 +1,37 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxKeyLength is the longest key stored verbatim when
+// Options.MaxKeyLength is zero.
+const DefaultMaxKeyLength = 256
+
+// hashedKeyPrefix marks keys that were replaced by their hash.
+const hashedKeyPrefix = "sha256:"
+
+// key computes the rate limiting key for the request.
+func (m *Manager) key(c *gin.Context) string {
+	return normalizeKey(m.opts.KeyFunc(c), m.opts.MaxKeyLength)
+}
+
+// normalizeKey bounds attacker-controlled keys. Keys longer than maxLen
+// bytes, or that are not valid UTF-8, are replaced by a hash of their
+// contents so they stay distinct without growing the store or corrupting
+// logs and exports. A negative maxLen disables the length check.
+func normalizeKey(key string, maxLen int) string {
+	if (maxLen < 0 || len(key) <= maxLen) && utf8.ValidString(key) {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hashedKeyPrefix + hex.EncodeToString(sum[:])
+}
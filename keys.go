@@ -0,0 +1,335 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Page sizes for key listings.
+const (
+	// DefaultKeyPageSize is the page size used when KeyQuery.Limit is zero.
+	DefaultKeyPageSize = 100
+	// MaxKeyPageSize is the largest page Keys returns.
+	MaxKeyPageSize = 1000
+)
+
+// KeySort is the order of a key listing.
+type KeySort string
+
+// Key orders.
+const (
+	// KeySortKey lists keys in lexical order.
+	KeySortKey KeySort = "key"
+	// KeySortDenied lists the most denied keys first.
+	KeySortDenied KeySort = "denied"
+	// KeySortLastSeen lists the most recently seen keys first.
+	KeySortLastSeen KeySort = "lastSeen"
+)
+
+// KeyInfo describes the traffic seen for a key, with its note if it has
+// one.
+type KeyInfo struct {
+	Key      string    `json:"key"`
+	Requests uint64    `json:"requests"`
+	Denied   uint64    `json:"denied"`
+	LastSeen time.Time `json:"lastSeen"`
+	Note     *KeyNote  `json:"note,omitempty"`
+}
+
+// KeyQuery selects a page of keys.
+type KeyQuery struct {
+	// Prefix restricts the listing to keys starting with it.
+	Prefix string
+	// Sort is the order of the listing. If empty, KeySortKey is used.
+	Sort KeySort
+	// Limit is the page size. If zero, DefaultKeyPageSize is used; larger
+	// values are capped at MaxKeyPageSize.
+	Limit int
+	// Cursor continues a listing from KeyPage.Next.
+	Cursor string
+}
+
+// KeyPage is a page of a key listing.
+type KeyPage struct {
+	Keys []KeyInfo `json:"keys"`
+	// Next is the cursor of the following page, empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// keyCursor is the decoded form of a listing cursor: the position of the
+// last key of the previous page.
+type keyCursor struct {
+	Sort     KeySort `json:"s"`
+	Key      string  `json:"k"`
+	Denied   uint64  `json:"d,omitempty"`
+	LastSeen int64   `json:"t,omitempty"`
+}
+
+// maxKeyStats is the most keys a manager keeps statistics for when
+// Options.MaxKeys is zero.
+const maxKeyStats = 100000
+
+// keyStats counts the requests of every key and class seen by a manager.
+// The per-key counts are partitioned between shards like the in-memory
+// store, and the totals are atomic, so requests of different keys rarely
+// contend for a lock.
+type keyStats struct {
+	total classCounter
+	// classes maps classes to their *classCounter.
+	classes sync.Map
+	// bypassed maps the reasons of requests that bypassed limiting to
+	// their *atomic.Uint64 count.
+	bypassed sync.Map
+	shards   []*keyStatsShard
+	seed     maphash.Seed
+}
+
+// keyStatsShard holds the statistics of a share of the keys.
+type keyStatsShard struct {
+	mu   sync.Mutex
+	keys map[string]*KeyInfo
+	// max is the most keys kept, and ttl how long an idle key is kept
+	// before others are dropped, or zero for no bound.
+	max int
+	ttl time.Duration
+}
+
+// newKeyStats creates empty statistics for at most maxKeys keys, or any
+// number if maxKeys is zero. Once a shard holds its share of the cap, the
+// keys idle for longer than ttl, then the least recently seen, make room
+// for new ones, as in the in-memory store.
+func newKeyStats(maxKeys int, ttl time.Duration) *keyStats {
+	n := shardCount(0, maxKeys)
+	s := &keyStats{shards: make([]*keyStatsShard, n), seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i] = &keyStatsShard{keys: make(map[string]*KeyInfo), max: shardMax(maxKeys, n), ttl: ttl}
+	}
+	return s
+}
+
+// shard returns the shard holding the statistics of key.
+func (s *keyStats) shard(key string) *keyStatsShard {
+	if len(s.shards) == 1 {
+		return s.shards[0]
+	}
+	return s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+}
+
+// record counts a request of key in class made at t.
+func (s *keyStats) record(key, class string, allowed bool, t time.Time) {
+	s.total.add(allowed)
+	if class != "" {
+		cc, ok := s.classes.Load(class)
+		if !ok {
+			cc, _ = s.classes.LoadOrStore(class, &classCounter{})
+		}
+		cc.(*classCounter).add(allowed)
+	}
+
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	info, ok := shard.keys[key]
+	if !ok {
+		if shard.max > 0 && len(shard.keys) >= shard.max {
+			shard.evict(t)
+		}
+		info = &KeyInfo{Key: key}
+		shard.keys[key] = info
+	}
+	info.Requests++
+	if !allowed {
+		info.Denied++
+	}
+	info.LastSeen = t
+}
+
+// evict makes room for a key, dropping at least a sixteenth of max keys:
+// those idle for longer than the TTL first, then the least recently seen.
+// The caller holds s.mu.
+func (s *keyStatsShard) evict(now time.Time) {
+	n := len(s.keys)
+	infos := make([]*KeyInfo, 0, n)
+	for key, info := range s.keys {
+		if s.ttl > 0 && now.Sub(info.LastSeen) > s.ttl {
+			delete(s.keys, key)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	if rest := max(1, s.max/16) - (n - len(s.keys)); rest > 0 {
+		slices.SortFunc(infos, func(a, b *KeyInfo) int {
+			return a.LastSeen.Compare(b.LastSeen)
+		})
+		for _, info := range infos[:min(rest, len(infos))] {
+			delete(s.keys, info.Key)
+		}
+	}
+}
+
+// bypass counts a request that bypassed limiting for reason.
+func (s *keyStats) bypass(reason BypassReason) {
+	n, ok := s.bypassed.Load(reason)
+	if !ok {
+		n, _ = s.bypassed.LoadOrStore(reason, new(atomic.Uint64))
+	}
+	n.(*atomic.Uint64).Add(1)
+}
+
+// each calls f with every shard, locked.
+func (s *keyStats) each(f func(shard *keyStatsShard)) {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		f(shard)
+		shard.mu.Unlock()
+	}
+}
+
+// matching returns a copy of the statistics of the keys starting with
+// prefix.
+func (s *keyStats) matching(prefix string) []KeyInfo {
+	var infos []KeyInfo
+	s.each(func(shard *keyStatsShard) {
+		for key, info := range shard.keys {
+			if strings.HasPrefix(key, prefix) {
+				infos = append(infos, *info)
+			}
+		}
+	})
+	return infos
+}
+
+// len returns the number of keys with statistics.
+func (s *keyStats) len() int {
+	n := 0
+	s.each(func(shard *keyStatsShard) {
+		n += len(shard.keys)
+	})
+	return n
+}
+
+// keyLess returns the ordering of a listing. Ties are broken by key so
+// every listing has a total order a cursor can resume from.
+func keyLess(order KeySort) (func(a, b KeyInfo) bool, error) {
+	switch order {
+	case "", KeySortKey:
+		return func(a, b KeyInfo) bool {
+			return a.Key < b.Key
+		}, nil
+	case KeySortDenied:
+		return func(a, b KeyInfo) bool {
+			if a.Denied != b.Denied {
+				return a.Denied > b.Denied
+			}
+			return a.Key < b.Key
+		}, nil
+	case KeySortLastSeen:
+		return func(a, b KeyInfo) bool {
+			if !a.LastSeen.Equal(b.LastSeen) {
+				return a.LastSeen.After(b.LastSeen)
+			}
+			return a.Key < b.Key
+		}, nil
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown key sort %q", order)
+	}
+}
+
+// Keys lists the keys seen by the manager with their request counts.
+// Counts are kept by the manager, so only traffic it handled is reported.
+// Keys whose counts change between pages may be listed twice or skipped.
+// With Options.Anonymize, keys are listed under their identifiers, while
+// the prefix still selects raw keys.
+func (m *Manager) Keys(q KeyQuery) (KeyPage, error) {
+	if q.Sort == "" {
+		q.Sort = KeySortKey
+	}
+	less, err := keyLess(q.Sort)
+	if err != nil {
+		return KeyPage{}, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultKeyPageSize
+	}
+	limit = min(limit, MaxKeyPageSize)
+
+	infos := m.stats.matching(q.Prefix)
+	m.controls.annotate(infos)
+	if m.opts.Anonymize != nil {
+		now := time.Now()
+		for i := range infos {
+			infos[i].Key = m.anonymize(infos[i].Key, now)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return less(infos[i], infos[j])
+	})
+
+	start := 0
+	if q.Cursor != "" {
+		last, err := decodeKeyCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return KeyPage{}, err
+		}
+		start = sort.Search(len(infos), func(i int) bool {
+			return less(last, infos[i])
+		})
+	}
+
+	end := min(start+limit, len(infos))
+	page := KeyPage{Keys: infos[start:end]}
+	if page.Keys == nil {
+		page.Keys = []KeyInfo{}
+	}
+	if end < len(infos) {
+		page.Next = encodeKeyCursor(q.Sort, infos[end-1])
+	}
+	return page, nil
+}
+
+// encodeKeyCursor returns an opaque cursor positioned after info.
+func encodeKeyCursor(order KeySort, info KeyInfo) string {
+	c := keyCursor{Sort: order, Key: info.Key}
+	switch order {
+	case KeySortDenied:
+		c.Denied = info.Denied
+	case KeySortLastSeen:
+		c.LastSeen = info.LastSeen.UnixNano()
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeKeyCursor decodes a cursor into the position it stands for.
+func decodeKeyCursor(cursor string, order KeySort) (KeyInfo, error) {
+	var c keyCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return KeyInfo{}, errors.New("ratelimit: invalid key cursor")
+	}
+	if c.Sort != order {
+		return KeyInfo{}, errors.New("ratelimit: key cursor belongs to another sort order")
+	}
+	info := KeyInfo{Key: c.Key, Denied: c.Denied}
+	if c.LastSeen != 0 {
+		info.LastSeen = time.Unix(0, c.LastSeen)
+	}
+	return info, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newManager := func() *Manager {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		start := time.Now()
+		for i, key := range []string{"user:a", "user:b", "user:c", "ip:1"} {
+			// Key i is denied i times and user:c is seen last.
+			for j := 0; j <= i; j++ {
+				m.stats.record(key, "", j == 0, start.Add(time.Duration(i)*time.Second))
+			}
+		}
+		m.stats.record("user:c", "", true, start.Add(time.Minute))
+		return m
+	}
+	keys := func(page KeyPage) []string {
+		var out []string
+		for _, info := range page.Keys {
+			out = append(out, info.Key)
+		}
+		return out
+	}
+
+	t.Run("Sort", func(t *testing.T) {
+		m := newManager()
+
+		page, err := m.Keys(KeyQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ip:1", "user:a", "user:b", "user:c"}, keys(page))
+		assert.Empty(t, page.Next)
+
+		page, err = m.Keys(KeyQuery{Sort: KeySortDenied})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ip:1", "user:c", "user:b", "user:a"}, keys(page))
+		assert.Equal(t, uint64(3), page.Keys[0].Denied)
+		assert.Equal(t, uint64(4), page.Keys[0].Requests)
+
+		page, err = m.Keys(KeyQuery{Sort: KeySortLastSeen})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user:c", "ip:1", "user:b", "user:a"}, keys(page))
+
+		_, err = m.Keys(KeyQuery{Sort: "size"})
+		assert.Error(t, err)
+	})
+
+	t.Run("PrefixAndCursor", func(t *testing.T) {
+		m := newManager()
+
+		for _, order := range []KeySort{KeySortKey, KeySortDenied, KeySortLastSeen} {
+			var all []string
+			q := KeyQuery{Prefix: "user:", Sort: order, Limit: 2}
+			for {
+				page, err := m.Keys(q)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(page.Keys), 2)
+				all = append(all, keys(page)...)
+				if page.Next == "" {
+					break
+				}
+				q.Cursor = page.Next
+			}
+			assert.ElementsMatch(t, []string{"user:a", "user:b", "user:c"}, all, order)
+			assert.Len(t, all, 3, order)
+		}
+
+		page, err := m.Keys(KeyQuery{Limit: 1})
+		require.NoError(t, err)
+		_, err = m.Keys(KeyQuery{Sort: KeySortDenied, Cursor: page.Next})
+		assert.Error(t, err)
+		_, err = m.Keys(KeyQuery{Cursor: "not a cursor"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		r := gin.New()
+		m.RegisterAdmin(r.Group("/admin"))
+		r.GET("/", m.Handler(), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "203.0.113.7:1234"
+			r.ServeHTTP(w, req)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/keys?prefix=203.&sort=denied&limit=10", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var page KeyPage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		if assert.Len(t, page.Keys, 1) {
+			assert.Equal(t, "203.0.113.7", page.Keys[0].Key)
+			assert.Equal(t, uint64(3), page.Keys[0].Requests)
+			assert.Equal(t, uint64(2), page.Keys[0].Denied)
+		}
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/admin/keys?limit=many", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestKeyStatsCap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("MaxKeys", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 2, MaxKeys: 64})
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		serve := func(ip string) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = ip + ":1234"
+			r.ServeHTTP(w, req)
+		}
+
+		// A flood of new keys does not grow the statistics past the cap,
+		// and keeps the active client.
+		for i := range 500 {
+			serve(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+			serve("192.0.2.1")
+		}
+		assert.LessOrEqual(t, m.Stats().Keys, 64)
+		page, err := m.Keys(KeyQuery{Prefix: "192.0.2.1"})
+		require.NoError(t, err)
+		assert.Len(t, page.Keys, 1)
+	})
+
+	t.Run("Idle keys first", func(t *testing.T) {
+		s := newKeyStats(32, time.Minute)
+		start := time.Now()
+		for i := range 32 {
+			// Key 0 stays active, the others go idle.
+			s.record(fmt.Sprint(i), "", true, start)
+		}
+		s.record("0", "", true, start.Add(2*time.Minute))
+		s.record("new", "", true, start.Add(2*time.Minute))
+		assert.Equal(t, 2, s.len())
+	})
+}
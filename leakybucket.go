@@ -0,0 +1,121 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// leakyBucket is the in-memory leaky bucket algorithm.
+type leakyBucket struct {
+	mu sync.Mutex
+	// drained holds, for every key, when the requests already let through
+	// or queued will have left the bucket.
+	drained map[string]time.Time
+	// sweepAt is the number of keys at which empty buckets are next
+	// dropped.
+	sweepAt int
+}
+
+// LeakyBucket returns an Algorithm that lets the requests of every key
+// through at a constant rate, evenly spaced, instead of allowing bursts,
+// in memory. Requests arriving faster are held until their turn, queuing
+// up to burst requests including the one being let through; requests
+// that would not fit are denied. It suits proxying to fragile upstreams
+// that can only handle a steady flow. A zero rate denies every request.
+func LeakyBucket() Algorithm {
+	return &leakyBucket{drained: make(map[string]time.Time), sweepAt: minLogSweep}
+}
+
+// Take implements Algorithm.
+func (b *leakyBucket) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	a, _, err := b.TakeLevels(ctx, []Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements LevelAlgorithm. Requests are held until every
+// level lets them through.
+func (b *leakyBucket) TakeLevels(_ context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweep(now)
+
+	// The requests leave once the queue of every level drained.
+	var delay time.Duration
+	for _, lv := range levels {
+		if lv.Rate != rate.Inf {
+			delay = max(delay, b.drained[lv.Key].Sub(now))
+		}
+	}
+
+	need := max(n, 1)
+	as := make([]Allowance, len(levels))
+	allowed := true
+	for i, lv := range levels {
+		switch {
+		case lv.Rate == rate.Inf:
+			as[i] = Allowance{Allowed: true, Remaining: lv.Burst}
+			continue
+		case lv.Rate <= 0:
+			as[i], allowed = Allowance{RetryAfter: -1}, false
+			continue
+		}
+		interval := time.Duration(float64(time.Second) / float64(lv.Rate))
+		capacity := time.Duration(lv.Burst) * interval
+		queued := max(0, b.drained[lv.Key].Sub(now))
+		as[i] = Allowance{Remaining: max(0, int((capacity-queued)/interval))}
+		if over := delay + time.Duration(need)*interval - capacity; over > 0 {
+			as[i].RetryAfter = -1
+			if need <= lv.Burst {
+				as[i].RetryAfter = over
+			}
+			allowed = false
+			continue
+		}
+		as[i].Allowed = true
+	}
+	if allowed {
+		for i, lv := range levels {
+			if lv.Rate == rate.Inf {
+				continue
+			}
+			interval := time.Duration(float64(time.Second) / float64(lv.Rate))
+			if n > 0 {
+				b.drained[lv.Key] = now.Add(delay + time.Duration(n)*interval)
+			}
+			as[i].Remaining = max(0, as[i].Remaining-n)
+			as[i].Delay = delay
+		}
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// sweep drops the keys whose bucket is empty, once there are sweepAt keys.
+func (b *leakyBucket) sweep(now time.Time) {
+	if len(b.drained) < b.sweepAt {
+		return
+	}
+	for key, drained := range b.drained {
+		if !drained.After(now) {
+			delete(b.drained, key)
+		}
+	}
+	b.sweepAt = max(minLogSweep, 2*len(b.drained))
+}
+
+// Reset implements Algorithm.
+func (b *leakyBucket) Reset(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.drained, key)
+	return nil
+}
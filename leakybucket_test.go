@@ -0,0 +1,138 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestLeakyBucket(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	// One request a second, up to 3 at once including the one let through.
+	r := rate.Every(time.Second)
+
+	t.Run("Pacing", func(t *testing.T) {
+		alg := LeakyBucket()
+		for i := range 3 {
+			a, err := alg.Take(ctx, "k", r, 3, 1, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, time.Duration(i)*time.Second, a.Delay, "requests are evenly spaced")
+			assert.Equal(t, 2-i, a.Remaining)
+		}
+
+		a, err := alg.Take(ctx, "k", r, 3, 1, start.Add(500*time.Millisecond))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 500*time.Millisecond, a.RetryAfter)
+
+		a, err = alg.Take(ctx, "k", r, 3, 1, start.Add(time.Second))
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 2*time.Second, a.Delay)
+
+		// Once the queue drained, requests go through right away.
+		a, err = alg.Take(ctx, "k", r, 3, 1, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Zero(t, a.Delay)
+	})
+
+	t.Run("Cost and Peek", func(t *testing.T) {
+		alg := LeakyBucket()
+		a, err := alg.Take(ctx, "k", r, 3, 2, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Zero(t, a.Delay)
+
+		a, err = alg.Take(ctx, "k", r, 3, 0, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 2*time.Second, a.Delay)
+
+		a, err = alg.Take(ctx, "k", r, 3, 2, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, time.Second, a.RetryAfter)
+
+		a, err = alg.Take(ctx, "k", r, 3, 4, start)
+		require.NoError(t, err)
+		assert.Negative(t, a.RetryAfter)
+
+		a, err = alg.Take(ctx, "k", 0, 3, 1, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+
+		require.NoError(t, alg.Reset(ctx, "k"))
+		a, err = alg.Take(ctx, "k", r, 3, 0, start)
+		require.NoError(t, err)
+		assert.Zero(t, a.Delay)
+		assert.Equal(t, 3, a.Remaining)
+	})
+
+	t.Run("Levels", func(t *testing.T) {
+		alg := LeakyBucket().(LevelAlgorithm)
+		levels := []Level{
+			{Key: "org:acme", Rate: rate.Every(time.Second), Burst: 5},
+			{Key: "alice", Rate: rate.Every(2 * time.Second), Burst: 2},
+		}
+		a, _, err := alg.TakeLevels(ctx, levels, 1, start)
+		require.NoError(t, err)
+		assert.Zero(t, a.Delay)
+		a, i, err := alg.TakeLevels(ctx, levels, 1, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 1, i)
+		assert.Equal(t, 2*time.Second, a.Delay, "the slower level sets the pace")
+	})
+}
+
+func TestLeakyBucketMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(New(Options{
+		Rate:      rate.Every(50 * time.Millisecond),
+		Burst:     3,
+		Algorithm: LeakyBucket(),
+	}))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	begin := time.Now()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.ServeHTTP(w, req)
+			mu.Lock()
+			codes[w.Code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[int]int{http.StatusOK: 3, http.StatusTooManyRequests: 1}, codes)
+	assert.GreaterOrEqual(t, time.Since(begin), 100*time.Millisecond, "queued requests are held")
+}
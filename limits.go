@@ -0,0 +1,291 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Per returns the rate that allows requests requests every window, so
+// Per(100, time.Minute) allows 100 requests per minute. A window that is
+// not positive allows any number of requests.
+func Per(requests int, window time.Duration) rate.Limit {
+	if window <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(float64(requests) / window.Seconds())
+}
+
+// LimitSpec expresses a limit in the units people think in: Requests
+// requests per Window, with bursts of up to Burst requests. The zero value
+// is unlimited.
+//
+// In JSON and YAML, a spec is either an object such as
+// {"requests": 100, "window": "1m", "burst": 20}, with the window given as
+// a unit name or Go duration, or a string accepted by ParseLimitSpec such
+// as "100/minute burst 20".
+type LimitSpec struct {
+	Requests int
+	// Window is the period Requests are allowed in. If zero, the spec is
+	// unlimited.
+	Window time.Duration
+	// Burst is the most requests allowed at once. If zero, a whole
+	// window's Requests may be sent at once.
+	Burst int
+}
+
+// Rate returns the spec's average rate.
+func (l LimitSpec) Rate() rate.Limit {
+	return Per(l.Requests, l.Window)
+}
+
+// BurstSize returns the spec's burst, with the default applied.
+func (l LimitSpec) BurstSize() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Validate reports whether the spec can be enforced.
+func (l LimitSpec) Validate() error {
+	switch {
+	case l.Requests < 0:
+		return fmt.Errorf("ratelimit: limit has negative requests %d", l.Requests)
+	case l.Window < 0:
+		return fmt.Errorf("ratelimit: limit has negative window %s", l.Window)
+	case l.Burst < 0:
+		return fmt.Errorf("ratelimit: limit has negative burst %d", l.Burst)
+	case l.Window > 0 && l.BurstSize() == 0:
+		return errors.New("ratelimit: limit allows no requests")
+	}
+	return nil
+}
+
+// String formats the spec in the form parsed by ParseLimitSpec.
+func (l LimitSpec) String() string {
+	if l.Window <= 0 {
+		return "unlimited"
+	}
+	window, ok := unitNames[l.Window]
+	if !ok {
+		window = formatWindow(l.Window)
+	}
+	if l.Burst <= 0 || l.Burst == l.Requests {
+		return fmt.Sprintf("%d/%s", l.Requests, window)
+	}
+	return fmt.Sprintf("%d/%s burst %d", l.Requests, window, l.Burst)
+}
+
+// limitSpecObject is the object form of a LimitSpec in JSON and YAML.
+type limitSpecObject struct {
+	Requests int    `json:"requests" yaml:"requests"`
+	Window   string `json:"window,omitempty" yaml:"window,omitempty"`
+	Burst    int    `json:"burst,omitempty" yaml:"burst,omitempty"`
+}
+
+func (l LimitSpec) object() limitSpecObject {
+	o := limitSpecObject{Requests: l.Requests, Burst: l.Burst}
+	if l.Window > 0 {
+		o.Window = formatWindow(l.Window)
+	}
+	return o
+}
+
+func (o limitSpecObject) spec() (LimitSpec, error) {
+	l := LimitSpec{Requests: o.Requests, Burst: o.Burst}
+	if o.Window != "" {
+		w, err := parseWindow(o.Window)
+		if err != nil {
+			return LimitSpec{}, fmt.Errorf("ratelimit: invalid limit window %q: %w", o.Window, err)
+		}
+		l.Window = w
+	}
+	return l, l.Validate()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l LimitSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.object())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *LimitSpec) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		spec, err := ParseLimitSpec(s)
+		if err != nil {
+			return err
+		}
+		*l = spec
+		return nil
+	}
+	var o limitSpecObject
+	if err := json.Unmarshal(data, &o); err != nil {
+		return err
+	}
+	spec, err := o.spec()
+	if err != nil {
+		return err
+	}
+	*l = spec
+	return nil
+}
+
+// MarshalYAML implements the Marshaler interface of the common YAML
+// packages, such as gopkg.in/yaml.v3.
+func (l LimitSpec) MarshalYAML() (any, error) {
+	return l.object(), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the common YAML
+// packages, such as gopkg.in/yaml.v3.
+func (l *LimitSpec) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if unmarshal(&s) == nil {
+		spec, err := ParseLimitSpec(s)
+		if err != nil {
+			return err
+		}
+		*l = spec
+		return nil
+	}
+	var o limitSpecObject
+	if err := unmarshal(&o); err != nil {
+		return err
+	}
+	spec, err := o.spec()
+	if err != nil {
+		return err
+	}
+	*l = spec
+	return nil
+}
+
+// limitUnits are the window names accepted by ParseLimitSpec.
+var limitUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+// unitNames are the names used for whole windows when formatting limits.
+var unitNames = map[time.Duration]string{
+	time.Second:    "second",
+	time.Minute:    "minute",
+	time.Hour:      "hour",
+	24 * time.Hour: "day",
+}
+
+// ParseLimitSpec parses a human-readable limit of the form
+// "<requests>/<window> [burst <burst>]", as in "100/minute", "5/30s" or
+// NGINX's "10r/s burst 20". "per" may stand for the slash, and windows are
+// a unit (s, m, h, d or their names) or a Go duration. "inf" and
+// "unlimited" parse as the unlimited spec.
+func ParseLimitSpec(s string) (LimitSpec, error) {
+	spec := strings.ToLower(strings.TrimSpace(s))
+	if spec == "inf" || spec == "unlimited" {
+		return LimitSpec{}, nil
+	}
+	invalid := func(reason string) (LimitSpec, error) {
+		return LimitSpec{}, fmt.Errorf("ratelimit: invalid limit %q: %s", s, reason)
+	}
+
+	spec, burstSpec, hasBurst := strings.Cut(spec, "burst")
+	spec = strings.TrimRight(strings.TrimSpace(spec), ",;")
+	count, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		count, window, ok = strings.Cut(spec, " per ")
+	}
+	if !ok {
+		return invalid("want <requests>/<window>")
+	}
+
+	var (
+		l   LimitSpec
+		err error
+	)
+	l.Requests, err = strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(count), "r"))
+	if err != nil || l.Requests < 0 {
+		return invalid("bad request count")
+	}
+	if l.Window, err = parseWindow(window); err != nil {
+		return invalid(err.Error())
+	}
+	if hasBurst {
+		burstSpec = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(burstSpec), "=:"))
+		if l.Burst, err = strconv.Atoi(burstSpec); err != nil || l.Burst <= 0 {
+			return invalid("burst must be a positive number")
+		}
+	}
+	if err := l.Validate(); err != nil {
+		return invalid("no requests allowed")
+	}
+	return l, nil
+}
+
+// ParseLimit parses a limit as ParseLimitSpec does and returns its rate
+// and burst. Without a burst, a whole window's requests may be sent at
+// once.
+func ParseLimit(s string) (rate.Limit, int, error) {
+	l, err := ParseLimitSpec(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return l.Rate(), l.BurstSize(), nil
+}
+
+// parseWindow parses a window given as a unit name or a Go duration.
+func parseWindow(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	w, ok := limitUnits[s]
+	if !ok {
+		var err error
+		if w, err = time.ParseDuration(s); err != nil {
+			return 0, errors.New("bad window")
+		}
+	}
+	if w <= 0 {
+		return 0, errors.New("window must be positive")
+	}
+	return w, nil
+}
+
+// formatWindow formats a window as a Go duration without zero trailing
+// units, such as "1m" rather than "1m0s".
+func formatWindow(w time.Duration) string {
+	s := w.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// FormatLimit formats a rate and burst in the form parsed by ParseLimit,
+// using the shortest window holding a whole number of requests, for
+// example "100/minute burst 20". The burst is omitted when it equals the
+// window's requests.
+func FormatLimit(r rate.Limit, burst int) string {
+	if r == rate.Inf {
+		return "inf"
+	}
+	if r <= 0 {
+		return fmt.Sprintf("0/second burst %d", burst)
+	}
+	p := newPacingPolicy(r, burst)
+	l := LimitSpec{Requests: p.Limit, Window: p.Window, Burst: burst}
+	return l.String()
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		for _, tt := range []struct {
+			spec  string
+			rate  rate.Limit
+			burst int
+		}{
+			{"100/minute", Per(100, time.Minute), 100},
+			{"10r/s burst 20", 10, 20},
+			{"10r/s, burst=20", 10, 20},
+			{"5/30s", Per(5, 30*time.Second), 5},
+			{"1000 per hour", Per(1000, time.Hour), 1000},
+			{" 2/Day ", Per(2, 24*time.Hour), 2},
+			{"1/1m30s burst 3", Per(1, 90*time.Second), 3},
+			{"0/s burst 5", 0, 5},
+			{"unlimited", rate.Inf, 0},
+			{"Inf", rate.Inf, 0},
+		} {
+			r, burst, err := ParseLimit(tt.spec)
+			if assert.NoError(t, err, tt.spec) {
+				assert.InDelta(t, float64(tt.rate), float64(r), 1e-12, tt.spec)
+				assert.Equal(t, tt.burst, burst, tt.spec)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, spec := range []string{
+			"",
+			"100",
+			"x/minute",
+			"-1/minute",
+			"10/fortnight",
+			"10/0s",
+			"10/-1m",
+			"10/s burst",
+			"10/s burst -1",
+			"10/s burst 0",
+			"0/s",
+		} {
+			_, _, err := ParseLimit(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		assert.Equal(t, "100/minute", FormatLimit(Per(100, time.Minute), 100))
+		assert.Equal(t, "10/second burst 20", FormatLimit(10, 20))
+		assert.Equal(t, "40/minute burst 1", FormatLimit(Per(1, 1500*time.Millisecond), 1))
+		assert.Equal(t, "1/7s", FormatLimit(Per(1, 7*time.Second), 1))
+		assert.Equal(t, "inf", FormatLimit(rate.Inf, 0))
+
+		for _, spec := range []string{"100/minute burst 20", "7/hour", "3/day burst 1"} {
+			r, burst, err := ParseLimit(spec)
+			assert.NoError(t, err)
+			assert.Equal(t, spec, FormatLimit(r, burst))
+		}
+	})
+}
+
+func TestLimitSpec(t *testing.T) {
+	t.Run("Limits", func(t *testing.T) {
+		l := LimitSpec{Requests: 100, Window: time.Minute, Burst: 20}
+		assert.InDelta(t, 100.0/60, float64(l.Rate()), 1e-12)
+		assert.Equal(t, 20, l.BurstSize())
+		assert.Equal(t, "100/minute burst 20", l.String())
+
+		l.Burst = 0
+		assert.Equal(t, 100, l.BurstSize())
+		assert.Equal(t, rate.Inf, LimitSpec{}.Rate())
+		assert.Equal(t, "unlimited", LimitSpec{}.String())
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, LimitSpec{}.Validate())
+		assert.NoError(t, LimitSpec{Requests: 5, Window: time.Second}.Validate())
+		assert.Error(t, LimitSpec{Requests: -1, Window: time.Second}.Validate())
+		assert.Error(t, LimitSpec{Requests: 1, Window: -time.Second}.Validate())
+		assert.Error(t, LimitSpec{Requests: 1, Window: time.Second, Burst: -1}.Validate())
+		assert.Error(t, LimitSpec{Window: time.Second}.Validate())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(LimitSpec{Requests: 100, Window: 90 * time.Second, Burst: 20})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"requests":100,"window":"1m30s","burst":20}`, string(data))
+
+		var specs []LimitSpec
+		require.NoError(t, json.Unmarshal([]byte(
+			`[{"requests":100,"window":"1m30s","burst":20},{"requests":5,"window":"hour"},"10r/s burst 20",{}]`,
+		), &specs))
+		assert.Equal(t, []LimitSpec{
+			{Requests: 100, Window: 90 * time.Second, Burst: 20},
+			{Requests: 5, Window: time.Hour},
+			{Requests: 10, Window: time.Second, Burst: 20},
+			{},
+		}, specs)
+
+		var l LimitSpec
+		assert.Error(t, json.Unmarshal([]byte(`{"requests":5,"window":"soon"}`), &l))
+		assert.Error(t, json.Unmarshal([]byte(`{"requests":-5,"window":"1m"}`), &l))
+		assert.Error(t, json.Unmarshal([]byte(`"often"`), &l))
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		var cfg struct {
+			Default LimitSpec `yaml:"default"`
+			Login   LimitSpec `yaml:"login"`
+		}
+		doc := "default:\n  requests: 100\n  window: 1m\n  burst: 20\nlogin: 5/minute\n"
+		require.NoError(t, yaml.Unmarshal([]byte(doc), &cfg))
+		assert.Equal(t, LimitSpec{Requests: 100, Window: time.Minute, Burst: 20}, cfg.Default)
+		assert.Equal(t, LimitSpec{Requests: 5, Window: time.Minute}, cfg.Login)
+
+		data, err := yaml.Marshal(cfg.Default)
+		require.NoError(t, err)
+		assert.Equal(t, "requests: 100\nwindow: 1m\nburst: 20\n", string(data))
+
+		assert.Error(t, yaml.Unmarshal([]byte("login: 5/fortnight\n"), &cfg))
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		m := NewManager(Options{Limit: &LimitSpec{Requests: 120, Window: time.Minute, Burst: 10}})
+		r, burst := m.limitsFor("k")
+		assert.Equal(t, rate.Limit(2), r)
+		assert.Equal(t, 10, burst)
+	})
+}
@@ -0,0 +1,399 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"cmp"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// Manager holds a resolved rate limiting configuration and the state shared
+// by the middleware it produces. Use it instead of New when the application
+// needs to inspect or operate on the limiter at runtime.
+type Manager struct {
+	opts Options
+	// limiters holds the token buckets of the manager: the Store if it is
+	// a LimiterStore, and local buckets otherwise.
+	limiters     LimiterStore
+	config       effectiveConfig
+	backpressure *backpressureMeter
+	regions      *regionBudget
+	duplicates   *duplicateLimiter
+	guardrails   []*guardrail
+	controls     *keyControls
+	stats        *keyStats
+	decisions    *decisionHub
+	learned      *learnedLimits
+	clock        *clockWatch
+	coldStart    *coldStart
+	groups       *groupLimiter
+	anomalies    *anomalyDetector
+	signatures   *signatureVerifier
+	ipLists      *ipLists
+	routes       routeAnnotations
+	usage        *usageSync
+	queue        *waitQueue
+	denials      *denyCache
+	limits       []Level
+	tuner        *tuner
+	notices      *quotaNotices
+	global       *Level
+	// owned is the in-memory store created by the manager, which Close
+	// closes.
+	owned *shardedStore
+}
+
+// NewManager creates a manager with the given options, applying defaults
+// for any option that is not set.
+func NewManager(opts Options) *Manager {
+	m := &Manager{
+		controls:  newKeyControls(),
+		stats:     newKeyStats(cmp.Or(opts.MaxKeys, maxKeyStats), opts.IdleTTL),
+		decisions: newDecisionHub(),
+		learned:   newLearnedLimits(),
+		clock:     newClockWatch(time.Now()),
+	}
+	m.routes.limitName = handlerName(m.RouteLimit(LimitSpec{}))
+
+	if len(opts.Limits) > 0 {
+		m.limits = extraLimits(opts.Limits)
+		opts.Limit = &opts.Limits[0]
+	}
+	if opts.Limit != nil {
+		opts.Rate, opts.Burst = opts.Limit.Rate(), opts.Limit.BurstSize()
+	}
+
+	// Record which options were customized before the defaults hide it.
+	m.config = newEffectiveConfig(opts)
+
+	// Set default options if not provided.
+	switch {
+	case opts.KeyFunc != nil:
+	case opts.Identity != nil || opts.Signatures != nil:
+		opts.KeyFunc = identityKeyFunc
+	default:
+		opts.KeyFunc = func(c *gin.Context) string {
+			return c.ClientIP()
+		}
+	}
+	if opts.MaxKeyLength == 0 {
+		opts.MaxKeyLength = DefaultMaxKeyLength
+	}
+	// Serverless processes do not live long enough to evict idle keys.
+	idleTTL := opts.IdleTTL
+	if opts.Serverless {
+		idleTTL = 0
+		opts.Consistency = ConsistencyStrict
+	}
+	if opts.Store == nil {
+		m.owned = newShardedStore(WithMaxKeys(opts.MaxKeys), WithIdleTTL(idleTTL))
+		opts.Store = m.owned
+	}
+	if limiters, ok := opts.Store.(LimiterStore); ok {
+		// Buckets kept in process memory do not survive serverless
+		// invocations.
+		if opts.Serverless && opts.Algorithm == nil {
+			panic("ratelimit: Serverless without an Algorithm or a Store deciding requests")
+		}
+		m.limiters = limiters
+	} else {
+		// Other stores decide requests themselves; the local buckets only
+		// serve parts such as outbound transports.
+		local := newShardedStore(
+			WithMaxKeys(cmp.Or(opts.MaxKeys, maxLocalLimiters)),
+			WithIdleTTL(idleTTL),
+		)
+		m.owned = local
+		m.limiters = local
+		if opts.Algorithm == nil {
+			opts.Algorithm = storeAlgorithm(opts.Store)
+		}
+	}
+	if opts.Faults != nil && opts.Algorithm != nil {
+		opts.Algorithm = InjectFaults(opts.Algorithm, *opts.Faults)
+	}
+	if opts.OnLimitExceeded == nil {
+		opts.OnLimitExceeded = func(c *gin.Context, l *rate.Limiter) {
+			c.String(http.StatusTooManyRequests, "Too Many Requests")
+		}
+	}
+	if opts.Backpressure != nil {
+		m.backpressure = newBackpressureMeter(*opts.Backpressure)
+	}
+	if opts.Regions != nil {
+		m.regions = newRegionBudget(*opts.Regions)
+	}
+	if opts.Duplicates != nil {
+		m.duplicates = newDuplicateLimiter(*opts.Duplicates)
+	}
+	now := time.Now()
+	if opts.ColdStart != nil {
+		m.coldStart = newColdStart(*opts.ColdStart, now)
+	}
+	if opts.IPLists != nil {
+		m.ipLists = newIPLists(*opts.IPLists)
+	}
+	if opts.Signatures != nil {
+		m.signatures = newSignatureVerifier(*opts.Signatures)
+	}
+	if opts.Anomalies != nil {
+		m.anomalies = newAnomalyDetector(*opts.Anomalies)
+	}
+	if opts.Anonymize != nil && len(opts.Anonymize.Secret) == 0 {
+		panic("ratelimit: Anonymize without a secret")
+	}
+	if opts.Global != nil {
+		m.global = newGlobalLevel(*opts.Global)
+	}
+	if opts.Tuning != nil {
+		m.tuner = newTuner(*opts.Tuning, now)
+	}
+	if opts.Groups != nil {
+		m.groups = newGroupLimiter(*opts.Groups)
+	}
+	if opts.QuotaNotifications != nil {
+		m.notices = newQuotaNotices(*opts.QuotaNotifications)
+	}
+	for _, g := range opts.Guardrails {
+		m.guardrails = append(m.guardrails, newGuardrail(g, now))
+	}
+
+	m.usage = newUsageSync(opts.SyncInterval)
+	m.queue = newWaitQueue(opts.MaxQueue)
+	m.denials = newDenyCache(opts.DenyCacheTTL)
+	if m.owned != nil {
+		m.owned.setOnEvict(m.evicted)
+	}
+	m.opts = opts
+	m.config.resolve(m)
+	return m
+}
+
+// Handler returns the rate limiting middleware. Routes marked with Exempt
+// or RouteLimit are left to their annotations.
+func (m *Manager) Handler() gin.HandlerFunc {
+	if m.opts.FastPath {
+		return fastPathHandler(m.opts.Rate, m.opts.Burst)
+	}
+	return func(c *gin.Context) {
+		if c.Request == nil {
+			// Contexts from gin.CreateTestContext have no request until the
+			// test sets one, so there is nothing to limit.
+			c.Next()
+			return
+		}
+		if an := m.routes.annotation(c); an != routeDefault {
+			if an == routeExempt {
+				m.bypass(c, BypassExempt)
+			}
+			c.Next()
+			return
+		}
+		m.serve(c, m.routes.endpoint(c))
+	}
+}
+
+// serve limits a request, using the route's own buckets if route is set.
+func (m *Manager) serve(c *gin.Context, route *routeLimit) {
+	opts := m.opts
+	d := m.decide(c, route, false)
+	switch {
+	case d.bypass != "":
+		m.bypass(c, d.bypass)
+		c.Next()
+		return
+	case d.denylisted:
+		c.String(m.ipLists.cfg.DenyStatus, http.StatusText(m.ipLists.cfg.DenyStatus))
+		c.Abort()
+		return
+	}
+
+	// Advertise global utilization on every response, allowed or not.
+	if m.backpressure != nil {
+		m.backpressure.annotate(c)
+	}
+
+	cl, key := d.cl, d.key
+	switch {
+	case d.banned:
+		// Reject banned clients outright.
+		m.record(c, cl, key, false, true)
+		c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		c.Abort()
+		return
+	case d.duplicate:
+		m.record(c, cl, key, false, false)
+		c.Set(limitKeyContextKey, key)
+		m.setRetryAfter(c, d.limiter)
+		opts.OnLimitExceeded(c, d.limiter)
+		c.Abort()
+		return
+	}
+
+	limiter, allowed := d.limiter, d.allowed
+	m.record(c, cl, key, allowed, false)
+	setQuota(c, key, limiter, allowed)
+	m.notifyQuota(c)
+	m.setHeaders(c, limiter)
+	if !allowed && !m.shadowDeny(cl, key) {
+		// If the rate limit is exceeded, call the OnLimitExceeded handler.
+		c.Set(limitKeyContextKey, key)
+		m.setRetryAfter(c, limiter)
+		opts.OnLimitExceeded(c, limiter)
+		c.Abort()
+		return
+	}
+
+	// If the rate limit is not exceeded, continue to the next handler.
+	if len(m.guardrails) == 0 {
+		c.Next()
+		return
+	}
+	start := time.Now()
+	c.Next()
+	m.observe(cl.Class, c.Writer.Status(), time.Since(start))
+}
+
+// record counts a decision in the key statistics and publishes it to the
+// decision watchers.
+func (m *Manager) record(c *gin.Context, cl Classification, key string, allowed, banned bool) {
+	now := time.Now()
+	m.stats.record(key, cl.Class, allowed, now)
+	if m.tuner != nil && !banned {
+		m.tuner.observe(c.FullPath(), cl.Class, key, now)
+	}
+	if m.anomalies != nil {
+		if e, ok := m.anomalies.observe(key, now); ok {
+			m.emit(e)
+		}
+	}
+	if !m.decisions.watched() {
+		return
+	}
+	m.decisions.publish(Decision{
+		Time:    now,
+		Key:     m.anonymize(key, now),
+		Class:   cl.Class,
+		Route:   c.FullPath(),
+		Allowed: allowed,
+		Banned:  banned,
+		DryRun:  !allowed && !banned && m.opts.DryRun,
+	})
+}
+
+// limitsFor returns the rate and burst this process enforces for key.
+func (m *Manager) limitsFor(key string) (rate.Limit, int) {
+	r, burst := m.configuredLimits(key)
+	return m.learned.capLimits(key, r, burst, time.Now())
+}
+
+// configuredLimits returns the limits of key before any learned limit.
+func (m *Manager) configuredLimits(key string) (rate.Limit, int) {
+	r, burst, _ := m.resolveLimits(key, time.Now())
+	return r, burst
+}
+
+// resolveLimits returns the limits of key at now before any learned
+// limit, and what set them.
+func (m *Manager) resolveLimits(key string, now time.Time) (rate.Limit, int, LimitSource) {
+	r, burst, src := m.opts.Rate, m.opts.Burst, LimitFromDefault
+	if m.signatures != nil && signed(key) {
+		r, burst, src = m.signatures.cfg.Rate, m.signatures.cfg.Burst, LimitFromSignature
+	}
+	if m.opts.Hierarchy != nil {
+		if hr, hburst := m.opts.Hierarchy.Resolve(key, r, burst); hr != r || hburst != burst {
+			r, burst, src = hr, hburst, LimitFromHierarchy
+		}
+	}
+	if o, ok := m.controls.override(key, now); ok {
+		r, burst, src = o.Rate, o.Burst, LimitFromOverride
+	}
+	r, burst = m.scale(r, burst)
+	return r, burst, src
+}
+
+// scale scales a limit to the share of the local region, if any.
+func (m *Manager) scale(r rate.Limit, burst int) (rate.Limit, int) {
+	if m.regions != nil {
+		return m.regions.scale(r, burst)
+	}
+	return r, burst
+}
+
+// limiter returns the rate limiter for key, creating it if needed.
+func (m *Manager) limiter(key string) *rate.Limiter {
+	r, burst := m.limitsFor(key)
+	return m.storedLimiter(key, r, burst)
+}
+
+// bucket returns the key and limits of the bucket of key for a request,
+// which is the route's own bucket if route is set.
+func (m *Manager) bucket(c *gin.Context, route *routeLimit, key string) (string, rate.Limit, int) {
+	if route == nil {
+		r, burst := m.limitsFor(key)
+		return key, r, burst
+	}
+	r, burst := route.rate, route.burst
+	if m.regions != nil {
+		r, burst = m.regions.scale(r, burst)
+	}
+	return route.storeKey(c, key), r, burst
+}
+
+// group returns the group of key, or "" if the key is limited on its own.
+// Route limits keep their own buckets.
+func (m *Manager) group(key string, route *routeLimit) string {
+	if m.groups == nil || m.groups.cfg.Group == nil || route != nil {
+		return ""
+	}
+	return m.groups.cfg.Group(key)
+}
+
+// storedLimiter returns the limiter stored under key, creating it with or
+// resizing it to the given limits as needed.
+func (m *Manager) storedLimiter(key string, r rate.Limit, burst int) *rate.Limiter {
+	// If the rate limiter does not exist, create a new one and add it to
+	// the store.
+	limiter := getOrCreate(m.limiters, key, func() *rate.Limiter {
+		if m.coldStart != nil {
+			return m.coldStart.newLimiter(key, r, burst, time.Now())
+		}
+		return rate.NewLimiter(r, burst)
+	})
+
+	if m.regions != nil {
+		m.regions.requests.Add(1)
+	}
+	// Apply overrides and regional shares changed since the limiter was
+	// created.
+	if limiter.Limit() != r || limiter.Burst() != burst {
+		limiter = resizeLimiter(limiter, r, burst, time.Now())
+		m.limiters.Set(key, limiter)
+	}
+	return limiter
+}
+
+// resizeLimiter returns a limiter with the given limits that carries over
+// the tokens left in old. Capacity added by a larger burst is available
+// immediately; tokens above a smaller burst are dropped.
+func resizeLimiter(old *rate.Limiter, r rate.Limit, burst int, now time.Time) *rate.Limiter {
+	tokens := old.TokensAt(now)
+	if grown := burst - old.Burst(); grown > 0 {
+		tokens += float64(grown)
+	}
+	tokens = math.Max(0, math.Min(tokens, float64(burst)))
+
+	limiter := rate.NewLimiter(r, burst)
+	// New limiters start full; take away what old had already spent.
+	if spent := burst - int(tokens); spent > 0 {
+		limiter.AllowN(now, spent)
+	}
+	return limiter
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"reflect"
+	"runtime"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SizedStore is implemented by stores that can report how much memory
+// they hold, for Stats.Memory.
+type SizedStore interface {
+	Store
+	// Size returns the number of entries held and an estimate of the bytes
+	// they occupy in this process.
+	Size() ComponentMemory
+}
+
+// ComponentMemory is the memory held by one part of the manager.
+type ComponentMemory struct {
+	// Entries is the number of entries held, such as keys.
+	Entries int `json:"entries"`
+	// Bytes is an estimate of the bytes the entries occupy, including map
+	// overhead but not memory shared with the rest of the application.
+	Bytes int64 `json:"bytes"`
+}
+
+// RuntimeMemory reports the memory and garbage collector statistics of
+// the process, from runtime.MemStats.
+type RuntimeMemory struct {
+	HeapAlloc     uint64  `json:"heapAlloc"`
+	HeapObjects   uint64  `json:"heapObjects"`
+	NumGC         uint32  `json:"numGC"`
+	GCPauseTotal  string  `json:"gcPauseTotal"`
+	GCCPUFraction float64 `json:"gcCPUFraction"`
+}
+
+// MemoryStats reports the memory held by the manager, so the cost of
+// tracking many keys can be measured in production.
+type MemoryStats struct {
+	// Components maps parts of the manager, such as "store" and "keys",
+	// to their memory. The store is only included if it implements
+	// SizedStore.
+	Components map[string]ComponentMemory `json:"components"`
+	// Runtime reports the process-wide heap and garbage collector
+	// statistics the components contribute to.
+	Runtime RuntimeMemory `json:"runtime"`
+}
+
+// mapEntryOverhead estimates the per-entry overhead of a Go map, which
+// keeps spare slots, a hash and a tophash byte per slot.
+const mapEntryOverhead = 16
+
+var (
+	limiterSize = int64(reflect.TypeOf(rate.Limiter{}).Size())
+	entrySize   = int64(reflect.TypeOf(memoryEntry{}).Size())
+	keyInfoSize = int64(reflect.TypeOf(KeyInfo{}).Size())
+	timeSize    = int64(reflect.TypeOf(time.Time{}).Size())
+	stringSize  = int64(reflect.TypeOf("").Size())
+)
+
+// Size implements SizedStore.
+func (s *memoryStore) Size() ComponentMemory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cm := ComponentMemory{Entries: len(s.limiters)}
+	for key := range s.limiters {
+		// The map holds a string header and a pointer to the entry, which
+		// points to the limiter.
+		cm.Bytes += stringSize + int64(len(key)) + 8 + entrySize + limiterSize + mapEntryOverhead
+	}
+	return cm
+}
+
+// size estimates the memory of the per-key statistics.
+func (s *keyStats) size() ComponentMemory {
+	var cm ComponentMemory
+	s.each(func(shard *keyStatsShard) {
+		cm.Entries += len(shard.keys)
+		for key := range shard.keys {
+			// The key is shared between the map and the KeyInfo.
+			cm.Bytes += stringSize + int64(len(key)) + 8 + keyInfoSize + mapEntryOverhead
+		}
+	})
+	return cm
+}
+
+// size estimates the memory of the bans, overrides and notes.
+func (kc *keyControls) size() ComponentMemory {
+	kc.mu.RLock()
+	defer kc.mu.RUnlock()
+	var cm ComponentMemory
+	overrideSize := int64(reflect.TypeOf(Override{}).Size())
+	banSize := int64(reflect.TypeOf(Ban{}).Size())
+	for _, bans := range []map[string]Ban{kc.bans, kc.prefixBans} {
+		for key := range bans {
+			cm.Entries++
+			cm.Bytes += stringSize + int64(len(key)) + banSize + mapEntryOverhead
+		}
+	}
+	for _, overrides := range []map[string]Override{kc.overrides, kc.prefixOverrides} {
+		for key := range overrides {
+			cm.Entries++
+			cm.Bytes += stringSize + int64(len(key)) + overrideSize + mapEntryOverhead
+		}
+	}
+	noteSize := int64(reflect.TypeOf(KeyNote{}).Size())
+	for key, n := range kc.notes {
+		cm.Entries++
+		cm.Bytes += stringSize + int64(len(key)) + noteSize + mapEntryOverhead
+		for k, v := range n.Fields {
+			cm.Bytes += 2*stringSize + int64(len(k)+len(v)) + mapEntryOverhead
+		}
+	}
+	return cm
+}
+
+// size estimates the memory of the duplicate request fingerprints.
+func (d *duplicateLimiter) size() ComponentMemory {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.limiters)
+	return ComponentMemory{
+		Entries: n,
+		Bytes:   int64(n) * (sha256.Size + 8 + limiterSize + mapEntryOverhead),
+	}
+}
+
+// memoryStats collects the memory statistics of the manager.
+func (m *Manager) memoryStats() *MemoryStats {
+	ms := &MemoryStats{Components: map[string]ComponentMemory{
+		"keys":     m.stats.size(),
+		"controls": m.controls.size(),
+	}}
+	if s, ok := m.opts.Store.(SizedStore); ok {
+		ms.Components["store"] = s.Size()
+	}
+	if m.duplicates != nil {
+		ms.Components["duplicates"] = m.duplicates.size()
+	}
+
+	var rt runtime.MemStats
+	runtime.ReadMemStats(&rt)
+	ms.Runtime = RuntimeMemory{
+		HeapAlloc:     rt.HeapAlloc,
+		HeapObjects:   rt.HeapObjects,
+		NumGC:         rt.NumGC,
+		GCPauseTotal:  time.Duration(rt.PauseTotalNs).String(),
+		GCCPUFraction: rt.GCCPUFraction,
+	}
+	return ms
+}
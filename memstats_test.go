@@ -0,0 +1,62 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestMemoryStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Disabled", func(t *testing.T) {
+		assert.Nil(t, NewManager(Options{}).Stats().Memory)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:        rate.Every(time.Hour),
+			Burst:       1,
+			MemoryStats: true,
+			Duplicates:  &Duplicates{Rate: 1},
+		})
+		r := gin.New()
+		r.POST("/", m.Handler(), func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		for i := 0; i < 10; i++ {
+			req, _ := http.NewRequest("POST", "/", nil)
+			req.RemoteAddr = fmt.Sprintf("203.0.113.%d:1234", i)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		require.NoError(t, m.Ban(context.Background(), "203.0.113.0", time.Hour))
+
+		ms := m.Stats().Memory
+		require.NotNil(t, ms)
+		assert.Equal(t, 10, ms.Components["store"].Entries)
+		assert.Equal(t, 10, ms.Components["keys"].Entries)
+		assert.Equal(t, 1, ms.Components["controls"].Entries)
+		assert.Zero(t, ms.Components["duplicates"].Entries)
+		// Every limiter takes more than its key.
+		assert.Greater(t, ms.Components["store"].Bytes, int64(10*limiterSize))
+		assert.Positive(t, ms.Runtime.HeapAlloc)
+	})
+
+	t.Run("UnsizedStore", func(t *testing.T) {
+		m := NewManager(Options{Store: struct{ Store }{newMemoryStore()}, MemoryStats: true})
+		_, ok := m.Stats().Memory.Components["store"]
+		assert.False(t, ok)
+	})
+}
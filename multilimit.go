@@ -0,0 +1,63 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import "golang.org/x/time/rate"
+
+// limitKeySeparator separates the key of a client from the spec of the
+// extra limit a bucket belongs to.
+const limitKeySeparator = "#"
+
+// extraLimits returns the levels of the limits after the first in specs,
+// with the key suffix of their buckets. It panics if a spec is invalid.
+func extraLimits(specs []LimitSpec) []Level {
+	var levels []Level
+	for i, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			panic(err)
+		}
+		if i == 0 {
+			continue
+		}
+		levels = append(levels, Level{
+			Key:   limitKeySeparator + spec.String(),
+			Rate:  spec.Rate(),
+			Burst: spec.BurstSize(),
+		})
+	}
+	return levels
+}
+
+// specStrings formats specs, returning nil if there are none.
+func specStrings(specs []LimitSpec) []string {
+	var s []string
+	for _, spec := range specs {
+		s = append(s, spec.String())
+	}
+	return s
+}
+
+// layered reports whether a request is checked against several levels of
+// limits at once: the global limit, or the extra limits of the manager on
+// the default limit. Key groups keep their own buckets.
+func (m *Manager) layered(key string, route *routeLimit) bool {
+	if m.opts.Algorithm == nil && m.group(key, route) != "" {
+		return false
+	}
+	return m.global != nil || (len(m.limits) > 0 && route == nil)
+}
+
+// levels returns the global and extra limits that apply to a request of
+// key, followed by the bucket of key with limits r and burst.
+func (m *Manager) levels(key string, r rate.Limit, burst int, route *routeLimit) []Level {
+	levels := m.globalLevels()
+	if route == nil {
+		for _, lv := range m.limits {
+			lr, lburst := m.scale(lv.Rate, lv.Burst)
+			levels = append(levels, Level{Key: key + lv.Key, Rate: lr, Burst: lburst})
+		}
+	}
+	return append(levels, Level{Key: key, Rate: r, Burst: burst})
+}
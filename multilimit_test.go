@@ -0,0 +1,79 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limits := []LimitSpec{
+		{Requests: 3, Window: 200 * time.Millisecond},
+		{Requests: 5, Window: time.Hour},
+		{Requests: 10, Window: 24 * time.Hour},
+	}
+	for name, alg := range map[string]Algorithm{"Token bucket": nil, "GCRA": GCRA()} {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(Options{Limits: limits, Algorithm: alg})
+			r := gin.New()
+			r.Use(m.Handler())
+			r.GET("/", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			serve := func() *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			// The short-term limit is the most restrictive at first.
+			for range 3 {
+				assert.Equal(t, http.StatusOK, serve().Code)
+			}
+			w := serve()
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+
+			// Once it refills, the hourly limit runs out first.
+			time.Sleep(200 * time.Millisecond)
+			for range 2 {
+				assert.Equal(t, http.StatusOK, serve().Code)
+			}
+			w = serve()
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"))
+		})
+	}
+
+	t.Run("Config", func(t *testing.T) {
+		m := NewManager(Options{Limits: limits})
+		data, err := m.ConfigJSON()
+		require.NoError(t, err)
+		var config struct {
+			Burst  int      `json:"burst"`
+			Limits []string `json:"limits"`
+		}
+		require.NoError(t, json.Unmarshal(data, &config))
+		assert.Equal(t, 3, config.Burst)
+		assert.Equal(t, []string{"3/200ms", "5/hour", "10/day"}, config.Limits)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewManager(Options{Limits: []LimitSpec{{Requests: -1, Window: time.Second}}})
+		})
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// KeyNote is freeform operational context attached to a key, such as who
+// banned it, a ticket number or the customer it belongs to. Notes are kept
+// with the bans and overrides, so SharedControls shares them between
+// instances, and are returned by Keys.
+type KeyNote struct {
+	// Fields holds the metadata, such as {"ticket": "OPS-123"}.
+	Fields map[string]string `json:"fields"`
+	// Author is the actor who last set the note; see WithActor.
+	Author string `json:"author,omitempty"`
+	// Updated is when the note was last set.
+	Updated time.Time `json:"updated"`
+}
+
+// SetNote replaces the note of key with fields, attributed to the actor of
+// ctx. Empty fields delete the note.
+func (m *Manager) SetNote(ctx context.Context, key string, fields map[string]string) error {
+	if err := m.audit(ctx, AuditNote, key, maps.Clone(fields)); err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return m.changeControls(ctx, func(kc *keyControls) {
+			delete(kc.notes, key)
+		})
+	}
+	n := KeyNote{Fields: maps.Clone(fields), Author: ActorFrom(ctx), Updated: time.Now()}
+	return m.changeControls(ctx, func(kc *keyControls) {
+		kc.notes[key] = n
+	})
+}
+
+// Note returns the note of key, if any.
+func (m *Manager) Note(key string) (KeyNote, bool) {
+	m.controls.mu.RLock()
+	defer m.controls.mu.RUnlock()
+	n, ok := m.controls.notes[key]
+	n.Fields = maps.Clone(n.Fields)
+	return n, ok
+}
+
+// annotate sets the notes of the listed keys, which are raw keys.
+func (kc *keyControls) annotate(infos []KeyInfo) {
+	kc.mu.RLock()
+	defer kc.mu.RUnlock()
+	if len(kc.notes) == 0 {
+		return
+	}
+	for i := range infos {
+		if n, ok := kc.notes[infos[i].Key]; ok {
+			n.Fields = maps.Clone(n.Fields)
+			infos[i].Note = &n
+		}
+	}
+}
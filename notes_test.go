@@ -0,0 +1,112 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestNotes(t *testing.T) {
+	ctx := WithActor(context.Background(), "ops")
+	now := time.Now()
+
+	t.Run("Set", func(t *testing.T) {
+		sink := &memoryAuditSink{}
+		m := NewManager(Options{Rate: rate.Every(time.Second), Burst: 1, AuditSink: sink})
+		fields := map[string]string{"ticket": "OPS-123"}
+		require.NoError(t, m.SetNote(ctx, "alice", fields))
+		fields["ticket"] = "changed"
+
+		n, ok := m.Note("alice")
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{"ticket": "OPS-123"}, n.Fields)
+		assert.Equal(t, "ops", n.Author)
+		assert.WithinDuration(t, now, n.Updated, time.Second)
+		n.Fields["ticket"] = "changed"
+		n, _ = m.Note("alice")
+		assert.Equal(t, "OPS-123", n.Fields["ticket"])
+
+		require.NoError(t, m.SetNote(ctx, "alice", nil))
+		_, ok = m.Note("alice")
+		assert.False(t, ok)
+
+		require.Len(t, sink.entries, 2)
+		assert.Equal(t, AuditNote, sink.entries[0].Action)
+		assert.Equal(t, map[string]string{"ticket": "OPS-123"}, sink.entries[0].Detail)
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Second), Burst: 1})
+		m.stats.record("alice", "", true, now)
+		m.stats.record("bob", "", true, now)
+		require.NoError(t, m.SetNote(ctx, "alice", map[string]string{"customer": "Acme"}))
+
+		page, err := m.Keys(KeyQuery{})
+		require.NoError(t, err)
+		require.Len(t, page.Keys, 2)
+		require.NotNil(t, page.Keys[0].Note)
+		assert.Equal(t, "Acme", page.Keys[0].Note.Fields["customer"])
+		assert.Nil(t, page.Keys[1].Note)
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		sync := NewMemoryControlSync()
+		a := NewManager(Options{Rate: 1, Burst: 1, SharedControls: &SharedControls{Sync: sync}})
+		b := NewManager(Options{Rate: 1, Burst: 1, SharedControls: &SharedControls{Sync: sync}})
+		require.NoError(t, a.SetNote(ctx, "alice", map[string]string{"ticket": "OPS-123"}))
+		require.NoError(t, b.SyncControls(ctx))
+		n, ok := b.Note("alice")
+		require.True(t, ok)
+		assert.Equal(t, "ops", n.Author)
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Second), Burst: 1})
+		require.NoError(t, m.SetNote(ctx, "alice", map[string]string{"ticket": "OPS-123"}))
+		report, err := m.Forget(ctx, "alice")
+		require.NoError(t, err)
+		assert.True(t, report.Note)
+		_, ok := m.Note("alice")
+		assert.False(t, ok)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		m := NewManager(Options{Rate: rate.Every(time.Second), Burst: 1})
+		m.stats.record("alice", "", true, now)
+		r := gin.New()
+		m.RegisterAdmin(r)
+
+		w := httptest.NewRecorder()
+		body := `{"key": "alice", "fields": {"ticket": "OPS-123"}}`
+		req, _ := http.NewRequest(http.MethodPost, "/note", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "198.51.100.1:1234"
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/keys", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var page KeyPage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Keys, 1)
+		require.NotNil(t, page.Keys[0].Note)
+		assert.Equal(t, "OPS-123", page.Keys[0].Note.Fields["ticket"])
+		assert.Equal(t, "198.51.100.1", page.Keys[0].Note.Author)
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// orgKeyPrefix prefixes the bucket keys of organizations, keeping them
+// apart from the keys of their users.
+const orgKeyPrefix = "org:"
+
+// Organization limits the requests of every organization as a whole, on
+// top of the limit of each of its users.
+type Organization struct {
+	// Key returns the organization of a request, or "" if the request
+	// only counts against its own key.
+	Key func(c *gin.Context) string
+
+	// Rate and Burst are the limit of every organization.
+	Rate  rate.Limit
+	Burst int
+}
+
+// Level is one of the limits checked together by LevelAlgorithm.
+type Level struct {
+	Key   string
+	Rate  rate.Limit
+	Burst int
+}
+
+// LevelAlgorithm is an Algorithm that checks several limits at once, such
+// as those of an organization and of one of its users, in a single step.
+type LevelAlgorithm interface {
+	Algorithm
+	// TakeLevels records n requests at now in every level if all of them
+	// allow it. It returns the allowance of the most restrictive level
+	// and the index of that level.
+	TakeLevels(ctx context.Context, levels []Level, n int, now time.Time) (Allowance, int, error)
+}
+
+// MostRestrictive returns the index of the most restrictive allowance: one
+// denied for good, then the longest wait, then the fewest remaining
+// requests.
+func MostRestrictive(as []Allowance) int {
+	best := 0
+	for i := 1; i < len(as); i++ {
+		if moreRestrictive(as[i], as[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// moreRestrictive reports whether a is more restrictive than b.
+func moreRestrictive(a, b Allowance) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if never := a.RetryAfter < 0; never != (b.RetryAfter < 0) {
+		return never
+	}
+	if a.RetryAfter != b.RetryAfter {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// organization returns the organization of a request, or "" if it has
+// none. Route limits are not shared with the organization.
+func (m *Manager) organization(c *gin.Context, route *routeLimit) string {
+	if m.opts.Organization == nil || m.opts.Organization.Key == nil || route != nil {
+		return ""
+	}
+	return m.opts.Organization.Key(c)
+}
+
+// orgLevels returns the levels of a request of organization org: the
+// global limit, the organization's and own, the bucket of the request.
+func (m *Manager) orgLevels(org string, own Level) []Level {
+	or, oburst := m.opts.Organization.Rate, m.opts.Organization.Burst
+	if m.regions != nil {
+		or, oburst = m.regions.scale(or, oburst)
+	}
+	return append(m.globalLevels(), Level{Key: orgKeyPrefix + org, Rate: or, Burst: oburst}, own)
+}
+
+// takeAll takes n tokens from every level, or from none of them, with the
+// Algorithm if there is one. It returns the limiter and index of the most
+// restrictive level, or of the last if the Algorithm fails.
+func (m *Manager) takeAll(c *gin.Context, levels []Level, n int) (*rate.Limiter, bool, int) {
+	if m.opts.Algorithm == nil {
+		return m.takeBuckets(levels, n)
+	}
+
+	if m.regions != nil {
+		m.regions.requests.Add(1)
+	}
+	now := time.Now()
+	a, i, err := takeLevels(c.Request.Context(), m.opts.Algorithm, levels, n, now)
+	if err != nil {
+		_ = c.Error(err)
+		i = len(levels) - 1
+		return rate.NewLimiter(levels[i].Rate, levels[i].Burst), true, i
+	}
+	return allowanceLimiter(levels[i].Rate, levels[i].Burst, a, now), a.Allowed && hold(c, a.Delay, m.queue), i
+}
+
+// takeBuckets takes n tokens from the token bucket of every level, or from
+// none of them, and returns the limiter and index of the most restrictive
+// level. Requests are never delayed.
+func (m *Manager) takeBuckets(levels []Level, n int) (*rate.Limiter, bool, int) {
+	now := time.Now()
+	limiters, denied := m.reserveAll(levels, func(int) int { return n }, now)
+	if denied < 0 {
+		// Report the level closest to running out.
+		least := 0
+		for i, l := range limiters {
+			if l.TokensAt(now) < limiters[least].TokensAt(now) {
+				least = i
+			}
+		}
+		return limiters[least], true, least
+	}
+	return limiters[denied], false, denied
+}
+
+// reserveAll takes cost(i) tokens at now from the token bucket of every
+// level i, or from none of them. It returns the limiters of the levels up
+// to the first one denying its cost, and the index of that level, or -1
+// if every level allowed its cost.
+func (m *Manager) reserveAll(levels []Level, cost func(i int) int, now time.Time) ([]*rate.Limiter, int) {
+	limiters := make([]*rate.Limiter, 0, len(levels))
+	reservations := make([]*rate.Reservation, 0, len(levels))
+	for i, lv := range levels {
+		limiters = append(limiters, m.storedLimiter(lv.Key, lv.Rate, lv.Burst))
+		res := limiters[i].ReserveN(now, cost(i))
+		reservations = append(reservations, res)
+		if !res.OK() || res.DelayFrom(now) > 0 {
+			for _, res := range reservations {
+				res.CancelAt(now)
+			}
+			return limiters, i
+		}
+	}
+	return limiters, -1
+}
+
+// takeLevels checks levels with alg in one step if it is a LevelAlgorithm.
+// Otherwise the levels are taken one after the other, stopping at the
+// first denial; requests denied by a later level then still count against
+// the earlier ones.
+func takeLevels(ctx context.Context, alg Algorithm, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	if la, ok := alg.(LevelAlgorithm); ok {
+		return la.TakeLevels(ctx, levels, n, now)
+	}
+	as := make([]Allowance, 0, len(levels))
+	for i, lv := range levels {
+		a, err := alg.Take(ctx, lv.Key, lv.Rate, lv.Burst, n, now)
+		if err != nil {
+			return Allowance{}, 0, err
+		}
+		if !a.Allowed {
+			return a, i, nil
+		}
+		as = append(as, a)
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestMostRestrictive(t *testing.T) {
+	as := []Allowance{
+		{Allowed: true, Remaining: 5},
+		{Allowed: true, Remaining: 2},
+	}
+	assert.Equal(t, 1, MostRestrictive(as))
+	as = append(as, Allowance{RetryAfter: time.Second}, Allowance{RetryAfter: time.Minute})
+	assert.Equal(t, 3, MostRestrictive(as))
+	as = append(as, Allowance{RetryAfter: -1})
+	assert.Equal(t, 4, MostRestrictive(as))
+}
+
+func TestLevelAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Minute)
+	r := Per(3, time.Minute)
+
+	for name, tt := range map[string]struct {
+		alg   Algorithm
+		retry time.Duration
+	}{
+		"SlidingWindowLog": {SlidingWindowLog(), time.Minute},
+		"FixedWindow":      {FixedWindow(0), time.Minute},
+		// GCRA refills one request at a time, like a token bucket.
+		"GCRA": {GCRA(), 15 * time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			la := tt.alg.(LevelAlgorithm)
+			levels := func(user string) []Level {
+				return []Level{
+					{Key: "org:acme", Rate: Per(4, time.Minute), Burst: 4},
+					{Key: user, Rate: r, Burst: 3},
+				}
+			}
+
+			a, i, err := la.TakeLevels(ctx, levels("alice"), 2, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 1, i, "alice is closer to her own limit")
+			assert.Equal(t, 1, a.Remaining)
+
+			a, i, err = la.TakeLevels(ctx, levels("alice"), 2, start)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, 1, i)
+
+			// Denied requests count against neither level.
+			a, i, err = la.TakeLevels(ctx, levels("bob"), 2, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 0, i, "the organization is exhausted")
+			assert.Equal(t, 0, a.Remaining)
+
+			a, i, err = la.TakeLevels(ctx, levels("carol"), 1, start)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, 0, i)
+			assert.Equal(t, tt.retry, a.RetryAfter)
+		})
+	}
+}
+
+func TestOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for name, alg := range map[string]Algorithm{
+		"TokenBucket":      nil,
+		"SlidingWindowLog": SlidingWindowLog(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(New(Options{
+				Rate:      rate.Every(time.Hour),
+				Burst:     2,
+				Algorithm: alg,
+				KeyFunc:   func(c *gin.Context) string { return c.GetHeader("X-User") },
+				Organization: &Organization{
+					Key:   func(c *gin.Context) string { return c.GetHeader("X-Org") },
+					Rate:  rate.Every(time.Hour),
+					Burst: 3,
+				},
+			}))
+			r.GET("/", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+
+			get := func(org, user string) int {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Org", org)
+				req.Header.Set("X-User", user)
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+			assert.Equal(t, http.StatusOK, get("acme", "alice"))
+			assert.Equal(t, http.StatusOK, get("acme", "alice"))
+			assert.Equal(t, http.StatusTooManyRequests, get("acme", "alice"), "alice's limit")
+			assert.Equal(t, http.StatusOK, get("acme", "bob"))
+			assert.Equal(t, http.StatusTooManyRequests, get("acme", "bob"), "the organization's limit")
+			assert.Equal(t, http.StatusOK, get("initech", "bob"))
+			assert.Equal(t, http.StatusOK, get("", "carol"))
+		})
+	}
+}
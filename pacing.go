@@ -0,0 +1,164 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// unlimitedToken is the pacing token of a key without a limit.
+const unlimitedToken = "unlimited"
+
+// PacingPolicy describes the limits of a key in the units clients pace
+// themselves with: Limit requests per Window, with bursts of up to Burst
+// requests. A zero Limit means the key is not limited.
+type PacingPolicy struct {
+	Limit  int
+	Window time.Duration
+	Burst  int
+}
+
+// String encodes the policy as a compact pacing token of the form
+// "<limit>;w=<window seconds>;burst=<burst>", for example "100;w=60;burst=20".
+func (p PacingPolicy) String() string {
+	if p.Limit <= 0 {
+		return unlimitedToken
+	}
+	w := strconv.FormatFloat(p.Window.Seconds(), 'f', -1, 64)
+	return fmt.Sprintf("%d;w=%s;burst=%d", p.Limit, w, p.Burst)
+}
+
+// Rate returns the policy's average rate.
+func (p PacingPolicy) Rate() rate.Limit {
+	if p.Limit <= 0 {
+		return rate.Inf
+	}
+	if p.Window <= 0 {
+		return 0
+	}
+	return rate.Limit(float64(p.Limit) / p.Window.Seconds())
+}
+
+// ParsePacingToken decodes a pacing token produced by PacingPolicy.String.
+func ParsePacingToken(token string) (PacingPolicy, error) {
+	token = strings.TrimSpace(token)
+	if token == unlimitedToken {
+		return PacingPolicy{}, nil
+	}
+
+	parts := strings.Split(token, ";")
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return PacingPolicy{}, fmt.Errorf("ratelimit: invalid pacing limit %q", parts[0])
+	}
+	p := PacingPolicy{Limit: limit, Burst: limit}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		switch name {
+		case "w":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 || math.IsInf(seconds, 0) {
+				return PacingPolicy{}, fmt.Errorf("ratelimit: invalid pacing window %q", value)
+			}
+			p.Window = time.Duration(seconds * float64(time.Second))
+		case "burst":
+			burst, err := strconv.Atoi(value)
+			if err != nil || burst < 0 {
+				return PacingPolicy{}, fmt.Errorf("ratelimit: invalid pacing burst %q", value)
+			}
+			p.Burst = burst
+		default:
+			// Ignore unknown parameters so newer servers can add them.
+		}
+	}
+	if p.Window <= 0 {
+		return PacingPolicy{}, errors.New("ratelimit: pacing token has no window")
+	}
+	return p, nil
+}
+
+// pacingWindows are the windows tried, in order, when expressing a rate as
+// a whole number of requests per window.
+var pacingWindows = []time.Duration{time.Second, time.Minute, time.Hour, 24 * time.Hour}
+
+// newPacingPolicy expresses a rate and burst as a pacing policy, using the
+// shortest common window that holds a whole number of requests.
+func newPacingPolicy(r rate.Limit, burst int) PacingPolicy {
+	if r == rate.Inf {
+		return PacingPolicy{}
+	}
+	if r <= 0 {
+		// Nothing is ever replenished; advertise the burst over a day.
+		return PacingPolicy{Limit: max(burst, 1), Window: 24 * time.Hour, Burst: burst}
+	}
+	for _, w := range pacingWindows {
+		n := float64(r) * w.Seconds()
+		if n >= 1 && math.Abs(n-math.Round(n)) < 1e-9 {
+			return PacingPolicy{Limit: int(math.Round(n)), Window: w, Burst: burst}
+		}
+	}
+	// One request per interval, rounded to the millisecond.
+	interval := time.Duration(float64(time.Second) / float64(r)).Round(time.Millisecond)
+	return PacingPolicy{Limit: 1, Window: max(interval, time.Millisecond), Burst: burst}
+}
+
+// PacingPolicy returns the limits currently enforced for key.
+func (m *Manager) PacingPolicy(key string) PacingPolicy {
+	return newPacingPolicy(m.limitsFor(key))
+}
+
+// PacingToken returns the pacing token of key; see PacingPolicy.String.
+func (m *Manager) PacingToken(key string) string {
+	return m.PacingPolicy(key).String()
+}
+
+// PacingHandler returns a handler that responds with the pacing token of
+// the calling client, so cooperative clients can fetch it and pace
+// themselves with a Pacer.
+func (m *Manager) PacingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.String(http.StatusOK, m.PacingToken(m.key(c)))
+	}
+}
+
+// Pacer spaces out requests on the client side according to a pacing
+// policy, so a cooperative client stays within its server-side limit.
+type Pacer struct {
+	limiter *rate.Limiter
+}
+
+// NewPacer creates a pacer from a pacing token.
+func NewPacer(token string) (*Pacer, error) {
+	p, err := ParsePacingToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return NewPacerFromPolicy(p), nil
+}
+
+// NewPacerFromPolicy creates a pacer from a pacing policy.
+func NewPacerFromPolicy(p PacingPolicy) *Pacer {
+	return &Pacer{limiter: rate.NewLimiter(p.Rate(), p.Burst)}
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (p *Pacer) Wait(ctx context.Context) error {
+	return p.limiter.Wait(ctx)
+}
+
+// Allow reports whether a request may be sent now, recording it if so.
+func (p *Pacer) Allow() bool {
+	return p.limiter.Allow()
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestPacing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Tokens", func(t *testing.T) {
+		tests := []struct {
+			rate  rate.Limit
+			burst int
+			token string
+		}{
+			{10, 20, "10;w=1;burst=20"},
+			{rate.Every(time.Minute), 5, "1;w=60;burst=5"},
+			{100.0 / 60, 10, "100;w=60;burst=10"},
+			{rate.Every(7 * time.Second), 1, "1;w=7;burst=1"},
+			{rate.Inf, 1, "unlimited"},
+		}
+		for _, tt := range tests {
+			p := newPacingPolicy(tt.rate, tt.burst)
+			assert.Equal(t, tt.token, p.String())
+
+			parsed, err := ParsePacingToken(tt.token)
+			assert.NoError(t, err)
+			assert.Equal(t, p, parsed)
+			assert.InEpsilon(t, float64(tt.rate), float64(parsed.Rate()), 1e-3)
+		}
+	})
+
+	t.Run("InvalidTokens", func(t *testing.T) {
+		for _, token := range []string{"", "x;w=1", "10", "10;w=0", "10;w=1;burst=-1", "-5;w=1"} {
+			_, err := ParsePacingToken(token)
+			assert.Error(t, err, token)
+		}
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:  rate.Every(time.Second),
+			Burst: 3,
+		})
+		r := gin.New()
+		r.GET("/limits", m.PacingHandler())
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/limits", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1;w=1;burst=3", w.Body.String())
+	})
+
+	t.Run("Pacer", func(t *testing.T) {
+		p, err := NewPacer("1;w=3600;burst=2")
+		assert.NoError(t, err)
+		assert.True(t, p.Allow())
+		assert.True(t, p.Allow())
+		assert.False(t, p.Allow())
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// NginxLimitReq returns options emulating the NGINX limit_req directive
+// (as used directly or through Kong's NGINX templates):
+//
+//	limit_req zone=... rate=<r> burst=<burst> [nodelay];
+//
+// NGINX admits one request per 1/r interval and lets up to burst excess
+// requests through. Without nodelay, the excess requests are queued and
+// released at the configured rate; with nodelay, they are served
+// immediately while their slots are freed at the configured rate. Requests
+// beyond the burst are rejected with 503 Service Unavailable, NGINX's
+// default limit_req_status.
+//
+// The returned options can be further customized, for example by setting
+// KeyFunc to mirror the zone key.
+func NginxLimitReq(r rate.Limit, burst int, nodelay bool) Options {
+	opts := Options{
+		Rate:            r,
+		OnLimitExceeded: nginxLimitExceeded,
+	}
+	if nodelay {
+		// The in-flight request plus the burst can be served back to back.
+		opts.Burst = burst + 1
+		return opts
+	}
+
+	// A single slot is served immediately; up to burst requests queue behind
+	// it, each released one interval after the previous one.
+	opts.Burst = 1
+	if burst > 0 && r > 0 {
+		opts.MaxDelay = time.Duration(float64(burst) / float64(r) * float64(time.Second))
+	}
+	return opts
+}
+
+// nginxLimitExceeded mirrors the response NGINX sends for rejected requests.
+func nginxLimitExceeded(c *gin.Context, _ *rate.Limiter) {
+	c.String(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+}
+
+// EnvoyTokenBucket returns options emulating Envoy's local rate limit filter
+// configured with:
+//
+//	token_bucket:
+//	  max_tokens: <maxTokens>
+//	  tokens_per_fill: <tokensPerFill>
+//	  fill_interval: <fillInterval>
+//
+// Buckets start full and hold at most maxTokens tokens. Rejected requests
+// receive 429 Too Many Requests with the "local_rate_limited" body, matching
+// Envoy's defaults.
+//
+// Envoy adds tokensPerFill tokens at the end of every fillInterval, whereas
+// the limiter refills continuously at the same average rate. Long-run
+// throughput and maximum burst are identical; within a fill interval, tokens
+// become available gradually rather than all at once.
+func EnvoyTokenBucket(maxTokens, tokensPerFill int, fillInterval time.Duration) Options {
+	r := rate.Limit(0)
+	if fillInterval > 0 {
+		r = rate.Limit(float64(tokensPerFill) / fillInterval.Seconds())
+	}
+	return Options{
+		Rate:            r,
+		Burst:           maxTokens,
+		OnLimitExceeded: envoyLimitExceeded,
+	}
+}
+
+// envoyLimitExceeded mirrors the response Envoy's local rate limit filter
+// sends for rejected requests.
+func envoyLimitExceeded(c *gin.Context, _ *rate.Limiter) {
+	c.String(http.StatusTooManyRequests, "local_rate_limited")
+}
+
+// IdentityWebhook is the identity type assigned to webhook senders.
+const IdentityWebhook = "webhook"
+
+// WebhookReceiver returns options for endpoints receiving webhooks, which
+// must not answer 429 Too Many Requests: providers such as Stripe and
+// GitHub disable endpoints that keep failing. Each sender, as identified by
+// sender, gets r requests per second with bursts of burst; requests over
+// the limit are queued for up to tolerance and only then rejected with
+// 503 Service Unavailable and a Retry-After header, which providers treat
+// as a transient failure and retry. Senders that cannot be identified share
+// a budget per client IP.
+//
+// Webhooks are usually verified by the handler, after the limiter has run,
+// so senders are identified by claims such as the signature key ID alone;
+// resolvers should only accept key IDs the application knows, or forged
+// IDs each get a fresh budget.
+func WebhookReceiver(r rate.Limit, burst int, tolerance time.Duration, sender IdentityResolver) Options {
+	return Options{
+		Rate:            r,
+		Burst:           burst,
+		MaxDelay:        tolerance,
+		Identity:        IdentityChain(sender, ClientIPResolver()),
+		OnLimitExceeded: webhookLimitExceeded,
+	}
+}
+
+// webhookLimitExceeded asks the provider to retry once a token is due.
+func webhookLimitExceeded(c *gin.Context, l *rate.Limiter) {
+	res := l.Reserve()
+	delay := res.Delay()
+	res.Cancel()
+	if delay != rate.InfDuration {
+		seconds := max(1, int(math.Ceil(delay.Seconds())))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+	c.String(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+}
+
+// WebhookHeaderResolver identifies webhook senders by the value of a header
+// naming the sender or its signing key, such as "X-GitHub-Hook-ID". If
+// known is not nil, only values it accepts are used.
+func WebhookHeaderResolver(header string, known func(string) bool) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		return webhookSender(c.GetHeader(header), known)
+	})
+}
+
+// SignatureKeyIDResolver identifies webhook senders by the keyid parameter
+// of their HTTP message signature, read from the RFC 9421 Signature-Input
+// header or the older Signature header. If known is not nil, only key IDs
+// it accepts are used.
+func SignatureKeyIDResolver(known func(string) bool) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		for _, header := range []string{"Signature-Input", "Signature"} {
+			if id, ok := signatureKeyID(c.GetHeader(header)); ok {
+				return webhookSender(id, known)
+			}
+		}
+		return Identity{}, false
+	})
+}
+
+func webhookSender(id string, known func(string) bool) (Identity, bool) {
+	if id == "" || (known != nil && !known(id)) {
+		return Identity{}, false
+	}
+	return Identity{Type: IdentityWebhook, ID: id}, true
+}
+
+// signatureKeyID returns the first keyid parameter in a signature header.
+// Parameter names are matched case-insensitively, since the older
+// signature scheme spells it keyId.
+func signatureKeyID(header string) (string, bool) {
+	const param = `keyid="`
+	i := strings.Index(strings.ToLower(header), param)
+	if i < 0 {
+		return "", false
+	}
+	rest := header[i+len(param):]
+	end := strings.IndexByte(rest, '"')
+	if end < 0 {
+		return "", false
+	}
+	return rest[:end], true
+}
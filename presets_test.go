@@ -0,0 +1,156 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestPresets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(r *gin.Engine) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	newRouter := func(opts Options) *gin.Engine {
+		r := gin.New()
+		r.Use(New(opts))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return r
+	}
+
+	t.Run("NginxNodelay", func(t *testing.T) {
+		r := newRouter(NginxLimitReq(1, 2, true))
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, serve(r).Code)
+		}
+		w := serve(r)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("NginxDelay", func(t *testing.T) {
+		opts := NginxLimitReq(20, 2, false)
+		// Create the limiter up front so the concurrent requests share it.
+		store := newMemoryStore()
+		store.Set("", rate.NewLimiter(opts.Rate, opts.Burst))
+		opts.Store = store
+		r := newRouter(opts)
+
+		var (
+			mu    sync.Mutex
+			codes []int
+			wg    sync.WaitGroup
+		)
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				code := serve(r).Code
+				mu.Lock()
+				codes = append(codes, code)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		sort.Ints(codes)
+		assert.Equal(t, []int{
+			http.StatusOK, http.StatusOK, http.StatusOK, http.StatusServiceUnavailable,
+		}, codes)
+		// The last queued request is released two intervals after the first.
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("EnvoyTokenBucket", func(t *testing.T) {
+		r := newRouter(EnvoyTokenBucket(2, 1, time.Hour))
+
+		assert.Equal(t, http.StatusOK, serve(r).Code)
+		assert.Equal(t, http.StatusOK, serve(r).Code)
+		w := serve(r)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "local_rate_limited", w.Body.String())
+	})
+}
+
+func TestWebhookReceiver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	known := func(id string) bool { return strings.HasPrefix(id, "key-") }
+	opts := WebhookReceiver(rate.Every(time.Hour), 1, 50*time.Millisecond, IdentityChain(
+		SignatureKeyIDResolver(known),
+		WebhookHeaderResolver("X-GitHub-Hook-ID", nil),
+	))
+	r := gin.New()
+	r.Use(New(opts))
+	r.POST("/hooks", func(c *gin.Context) {
+		id, _ := IdentityFrom(c)
+		c.String(http.StatusOK, id.Key())
+	})
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/hooks", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Senders", func(t *testing.T) {
+		w := serve("Signature-Input", `sig1=("@method" "content-digest");created=1618884473;keyid="key-a"`)
+		assert.Equal(t, "webhook:key-a", w.Body.String())
+		w = serve("Signature", `keyId="key-b",algorithm="hmac-sha256",signature="..."`)
+		assert.Equal(t, "webhook:key-b", w.Body.String())
+		w = serve("X-GitHub-Hook-ID", "123")
+		assert.Equal(t, "webhook:123", w.Body.String())
+
+		// Unknown key IDs do not get a budget of their own.
+		w = serve("Signature-Input", `sig1=();keyid="forged"`)
+		assert.Equal(t, "ip:203.0.113.7", w.Body.String())
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		w := serve("X-GitHub-Hook-ID", "123")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		r := gin.New()
+		r.Use(New(WebhookReceiver(20, 1, time.Second, WebhookHeaderResolver("X-GitHub-Hook-ID", nil))))
+		r.POST("/hooks", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest("POST", "/hooks", nil)
+			req.Header.Set("X-GitHub-Hook-ID", "123")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		// Requests over the limit were delayed rather than rejected.
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+}
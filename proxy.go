@@ -0,0 +1,135 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// ErrUpstreamBusy is returned by the transport of Manager.ProxyTransport
+// for requests the upstream target's budget cannot take.
+var ErrUpstreamBusy = errors.New("ratelimit: upstream budget exhausted")
+
+// UpstreamBudget caps the traffic a reverse proxy forwards to each upstream
+// target, on top of the rate and burst of the manager.
+type UpstreamBudget struct {
+	// MaxInFlight is the most requests forwarded to one target at once.
+	// A request counts until its response body is closed, as
+	// httputil.ReverseProxy does once the response is copied. If zero,
+	// concurrency is not limited.
+	MaxInFlight int
+
+	// Key returns the target an outgoing request is forwarded to. If nil,
+	// TransportHostKey is used.
+	Key func(*http.Request) string
+}
+
+// proxyTransport enforces upstream budgets.
+type proxyTransport struct {
+	m      *Manager
+	base   http.RoundTripper
+	budget UpstreamBudget
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// ProxyTransport returns an http.RoundTripper for reverse proxies, such as
+// httputil.ReverseProxy inside a handler, that protects upstream targets
+// whichever clients drive the traffic. Every target gets the manager's
+// rate and burst, and requests may wait up to its MaxDelay for a token;
+// requests that would wait longer, or exceed budget.MaxInFlight, fail with
+// ErrUpstreamBusy without reaching the target. Pair it with
+// ProxyErrorHandler to answer them with 503 Service Unavailable. If base is
+// nil, http.DefaultTransport is used.
+func (m *Manager) ProxyTransport(base http.RoundTripper, budget UpstreamBudget) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if budget.Key == nil {
+		budget.Key = TransportHostKey
+	}
+	return &proxyTransport{
+		m:        m,
+		base:     base,
+		budget:   budget,
+		inFlight: make(map[string]int),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := normalizeKey(t.budget.Key(req), t.m.opts.MaxKeyLength)
+	if !t.acquire(key) {
+		return nil, ErrUpstreamBusy
+	}
+	if !takeContext(req.Context(), t.m.limiter(key), 1, t.m.waitBudget(req.Context()), t.m.queue) {
+		t.release(key)
+		return nil, ErrUpstreamBusy
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.release(key)
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() { t.release(key) }}
+	return resp, nil
+}
+
+// acquire takes an in-flight slot for key, reporting false if none is free.
+func (t *proxyTransport) acquire(key string) bool {
+	if t.budget.MaxInFlight <= 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inFlight[key] >= t.budget.MaxInFlight {
+		return false
+	}
+	t.inFlight[key]++
+	return true
+}
+
+func (t *proxyTransport) release(key string) {
+	if t.budget.MaxInFlight <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inFlight[key]--; t.inFlight[key] <= 0 {
+		delete(t.inFlight, key)
+	}
+}
+
+// releaseBody frees an in-flight slot when the response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// ProxyErrorHandler is an httputil.ReverseProxy error handler answering
+// requests rejected by Manager.ProxyTransport with 503 Service Unavailable
+// and a short Retry-After, and other failures with 502 Bad Gateway, as
+// the default handler does.
+func ProxyErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	if errors.Is(err, ErrUpstreamBusy) {
+		w.Header().Set("Retry-After", strconv.Itoa(1))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusBadGateway)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestProxyTransport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	newProxy := func(opts Options, budget UpstreamBudget) *httptest.Server {
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = NewManager(opts).ProxyTransport(nil, budget)
+		proxy.ErrorHandler = ProxyErrorHandler
+		r := gin.New()
+		r.Any("/*path", func(c *gin.Context) {
+			proxy.ServeHTTP(c.Writer, c.Request)
+		})
+		return httptest.NewServer(r)
+	}
+	serve := func(srv *httptest.Server, path string) *http.Response {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Rate", func(t *testing.T) {
+		srv := newProxy(Options{Rate: rate.Every(time.Hour), Burst: 2}, UpstreamBudget{})
+		defer srv.Close()
+
+		assert.Equal(t, http.StatusOK, serve(srv, "/a").StatusCode)
+		assert.Equal(t, http.StatusOK, serve(srv, "/b").StatusCode)
+		resp := serve(srv, "/c")
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("InFlight", func(t *testing.T) {
+		srv := newProxy(Options{Rate: rate.Inf}, UpstreamBudget{MaxInFlight: 1})
+		defer srv.Close()
+
+		done := make(chan int)
+		go func() {
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", srv.URL+"/slow", nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				done <- 0
+				return
+			}
+			resp.Body.Close()
+			done <- resp.StatusCode
+		}()
+		assert.Eventually(t, func() bool {
+			return serve(srv, "/").StatusCode == http.StatusServiceUnavailable
+		}, time.Second, 5*time.Millisecond)
+
+		close(release)
+		assert.Equal(t, http.StatusOK, <-done)
+		// The slot is freed once the response has been copied.
+		assert.Equal(t, http.StatusOK, serve(srv, "/").StatusCode)
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import "sync/atomic"
+
+// waitQueue bounds the number of requests held waiting for tokens at
+// once. A nil queue is unbounded.
+type waitQueue struct {
+	max     int32
+	waiting atomic.Int32
+}
+
+// newWaitQueue creates a queue holding up to max requests, or returns nil
+// if max is not positive.
+func newWaitQueue(max int) *waitQueue {
+	if max <= 0 {
+		return nil
+	}
+	return &waitQueue{max: int32(max)}
+}
+
+// enter reports whether a request may wait, counting it as waiting until
+// it calls leave.
+func (q *waitQueue) enter() bool {
+	if q == nil {
+		return true
+	}
+	if q.waiting.Add(1) > q.max {
+		q.waiting.Add(-1)
+		return false
+	}
+	return true
+}
+
+// leave counts a request as no longer waiting.
+func (q *waitQueue) leave() {
+	if q != nil {
+		q.waiting.Add(-1)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{
+		Rate:     10,
+		Burst:    1,
+		MaxDelay: time.Second,
+		MaxQueue: 1,
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve())
+
+	// The second request waits for the next token, filling the queue.
+	waited := make(chan int)
+	go func() { waited <- serve() }()
+	assert.Eventually(t, func() bool {
+		return m.queue.waiting.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// The third request would have to wait too, but the queue is full.
+	start := time.Now()
+	assert.Equal(t, http.StatusTooManyRequests, serve())
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, <-waited)
+	assert.Equal(t, int32(0), m.queue.waiting.Load())
+	assert.Nil(t, newWaitQueue(0))
+}
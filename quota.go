@@ -0,0 +1,85 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// quotaKey is the context key under which the middleware stores the
+// request's Quota.
+const quotaKey = "github.com/gin-contrib/ratelimit/quota"
+
+// Quota is the state of a client's limit after the middleware decided on
+// its request, for handlers and logging middleware that report it in
+// their own responses.
+type Quota struct {
+	// Key is the client's rate limiting key.
+	Key string
+	// Allowed reports whether the request was allowed.
+	Allowed bool
+	// Rate is the client's limit in requests per second, and Limit its
+	// burst: the requests allowed with a full bucket.
+	Rate  rate.Limit
+	Limit int
+	// Remaining is the number of requests the client may still send right
+	// now.
+	Remaining int
+	// ResetAt is when the full burst is available again. It is zero for
+	// unlimited rates and limits that never refill.
+	ResetAt time.Time
+}
+
+// QuotaFrom returns the quota the middleware recorded for the request, and
+// whether there is one. Requests that are banned, exempt or not limited
+// yet have none.
+func QuotaFrom(c *gin.Context) (Quota, bool) {
+	v, ok := c.Get(quotaKey)
+	if !ok {
+		return Quota{}, false
+	}
+	q, ok := v.(Quota)
+	return q, ok
+}
+
+// Remaining returns the requests the client may still send right now, or
+// zero if the request has no quota.
+func Remaining(c *gin.Context) int {
+	q, _ := QuotaFrom(c)
+	return q.Remaining
+}
+
+// LimitFrom returns the burst of the client's limit, or zero if the
+// request has no quota.
+func LimitFrom(c *gin.Context) int {
+	q, _ := QuotaFrom(c)
+	return q.Limit
+}
+
+// ResetAt returns when the client's full burst is available again, or the
+// zero time if the request has no quota or the limit never resets.
+func ResetAt(c *gin.Context) time.Time {
+	q, _ := QuotaFrom(c)
+	return q.ResetAt
+}
+
+// setQuota records the quota described by limiter in the context.
+func setQuota(c *gin.Context, key string, limiter *rate.Limiter, allowed bool) {
+	if limiter == nil {
+		return
+	}
+	d := newDenyData(c, limiter, time.Now())
+	c.Set(quotaKey, Quota{
+		Key:       key,
+		Allowed:   allowed,
+		Rate:      limiter.Limit(),
+		Limit:     d.Burst,
+		Remaining: d.Remaining,
+		ResetAt:   d.ResetAt,
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var quota Quota
+	r := gin.New()
+	r.Use(New(Options{
+		Rate:  rate.Every(time.Second),
+		Burst: 3,
+		OnLimitExceeded: func(c *gin.Context, _ *rate.Limiter) {
+			quota, _ = QuotaFrom(c)
+			c.Status(http.StatusTooManyRequests)
+		},
+	}))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"remaining": Remaining(c),
+			"limit":     LimitFrom(c),
+			"resetIn":   time.Until(ResetAt(c)).Round(time.Second).String(),
+		})
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		r.ServeHTTP(w, req)
+		return w
+	}
+	assert.JSONEq(t, `{"remaining": 2, "limit": 3, "resetIn": "1s"}`, serve().Body.String())
+	assert.JSONEq(t, `{"remaining": 1, "limit": 3, "resetIn": "2s"}`, serve().Body.String())
+	serve()
+
+	// Deny handlers see the quota too.
+	assert.Equal(t, http.StatusTooManyRequests, serve().Code)
+	assert.Equal(t, "203.0.113.1", quota.Key)
+	assert.False(t, quota.Allowed)
+	assert.Equal(t, rate.Every(time.Second), quota.Rate)
+	assert.Zero(t, quota.Remaining)
+
+	t.Run("Without middleware", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		_, ok := QuotaFrom(c)
+		assert.False(t, ok)
+		assert.Zero(t, Remaining(c))
+		assert.Zero(t, LimitFrom(c))
+		assert.True(t, ResetAt(c).IsZero())
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultQuotaThresholds are the shares of their quota clients are
+// notified of using when QuotaNotifications.Thresholds is empty.
+var DefaultQuotaThresholds = []float64{0.5, 0.8, 1}
+
+// QuotaNotifications notifies clients as they use up their quota, so API
+// products can warn customers, for example by email, before they are
+// limited. A key's quota is the burst of its limit, and a request uses the
+// share of it that is no longer available afterwards; denied requests use
+// all of it.
+type QuotaNotifications struct {
+	// Notifier delivers the notifications. It is required.
+	Notifier QuotaNotifier
+
+	// Thresholds are the shares of the quota, in increasing order between
+	// 0 and 1, whose crossing is notified. If empty,
+	// DefaultQuotaThresholds is used.
+	Thresholds []float64
+
+	// Period is how long a key is notified of every threshold at most
+	// once. When a request crosses several thresholds at once, only the
+	// highest is notified. If zero, it is the time the key's limit takes
+	// to refill its whole burst.
+	Period time.Duration
+
+	// OnError is called when Notifier fails. If nil, errors are ignored.
+	OnError func(error)
+}
+
+// QuotaNotifier delivers quota notifications, for example by email or
+// webhook. NotifyQuota is called in a goroutine of its own, so it may
+// block, but should give up eventually. With Options.Serverless, it holds
+// up the request instead.
+type QuotaNotifier interface {
+	NotifyQuota(ctx context.Context, n QuotaNotification) error
+}
+
+// QuotaNotifierFunc adapts a function to QuotaNotifier.
+type QuotaNotifierFunc func(ctx context.Context, n QuotaNotification) error
+
+// NotifyQuota implements QuotaNotifier.
+func (f QuotaNotifierFunc) NotifyQuota(ctx context.Context, n QuotaNotification) error {
+	return f(ctx, n)
+}
+
+// QuotaNotification reports that a key crossed a threshold of its quota.
+type QuotaNotification struct {
+	// Key is the key whose quota crossed the threshold.
+	Key string
+	// Threshold is the share of the quota crossed, one of the Thresholds.
+	Threshold float64
+	// Quota is the quota of the key after the request crossing it.
+	Quota Quota
+	// Time is when the threshold was crossed.
+	Time time.Time
+}
+
+// quotaNotice is the highest threshold a key was notified of in the
+// current period.
+type quotaNotice struct {
+	level int
+	until time.Time
+}
+
+// minQuotaSweep is the fewest keys that trigger a sweep of the notices.
+const minQuotaSweep = 1024
+
+// quotaNotices tracks the thresholds every key was notified of.
+type quotaNotices struct {
+	cfg QuotaNotifications
+
+	mu      sync.Mutex
+	keys    map[string]quotaNotice
+	sweepAt int
+}
+
+// newQuotaNotices creates the notices of the given configuration. It
+// panics if the configuration is invalid.
+func newQuotaNotices(cfg QuotaNotifications) *quotaNotices {
+	if cfg.Notifier == nil {
+		panic("ratelimit: QuotaNotifications without a Notifier")
+	}
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = DefaultQuotaThresholds
+	}
+	for i, t := range cfg.Thresholds {
+		if t <= 0 || t > 1 || (i > 0 && t <= cfg.Thresholds[i-1]) {
+			panic(fmt.Sprintf("ratelimit: invalid quota thresholds %v", cfg.Thresholds))
+		}
+	}
+	return &quotaNotices{cfg: cfg, keys: make(map[string]quotaNotice), sweepAt: minQuotaSweep}
+}
+
+// crossed returns the highest threshold q crossed that its key was not
+// notified of in the current period, and records the notification.
+func (qn *quotaNotices) crossed(q Quota, now time.Time) (float64, bool) {
+	if q.Limit <= 0 {
+		return 0, false
+	}
+	used := 1.0
+	if q.Allowed {
+		used = float64(q.Limit-q.Remaining) / float64(q.Limit)
+	}
+	level := -1
+	for i, t := range qn.cfg.Thresholds {
+		if used >= t {
+			level = i
+		}
+	}
+	if level < 0 {
+		return 0, false
+	}
+
+	qn.mu.Lock()
+	defer qn.mu.Unlock()
+	n, ok := qn.keys[q.Key]
+	if !ok || (!n.until.IsZero() && !now.Before(n.until)) {
+		n = quotaNotice{level: -1, until: qn.periodEnd(q, now)}
+	}
+	if level <= n.level {
+		return 0, false
+	}
+	if !ok && len(qn.keys) >= qn.sweepAt {
+		qn.sweep(now)
+	}
+	n.level = level
+	qn.keys[q.Key] = n
+	return qn.cfg.Thresholds[level], true
+}
+
+// periodEnd returns when the period of q starting at now ends, or zero if
+// it never does.
+func (qn *quotaNotices) periodEnd(q Quota, now time.Time) time.Time {
+	if qn.cfg.Period > 0 {
+		return now.Add(qn.cfg.Period)
+	}
+	if q.Rate <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(float64(q.Limit) / float64(q.Rate) * float64(time.Second)))
+}
+
+// sweep drops the notices of ended periods. The caller holds qn.mu.
+func (qn *quotaNotices) sweep(now time.Time) {
+	for key, n := range qn.keys {
+		if !n.until.IsZero() && !now.Before(n.until) {
+			delete(qn.keys, key)
+		}
+	}
+	qn.sweepAt = max(minQuotaSweep, 2*len(qn.keys))
+}
+
+// forget drops the notices of key, reporting whether it had any.
+func (qn *quotaNotices) forget(key string) bool {
+	qn.mu.Lock()
+	defer qn.mu.Unlock()
+	_, ok := qn.keys[key]
+	delete(qn.keys, key)
+	return ok
+}
+
+// notifyQuota notifies the quota recorded for the request, if it crossed a
+// threshold.
+func (m *Manager) notifyQuota(c *gin.Context) {
+	if m.notices == nil {
+		return
+	}
+	q, ok := QuotaFrom(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	t, ok := m.notices.crossed(q, now)
+	if !ok {
+		return
+	}
+	cfg := m.notices.cfg
+	n := QuotaNotification{Key: q.Key, Threshold: t, Quota: q, Time: now}
+	notify := func() {
+		if err := cfg.Notifier.NotifyQuota(context.Background(), n); err != nil && cfg.OnError != nil {
+			cfg.OnError(err)
+		}
+	}
+	// Serverless processes may be frozen before a goroutine gets to run.
+	if m.opts.Serverless {
+		notify()
+		return
+	}
+	go notify()
+}
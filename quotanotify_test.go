@@ -0,0 +1,143 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestQuotaNotifications(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	notifications := make(chan QuotaNotification, 10)
+	m := NewManager(Options{
+		Rate:  rate.Every(time.Hour),
+		Burst: 10,
+		QuotaNotifications: &QuotaNotifications{
+			Notifier: QuotaNotifierFunc(func(_ context.Context, n QuotaNotification) error {
+				notifications <- n
+				return nil
+			}),
+		},
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	next := func() QuotaNotification {
+		select {
+		case n := <-notifications:
+			return n
+		case <-time.After(time.Second):
+			t.Fatal("no notification")
+			return QuotaNotification{}
+		}
+	}
+
+	t.Run("Thresholds", func(t *testing.T) {
+		for range 4 {
+			assert.Equal(t, http.StatusOK, serve())
+		}
+		assert.Empty(t, notifications)
+
+		assert.Equal(t, http.StatusOK, serve())
+		n := next()
+		assert.Equal(t, "203.0.113.7", n.Key)
+		assert.InDelta(t, 0.5, n.Threshold, 0)
+		assert.Equal(t, 5, n.Quota.Remaining)
+
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve())
+		}
+		assert.InDelta(t, 0.8, next().Threshold, 0)
+
+		for range 2 {
+			assert.Equal(t, http.StatusOK, serve())
+		}
+		assert.InDelta(t, 1.0, next().Threshold, 0)
+	})
+
+	t.Run("Deduplicated", func(t *testing.T) {
+		for range 3 {
+			assert.Equal(t, http.StatusTooManyRequests, serve())
+		}
+		time.Sleep(10 * time.Millisecond)
+		assert.Empty(t, notifications)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		b, err := m.ConfigJSON()
+		require.NoError(t, err)
+		var config struct {
+			QuotaNotifications quotaNoticesConfig `json:"quotaNotifications"`
+		}
+		require.NoError(t, json.Unmarshal(b, &config))
+		assert.Equal(t, "ratelimit.QuotaNotifierFunc", config.QuotaNotifications.Notifier)
+		assert.Equal(t, DefaultQuotaThresholds, config.QuotaNotifications.Thresholds)
+	})
+}
+
+func TestQuotaNotices(t *testing.T) {
+	now := time.Now()
+	notifier := QuotaNotifierFunc(func(context.Context, QuotaNotification) error { return nil })
+
+	t.Run("Period", func(t *testing.T) {
+		qn := newQuotaNotices(QuotaNotifications{Notifier: notifier, Period: time.Hour})
+		q := Quota{Key: "alice", Allowed: true, Rate: 1, Limit: 10, Remaining: 1}
+
+		// Requests crossing several thresholds notify the highest.
+		threshold, ok := qn.crossed(q, now)
+		assert.True(t, ok)
+		assert.InDelta(t, 0.8, threshold, 0)
+		_, ok = qn.crossed(q, now.Add(30*time.Minute))
+		assert.False(t, ok)
+
+		threshold, ok = qn.crossed(q, now.Add(time.Hour))
+		assert.True(t, ok)
+		assert.InDelta(t, 0.8, threshold, 0)
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		qn := newQuotaNotices(QuotaNotifications{Notifier: notifier})
+		q := Quota{Key: "alice", Allowed: false, Rate: 1, Limit: 10}
+		_, ok := qn.crossed(q, now)
+		assert.True(t, ok)
+		_, ok = qn.crossed(q, now.Add(9*time.Second))
+		assert.False(t, ok)
+		_, ok = qn.crossed(q, now.Add(10*time.Second))
+		assert.True(t, ok)
+
+		assert.True(t, qn.forget("alice"))
+		assert.False(t, qn.forget("alice"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Panics(t, func() { newQuotaNotices(QuotaNotifications{}) })
+		assert.Panics(t, func() {
+			newQuotaNotices(QuotaNotifications{Notifier: notifier, Thresholds: []float64{0.8, 0.5}})
+		})
+		assert.Panics(t, func() {
+			newQuotaNotices(QuotaNotifications{Notifier: notifier, Thresholds: []float64{1.5}})
+		})
+	})
+}
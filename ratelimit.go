@@ -8,8 +8,12 @@
 package ratelimit
 
 import (
-	"net/http"
+	"cmp"
+	"context"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
@@ -26,100 +30,582 @@ type Options struct {
 	// handled in a short burst.
 	Burst int
 
+	// Limit, if set, replaces Rate and Burst with a limit expressed as
+	// requests per window, such as 100 requests per minute.
+	Limit *LimitSpec
+
+	// Limits, if set, replaces Limit with several limits enforced together
+	// on every key, such as 10 requests per second, 1000 per hour and
+	// 10000 per day. A request is denied if any of them is exceeded, and
+	// the most restrictive one is reported in headers. The first limit is
+	// the one overrides replace and route limits stand in for; the others
+	// apply to the requests limited by the default limit, unless they
+	// belong to an Organization. NewManager panics if a limit is invalid.
+	Limits []LimitSpec
+
+	// Global caps the throughput of all clients together, such as 5000
+	// requests per second, checked along with the limit of every request's
+	// key: requests are denied if either is exceeded. Every instance
+	// enforces its own cap, unless an Algorithm shares it through a store.
+	// NewManager panics if it is invalid. If nil, only keys are limited.
+	Global *LimitSpec
+
+	// MaxDelay is the longest a request may be held waiting for a token
+	// when the bucket is empty. Requests that would have to wait longer are
+	// rejected. If zero, requests are never delayed and are rejected as
+	// soon as the bucket is empty.
+	MaxDelay time.Duration
+
+	// HandlerAllowance is the part of a request's deadline kept for the
+	// handler when requests wait for tokens. A request whose context has a
+	// deadline waits at most until the deadline minus HandlerAllowance, and
+	// is rejected right away if it would have to wait longer, rather than
+	// timing out in the handler. Requests without a deadline wait up to
+	// MaxDelay.
+	HandlerAllowance time.Duration
+
+	// MaxQueue is the most requests held waiting for tokens at once,
+	// across all clients, so a burst is smoothed by delaying requests
+	// without piling up an unbounded number of them. Requests that would
+	// have to wait while the queue is full are rejected. If zero, any
+	// number of requests may wait.
+	MaxQueue int
+
 	// KeyFunc is a function to generate a key for rate limiting.
 	// The key is used to identify a client and apply the rate limit
-	// to that client. If nil, the client's IP address is used.
+	// to that client. If nil, the key of the request's Identity is used, or
+	// the client's IP address without an identity resolver.
 	KeyFunc func(*gin.Context) string
 
+	// Skip exempts requests from limiting, such as health checks, internal
+	// callers or admins. It is called first, before the request is
+	// identified or the store is touched, and requests for which it
+	// returns true go straight to the next handler and are not counted.
+	// If nil, every request is limited.
+	Skip func(*gin.Context) bool
+
+	// IPLists lets clients in an allowlist bypass limiting and rejects
+	// those in a denylist, before the request is identified. If nil, all
+	// clients are limited.
+	IPLists *IPLists
+
+	// Identity resolves who sent each request before it is classified. The
+	// identity is available to Classifier, KeyFunc and later handlers
+	// through IdentityFrom; requests it does not recognize are identified
+	// by their IP. If nil, requests are not identified.
+	Identity IdentityResolver
+
+	// Signatures gives requests with a valid HMAC signature the limits of
+	// an authenticated tier, identifying them before Identity is tried.
+	// If nil, signatures are not verified.
+	Signatures *Signatures
+
+	// Classifier assigns every request to a traffic class before the key is
+	// generated. The classification is available to KeyFunc and to later
+	// handlers through ClassificationFrom. If nil, requests are not
+	// classified.
+	Classifier Classifier
+
+	// MaxKeyLength is the longest key, in bytes, that is stored verbatim.
+	// Longer keys, and keys that are not valid UTF-8, are replaced by a
+	// fixed-size hash so values taken from request headers cannot blow up
+	// the store. If zero, DefaultMaxKeyLength is used; if negative, keys are
+	// only checked for valid UTF-8.
+	MaxKeyLength int
+
+	// CostFunc returns the number of tokens a request takes, so expensive
+	// endpoints, such as a bulk export, drain the bucket faster than cheap
+	// ones. Costs below one count as one, and requests costing more than
+	// the burst are always denied. If nil, every request costs one token.
+	CostFunc func(*gin.Context) int
+
+	// FastPath replaces the middleware with a specialized handler limiting
+	// all requests together with one in-memory token bucket of Rate and
+	// Burst, for services that need protection at well over 100k requests
+	// per second and cannot afford per-request identification. It sets no
+	// headers, calls no hooks and keeps no statistics: denied requests get
+	// a bare 429 Too Many Requests, and every other option is ignored by
+	// the handler.
+	FastPath bool
+
 	// Store is the storage for rate limiters.
 	// It is used to store the rate limiters for each client.
-	// If nil, a default in-memory store is used.
+	// If nil, a default in-memory store is used. Stores that are not a
+	// LimiterStore decide requests with Store.Allow.
 	Store Store
 
+	// IdleTTL evicts the token buckets of keys unused for longer from the
+	// in-memory store the manager creates when Store is nil, or keeps for
+	// outbound transports otherwise, so scanning traffic does not grow it
+	// forever. Evicted keys start over with a full bucket, so IdleTTL
+	// should exceed the time buckets take to refill. Call Manager.Close to
+	// stop evicting. If zero, buckets are kept.
+	IdleTTL time.Duration
+
+	// MaxKeys caps the token buckets of the same in-memory store, so a
+	// flood of random keys cannot exhaust memory. At the cap, full buckets
+	// are dropped first, as they are indistinguishable from new ones, then
+	// the least recently used, which are mostly the flood's, a sixteenth
+	// of the cap at a time. Evictions emit EventKeysEvicted and are
+	// counted in Stats.Evicted. If zero, the default store keeps every
+	// key, and the one for outbound transports keeps 10000. MaxKeys also
+	// caps the key statistics listed by Manager.Keys, which otherwise keep
+	// 100000 keys; keys idle for longer than IdleTTL make room first.
+	MaxKeys int
+
+	// Algorithm replaces the token buckets kept in Store with another
+	// algorithm, such as SlidingWindowLog. Requests are only delayed by
+	// algorithms that pace them, such as LeakyBucket, so MaxDelay does not
+	// apply, and outbound transports keep using token buckets. If nil,
+	// token buckets are used if the Store is a LimiterStore, and the
+	// Store otherwise.
+	Algorithm Algorithm
+
+	// Faults injects faults into the Algorithm, including the one of a
+	// Store deciding requests itself, to exercise failure handling in
+	// staging. Token buckets kept in process never fail, so they are
+	// unaffected. If nil, no faults are injected.
+	Faults *Faults
+
+	// DenyCacheTTL caps how long denials of the Algorithm are cached, so
+	// requests of clients over their limit are denied without a store
+	// round trip. A denial is cached until the request would be allowed,
+	// never longer, so the cache denies nothing the store would allow
+	// unless other instances reset the key. If zero, denials are not
+	// cached.
+	DenyCacheTTL time.Duration
+
+	// Consistency selects how the limits are enforced with an Algorithm,
+	// which RouteLimitWith can change per route. If zero, every request
+	// is checked against the store.
+	Consistency Consistency
+
+	// SyncInterval is how often eventually consistent limits report their
+	// usage to the store. If zero, DefaultSyncInterval is used.
+	SyncInterval time.Duration
+
+	// Serverless runs the manager in short-lived processes, such as Gin
+	// applications on AWS Lambda, which are frozen between invocations
+	// and can lose their memory at any time. The manager then starts no
+	// goroutines or timers of its own: local buckets are never evicted by
+	// IdleTTL, every limit is checked against the Algorithm whatever the
+	// Consistency, and quota notifications are sent before the response.
+	// Only checks that need no state, such as Skip and IPLists, and
+	// cached denials are decided locally. NewManager panics if the limits
+	// are not kept in a shared Algorithm or Store.
+	Serverless bool
+
 	// OnLimitExceeded is a handler called when the rate limit is exceeded.
 	// It can be used to customize the response sent to the client when
 	// the rate limit is exceeded. If nil, a default handler that sends a
 	// 429 Too Many Requests response is used.
 	OnLimitExceeded func(*gin.Context, *rate.Limiter)
+
+	// Headers is the format of the rate limit headers set on every
+	// limited response, allowed or not, so clients can tell how close
+	// they are to the limit. The zero value sets the X-RateLimit headers,
+	// HeadersIETF the headers of the IETF draft, and HeadersNone disables
+	// them.
+	Headers HeaderFormat
+
+	// RetryAfterDate sends the Retry-After header of denied requests as an
+	// HTTP-date rather than a number of seconds. The header is set before
+	// OnLimitExceeded is called, which may replace it.
+	RetryAfterDate bool
+
+	// Backpressure enables an advisory response header reporting global
+	// utilization, so clients can slow down before they are denied.
+	// If nil, no backpressure header is sent.
+	Backpressure *Backpressure
+
+	// Duplicates limits identical requests across all senders, so floods
+	// replaying the same payload are throttled separately from each
+	// sender's budget. If nil, duplicates are not limited.
+	Duplicates *Duplicates
+
+	// Retries applies a separate policy to requests marked as retries,
+	// so retry storms are shaped separately from fresh traffic. If nil,
+	// retries are limited like any other request.
+	Retries *Retries
+
+	// ColdStart starts buckets created shortly after the manager partially
+	// filled, smoothing the burst of traffic after a deploy. If nil, new
+	// buckets are always full.
+	ColdStart *ColdStart
+
+	// Groups pools the budget of related keys, which then take tokens from
+	// their group instead of their own bucket. If nil, every key has its
+	// own bucket.
+	Groups *KeyGroups
+
+	// Organization limits every organization as a whole on top of the
+	// limit of each of its users, allowing a request only if both limits
+	// do. If nil, requests only count against their own key.
+	Organization *Organization
+
+	// Hierarchy resolves the limits of keys from the limits set along
+	// their path in a tenant hierarchy, which take precedence over Rate
+	// and Burst. Overrides set with Manager.SetOverride still win. If nil,
+	// every key has the limits of Options.
+	Hierarchy *TenantHierarchy
+
+	// Regions splits the limit of every key between several regions, each
+	// enforcing its share locally. If nil, this process enforces the whole
+	// limit.
+	Regions *Regions
+
+	// Guardrails watch the SLOs of traffic classes and tighten the limits
+	// of lower-priority classes while an SLO burns too fast.
+	Guardrails []Guardrail
+
+	// Anomalies flags keys whose request rate deviates sharply from their
+	// baseline. If nil, request rates are not tracked.
+	Anomalies *Anomalies
+
+	// OnEvent is called for noteworthy changes in the limiter's behavior,
+	// such as guardrails tripping. It is called synchronously and must not
+	// block. If nil, events are discarded.
+	OnEvent func(Event)
+
+	// Anonymize replaces the keys in events, decisions, key listings and
+	// statistics with keyed hashes. If nil, keys are reported as is.
+	Anonymize *Anonymizer
+
+	// QuotaNotifications notifies clients through a QuotaNotifier as they
+	// cross thresholds of their quota. If nil, no notifications are sent.
+	QuotaNotifications *QuotaNotifications
+
+	// DryRun evaluates the limits without enforcing them, to observe what
+	// new limits would block before rolling them out. Denied requests are
+	// counted and get the rate limit headers as usual, and are reported
+	// as EventDryRunDenied events, but are let through. Bans are still
+	// enforced.
+	DryRun bool
+
+	// Tuning observes the traffic of every route and traffic class to
+	// suggest limits that would deny a target share of it, typically
+	// along with DryRun. If nil, no suggestions are made.
+	Tuning *Tuning
+
+	// AuditBypasses reports every request that bypasses limiting, through
+	// Skip, IPLists or an exempt route, as an EventBypassed event, so
+	// exemptions can be checked for abuse. Bypassed requests are counted
+	// in Stats either way.
+	AuditBypasses bool
+
+	// MemoryStats adds estimates of the memory held by the limiter, along
+	// with the process's heap and garbage collector statistics, to Stats.
+	// Collecting them walks every key and briefly stops the world, so it
+	// is meant for capacity planning rather than frequent polling.
+	MemoryStats bool
+
+	// AuditSink records administrative changes, such as resets, bans and
+	// overrides, along with the actor who made them. If nil, changes are
+	// not audited.
+	AuditSink AuditSink
+
+	// Retention bounds how long key statistics, lapsed controls and audit
+	// entries are kept. If nil, they are kept until the manager is
+	// discarded, or by the AuditSink.
+	Retention *Retention
+
+	// SharedControls shares the bans and overrides set on any instance
+	// with all others. If nil, they only apply to this manager.
+	SharedControls *SharedControls
+
+	// AdminAuth authenticates and authorizes the callers of the admin
+	// endpoints mounted by Manager.RegisterAdmin. If nil, the endpoints
+	// perform no checks of their own.
+	AdminAuth *AdminAuth
 }
 
-// Store is the interface for storing rate limiters.
-// It can be implemented to use different storage backends,
-// such as in-memory, Redis, or others.
+// Store makes the rate limiting decisions of the middleware. It can be
+// implemented to use different storage backends, such as in-memory, Redis,
+// or others. Allow evaluates and updates the state of a key in one step, so
+// backends shared between processes can do it atomically.
+//
+// Stores that hold *rate.Limiter token buckets in process also implement
+// LimiterStore, which the middleware then uses to keep honoring MaxDelay,
+// ColdStart and the other token bucket options.
 type Store interface {
+	// Allow records limit.N requests of key if the limit allows all of
+	// them, and reports the outcome. With limit.N zero, it records nothing
+	// and reports whether one request would be allowed.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limit is the limit Store.Allow enforces for a key.
+type Limit struct {
+	// Rate and Burst describe the token bucket of the key.
+	Rate  rate.Limit
+	Burst int
+	// N is the number of requests to record.
+	N int
+}
+
+// Result is the outcome of Store.Allow.
+type Result = Allowance
+
+// LimiterStore is the interface of stores keeping *rate.Limiter token
+// buckets, which was Store before Allow was introduced. Since the buckets
+// only live in one process, such stores cannot be shared between
+// processes.
+type LimiterStore interface {
 	// Get retrieves a rate limiter from the store for the given key.
 	Get(key string) (*rate.Limiter, bool)
 	// Set adds a rate limiter to the store for the given key.
 	Set(key string, limiter *rate.Limiter)
 }
 
-// New creates a new rate limiting middleware with the given options.
-func New(opts Options) gin.HandlerFunc {
-	// Set default options if not provided.
-	if opts.KeyFunc == nil {
-		opts.KeyFunc = func(c *gin.Context) string {
-			return c.ClientIP()
-		}
+// LimiterCreator is implemented by LimiterStores that create the limiters
+// of new keys atomically. With other stores, concurrent first requests of
+// a key may each create a limiter, and the tokens taken from all but the
+// one stored last are lost, letting a burst through twice.
+type LimiterCreator interface {
+	// GetOrCreate returns the limiter of key, storing the one returned by
+	// create if there is none. create is called at most once, and only if
+	// key has no limiter, possibly with the store locked, so it must not
+	// use the store.
+	GetOrCreate(key string, create func() *rate.Limiter) *rate.Limiter
+}
+
+// getOrCreate returns the limiter of key in s, storing the one returned by
+// create if there is none, atomically if s is a LimiterCreator.
+func getOrCreate(s LimiterStore, key string, create func() *rate.Limiter) *rate.Limiter {
+	if lc, ok := s.(LimiterCreator); ok {
+		return lc.GetOrCreate(key, create)
 	}
-	if opts.Store == nil {
-		opts.Store = newMemoryStore()
+	l, ok := s.Get(key)
+	if !ok {
+		l = create()
+		s.Set(key, l)
 	}
-	if opts.OnLimitExceeded == nil {
-		opts.OnLimitExceeded = func(c *gin.Context, l *rate.Limiter) {
-			c.String(http.StatusTooManyRequests, "Too Many Requests")
-		}
+	return l
+}
+
+// FromLimiterStore adapts a store implementing only Get and Set to Store.
+// The middleware keeps using its token buckets directly.
+func FromLimiterStore(s LimiterStore) Store {
+	return limiterStore{s}
+}
+
+// limiterStore implements Store.Allow with the token buckets of a
+// LimiterStore.
+type limiterStore struct {
+	LimiterStore
+}
+
+// Allow implements Store.
+func (s limiterStore) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	return allowLimiter(s.LimiterStore, key, limit, time.Now()), nil
+}
+
+// allowLimiter decides limit for key with the token bucket stored in s,
+// creating it with or resizing it to the limit as needed.
+func allowLimiter(s LimiterStore, key string, limit Limit, now time.Time) Result {
+	l := getOrCreate(s, key, func() *rate.Limiter {
+		return rate.NewLimiter(limit.Rate, limit.Burst)
+	})
+	if l.Limit() != limit.Rate || l.Burst() != limit.Burst {
+		l = resizeLimiter(l, limit.Rate, limit.Burst, now)
+		s.Set(key, l)
 	}
+	remaining := func() int { return max(0, int(l.TokensAt(now))) }
+	r := l.ReserveN(now, max(limit.N, 1))
+	if !r.OK() {
+		return Result{Remaining: remaining(), RetryAfter: -1}
+	}
+	if delay := r.DelayFrom(now); delay > 0 || limit.N == 0 {
+		r.CancelAt(now)
+		return Result{Allowed: delay == 0, Remaining: remaining(), RetryAfter: delay}
+	}
+	return Result{Allowed: true, Remaining: remaining()}
+}
 
-	return func(c *gin.Context) {
-		// Generate a key for the client.
-		key := opts.KeyFunc(c)
-		// Get the rate limiter for the client from the store.
-		limiter, exists := opts.Store.Get(key)
-		if !exists {
-			// If the rate limiter does not exist, create a new one
-			// and add it to the store.
-			limiter = rate.NewLimiter(opts.Rate, opts.Burst)
-			opts.Store.Set(key, limiter)
-		}
+// New creates a new rate limiting middleware with the given options.
+// It is a shorthand for NewManager(opts).Handler().
+func New(opts Options) gin.HandlerFunc {
+	return NewManager(opts).Handler()
+}
 
-		// Check if the client has exceeded the rate limit.
-		if !limiter.Allow() {
-			// If the rate limit is exceeded, call the OnLimitExceeded handler.
-			opts.OnLimitExceeded(c, limiter)
-			c.Abort()
-			return
-		}
+// waitBudget returns the longest a request with context ctx may wait for
+// tokens: MaxDelay, shortened to the time left before the deadline of ctx
+// minus HandlerAllowance.
+func (m *Manager) waitBudget(ctx context.Context) time.Duration {
+	maxDelay := m.opts.MaxDelay
+	if deadline, ok := ctx.Deadline(); ok && maxDelay > 0 {
+		maxDelay = min(maxDelay, time.Until(deadline)-m.opts.HandlerAllowance)
+	}
+	return maxDelay
+}
 
-		// If the rate limit is not exceeded, continue to the next handler.
-		c.Next()
+// take consumes n tokens from limiter, waiting up to maxDelay for them to
+// become available if q has room for another waiting request. It reports
+// whether the request may proceed.
+func take(c *gin.Context, limiter *rate.Limiter, n int, maxDelay time.Duration, q *waitQueue) bool {
+	return takeContext(c.Request.Context(), limiter, n, maxDelay, q)
+}
+
+// takeContext is take for callers without a gin context; waiting stops
+// when ctx is done.
+func takeContext(ctx context.Context, limiter *rate.Limiter, n int, maxDelay time.Duration, q *waitQueue) bool {
+	if maxDelay <= 0 {
+		return limiter.AllowN(time.Now(), n)
+	}
+
+	r := limiter.ReserveN(time.Now(), n)
+	if !r.OK() {
+		return false
+	}
+	delay := r.Delay()
+	if delay > maxDelay {
+		// Give the token back so the rejected request is not charged.
+		r.Cancel()
+		return false
+	}
+	if delay == 0 {
+		return true
+	}
+	if !q.enter() {
+		r.Cancel()
+		return false
+	}
+	defer q.leave()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		r.Cancel()
+		return false
 	}
 }
 
-// memoryStore is an in-memory implementation of the Store interface.
-// It uses a map to store the rate limiters for each client.
+// memoryStore is an in-memory implementation of the Store and
+// LimiterStore interfaces. It uses a map to store the rate limiters for
+// each client, and is a shard of the stores of NewMemoryStore.
 type memoryStore struct {
-	limiters map[string]*rate.Limiter
+	limiters map[string]*memoryEntry
 	mu       sync.RWMutex
+	// max is the most limiters kept, or zero for no bound.
+	max int
+	// evicted counts the limiters dropped to stay within max, and onEvict,
+	// if set, is called with the number dropped at once.
+	evicted atomic.Uint64
+	onEvict func(n int)
+	// ttl is how long limiters are kept unused, or zero for no bound.
+	ttl time.Duration
+}
+
+// memoryEntry is a limiter of a memoryStore.
+type memoryEntry struct {
+	limiter *rate.Limiter
+	// used is when the limiter was last retrieved or set, in Unix
+	// nanoseconds. It is only tracked with a bound on the limiters.
+	used atomic.Int64
 }
 
 // newMemoryStore creates a new in-memory store.
 func newMemoryStore() *memoryStore {
 	return &memoryStore{
-		limiters: make(map[string]*rate.Limiter),
+		limiters: make(map[string]*memoryEntry),
 	}
 }
 
+// Allow implements Store.
+func (s *memoryStore) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	return allowLimiter(s, key, limit, time.Now()), nil
+}
+
 // Get retrieves a rate limiter from the store.
 func (s *memoryStore) Get(key string) (*rate.Limiter, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	limiter, exists := s.limiters[key]
-	return limiter, exists
+	e, exists := s.limiters[key]
+	if !exists {
+		return nil, false
+	}
+	s.touch(e)
+	return e.limiter, true
 }
 
 // Set adds a rate limiter to the store.
 func (s *memoryStore) Set(key string, limiter *rate.Limiter) {
+	_, n := s.put(key, func() *rate.Limiter { return limiter }, true)
+	s.reportEvicted(n)
+}
+
+// GetOrCreate implements LimiterCreator.
+func (s *memoryStore) GetOrCreate(key string, create func() *rate.Limiter) *rate.Limiter {
+	if l, ok := s.Get(key); ok {
+		return l
+	}
+	l, n := s.put(key, create, false)
+	s.reportEvicted(n)
+	return l
+}
+
+// put stores the limiter returned by create for key, unless key has one
+// and replace is false. It returns the limiter of key, and the number of
+// limiters evicted to make room for it.
+func (s *memoryStore) put(key string, create func() *rate.Limiter, replace bool) (*rate.Limiter, int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.limiters[key] = limiter
+	old, exists := s.limiters[key]
+	if exists && !replace {
+		s.touch(old)
+		return old.limiter, 0
+	}
+	evicted := 0
+	if !exists && s.max > 0 && len(s.limiters) >= s.max {
+		evicted = s.evict(time.Now())
+	}
+	e := &memoryEntry{limiter: create()}
+	s.touch(e)
+	s.limiters[key] = e
+	return e.limiter, evicted
+}
+
+// reportEvicted counts the n limiters evicted at once, if any.
+func (s *memoryStore) reportEvicted(n int) {
+	if n == 0 {
+		return
+	}
+	s.evicted.Add(uint64(n))
+	if s.onEvict != nil {
+		s.onEvict(n)
+	}
+}
+
+// evict makes room for a limiter, and returns the number of limiters
+// dropped. Every pass drops at least a sixteenth of max, so floods of new
+// keys scan the limiters once per batch rather than on every request.
+// Full limiters are dropped first, since they are indistinguishable from
+// new ones, then the least recently used make up the rest of the batch.
+func (s *memoryStore) evict(now time.Time) int {
+	type entry struct {
+		key  string
+		used int64
+	}
+	n := len(s.limiters)
+	entries := make([]entry, 0, n)
+	for key, e := range s.limiters {
+		if l := e.limiter; l.TokensAt(now) >= float64(l.Burst()) {
+			delete(s.limiters, key)
+			continue
+		}
+		entries = append(entries, entry{key, e.used.Load()})
+	}
+	batch := max(1, s.max/16)
+	if rest := batch - (n - len(s.limiters)); rest > 0 {
+		slices.SortFunc(entries, func(a, b entry) int {
+			return cmp.Compare(a.used, b.used)
+		})
+		for _, e := range entries[:min(rest, len(entries))] {
+			delete(s.limiters, e.key)
+		}
+	}
+	return n - len(s.limiters)
 }
@@ -5,6 +5,7 @@
 package ratelimit
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
 )
 
@@ -96,3 +98,166 @@ func TestRateLimiter(t *testing.T) {
 		assert.Equal(t, "I'm a teapot", w.Body.String())
 	})
 }
+
+// allowStore is a Store deciding requests with Allow only.
+type allowStore struct {
+	limits []Limit
+}
+
+func (s *allowStore) Allow(_ context.Context, _ string, limit Limit) (Result, error) {
+	s.limits = append(s.limits, limit)
+	return Result{Allowed: len(s.limits) == 1, Remaining: 0, RetryAfter: time.Second}, nil
+}
+
+func TestStoreAllow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	t.Run("Memory", func(t *testing.T) {
+		s := newMemoryStore()
+		limit := Limit{Rate: rate.Every(time.Minute), Burst: 2, N: 1}
+
+		res, err := s.Allow(ctx, "k", Limit{Rate: limit.Rate, Burst: limit.Burst})
+		require.NoError(t, err)
+		assert.Equal(t, Result{Allowed: true, Remaining: 2}, res)
+
+		for _, remaining := range []int{1, 0} {
+			res, err = s.Allow(ctx, "k", limit)
+			require.NoError(t, err)
+			assert.Equal(t, Result{Allowed: true, Remaining: remaining}, res)
+		}
+		res, err = s.Allow(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.InDelta(t, time.Minute.Seconds(), res.RetryAfter.Seconds(), 1)
+
+		// Requests beyond the burst never fit.
+		res, err = s.Allow(ctx, "k", Limit{Rate: limit.Rate, Burst: 2, N: 3})
+		require.NoError(t, err)
+		assert.Equal(t, Result{RetryAfter: -1}, res)
+	})
+
+	t.Run("LimiterStore", func(t *testing.T) {
+		// Stores written for Get and Set keep their token buckets.
+		legacy := struct{ LimiterStore }{newMemoryStore()}
+		m := NewManager(Options{Rate: rate.Every(time.Minute), Burst: 1, Store: FromLimiterStore(legacy)})
+		assert.Nil(t, m.opts.Algorithm)
+
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		for _, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "203.0.113.1:1234"
+			r.ServeHTTP(w, req)
+			assert.Equal(t, code, w.Code)
+		}
+		_, ok := legacy.Get("203.0.113.1")
+		assert.True(t, ok)
+	})
+
+	t.Run("Allow", func(t *testing.T) {
+		s := &allowStore{}
+		m := NewManager(Options{Rate: rate.Every(time.Minute), Burst: 3, Store: s})
+
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		for _, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, code, w.Code)
+		}
+		require.Len(t, s.limits, 2)
+		assert.Equal(t, Limit{Rate: rate.Every(time.Minute), Burst: 3, N: 1}, s.limits[0])
+
+		// The store cannot forget keys.
+		assert.ErrorIs(t, m.Reset(ctx, "k"), errStoreReset)
+	})
+}
+
+func TestHandlerAllowance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(New(Options{
+		Rate:             rate.Every(100 * time.Millisecond),
+		Burst:            1,
+		MaxDelay:         time.Second,
+		HandlerAllowance: 50 * time.Millisecond,
+	}))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func(timeout time.Duration) (int, time.Duration) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		start := time.Now()
+		r.ServeHTTP(w, req)
+		return w.Code, time.Since(start)
+	}
+
+	code, _ := serve(time.Second)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Waiting for the next token would leave the handler less than its
+	// allowance.
+	code, elapsed := serve(120 * time.Millisecond)
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Less(t, elapsed, 50*time.Millisecond)
+
+	code, elapsed = serve(time.Second)
+	assert.Equal(t, http.StatusOK, code)
+	assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+}
+
+func TestSkip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{
+		Rate:  rate.Every(time.Hour),
+		Burst: 1,
+		Skip: func(c *gin.Context) bool {
+			return c.Request.URL.Path == "/healthz" || c.GetHeader("X-Internal") == "true"
+		},
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/healthz", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func(path string, internal bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		if internal {
+			req.Header.Set("X-Internal", "true")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for range 3 {
+		w := serve("/healthz", false)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	}
+	// Skipped requests never reach the store.
+	_, ok := m.limiters.Get("203.0.113.1")
+	assert.False(t, ok)
+
+	assert.Equal(t, http.StatusOK, serve("/", false).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("/", false).Code)
+	assert.Equal(t, http.StatusOK, serve("/", true).Code)
+}
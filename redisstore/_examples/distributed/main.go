@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-contrib/ratelimit/redisstore"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/time/rate"
+)
+
+// Run several instances of this program against the same Redis server to
+// share limits between them; see ../docker-compose.yml.
+func main() {
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	app := gin.Default()
+	app.Use(ratelimit.New(ratelimit.Options{
+		Rate:  rate.Every(time.Second),
+		Burst: 1,
+		Store: redisstore.New(redisClient),
+	}))
+	app.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, Redis World!")
+	})
+
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	if err := app.Run(addr); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // script hashes, as EVALSHA takes them
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Cmd is the result of a command, such as *redis.Cmd of go-redis v8 or v9.
+type Cmd interface {
+	Result() (interface{}, error)
+}
+
+// RedisClient is the part of a Redis client the backends use. Every
+// backend runs Lua scripts, so clients of go-redis v8 (github.com/go-redis
+// /redis/v8) and v9 (github.com/redis/go-redis/v9) both implement it,
+// including their cluster and ring clients, and can be passed to the
+// constructors as they are:
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	store := redisstore.New(client)
+type RedisClient[C Cmd] interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) C
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) C
+}
+
+// scripter runs scripts with a RedisClient of any version.
+type scripter interface {
+	run(ctx context.Context, s *script, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// clientOf adapts a RedisClient to scripter.
+type clientOf[C Cmd] struct {
+	client RedisClient[C]
+}
+
+// run runs s by its hash, loading it if the server does not have it yet.
+func (c clientOf[C]) run(ctx context.Context, s *script, keys []string, args ...interface{}) (interface{}, error) {
+	res, err := c.client.EvalSha(ctx, s.hash, keys, args...).Result()
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT ") {
+		return c.client.Eval(ctx, s.src, keys, args...).Result()
+	}
+	return res, err
+}
+
+// script is a Lua script run by its hash.
+type script struct {
+	src  string
+	hash string
+}
+
+// newScript creates a script from its source.
+func newScript(src string) *script {
+	h := sha1.Sum([]byte(src)) //nolint:gosec // script hashes, as EVALSHA takes them
+	return &script{src: src, hash: hex.EncodeToString(h[:])}
+}
+
+// delScript deletes a key.
+var delScript = newScript(`return redis.call('DEL', KEYS[1])`)
+
+// del deletes key.
+func del(ctx context.Context, c scripter, key string) error {
+	_, err := c.run(ctx, delScript, []string{key})
+	return err
+}
+
+// int64s converts the array returned by a script to integers.
+func int64s(res interface{}) ([]int64, error) {
+	values, ok := res.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redisstore: unexpected script result %T", res)
+	}
+	ints := make([]int64, len(values))
+	for i, v := range values {
+		n, err := int64Of(v)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = n
+	}
+	return ints, nil
+}
+
+// int64Of converts the integer returned by a script.
+func int64Of(res interface{}) (int64, error) {
+	n, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redisstore: unexpected script result %T", res)
+	}
+	return n, nil
+}
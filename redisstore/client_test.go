@@ -0,0 +1,87 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	redisv9 "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRedisV9(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redisv9.NewClient(&redisv9.Options{Addr: mr.Addr()})
+	now := time.Now()
+
+	t.Run("Algorithms", func(t *testing.T) {
+		algs := map[string]ratelimit.Algorithm{
+			"gcra":   NewGCRA(client),
+			"window": NewFixedWindow(client, time.Hour),
+			"log":    NewSlidingWindowLog(client),
+		}
+		for name, alg := range algs {
+			a, err := alg.Take(ctx, name, rate.Every(time.Hour), 1, 1, now)
+			require.NoError(t, err, name)
+			assert.True(t, a.Allowed, name)
+			a, err = alg.Take(ctx, name, rate.Every(time.Hour), 1, 1, now)
+			require.NoError(t, err, name)
+			assert.False(t, a.Allowed, name)
+
+			require.NoError(t, alg.Reset(ctx, name), name)
+			a, err = alg.Take(ctx, name, rate.Every(time.Hour), 1, 1, now)
+			require.NoError(t, err, name)
+			assert.True(t, a.Allowed, name)
+		}
+	})
+
+	t.Run("Universal client", func(t *testing.T) {
+		var universal redisv9.UniversalClient = client
+		res, err := New(universal).Allow(ctx, "u", ratelimit.Limit{Rate: 1, Burst: 1, N: 1})
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	})
+
+	t.Run("Flushed scripts", func(t *testing.T) {
+		mr.FlushAll()
+		require.NoError(t, client.ScriptFlush(ctx).Err())
+		a, err := NewGCRA(client).Take(ctx, "k", rate.Every(time.Hour), 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+
+	t.Run("Controls", func(t *testing.T) {
+		s := NewControlSync(client, "ratelimit:controls")
+		version, err := s.Version(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, version)
+
+		version, err = s.Publish(ctx, ratelimit.ControlSnapshot{
+			Bans: map[string]ratelimit.Ban{"mallory": {}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+		snap, err := s.Load(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), snap.Version)
+		assert.Contains(t, snap.Bans, "mallory")
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		sink := NewAuditSink(client, "ratelimit:audit")
+		require.NoError(t, sink.Record(ctx, ratelimit.AuditEntry{Time: now, Action: ratelimit.AuditBan, Key: "k"}))
+		entries, err := client.XRange(ctx, "ratelimit:audit", "-", "+").Result()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "ban", entries[0].Values["action"])
+	})
+}
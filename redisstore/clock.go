@@ -0,0 +1,146 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+)
+
+// Option configures a store or algorithm of the package.
+type Option func(*config)
+
+// config is the configuration of a store or algorithm.
+type config struct {
+	// serverClock takes the time of requests from the Redis server.
+	serverClock bool
+	// skew observes the skew between the clocks of the instance and the
+	// server, with WithSkewTolerance.
+	skew *skewWatch
+	// hotKeyPeriod is the period over which requests are counted, with
+	// WithHotKeys.
+	hotKeyPeriod time.Duration
+}
+
+// WithServerClock makes the scripts take the time of every request from
+// the Redis TIME command instead of the clock of the instance, so
+// instances with skewed clocks still agree on token accounting. The times
+// passed to Take are then ignored. Before Redis 5, scripts are replicated
+// by their effects for TIME to be allowed before writes.
+func WithServerClock() Option {
+	return func(c *config) { c.serverClock = true }
+}
+
+// WithSkewTolerance makes the scripts compare the time of every request
+// with the Redis TIME command. Requests timed more than d away from the
+// server are timed by the server instead, so instances whose clocks
+// drifted neither deny requests spuriously nor let too many through, while
+// the rest keep their own clocks. The skew observed is reported by the
+// algorithm's Skew method, which Manager.Stats includes under Clock.
+// WithServerClock takes precedence.
+func WithSkewTolerance(d time.Duration) Option {
+	return func(c *config) { c.skew = &skewWatch{tolerance: max(d, 0)} }
+}
+
+// newConfig applies opts to the default configuration.
+func newConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// clockArgs returns the arguments of clockLua for a request at t, in
+// units since the Unix epoch.
+func (c config) clockArgs(t time.Time, unit time.Duration) []interface{} {
+	now, tolerance := t.UnixNano()/int64(unit), int64(-1)
+	switch {
+	case c.serverClock:
+		now = -1
+	case c.skew != nil:
+		tolerance = int64(c.skew.tolerance / unit)
+	}
+	return []interface{}{now, tolerance}
+}
+
+// observe records the skew of a request at t from the time of the server
+// returned by a script, in units since the Unix epoch, if it was observed.
+func (c config) observe(t time.Time, server int64, unit time.Duration) {
+	if c.skew == nil || c.serverClock || server < 0 {
+		return
+	}
+	// Compare in units, as the script did.
+	skew := t.UnixNano()/int64(unit) - server
+	c.skew.observe(time.Duration(skew)*unit, max(skew, -skew) > int64(c.skew.tolerance/unit))
+}
+
+// clockLua returns the start of a script setting now to ARGV[1], the time
+// of the request in units since the Unix epoch, unless the time of the
+// server is used instead, in which case server is true: if ARGV[1] is
+// negative, or if ARGV[2], the tolerated skew in units, is not and now is
+// further away from the server. clock is the time of the server, if it was
+// read, and -1 otherwise; the scripts return it last.
+func clockLua(unit time.Duration) string {
+	return fmt.Sprintf(`
+local now = tonumber(ARGV[1])
+local maxSkew = tonumber(ARGV[2])
+local server = now < 0
+local clock = -1
+if server or maxSkew >= 0 then
+	if redis.replicate_commands then
+		redis.replicate_commands()
+	end
+	local t = redis.call('TIME')
+	clock = tonumber(t[1]) * %d + math.floor(tonumber(t[2]) / %d)
+	if server or math.abs(now - clock) > maxSkew then
+		now, server = clock, true
+	end
+end`, time.Second/unit, unit/time.Microsecond)
+}
+
+// skewWatch records the skew observed between the clocks of the instance
+// and the server.
+type skewWatch struct {
+	tolerance time.Duration
+	// last and max are in nanoseconds, max in absolute value.
+	last      atomic.Int64
+	max       atomic.Int64
+	samples   atomic.Uint64
+	corrected atomic.Uint64
+}
+
+// observe records a request whose clock was skew ahead of the server, and
+// whether it was corrected.
+func (w *skewWatch) observe(skew time.Duration, corrected bool) {
+	w.last.Store(int64(skew))
+	for abs := int64(skew.Abs()); ; {
+		prev := w.max.Load()
+		if abs <= prev || w.max.CompareAndSwap(prev, abs) {
+			break
+		}
+	}
+	w.samples.Add(1)
+	if corrected {
+		w.corrected.Add(1)
+	}
+}
+
+// stats returns the skew observed so far, or nil without a watch.
+func (w *skewWatch) stats() *ratelimit.SkewStats {
+	if w == nil {
+		return nil
+	}
+	return &ratelimit.SkewStats{
+		Tolerance: w.tolerance.String(),
+		Last:      time.Duration(w.last.Load()).String(),
+		Max:       time.Duration(w.max.Load()).String(),
+		Samples:   w.samples.Load(),
+		Corrected: w.corrected.Load(),
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestServerClock(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Minute).Add(50 * time.Second)
+	r := rate.Every(5 * time.Second)
+
+	for name, tt := range map[string]struct {
+		alg   func(client *redis.Client) ratelimit.Algorithm
+		retry time.Duration
+	}{
+		"GCRA": {
+			func(client *redis.Client) ratelimit.Algorithm { return NewGCRA(client, WithServerClock()) },
+			5 * time.Second,
+		},
+		"Store": {
+			func(client *redis.Client) ratelimit.Algorithm {
+				return New(client, WithServerClock()).(ratelimit.Algorithm)
+			},
+			5 * time.Second,
+		},
+		"SlidingWindowLog": {
+			func(client *redis.Client) ratelimit.Algorithm { return NewSlidingWindowLog(client, WithServerClock()) },
+			10 * time.Second,
+		},
+		"FixedWindow": {
+			func(client *redis.Client) ratelimit.Algorithm {
+				return NewFixedWindow(client, 10*time.Second, WithServerClock())
+			},
+			10 * time.Second,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			mr.SetTime(start)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			alg := tt.alg(client)
+
+			// Instances an hour apart agree on the buckets.
+			for _, skew := range []time.Duration{-time.Hour, time.Hour} {
+				a, err := alg.Take(ctx, "k", r, 2, 1, start.Add(skew))
+				require.NoError(t, err)
+				assert.True(t, a.Allowed)
+			}
+			a, err := alg.Take(ctx, "k", r, 2, 1, start)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, tt.retry, a.RetryAfter)
+
+			mr.SetTime(start.Add(tt.retry))
+			a, err = alg.Take(ctx, "k", r, 2, 1, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+		})
+	}
+}
+
+func TestSkewTolerance(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	start := time.Now().Truncate(time.Second)
+	mr.SetTime(start)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	alg := NewGCRA(client, WithSkewTolerance(time.Second))
+	r := rate.Every(10 * time.Second)
+
+	// An instance an hour ahead is timed by the server.
+	a, err := alg.Take(ctx, "k", r, 1, 1, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, a.Allowed)
+	a, err = alg.Take(ctx, "k", r, 1, 1, start)
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+	assert.Equal(t, 10*time.Second, a.RetryAfter)
+
+	// Instances within the tolerance keep their clocks.
+	a, err = alg.Take(ctx, "k", r, 1, 1, start.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+	assert.Equal(t, 9500*time.Millisecond, a.RetryAfter)
+
+	m := ratelimit.NewManager(ratelimit.Options{Rate: r, Burst: 1, Algorithm: alg})
+	clock := m.Stats().Clock
+	require.NotNil(t, clock)
+	assert.Equal(t, &ratelimit.SkewStats{
+		Tolerance: "1s",
+		Last:      "500ms",
+		Max:       "1h0m0s",
+		Samples:   3,
+		Corrected: 1,
+	}, clock.Skew)
+
+	// Without the option, the skew is not observed.
+	assert.Nil(t, NewGCRA(client).(ratelimit.SkewReporter).Skew())
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// publishScript bumps the version of the controls and stores them in one
+// step, so the version always matches the stored controls. It stores
+// nothing and returns -1 if the version is no longer ARGV[2], the one the
+// controls were based on.
+var publishScript = newScript(`
+if tonumber(redis.call('GET', KEYS[1]) or '0') ~= tonumber(ARGV[2]) then
+	return -1
+end
+local version = redis.call('INCR', KEYS[1])
+redis.call('SET', KEYS[2], ARGV[1])
+return version
+`)
+
+// loadScript returns the version and the controls, or empty strings if
+// none were published, reading both in one step.
+var loadScript = newScript(`
+return {redis.call('GET', KEYS[1]) or '', redis.call('GET', KEYS[2]) or ''}
+`)
+
+// versionScript returns the version of the controls, or 0.
+var versionScript = newScript(`
+return tonumber(redis.call('GET', KEYS[1]) or '0')
+`)
+
+// controlSync is a ratelimit.ControlSync keeping the controls in Redis.
+type controlSync struct {
+	client  scripter
+	version string
+	data    string
+}
+
+// NewControlSync creates a ratelimit.ControlSync storing the shared bans
+// and overrides as JSON under key, and their version under key
+// + ":version". On Redis Cluster, put key in a hash tag, such as
+// "{ratelimit:controls}", so both keys hash to the same slot.
+func NewControlSync[C Cmd](client RedisClient[C], key string) ratelimit.ControlSync {
+	return &controlSync{
+		client:  clientOf[C]{client},
+		version: key + ":version",
+		data:    key,
+	}
+}
+
+// controlsJSON is the stored form of a ratelimit.ControlSnapshot.
+type controlsJSON struct {
+	Bans            map[string]ratelimit.Ban     `json:"bans,omitempty"`
+	Overrides       map[string]overrideJSON      `json:"overrides,omitempty"`
+	PrefixBans      map[string]ratelimit.Ban     `json:"prefixBans,omitempty"`
+	PrefixOverrides map[string]overrideJSON      `json:"prefixOverrides,omitempty"`
+	Notes           map[string]ratelimit.KeyNote `json:"notes,omitempty"`
+}
+
+// overrideJSON is the stored form of a ratelimit.Override. The rate is a
+// string, since JSON numbers cannot hold rate.Inf.
+type overrideJSON struct {
+	Rate  string    `json:"rate"`
+	Burst int       `json:"burst"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Version implements ratelimit.ControlSync.
+func (s *controlSync) Version(ctx context.Context) (int64, error) {
+	res, err := s.client.run(ctx, versionScript, []string{s.version})
+	if err != nil {
+		return 0, err
+	}
+	return int64Of(res)
+}
+
+// Load implements ratelimit.ControlSync.
+func (s *controlSync) Load(ctx context.Context) (ratelimit.ControlSnapshot, error) {
+	var snap ratelimit.ControlSnapshot
+	res, err := s.client.run(ctx, loadScript, []string{s.version, s.data})
+	if err != nil {
+		return snap, err
+	}
+	values, _ := res.([]interface{})
+	if len(values) != 2 {
+		return snap, fmt.Errorf("redisstore: unexpected script result %T", res)
+	}
+	version, _ := values[0].(string)
+	data, _ := values[1].(string)
+	if version == "" || data == "" {
+		return snap, nil
+	}
+	if snap.Version, err = strconv.ParseInt(version, 10, 64); err != nil {
+		return snap, err
+	}
+	var c controlsJSON
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return snap, err
+	}
+	snap.Bans, snap.PrefixBans, snap.Notes = c.Bans, c.PrefixBans, c.Notes
+	if snap.Overrides, err = decodeOverrides(c.Overrides); err != nil {
+		return snap, err
+	}
+	snap.PrefixOverrides, err = decodeOverrides(c.PrefixOverrides)
+	return snap, err
+}
+
+// Publish implements ratelimit.ControlSync.
+func (s *controlSync) Publish(ctx context.Context, snap ratelimit.ControlSnapshot) (int64, error) {
+	data, err := json.Marshal(controlsJSON{
+		Bans:            snap.Bans,
+		Overrides:       encodeOverrides(snap.Overrides),
+		PrefixBans:      snap.PrefixBans,
+		PrefixOverrides: encodeOverrides(snap.PrefixOverrides),
+		Notes:           snap.Notes,
+	})
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.client.run(ctx, publishScript, []string{s.version, s.data}, data, snap.Version)
+	if err != nil {
+		return 0, err
+	}
+	version, err := int64Of(res)
+	if err == nil && version < 0 {
+		return 0, ratelimit.ErrControlsChanged
+	}
+	return version, err
+}
+
+func encodeOverrides(overrides map[string]ratelimit.Override) map[string]overrideJSON {
+	encoded := make(map[string]overrideJSON, len(overrides))
+	for key, o := range overrides {
+		encoded[key] = overrideJSON{
+			Rate:  strconv.FormatFloat(float64(o.Rate), 'g', -1, 64),
+			Burst: o.Burst,
+			Start: o.Start,
+			End:   o.End,
+		}
+	}
+	return encoded
+}
+
+func decodeOverrides(encoded map[string]overrideJSON) (map[string]ratelimit.Override, error) {
+	overrides := make(map[string]ratelimit.Override, len(encoded))
+	for key, o := range encoded {
+		r, err := strconv.ParseFloat(o.Rate, 64)
+		if err != nil {
+			return nil, err
+		}
+		overrides[key] = ratelimit.Override{Rate: rate.Limit(r), Burst: o.Burst, Start: o.Start, End: o.End}
+	}
+	return overrides, nil
+}
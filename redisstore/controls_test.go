@@ -0,0 +1,109 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestControlSync(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s := NewControlSync(client, "ratelimit:controls")
+
+	version, err := s.Version(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, version)
+	snap, err := s.Load(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, snap.Version)
+
+	until := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	version, err = s.Publish(ctx, ratelimit.ControlSnapshot{
+		Bans:            map[string]ratelimit.Ban{"mallory": {}},
+		Overrides:       map[string]ratelimit.Override{"alice": {Rate: rate.Inf, Burst: 5, End: until}},
+		PrefixBans:      map[string]ratelimit.Ban{"bot:": {Until: until}},
+		PrefixOverrides: map[string]ratelimit.Override{"org:": {Rate: 2.5, Burst: 10}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	version, err = s.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+	snap, err = s.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), snap.Version)
+	assert.Equal(t, map[string]ratelimit.Ban{"mallory": {}}, snap.Bans)
+	assert.Equal(t, rate.Inf, snap.Overrides["alice"].Rate)
+	assert.True(t, until.Equal(snap.Overrides["alice"].End))
+	assert.True(t, until.Equal(snap.PrefixBans["bot:"].Until))
+	assert.Equal(t, map[string]ratelimit.Override{"org:": {Rate: 2.5, Burst: 10}}, snap.PrefixOverrides)
+
+	// A snapshot based on an older version is not stored.
+	_, err = s.Publish(ctx, ratelimit.ControlSnapshot{})
+	require.ErrorIs(t, err, ratelimit.ErrControlsChanged)
+	snap, err = s.Load(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, snap.Bans, "mallory")
+
+	// Managers sharing the server share their controls.
+	newManager := func() *ratelimit.Manager {
+		return ratelimit.NewManager(ratelimit.Options{
+			Rate:           rate.Every(time.Second),
+			Burst:          1,
+			SharedControls: &ratelimit.SharedControls{Sync: s},
+		})
+	}
+	a, b := newManager(), newManager()
+	require.NoError(t, a.Ban(ctx, "203.0.113.9", time.Hour))
+	require.NoError(t, b.SyncControls(ctx))
+	ev, err := b.Evaluate(ratelimit.SyntheticRequest{IP: "203.0.113.9"})
+	require.NoError(t, err)
+	assert.True(t, ev.Banned)
+}
+
+func TestControlSyncConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s := NewControlSync(client, "ratelimit:controls")
+	managers := make([]*ratelimit.Manager, 2)
+	for i := range managers {
+		managers[i] = ratelimit.NewManager(ratelimit.Options{
+			Rate:           rate.Every(time.Second),
+			Burst:          1,
+			SharedControls: &ratelimit.SharedControls{Sync: s},
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i, m := range managers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				assert.NoError(t, m.Ban(ctx, fmt.Sprintf("m%d:k%d", i, j), 0))
+			}
+		}()
+	}
+	wg.Wait()
+
+	snap, err := s.Load(ctx)
+	require.NoError(t, err)
+	assert.Len(t, snap.Bans, 20)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// TestAlgorithmEquivalence checks that every algorithm kept in Redis makes
+// the same decisions as its in-memory counterpart for random sequences of
+// requests, so the backends cannot drift apart.
+func TestAlgorithmEquivalence(t *testing.T) {
+	ctx := context.Background()
+	algorithms := map[string]struct {
+		memory func() ratelimit.Algorithm
+		redis  func(client *redis.Client) ratelimit.Algorithm
+	}{
+		"GCRA": {
+			ratelimit.GCRA,
+			func(client *redis.Client) ratelimit.Algorithm { return NewGCRA(client) },
+		},
+		"FixedWindow": {
+			func() ratelimit.Algorithm { return ratelimit.FixedWindow(time.Second) },
+			func(client *redis.Client) ratelimit.Algorithm { return NewFixedWindow(client, time.Second) },
+		},
+		"FixedWindowOfBurst": {
+			func() ratelimit.Algorithm { return ratelimit.FixedWindow(0) },
+			func(client *redis.Client) ratelimit.Algorithm { return NewFixedWindow(client, 0) },
+		},
+		"SlidingWindowLog": {
+			ratelimit.SlidingWindowLog,
+			func(client *redis.Client) ratelimit.Algorithm { return NewSlidingWindowLog(client) },
+		},
+	}
+	// The limits of the keys, with intervals of whole microseconds, the
+	// unit of the Redis scripts.
+	limits := []ratelimit.Level{
+		{Rate: rate.Every(100 * time.Millisecond), Burst: 5},
+		{Rate: rate.Every(time.Second), Burst: 1},
+		{Rate: 4, Burst: 8},
+		{Rate: 0, Burst: 3},
+		{Rate: rate.Inf, Burst: 2},
+	}
+	shared := ratelimit.Level{Key: "shared", Rate: 20, Burst: 10}
+
+	for name, alg := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			allowed, denied := 0, 0
+			for seed := int64(1); seed <= 20; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				mr := miniredis.RunT(t)
+				client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+				memory := alg.memory().(ratelimit.LevelAlgorithm)
+				red := alg.redis(client).(ratelimit.LevelAlgorithm)
+
+				now := time.Now().Truncate(time.Second)
+				for step := 0; step < 200; step++ {
+					now = now.Add(time.Duration(rng.Intn(300)) * time.Millisecond)
+					k := rng.Intn(len(limits))
+					lv := limits[k]
+					lv.Key = fmt.Sprintf("k%d", k)
+					levels := []ratelimit.Level{lv}
+					if rng.Intn(2) == 0 {
+						levels = append(levels, shared)
+					}
+					n := rng.Intn(4)
+
+					want, wi, err := memory.TakeLevels(ctx, levels, n, now)
+					require.NoError(t, err)
+					got, gi, err := red.TakeLevels(ctx, levels, n, now)
+					require.NoError(t, err)
+					require.Equal(t, want, got, "seed %d, step %d: %d from %v", seed, step, n, levels)
+					require.Equal(t, wi, gi, "seed %d, step %d: %d from %v", seed, step, n, levels)
+					if got.Allowed {
+						allowed++
+					} else {
+						denied++
+					}
+				}
+			}
+			// The sequences exercise both outcomes.
+			require.Positive(t, allowed)
+			require.Positive(t, denied)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExample builds the example under _examples, runs it against an
+// in-process Redis server and checks that it serves requests.
+func TestExample(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping example programs in short mode")
+	}
+
+	bin := filepath.Join(t.TempDir(), "distributed")
+	out, err := exec.Command("go", "build", "-o", bin, "./_examples/distributed").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	mr := miniredis.RunT(t)
+	cmd := exec.Command(bin)
+	cmd.Env = append(os.Environ(), "ADDR="+addr, "REDIS_ADDR="+mr.Addr(), "GIN_MODE=release")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			return
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("example did not start: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
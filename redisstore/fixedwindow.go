@@ -0,0 +1,134 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// fixedWindowPrefix prefixes the Redis keys of the fixed window counters.
+const fixedWindowPrefix = "ratelimit:window:"
+
+// fixedWindowScript counts requests with HINCRBY in hashes holding the
+// start of the current window, in milliseconds, and its count, starting
+// over when the window changes. Hashes expire at the end of their window
+// from the moment it starts, whether or not its first requests are
+// allowed. Windows are given by their start and end, or with the server's
+// clock by their size, aligned as time.Truncate aligns them. The requests
+// are only counted if every key allows them. Its results are those
+// described by runLevels.
+var fixedWindowScript = newScript(layoutLua() + clockLua(time.Millisecond) + fmt.Sprintf(`
+local n = tonumber(ARGV[3])
+local need = math.max(n, 1)
+local res = {1}
+local expires = {}
+for i, key in ipairs(KEYS) do
+	local start = ARGV[4 * i]
+	local expire = tonumber(ARGV[4 * i + 1])
+	local limit = tonumber(ARGV[4 * i + 2])
+	local window = tonumber(ARGV[4 * i + 3])
+	if server and window > 0 then
+		local first = now - (now + %d) %% window
+		start, expire = string.format('%%d', first), first + window
+	end
+	local count = 0
+	if redis.call('HGET', key, 'start') ~= start then
+		redis.call('HSET', key, 'start', start, 'count', 0)
+		if expire > 0 then
+			redis.call('PEXPIREAT', key, expire)
+		end
+	else
+		count = tonumber(redis.call('HGET', key, 'count'))
+	end
+	local allowed, retry = 1, 0
+	if count + need > limit then
+		allowed, retry, res[1] = 0, -1, 0
+		if need <= limit and expire > 0 then
+			retry = expire - now
+		end
+	end
+	res[3 * i - 1] = allowed
+	res[3 * i] = math.max(0, limit - count)
+	res[3 * i + 1] = retry
+	expires[i] = expire
+end
+if res[1] == 1 then
+	for i, key in ipairs(KEYS) do
+		redis.call('HINCRBY', key, 'count', n)
+		if expires[i] > 0 then
+			redis.call('PEXPIREAT', key, expires[i])
+		end
+		res[3 * i] = res[3 * i] - n
+	end
+end
+`, -time.Time{}.UnixMilli()) + hotKeysLua + `
+res[#res + 1] = clock
+return res
+`)
+
+// fixedWindow is the fixed window counter algorithm kept in Redis.
+type fixedWindow struct {
+	client scripter
+	config config
+	window time.Duration
+}
+
+// NewFixedWindow returns a ratelimit.Algorithm counting the requests of
+// every key in fixed windows in Redis, so all instances sharing the server
+// share the limit. Windows are sized as with ratelimit.FixedWindow and
+// aligned to the instances' clocks, or to the server's with
+// WithServerClock. The counters are hashes under "ratelimit:window:<key>",
+// which expire at the end of their window.
+func NewFixedWindow[C Cmd](client RedisClient[C], window time.Duration, opts ...Option) ratelimit.Algorithm {
+	return &fixedWindow{client: clientOf[C]{client}, config: newConfig(opts), window: window}
+}
+
+// Take implements ratelimit.Algorithm.
+func (f *fixedWindow) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (ratelimit.Allowance, error) {
+	a, _, err := f.TakeLevels(ctx, []ratelimit.Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements ratelimit.LevelAlgorithm, checking all levels in
+// one round trip. On Redis Cluster, the keys of the levels must hash to
+// the same slot.
+func (f *fixedWindow) TakeLevels(
+	ctx context.Context, levels []ratelimit.Level, n int, now time.Time,
+) (ratelimit.Allowance, int, error) {
+	return runLevels(ctx, f.client, fixedWindowScript, fixedWindowPrefix, levels, time.Millisecond, f.config, now,
+		[]interface{}{n},
+		func(_ int, lv ratelimit.Level) []interface{} {
+			window, limit := f.window, lv.Burst
+			if window > 0 {
+				limit = int(math.Round(float64(lv.Rate) * window.Seconds()))
+			} else if lv.Rate > 0 {
+				window = time.Duration(float64(lv.Burst) / float64(lv.Rate) * float64(time.Second))
+			}
+			var start, expire int64
+			if window > 0 {
+				start = now.Truncate(window).UnixMilli()
+				expire = now.Truncate(window).Add(window).UnixMilli()
+			}
+			return []interface{}{start, expire, limit, window.Milliseconds()}
+		})
+}
+
+// Reset implements ratelimit.Algorithm.
+func (f *fixedWindow) Reset(ctx context.Context, key string) error {
+	return del(ctx, f.client, fixedWindowPrefix+key)
+}
+
+// Skew implements ratelimit.SkewReporter.
+func (f *fixedWindow) Skew() *ratelimit.SkewStats {
+	return f.config.skew.stats()
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedWindow(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	start := time.Now().Truncate(time.Minute)
+	r := ratelimit.Per(3, time.Minute)
+
+	// Two instances sharing the server share the limit.
+	a, b := NewFixedWindow(client, time.Minute), NewFixedWindow(client, time.Minute)
+	for i, alg := range []ratelimit.Algorithm{a, b, a} {
+		res, err := alg.Take(ctx, "k", r, 1, 1, start.Add(time.Duration(i)*10*time.Second))
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, err := b.Take(ctx, "k", r, 1, 1, start.Add(45*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 15*time.Second, res.RetryAfter)
+	assert.True(t, mr.Exists("ratelimit:window:k"))
+
+	// The next window starts over, even before the counter expires.
+	res, err = a.Take(ctx, "k", r, 1, 2, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, err = a.Take(ctx, "k", r, 1, 4, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Negative(t, res.RetryAfter)
+
+	require.NoError(t, b.Reset(ctx, "k"))
+	res, err = a.Take(ctx, "k", r, 1, 0, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+
+	// Windows whose first requests are denied expire all the same, by
+	// themselves or because another level denies them.
+	res, err = a.Take(ctx, "denied", r, 1, 4, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Positive(t, mr.TTL("ratelimit:window:denied"))
+	_, i, err := a.(ratelimit.LevelAlgorithm).TakeLevels(ctx, []ratelimit.Level{
+		{Key: "fresh", Rate: r, Burst: 1},
+		{Key: "denied", Rate: r, Burst: 1},
+	}, 4, start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, i)
+	assert.Positive(t, mr.TTL("ratelimit:window:fresh"))
+	mr.FastForward(2 * time.Minute)
+	assert.False(t, mr.Exists("ratelimit:window:denied"))
+	assert.False(t, mr.Exists("ratelimit:window:fresh"))
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// gcraPrefix prefixes the Redis keys of the GCRA arrival times.
+const gcraPrefix = "ratelimit:gcra:"
+
+// gcraScript keeps the theoretical arrival time of every key, in
+// microseconds, as a plain string that expires once the bucket is full
+// again. Every key has an interval, a tolerance and a cost, the requests
+// taken from it. Arrival times are only advanced if every key allows its
+// cost; a zero cost only checks that the key allows one request. A
+// non-positive interval denies every request. Its results are those
+// described by runLevels.
+var gcraScript = newScript(layoutLua() + clockLua(time.Microsecond) + `
+local res = {1}
+local tats = {}
+for i, key in ipairs(KEYS) do
+	local interval = tonumber(ARGV[3 * i])
+	local tolerance = tonumber(ARGV[3 * i + 1])
+	local need = math.max(tonumber(ARGV[3 * i + 2]), 1)
+	local allowed, remaining, retry = 0, 0, -1
+	if interval > 0 then
+		local tat = math.max(tonumber(redis.call('GET', key) or now), now)
+		tats[i] = tat
+		remaining = math.max(0, math.floor((tolerance - (tat - now)) / interval))
+		local wait = tat + need * interval - now - tolerance
+		if wait <= 0 then
+			allowed, retry = 1, 0
+		elseif need * interval <= tolerance then
+			retry = wait
+		end
+	end
+	if allowed == 0 then
+		res[1] = 0
+	end
+	res[3 * i - 1] = allowed
+	res[3 * i] = remaining
+	res[3 * i + 1] = retry
+end
+if res[1] == 1 then
+	for i, key in ipairs(KEYS) do
+		local n = tonumber(ARGV[3 * i + 2])
+		if n > 0 then
+			local tat = tats[i] + n * tonumber(ARGV[3 * i])
+			redis.call('SET', key, string.format('%d', tat), 'PX', math.ceil((tat - now) / 1000))
+			res[3 * i] = math.max(0, res[3 * i] - n)
+		end
+	end
+end
+` + hotKeysLua + `
+res[#res + 1] = clock
+return res
+`)
+
+// gcra is the generic cell rate algorithm kept in Redis.
+type gcra struct {
+	client scripter
+	config config
+}
+
+// NewGCRA returns a ratelimit.Algorithm implementing the generic cell rate
+// algorithm in Redis, so all instances sharing the server share the limit.
+// Its state per key is a single timestamp under "ratelimit:gcra:<key>",
+// updated by one script, so unlike token buckets it stays exact under
+// concurrent requests. Request times come from the instances' clocks,
+// unless WithServerClock is given.
+func NewGCRA[C Cmd](client RedisClient[C], opts ...Option) ratelimit.Algorithm {
+	return &gcra{client: clientOf[C]{client}, config: newConfig(opts)}
+}
+
+// Take implements ratelimit.Algorithm.
+func (g *gcra) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (ratelimit.Allowance, error) {
+	a, _, err := g.TakeLevels(ctx, []ratelimit.Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements ratelimit.LevelAlgorithm, checking all levels in
+// one round trip. On Redis Cluster, the keys of the levels must hash to
+// the same slot.
+func (g *gcra) TakeLevels(
+	ctx context.Context, levels []ratelimit.Level, n int, now time.Time,
+) (ratelimit.Allowance, int, error) {
+	costs := make([]int, len(levels))
+	for i := range costs {
+		costs[i] = n
+	}
+	return g.TakeCosts(ctx, levels, costs, now)
+}
+
+// TakeCosts implements ratelimit.CostAlgorithm, charging all levels in one
+// round trip. On Redis Cluster, the keys of the levels must hash to the
+// same slot.
+func (g *gcra) TakeCosts(
+	ctx context.Context, levels []ratelimit.Level, costs []int, now time.Time,
+) (ratelimit.Allowance, int, error) {
+	return runLevels(ctx, g.client, gcraScript, gcraPrefix, levels, time.Microsecond, g.config, now, nil,
+		func(i int, lv ratelimit.Level) []interface{} {
+			var interval int64
+			if lv.Rate > 0 {
+				interval = max(1, int64(1e6/float64(lv.Rate)))
+			}
+			return []interface{}{interval, interval * int64(lv.Burst), costs[i]}
+		})
+}
+
+// Reset implements ratelimit.Algorithm.
+func (g *gcra) Reset(ctx context.Context, key string) error {
+	return del(ctx, g.client, gcraPrefix+key)
+}
+
+// Skew implements ratelimit.SkewReporter.
+func (g *gcra) Skew() *ratelimit.SkewStats {
+	return g.config.skew.stats()
+}
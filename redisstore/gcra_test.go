@@ -0,0 +1,96 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestGCRA(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	start := time.Now()
+	r := rate.Every(10 * time.Second)
+
+	// Two instances sharing the server share the limit.
+	a, b := NewGCRA(client), NewGCRA(client)
+	for i, alg := range []ratelimit.Algorithm{a, b, a} {
+		res, err := alg.Take(ctx, "k", r, 3, 1, start)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, err := b.Take(ctx, "k", r, 3, 1, start.Add(4*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 6*time.Second, res.RetryAfter)
+
+	// The state is a single timestamp, which expires with the bucket full.
+	tat, err := mr.Get("ratelimit:gcra:k")
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(start.Add(30*time.Second).UnixMicro(), 10), tat)
+	assert.Positive(t, mr.TTL("ratelimit:gcra:k"))
+
+	res, err = a.Take(ctx, "k", r, 3, 1, start.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = a.Take(ctx, "k", r, 3, 4, start.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Negative(t, res.RetryAfter)
+
+	res, err = a.Take(ctx, "k", 0, 3, 1, start)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	require.NoError(t, b.Reset(ctx, "k"))
+	res, err = a.Take(ctx, "k", r, 3, 0, start.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
+
+func TestGCRACosts(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	start := time.Now()
+	alg := NewGCRA(client).(ratelimit.CostAlgorithm)
+	levels := []ratelimit.Level{
+		{Key: "user:42", Rate: ratelimit.Per(10, time.Minute), Burst: 10},
+		{Key: "org:acme", Rate: ratelimit.Per(100, time.Minute), Burst: 100},
+	}
+
+	a, _, err := alg.TakeCosts(ctx, levels, []int{1, 60}, start)
+	require.NoError(t, err)
+	assert.True(t, a.Allowed)
+	assert.Equal(t, 9, a.Remaining)
+
+	// The organization cannot afford 60 more, so the user is not charged.
+	a, i, err := alg.TakeCosts(ctx, levels, []int{1, 60}, start)
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, 40, a.Remaining)
+
+	a, _, err = alg.TakeCosts(ctx, levels, []int{0, 0}, start)
+	require.NoError(t, err)
+	assert.True(t, a.Allowed)
+	assert.Equal(t, 9, a.Remaining)
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// hotKeysPrefix prefixes the Redis keys of the request counts of
+// WithHotKeys, one sorted set per period.
+const hotKeysPrefix = "ratelimit:hotkeys:"
+
+// WithHotKeys makes the scripts count the requests of every key over
+// periods of d, along with the limits the key was checked against, so the
+// algorithm's HotKeys method can report the busiest keys of the current
+// and previous periods to Manager.Hydrate. The counts of a period are kept
+// under "ratelimit:hotkeys:<period>" and expire after two periods. As they
+// are written by the scripts checking the levels, WithHotKeys cannot be
+// used on Redis Cluster.
+func WithHotKeys(d time.Duration) Option {
+	return func(c *config) { c.hotKeyPeriod = max(d, time.Millisecond) }
+}
+
+// hotKeysLua counts a request of every key of a script in the sorted set
+// named by the next to last argument, with a time to live in milliseconds
+// of the last one, which is empty if the counts are not kept. The members,
+// one per key, come before them.
+const hotKeysLua = `
+if ARGV[#ARGV] ~= '' then
+	local hot = ARGV[#ARGV - 1]
+	for i = 1, #KEYS do
+		redis.call('ZINCRBY', hot, 1, ARGV[#ARGV - 2 - #KEYS + i])
+	end
+	redis.call('PEXPIRE', hot, ARGV[#ARGV])
+end`
+
+// hotKeysScript returns the members and counts of the busiest ARGV[1]
+// members of every sorted set.
+var hotKeysScript = newScript(`
+local res = {}
+for _, key in ipairs(KEYS) do
+	local top = redis.call('ZREVRANGE', key, 0, tonumber(ARGV[1]) - 1, 'WITHSCORES')
+	for i = 1, #top, 2 do
+		res[#res + 1] = top[i]
+		res[#res + 1] = tonumber(top[i + 1])
+	end
+end
+return res
+`)
+
+// hotKeysKey returns the key of the sorted set of the period containing t.
+func (c config) hotKeysKey(t time.Time) string {
+	return hotKeysPrefix + strconv.FormatInt(t.UnixNano()/int64(c.hotKeyPeriod), 10)
+}
+
+// hotKeyArgs returns the arguments of hotKeysLua for the checked levels of
+// a request at t.
+func (c config) hotKeyArgs(levels []ratelimit.Level, checked []int, t time.Time) []interface{} {
+	if c.hotKeyPeriod == 0 {
+		return []interface{}{""}
+	}
+	args := make([]interface{}, 0, len(checked)+2)
+	for _, i := range checked {
+		args = append(args, hotKeyMember(levels[i]))
+	}
+	return append(args, c.hotKeysKey(t), (2 * c.hotKeyPeriod).Milliseconds())
+}
+
+// hotKeyMember returns the member counting the requests of lv: its rate,
+// its burst and its key, separated by spaces.
+func hotKeyMember(lv ratelimit.Level) string {
+	return fmt.Sprintf("%s %d %s", strconv.FormatFloat(float64(lv.Rate), 'g', -1, 64), lv.Burst, lv.Key)
+}
+
+// parseHotKeyMember parses a member of hotKeyMember.
+func parseHotKeyMember(member string) (ratelimit.HotKey, error) {
+	fields := strings.SplitN(member, " ", 3)
+	if len(fields) != 3 {
+		return ratelimit.HotKey{}, fmt.Errorf("redisstore: invalid hot key %q", member)
+	}
+	r, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return ratelimit.HotKey{}, fmt.Errorf("redisstore: invalid hot key %q: %w", member, err)
+	}
+	burst, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return ratelimit.HotKey{}, fmt.Errorf("redisstore: invalid hot key %q: %w", member, err)
+	}
+	return ratelimit.HotKey{Key: fields[2], Rate: rate.Limit(r), Burst: burst}, nil
+}
+
+// hotKeys returns up to n of the keys with the most requests in the
+// current and previous periods, busiest first. A key checked against
+// several limits is reported with those it was checked against the most.
+func hotKeys(ctx context.Context, client scripter, c config, n int) ([]ratelimit.HotKey, error) {
+	if c.hotKeyPeriod == 0 || n <= 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	out, err := client.run(ctx, hotKeysScript,
+		[]string{c.hotKeysKey(now), c.hotKeysKey(now.Add(-c.hotKeyPeriod))}, n)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := out.([]interface{})
+	if !ok || len(values)%2 != 0 {
+		return nil, fmt.Errorf("redisstore: unexpected script result %T", out)
+	}
+
+	counts := make(map[string]int64)
+	var keys []ratelimit.HotKey
+	for i := 0; i < len(values); i += 2 {
+		member, ok := values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("redisstore: unexpected script result %T", values[i])
+		}
+		count, err := int64Of(values[i+1])
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseHotKeyMember(member)
+		if err != nil {
+			return nil, err
+		}
+		counts[member] += count
+		k.Requests = counts[member]
+		keys = append(keys, k)
+	}
+
+	// Keep the busiest member of every key, with its counts of both
+	// periods.
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Requests > keys[j].Requests })
+	seen := make(map[string]bool)
+	hot := keys[:0]
+	for _, k := range keys {
+		if !seen[k.Key] {
+			seen[k.Key] = true
+			hot = append(hot, k)
+		}
+	}
+	return hot[:min(n, len(hot))], nil
+}
+
+// HotKeys implements ratelimit.HotKeyReporter, with WithHotKeys.
+func (g *gcra) HotKeys(ctx context.Context, n int) ([]ratelimit.HotKey, error) {
+	return hotKeys(ctx, g.client, g.config, n)
+}
+
+// HotKeys implements ratelimit.HotKeyReporter, with WithHotKeys.
+func (f *fixedWindow) HotKeys(ctx context.Context, n int) ([]ratelimit.HotKey, error) {
+	return hotKeys(ctx, f.client, f.config, n)
+}
+
+// HotKeys implements ratelimit.HotKeyReporter, with WithHotKeys.
+func (s *slidingWindowLog) HotKeys(ctx context.Context, n int) ([]ratelimit.HotKey, error) {
+	return hotKeys(ctx, s.client, s.config, n)
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotKeys(t *testing.T) {
+	ctx := context.Background()
+	heavy := ratelimit.Level{Key: "heavy", Rate: 0.5, Burst: 3}
+	light := ratelimit.Level{Key: "light user", Rate: 2, Burst: 4}
+	org := ratelimit.Level{Key: "org", Rate: 10, Burst: 20}
+
+	for name, newAlg := range map[string]func(client *redis.Client) ratelimit.Algorithm{
+		"GCRA": func(client *redis.Client) ratelimit.Algorithm { return NewGCRA(client, WithHotKeys(time.Minute)) },
+		"FixedWindow": func(client *redis.Client) ratelimit.Algorithm {
+			return NewFixedWindow(client, time.Second, WithHotKeys(time.Minute))
+		},
+		"SlidingWindowLog": func(client *redis.Client) ratelimit.Algorithm {
+			return NewSlidingWindowLog(client, WithHotKeys(time.Minute))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			alg := newAlg(client).(ratelimit.LevelAlgorithm)
+			now := time.Now()
+			for range 5 {
+				_, _, err := alg.TakeLevels(ctx, []ratelimit.Level{heavy, org}, 1, now)
+				require.NoError(t, err)
+			}
+			_, _, err := alg.TakeLevels(ctx, []ratelimit.Level{light, org}, 1, now)
+			require.NoError(t, err)
+
+			hot, err := alg.(ratelimit.HotKeyReporter).HotKeys(ctx, 2)
+			require.NoError(t, err)
+			assert.Equal(t, []ratelimit.HotKey{
+				{Key: "org", Rate: 10, Burst: 20, Requests: 6},
+				{Key: "heavy", Rate: 0.5, Burst: 3, Requests: 5},
+			}, hot)
+
+			hot, err = alg.(ratelimit.HotKeyReporter).HotKeys(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, hot, 3)
+			assert.Equal(t, ratelimit.HotKey{Key: "light user", Rate: 2, Burst: 4, Requests: 1}, hot[2])
+		})
+	}
+
+	t.Run("Limits", func(t *testing.T) {
+		// A key is reported with the limits it was checked against the
+		// most, over both periods.
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		alg := NewGCRA(client, WithHotKeys(time.Minute)).(*gcra)
+		old := ratelimit.Level{Key: "k", Rate: 1, Burst: 1}
+		now := time.Now()
+		for range 3 {
+			_, err := alg.Take(ctx, old.Key, old.Rate, old.Burst, 0, now)
+			require.NoError(t, err)
+		}
+		// Take counts in the period of the request.
+		_, err := alg.Take(ctx, "k", 2, 2, 0, now.Add(-time.Minute))
+		require.NoError(t, err)
+		_, err = alg.Take(ctx, "k", 2, 2, 0, now.Add(-time.Minute))
+		require.NoError(t, err)
+
+		hot, err := alg.HotKeys(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []ratelimit.HotKey{{Key: "k", Rate: 1, Burst: 1, Requests: 3}}, hot)
+
+		// The counts expire after two periods.
+		mr.FastForward(2 * time.Minute)
+		hot, err = alg.HotKeys(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, hot)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		alg := NewGCRA(client)
+		_, err := alg.Take(ctx, "k", 1, 1, 1, time.Now())
+		require.NoError(t, err)
+		hot, err := alg.(ratelimit.HotKeyReporter).HotKeys(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, hot)
+		assert.Equal(t, []string{"ratelimit:gcra:k", layoutKey}, mr.Keys())
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// LayoutVersion is the newest layout of the state the scripts of this
+// version of the package write. They read the previous layout too, and
+// write the layout recorded under "ratelimit:layout": the previous one
+// until UpgradeLayout records LayoutVersion, so fleets mixing two versions
+// during a rolling deploy neither reset limits nor fail to decode each
+// other's state. Layout 0 is the state written before the meta key
+// existed, which layout 1 encodes the same way.
+const LayoutVersion = 1
+
+// layoutKey holds the layout of the state shared in Redis.
+const layoutKey = "ratelimit:layout"
+
+// layoutLua returns the start of a script setting layout to the layout
+// recorded in the meta key, recording the previous layout if there is
+// none, and failing if the layout cannot be read. The meta key is not
+// one of the script's keys, so on Redis Cluster, where the node of the
+// script's keys may not hold it, the scripts do without it.
+func layoutLua() string {
+	return fmt.Sprintf(`
+local layout = redis.pcall('GET', '%[1]s')
+if type(layout) == 'table' then
+	layout = %[3]d
+elseif not layout then
+	redis.pcall('SET', '%[1]s', %[3]d, 'NX')
+	layout = %[3]d
+else
+	layout = tonumber(layout)
+	if layout > %[2]d or layout < %[3]d then
+		return redis.error_reply('redisstore: unsupported state layout ' .. layout)
+	end
+end`, layoutKey, LayoutVersion, max(LayoutVersion-1, 0))
+}
+
+// upgradeLayoutScript raises the recorded layout to ARGV[1].
+var upgradeLayoutScript = newScript(`
+local layout = tonumber(redis.call('GET', KEYS[1]) or 0)
+if layout < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return layout
+`)
+
+// UpgradeLayout makes the scripts write LayoutVersion from now on. Run it
+// once every instance sharing the server runs a version of the package
+// reading LayoutVersion; until then, the scripts keep writing the layout
+// older instances read.
+func UpgradeLayout[C Cmd](ctx context.Context, client RedisClient[C]) error {
+	_, err := clientOf[C]{client}.run(ctx, upgradeLayoutScript, []string{layoutKey}, LayoutVersion)
+	return err
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestLayout(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	alg := NewGCRA(client)
+	now := time.Now()
+	r := rate.Every(time.Minute)
+
+	// State written before the meta key existed is read as it is.
+	tat := now.Add(time.Minute).UnixMicro()
+	require.NoError(t, mr.Set(gcraPrefix+"k", strconv.FormatInt(tat, 10)))
+	a, err := alg.Take(ctx, "k", r, 1, 1, now)
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+	assert.InDelta(t, time.Minute, a.RetryAfter, float64(time.Millisecond))
+
+	// The first script records the previous layout, which UpgradeLayout
+	// raises once the fleet reads the new one.
+	layout, err := mr.Get(layoutKey)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(LayoutVersion-1), layout)
+	require.NoError(t, UpgradeLayout(ctx, client))
+	layout, err = mr.Get(layoutKey)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(LayoutVersion), layout)
+	a, err = alg.Take(ctx, "k", r, 1, 1, now)
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+
+	// Layouts newer than the package's are not decoded.
+	require.NoError(t, mr.Set(layoutKey, strconv.Itoa(LayoutVersion+1)))
+	_, err = alg.Take(ctx, "k", r, 1, 1, now)
+	assert.ErrorContains(t, err, "unsupported state layout")
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// runLevels checks the limited levels at now with one run of script, which
+// gets the keys of the levels, the arguments of clockLua and head followed
+// by the arguments of every level, given its index, and those of
+// hotKeysLua. The script returns whether all levels allowed the requests,
+// followed by whether each level allowed them, its remaining requests and
+// its wait in units, or -1 if the requests never will be allowed, and last
+// the clock of clockLua.
+func runLevels(
+	ctx context.Context, client scripter, script *script, prefix string, levels []ratelimit.Level,
+	unit time.Duration, cfg config, now time.Time, head []interface{}, args func(int, ratelimit.Level) []interface{},
+) (ratelimit.Allowance, int, error) {
+	as := make([]ratelimit.Allowance, len(levels))
+	var (
+		keys    []string
+		checked []int
+	)
+	argv := append(cfg.clockArgs(now, unit), head...)
+	for i, lv := range levels {
+		if lv.Rate == rate.Inf {
+			as[i] = ratelimit.Allowance{Allowed: true, Remaining: lv.Burst}
+			continue
+		}
+		keys = append(keys, prefix+lv.Key)
+		argv = append(argv, args(i, lv)...)
+		checked = append(checked, i)
+	}
+	argv = append(argv, cfg.hotKeyArgs(levels, checked, now)...)
+	if len(keys) > 0 {
+		out, err := client.run(ctx, script, keys, argv...)
+		if err != nil {
+			return ratelimit.Allowance{}, 0, err
+		}
+		res, err := int64s(out)
+		if err != nil {
+			return ratelimit.Allowance{}, 0, err
+		}
+		cfg.observe(now, res[len(res)-1], unit)
+		for j, i := range checked {
+			lr := res[1+3*j:]
+			as[i] = ratelimit.Allowance{Allowed: lr[0] == 1, Remaining: int(lr[1]), RetryAfter: -1}
+			if lr[2] >= 0 {
+				as[i].RetryAfter = time.Duration(lr[2]) * unit
+			}
+		}
+	}
+	i := ratelimit.MostRestrictive(as)
+	return as[i], i, nil
+}
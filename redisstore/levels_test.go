@@ -0,0 +1,70 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestTakeLevels(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	start := time.Now().Truncate(time.Minute)
+	levels := func(user string) []ratelimit.Level {
+		return []ratelimit.Level{
+			{Key: "org:acme", Rate: ratelimit.Per(4, time.Minute), Burst: 4},
+			{Key: user, Rate: ratelimit.Per(3, time.Minute), Burst: 3},
+			{Key: "unlimited", Rate: rate.Inf, Burst: 1},
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		alg   ratelimit.Algorithm
+		retry time.Duration
+	}{
+		"SlidingWindowLog": {NewSlidingWindowLog(client), time.Minute},
+		"FixedWindow":      {NewFixedWindow(client, 0), time.Minute},
+		"GCRA":             {NewGCRA(client), 15 * time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mr.FlushAll()
+			la := tt.alg.(ratelimit.LevelAlgorithm)
+
+			a, i, err := la.TakeLevels(ctx, levels("alice"), 2, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 1, i)
+			assert.Equal(t, 1, a.Remaining)
+
+			a, i, err = la.TakeLevels(ctx, levels("alice"), 2, start)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, 1, i)
+
+			// Denied requests count against neither level.
+			a, i, err = la.TakeLevels(ctx, levels("bob"), 2, start)
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 0, i)
+			assert.Equal(t, 0, a.Remaining)
+
+			a, i, err = la.TakeLevels(ctx, levels("carol"), 1, start)
+			require.NoError(t, err)
+			assert.False(t, a.Allowed)
+			assert.Equal(t, 0, i)
+			assert.Equal(t, tt.retry, a.RetryAfter)
+		})
+	}
+}
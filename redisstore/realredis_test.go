@@ -0,0 +1,109 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build redis
+
+// The tests of this file run against a real Redis server, at REDIS_ADDR
+// or localhost:6379, with the redis build tag:
+//
+//	docker compose -f redisstore/_examples/docker-compose.yml up -d redis
+//	go test -tags redis ./...
+
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// realClient returns a client of the Redis server of the tests.
+func realClient(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.Ping(context.Background()).Err(), "Redis at %s", addr)
+	return client
+}
+
+// realKey returns a key of its own for the test.
+func realKey(t *testing.T) string {
+	return fmt.Sprintf("test:%s:%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestRealRedis(t *testing.T) {
+	ctx := context.Background()
+	client := realClient(t)
+
+	t.Run("Expiry", func(t *testing.T) {
+		s := New(client).(*store)
+		key := realKey(t)
+		t.Cleanup(func() { _ = s.Reset(ctx, key) })
+		limit := ratelimit.Limit{Rate: rate.Every(100 * time.Millisecond), Burst: 2, N: 1}
+		for range 2 {
+			res, err := s.Allow(ctx, key, limit)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+		}
+		res, err := s.Allow(ctx, key, limit)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+
+		// The key expires once its bucket is full again.
+		ttl, err := client.PTTL(ctx, gcraPrefix+key).Result()
+		require.NoError(t, err)
+		assert.Positive(t, ttl)
+		assert.LessOrEqual(t, ttl, 200*time.Millisecond)
+		time.Sleep(ttl + 50*time.Millisecond)
+		assert.Zero(t, client.Exists(ctx, gcraPrefix+key).Val())
+	})
+
+	t.Run("Flushed scripts", func(t *testing.T) {
+		alg := NewGCRA(client)
+		key := realKey(t)
+		t.Cleanup(func() { _ = alg.Reset(ctx, key) })
+		require.NoError(t, client.ScriptFlush(ctx).Err())
+		a, err := alg.Take(ctx, key, 1, 1, 1, time.Now())
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+
+	t.Run("Concurrent instances", func(t *testing.T) {
+		// Instances sharing a key together allow exactly its burst.
+		key := realKey(t)
+		var allowed atomic.Int64
+		var wg sync.WaitGroup
+		for range 4 {
+			alg := NewGCRA(redis.NewClient(client.Options()))
+			t.Cleanup(func() { _ = alg.Reset(ctx, key) })
+			for range 25 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					a, err := alg.Take(ctx, key, rate.Every(time.Hour), 10, 1, time.Now())
+					assert.NoError(t, err)
+					if a.Allowed {
+						allowed.Add(1)
+					}
+				}()
+			}
+		}
+		wg.Wait()
+		assert.Equal(t, int64(10), allowed.Load())
+	})
+}
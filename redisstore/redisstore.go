@@ -0,0 +1,90 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package redisstore provides Redis backends for the ratelimit middleware.
+// It is a module of its own, so applications using the in-memory limiter
+// do not depend on go-redis. The backends take clients of go-redis v8 or
+// v9; see RedisClient. Importing it registers the redis and rediss schemes
+// with ratelimit.NewStoreFromDSN, which create go-redis v8 clients.
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+)
+
+func init() { //nolint:gochecknoinits // importing the module registers its DSN schemes
+	ratelimit.RegisterStore("redis", newFromDSN)
+	ratelimit.RegisterStore("rediss", newFromDSN)
+}
+
+// store is a Redis-based implementation of the ratelimit.Store interface.
+// It decides requests with the GCRA script, so instances sharing the
+// server share exact token buckets. It is also a ratelimit.Algorithm, so
+// organization levels are checked in one round trip.
+type store struct {
+	gcra
+}
+
+// New creates a new Redis-based store. The middleware limits requests
+// through Redis, with the same token bucket semantics as the in-memory
+// store; see NewGCRA.
+func New[C Cmd](client RedisClient[C], opts ...Option) ratelimit.Store {
+	return &store{gcra: gcra{client: clientOf[C]{client}, config: newConfig(opts)}}
+}
+
+// Allow implements ratelimit.Store.
+func (s *store) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return s.Take(ctx, key, limit.Rate, limit.Burst, limit.N, time.Now())
+}
+
+// newFromDSN creates a store from a redis:// or rediss:// DSN, accepting
+// the options of redis.ParseURL.
+func newFromDSN(dsn *url.URL) (ratelimit.Store, error) {
+	opts, err := redis.ParseURL(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	return New(redis.NewClient(opts)), nil
+}
+
+// xaddScript appends the field-value pairs in ARGV to the stream KEYS[1].
+var xaddScript = newScript(`return redis.call('XADD', KEYS[1], '*', unpack(ARGV))`)
+
+// auditSink is a ratelimit.AuditSink appending entries to a Redis Stream.
+type auditSink struct {
+	client scripter
+	stream string
+}
+
+// NewAuditSink creates a ratelimit.AuditSink that appends every entry to
+// the given Redis Stream, with the fields time, actor, action, key and
+// detail (a JSON object).
+func NewAuditSink[C Cmd](client RedisClient[C], stream string) ratelimit.AuditSink {
+	return &auditSink{
+		client: clientOf[C]{client},
+		stream: stream,
+	}
+}
+
+// Record appends the entry to the stream.
+func (s *auditSink) Record(ctx context.Context, e ratelimit.AuditEntry) error {
+	detail, err := json.Marshal(e.Detail)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.run(ctx, xaddScript, []string{s.stream},
+		"time", e.Time.UTC().Format(time.RFC3339Nano),
+		"actor", e.Actor,
+		"action", string(e.Action),
+		"key", e.Key,
+		"detail", string(detail),
+	)
+	return err
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// dsnClient returns the client of a store created from a DSN.
+func dsnClient(s ratelimit.Store) *redis.Client {
+	return s.(*store).client.(clientOf[*redis.Cmd]).client.(*redis.Client)
+}
+
+func TestNewStoreFromDSN(t *testing.T) {
+	assert.Subset(t, ratelimit.StoreSchemes(), []string{"redis", "rediss"})
+
+	t.Run("Redis", func(t *testing.T) {
+		s, err := ratelimit.NewStoreFromDSN("redis://:secret@localhost:6379/2")
+		require.NoError(t, err)
+		opts := dsnClient(s).Options()
+		assert.Equal(t, "localhost:6379", opts.Addr)
+		assert.Equal(t, "secret", opts.Password)
+		assert.Equal(t, 2, opts.DB)
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		s, err := ratelimit.NewStoreFromDSN("rediss://localhost:6380")
+		require.NoError(t, err)
+		assert.NotNil(t, dsnClient(s).Options().TLSConfig)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := ratelimit.NewStoreFromDSN("redis://localhost:6379/zero")
+		assert.Error(t, err)
+	})
+}
+
+func TestAuditSink(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	m := ratelimit.NewManager(ratelimit.Options{
+		Rate:      rate.Inf,
+		Burst:     1,
+		AuditSink: NewAuditSink(client, "ratelimit:audit"),
+	})
+
+	ctx := ratelimit.WithActor(context.Background(), "bob")
+	require.NoError(t, m.SetOverride(ctx, "k", ratelimit.Override{Rate: 5, Burst: 10}))
+
+	entries, err := client.XRange(context.Background(), "ratelimit:audit", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	values := entries[0].Values
+	assert.Equal(t, "bob", values["actor"])
+	assert.Equal(t, "override", values["action"])
+	assert.Equal(t, "k", values["key"])
+	assert.JSONEq(t, `{"rate": "5", "burst": "10"}`, values["detail"].(string))
+}
+
+func TestStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	// Two instances sharing the server share the limit.
+	var routers []*gin.Engine
+	for range 2 {
+		r := gin.New()
+		r.Use(ratelimit.New(ratelimit.Options{
+			Rate:  rate.Every(time.Minute),
+			Burst: 3,
+			Store: New(client),
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		routers = append(routers, r)
+	}
+	codes := make([]int, 0, 4)
+	for i := range 4 {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		w := httptest.NewRecorder()
+		routers[i%2].ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+
+	// Buckets expire once they are full again.
+	assert.True(t, mr.Exists("ratelimit:gcra:203.0.113.1"))
+	ttl := mr.TTL("ratelimit:gcra:203.0.113.1")
+	assert.Positive(t, ttl)
+	assert.LessOrEqual(t, ttl, 3*time.Minute)
+
+	t.Run("Allow", func(t *testing.T) {
+		s := New(client)
+		ctx := context.Background()
+		limit := ratelimit.Limit{Rate: rate.Every(time.Minute), Burst: 2, N: 1}
+		for range 2 {
+			res, err := s.Allow(ctx, "allow", limit)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+		}
+		res, err := s.Allow(ctx, "allow", limit)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Zero(t, res.Remaining)
+		assert.InDelta(t, time.Minute.Seconds(), res.RetryAfter.Seconds(), 1)
+	})
+}
+
+// storeRouter returns a router limited by s, whose handler responds with
+// the errors recorded by the middleware.
+func storeRouter(s ratelimit.Store, r rate.Limit, burst int) *gin.Engine {
+	router := gin.New()
+	router.Use(ratelimit.New(ratelimit.Options{Rate: r, Burst: burst, Store: s}))
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Errors.String())
+	})
+	return router
+}
+
+// serveStore sends a request from the client 203.0.113.1 to router.
+func serveStore(router *gin.Engine) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.1:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestStoreFailover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	router := storeRouter(New(client), rate.Every(time.Minute), 1)
+
+	w := serveStore(router)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// While the server is down, requests fail open with the error
+	// recorded in the context.
+	mr.Close()
+	for range 2 {
+		w = serveStore(router)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Body.String())
+	}
+
+	// Once it is back, the limit holds again with the state it kept.
+	require.NoError(t, mr.Restart())
+	assert.Equal(t, http.StatusTooManyRequests, serveStore(router).Code)
+}
+
+func TestStoreExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	router := storeRouter(New(client), rate.Every(time.Minute), 3)
+
+	for range 3 {
+		assert.Equal(t, http.StatusOK, serveStore(router).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serveStore(router).Code)
+
+	// Once the key expires, the whole burst is available again.
+	const key = "ratelimit:gcra:203.0.113.1"
+	ttl := mr.TTL(key)
+	require.Positive(t, ttl)
+	mr.FastForward(ttl + time.Millisecond)
+	assert.False(t, mr.Exists(key))
+	for range 3 {
+		assert.Equal(t, http.StatusOK, serveStore(router).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serveStore(router).Code)
+}
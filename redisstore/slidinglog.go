@@ -0,0 +1,121 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// slidingLogPrefix prefixes the Redis keys of the sliding window logs.
+const slidingLogPrefix = "ratelimit:log:"
+
+// slidingLogScript records requests in sorted sets scored by time, in
+// microseconds, dropping the requests that left the window first. The
+// requests are only recorded if every key allows them. Its results are
+// those described by runLevels.
+var slidingLogScript = newScript(layoutLua() + clockLua(time.Microsecond) + `
+local n = tonumber(ARGV[3])
+local need = math.max(n, 1)
+local res = {1}
+for i, key in ipairs(KEYS) do
+	local window = tonumber(ARGV[3 + 2 * i])
+	local limit = tonumber(ARGV[4 + 2 * i])
+	if window > 0 then
+		redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+	end
+	local count = redis.call('ZCARD', key)
+	local allowed, retry = 1, 0
+	if count + need > limit then
+		allowed, retry, res[1] = 0, -1, 0
+		if need <= limit and window > 0 then
+			local oldest = redis.call('ZRANGE', key, count + need - limit - 1, count + need - limit - 1, 'WITHSCORES')
+			retry = tonumber(oldest[2]) + window - now
+		end
+	end
+	res[3 * i - 1] = allowed
+	res[3 * i] = math.max(0, limit - count)
+	res[3 * i + 1] = retry
+end
+if res[1] == 1 then
+	for i, key in ipairs(KEYS) do
+		local window = tonumber(ARGV[3 + 2 * i])
+		for j = 1, n do
+			redis.call('ZADD', key, now, ARGV[4] .. ':' .. j)
+		end
+		if n > 0 and window > 0 then
+			redis.call('PEXPIRE', key, math.ceil(window / 1000))
+		end
+		res[3 * i] = res[3 * i] - n
+	end
+end
+` + hotKeysLua + `
+res[#res + 1] = clock
+return res
+`)
+
+// slidingWindowLog is the sliding window log algorithm kept in Redis.
+type slidingWindowLog struct {
+	client scripter
+	config config
+	// id and seq make the members of the sorted sets unique across
+	// processes.
+	id  string
+	seq atomic.Uint64
+}
+
+// NewSlidingWindowLog returns a ratelimit.Algorithm keeping a sliding
+// window log per key in Redis, so all instances sharing the server share
+// the limit. The logs are sorted sets under "ratelimit:log:<key>", which
+// expire once their requests leave the window. Request times come from the
+// instances' clocks, unless WithServerClock is given.
+func NewSlidingWindowLog[C Cmd](client RedisClient[C], opts ...Option) ratelimit.Algorithm {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return &slidingWindowLog{client: clientOf[C]{client}, config: newConfig(opts), id: hex.EncodeToString(id[:])}
+}
+
+// Take implements ratelimit.Algorithm.
+func (s *slidingWindowLog) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (ratelimit.Allowance, error) {
+	a, _, err := s.TakeLevels(ctx, []ratelimit.Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements ratelimit.LevelAlgorithm, checking all levels in
+// one round trip. On Redis Cluster, the keys of the levels must hash to
+// the same slot.
+func (s *slidingWindowLog) TakeLevels(
+	ctx context.Context, levels []ratelimit.Level, n int, now time.Time,
+) (ratelimit.Allowance, int, error) {
+	member := s.id + ":" + strconv.FormatUint(s.seq.Add(1), 36)
+	return runLevels(ctx, s.client, slidingLogScript, slidingLogPrefix, levels, time.Microsecond, s.config, now,
+		[]interface{}{n, member},
+		func(_ int, lv ratelimit.Level) []interface{} {
+			var window int64
+			if lv.Rate > 0 {
+				window = int64(float64(lv.Burst) / float64(lv.Rate) * 1e6)
+			}
+			return []interface{}{window, lv.Burst}
+		})
+}
+
+// Reset implements ratelimit.Algorithm.
+func (s *slidingWindowLog) Reset(ctx context.Context, key string) error {
+	return del(ctx, s.client, slidingLogPrefix+key)
+}
+
+// Skew implements ratelimit.SkewReporter.
+func (s *slidingWindowLog) Skew() *ratelimit.SkewStats {
+	return s.config.skew.stats()
+}
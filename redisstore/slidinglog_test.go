@@ -0,0 +1,59 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindowLog(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	start := time.Now()
+	r := ratelimit.Per(3, 30*time.Second)
+
+	// Two instances sharing the server share the limit.
+	a, b := NewSlidingWindowLog(client), NewSlidingWindowLog(client)
+	for i, alg := range []ratelimit.Algorithm{a, b, a} {
+		res, err := alg.Take(ctx, "k", r, 3, 1, start.Add(time.Duration(i)*10*time.Second))
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, err := b.Take(ctx, "k", r, 3, 1, start.Add(25*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+	res, err = a.Take(ctx, "k", r, 3, 1, start.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = a.Take(ctx, "k", r, 3, 4, start.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Negative(t, res.RetryAfter)
+
+	// Logs expire with their window.
+	assert.True(t, mr.Exists("ratelimit:log:k"))
+	assert.Positive(t, mr.TTL("ratelimit:log:k"))
+
+	require.NoError(t, a.Reset(ctx, "k"))
+	res, err = b.Take(ctx, "k", r, 3, 0, start.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
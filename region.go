@@ -0,0 +1,205 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Regions splits the limit of every key between the regions serving the
+// API. Each region enforces its share of Rate and Burst locally, so no
+// cross-region calls are made on the request path. Shares are rebalanced
+// by RunRegions or ReconcileRegions, so budget left unused by quiet regions
+// can be used by busy ones.
+type Regions struct {
+	// Local is the region served by this process.
+	Local string
+
+	// Shares maps every region to its configured fraction of the limit.
+	// Fractions are normalized to sum to one. Local must be present.
+	Shares map[string]float64
+
+	// Ledger exchanges observed demand between regions.
+	// If nil, shares are never rebalanced.
+	Ledger RegionLedger
+
+	// OnError is called when a reconciliation fails. The previous share is
+	// kept until the next successful reconciliation. If nil, errors are
+	// ignored.
+	OnError func(error)
+}
+
+// RegionLedger exchanges the demand observed by each region. It is
+// typically backed by an asynchronously replicated database, so reports
+// from other regions may lag behind.
+type RegionLedger interface {
+	// Report publishes the demand observed in region, in requests per second.
+	Report(ctx context.Context, region string, demand float64) error
+	// Demand returns the latest demand reported by every region.
+	Demand(ctx context.Context) (map[string]float64, error)
+}
+
+// regionBudget tracks the share of the limit enforced by the local region.
+type regionBudget struct {
+	cfg    Regions
+	shares map[string]float64
+
+	// share holds the bits of the current local share.
+	share    atomic.Uint64
+	requests atomic.Int64
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// newRegionBudget validates the configuration and starts with the
+// configured local share.
+func newRegionBudget(cfg Regions) *regionBudget {
+	total := 0.0
+	for _, share := range cfg.Shares {
+		total += share
+	}
+	if _, ok := cfg.Shares[cfg.Local]; !ok || total <= 0 {
+		panic(fmt.Sprintf("ratelimit: region %q has no share of the limit", cfg.Local))
+	}
+
+	shares := make(map[string]float64, len(cfg.Shares))
+	for region, share := range cfg.Shares {
+		shares[region] = share / total
+	}
+	b := &regionBudget{
+		cfg:    cfg,
+		shares: shares,
+		last:   time.Now(),
+	}
+	b.setShare(shares[cfg.Local])
+	return b
+}
+
+// currentShare returns the fraction of the limit enforced locally.
+func (b *regionBudget) currentShare() float64 {
+	return math.Float64frombits(b.share.Load())
+}
+
+// setShare updates the fraction of the limit enforced locally.
+func (b *regionBudget) setShare(share float64) {
+	b.share.Store(math.Float64bits(share))
+}
+
+// scale returns the local part of a limit.
+func (b *regionBudget) scale(r rate.Limit, burst int) (rate.Limit, int) {
+	share := b.currentShare()
+	if r != rate.Inf {
+		r *= rate.Limit(share)
+	}
+	if burst > 0 {
+		burst = int(math.Ceil(float64(burst) * share))
+	}
+	return r, burst
+}
+
+// rebalance computes the local share from the demand of every region.
+// Each region's share moves halfway between its configured share and its
+// fraction of the total demand: busy regions borrow unused budget while
+// quiet regions keep at least half of their configured share.
+func (b *regionBudget) rebalance(demand map[string]float64) float64 {
+	total := 0.0
+	for region := range b.shares {
+		total += demand[region]
+	}
+	configured := b.shares[b.cfg.Local]
+	if total <= 0 {
+		return configured
+	}
+	return (configured + demand[b.cfg.Local]/total) / 2
+}
+
+// ReconcileRegions reports the demand observed since the previous call to
+// the ledger and rebalances the local share from the demand of all
+// regions. It does nothing if Regions or its Ledger is not configured.
+func (m *Manager) ReconcileRegions(ctx context.Context) error {
+	b := m.regions
+	if b == nil || b.cfg.Ledger == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.mu.Unlock()
+
+	demand := 0.0
+	if requests := b.requests.Swap(0); elapsed > 0 {
+		demand = float64(requests) / elapsed
+	}
+	if err := b.cfg.Ledger.Report(ctx, b.cfg.Local, demand); err != nil {
+		return err
+	}
+	all, err := b.cfg.Ledger.Demand(ctx)
+	if err != nil {
+		return err
+	}
+	b.setShare(b.rebalance(all))
+	return nil
+}
+
+// RunRegions reconciles regional shares every interval until ctx is done.
+// Run it in its own goroutine.
+func (m *Manager) RunRegions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.ReconcileRegions(ctx); err != nil && m.regions.cfg.OnError != nil {
+				m.regions.cfg.OnError(err)
+			}
+		}
+	}
+}
+
+// memoryRegionLedger is an in-process RegionLedger.
+type memoryRegionLedger struct {
+	mu     sync.Mutex
+	demand map[string]float64
+}
+
+// NewMemoryRegionLedger creates a RegionLedger that keeps reports in
+// memory. It is useful for tests, or when all regions are served by
+// managers in the same process.
+func NewMemoryRegionLedger() RegionLedger {
+	return &memoryRegionLedger{
+		demand: make(map[string]float64),
+	}
+}
+
+// Report implements RegionLedger.
+func (l *memoryRegionLedger) Report(_ context.Context, region string, demand float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.demand[region] = demand
+	return nil
+}
+
+// Demand implements RegionLedger.
+func (l *memoryRegionLedger) Demand(context.Context) (map[string]float64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	demand := make(map[string]float64, len(l.demand))
+	for region, d := range l.demand {
+		demand[region] = d
+	}
+	return demand, nil
+}
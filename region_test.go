@@ -0,0 +1,108 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRegions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(r *gin.Engine) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	newRouter := func(m *Manager) *gin.Engine {
+		r := gin.New()
+		r.Use(m.Handler())
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return r
+	}
+
+	t.Run("EnforcesLocalShare", func(t *testing.T) {
+		m := NewManager(Options{
+			Rate:  rate.Every(time.Hour),
+			Burst: 10,
+			Regions: &Regions{
+				Local:  "eu",
+				Shares: map[string]float64{"eu": 3, "us": 7},
+			},
+		})
+		r := newRouter(m)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, serve(r))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+	})
+
+	t.Run("Rebalance", func(t *testing.T) {
+		b := newRegionBudget(Regions{
+			Local:  "eu",
+			Shares: map[string]float64{"eu": 1, "us": 1},
+		})
+
+		assert.Equal(t, 0.5, b.rebalance(nil))
+		assert.Equal(t, 0.75, b.rebalance(map[string]float64{"eu": 10}))
+		assert.Equal(t, 0.25, b.rebalance(map[string]float64{"us": 10}))
+		assert.Equal(t, 0.5, b.rebalance(map[string]float64{"eu": 5, "us": 5, "ap": 100}))
+	})
+
+	t.Run("ReconcileGrowsBusyRegion", func(t *testing.T) {
+		ledger := NewMemoryRegionLedger()
+		assert.NoError(t, ledger.Report(context.Background(), "us", 0))
+
+		m := NewManager(Options{
+			Rate:  rate.Every(time.Hour),
+			Burst: 10,
+			Regions: &Regions{
+				Local:  "eu",
+				Shares: map[string]float64{"eu": 1, "us": 1},
+				Ledger: ledger,
+			},
+		})
+		r := newRouter(m)
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, serve(r))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+
+		// All demand came from this region, so it takes the larger share
+		// and existing limiters grow accordingly.
+		assert.NoError(t, m.ReconcileRegions(context.Background()))
+		assert.Equal(t, 0.75, m.regions.currentShare())
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, serve(r))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(r))
+	})
+
+	t.Run("MissingLocalShare", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewManager(Options{
+				Rate:  1,
+				Burst: 1,
+				Regions: &Regions{
+					Local:  "ap",
+					Shares: map[string]float64{"eu": 1},
+				},
+			})
+		})
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Retention bounds how long the manager keeps the data it accumulates, so
+// deployments meet their data-retention policies without cleanup scripts.
+// Data is purged by PurgeExpired, which RunRetention calls periodically.
+// Decisions are only streamed to watchers, never kept, so they need no
+// retention here.
+type Retention struct {
+	// Keys is how long the statistics of a key are kept after it was last
+	// seen. If zero, they are kept.
+	Keys time.Duration
+
+	// Controls is how long bans and overrides are kept once they lapsed.
+	// With SharedControls, the purge is published to all instances. If
+	// zero, they are kept.
+	Controls time.Duration
+
+	// Audit is how long entries are kept by an AuditSink implementing
+	// AuditPurger. If zero, they are kept.
+	Audit time.Duration
+
+	// OnError is called when RunRetention fails to purge. If nil, errors
+	// are ignored.
+	OnError func(error)
+}
+
+// AuditPurger is implemented by AuditSinks that can delete old entries.
+type AuditPurger interface {
+	// Purge deletes the entries recorded before t.
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// PurgeExpired deletes the data kept longer than Options.Retention allows.
+// It does nothing without Retention.
+func (m *Manager) PurgeExpired(ctx context.Context) error {
+	rt := m.opts.Retention
+	if rt == nil {
+		return nil
+	}
+	now := time.Now()
+	if rt.Keys > 0 {
+		m.stats.purge(now.Add(-rt.Keys))
+	}
+	if rt.Controls > 0 {
+		if err := m.purgeControls(ctx, now.Add(-rt.Controls)); err != nil {
+			return err
+		}
+	}
+	if p, ok := m.opts.AuditSink.(AuditPurger); ok && rt.Audit > 0 {
+		if err := p.Purge(ctx, now.Add(-rt.Audit)); err != nil {
+			return fmt.Errorf("ratelimit: purging audit entries: %w", err)
+		}
+	}
+	return nil
+}
+
+// RunRetention purges expired data every interval until ctx is done. Run
+// it in its own goroutine.
+func (m *Manager) RunRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.PurgeExpired(ctx); err != nil && m.opts.Retention.OnError != nil {
+				m.opts.Retention.OnError(err)
+			}
+		}
+	}
+}
+
+// purgeControls deletes the bans and overrides that lapsed before t. The
+// controls are only changed, and published, if some did.
+func (m *Manager) purgeControls(ctx context.Context, t time.Time) error {
+	kc := m.controls
+	kc.mu.RLock()
+	lapsed := kc.lapsed(t)
+	kc.mu.RUnlock()
+	if !lapsed {
+		return nil
+	}
+	return m.changeControls(ctx, func(kc *keyControls) {
+		deleteLapsed(kc.bans, t, banEnd)
+		deleteLapsed(kc.prefixBans, t, banEnd)
+		deleteLapsed(kc.overrides, t, overrideEnd)
+		deleteLapsed(kc.prefixOverrides, t, overrideEnd)
+	})
+}
+
+func banEnd(b Ban) time.Time           { return b.Until }
+func overrideEnd(o Override) time.Time { return o.End }
+
+// lapsed reports whether a ban or override lapsed before t. The caller
+// holds kc.mu.
+func (kc *keyControls) lapsed(t time.Time) bool {
+	return anyLapsed(kc.bans, t, banEnd) || anyLapsed(kc.prefixBans, t, banEnd) ||
+		anyLapsed(kc.overrides, t, overrideEnd) || anyLapsed(kc.prefixOverrides, t, overrideEnd)
+}
+
+// anyLapsed reports whether a control ended before t.
+func anyLapsed[V any](controls map[string]V, t time.Time, end func(V) time.Time) bool {
+	for _, v := range controls {
+		if endedBefore(end(v), t) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteLapsed deletes the controls that ended before t.
+func deleteLapsed[V any](controls map[string]V, t time.Time, end func(V) time.Time) {
+	for key, v := range controls {
+		if endedBefore(end(v), t) {
+			delete(controls, key)
+		}
+	}
+}
+
+// endedBefore reports whether a control ending at end, zero for never,
+// lapsed before t.
+func endedBefore(end, t time.Time) bool {
+	return !end.IsZero() && end.Before(t)
+}
+
+// purge deletes the statistics of the keys last seen before t.
+func (s *keyStats) purge(t time.Time) {
+	s.each(func(shard *keyStatsShard) {
+		for key, info := range shard.keys {
+			if info.LastSeen.Before(t) {
+				delete(shard.keys, key)
+			}
+		}
+	})
+}
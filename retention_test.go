@@ -0,0 +1,97 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// purgingAuditSink is a memoryAuditSink that deletes old entries.
+type purgingAuditSink struct {
+	memoryAuditSink
+	purgeErr error
+}
+
+func (s *purgingAuditSink) Purge(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.purgeErr != nil {
+		return s.purgeErr
+	}
+	kept := s.entries[:0]
+	for _, e := range s.entries {
+		if !e.Time.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	s.entries = kept
+	return nil
+}
+
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	sink := &purgingAuditSink{}
+	m := NewManager(Options{
+		Rate:      rate.Every(time.Second),
+		Burst:     1,
+		AuditSink: sink,
+		Retention: &Retention{Keys: time.Hour, Controls: 24 * time.Hour, Audit: 30 * 24 * time.Hour},
+	})
+
+	m.stats.record("old", "", true, now.Add(-2*time.Hour))
+	m.stats.record("recent", "", true, now.Add(-time.Minute))
+	require.NoError(t, m.ScheduleBan(ctx, "lapsed", now.Add(-3*24*time.Hour), time.Hour))
+	require.NoError(t, m.ScheduleBan(ctx, "lapsing", now.Add(-time.Hour), 30*time.Minute))
+	require.NoError(t, m.Ban(ctx, "forever", 0))
+	require.NoError(t, m.SetOverride(ctx, "ended", Override{Rate: 1, Burst: 1, End: now.Add(-48 * time.Hour)}))
+	sink.entries = append(sink.entries, AuditEntry{Time: now.Add(-60 * 24 * time.Hour), Action: AuditReset})
+
+	require.NoError(t, m.PurgeExpired(ctx))
+	page, err := m.Keys(KeyQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Keys, 1)
+	assert.Equal(t, "recent", page.Keys[0].Key)
+
+	assert.NotContains(t, m.controls.bans, "lapsed")
+	assert.Contains(t, m.controls.bans, "lapsing")
+	assert.Contains(t, m.controls.bans, "forever")
+	assert.Empty(t, m.controls.overrides)
+	assert.Len(t, sink.entries, 4)
+
+	t.Run("Run", func(t *testing.T) {
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		errs := make(chan error, 1)
+		m.opts.Retention.OnError = func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+		sink.purgeErr = errors.New("disk full")
+		go m.RunRetention(runCtx, 10*time.Millisecond)
+		select {
+		case err := <-errs:
+			require.ErrorIs(t, err, sink.purgeErr)
+		case <-time.After(time.Second):
+			t.Fatal("purge never failed")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		m := NewManager(Options{Rate: 1, Burst: 1})
+		m.stats.record("old", "", true, now.Add(-365*24*time.Hour))
+		require.NoError(t, m.PurgeExpired(ctx))
+		assert.Equal(t, 1, m.stats.len())
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// DefaultRetryHeader is the header marking retried requests when
+// Retries.Header is empty.
+const DefaultRetryHeader = "X-Retry-Attempt"
+
+// retryKeySuffix is appended to the bucket key of retries with their own
+// limits.
+const retryKeySuffix = ":retry"
+
+// Retries configures a separate policy for retried requests, which clients
+// mark with the attempt number in a header, so retry storms can be shaped
+// differently from fresh traffic. Requests without the header, or with
+// attempt 0, are fresh.
+type Retries struct {
+	// Header carries the attempt number of a retried request. If empty,
+	// DefaultRetryHeader is used.
+	Header string
+
+	// Cost is the number of tokens a retry takes, so values above one make
+	// retries stricter than fresh requests. If zero, retries cost as much
+	// as fresh requests.
+	Cost int
+
+	// Rate and Burst, if Rate is set, limit the retries of every client in
+	// a bucket of their own, more lenient or stricter than its budget,
+	// instead of the client's budget. Burst is raised to Cost if it is
+	// lower, so retries can always succeed.
+	Rate  rate.Limit
+	Burst int
+}
+
+// retryAttempt returns the attempt number a request is marked with, or
+// zero for fresh requests.
+func (r *Retries) retryAttempt(c *gin.Context) int {
+	header := r.Header
+	if header == "" {
+		header = DefaultRetryHeader
+	}
+	attempt, err := strconv.Atoi(c.GetHeader(header))
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// retry adjusts the bucket and cost of a request if it is a retry.
+func (m *Manager) retry(
+	c *gin.Context, bucket string, r rate.Limit, burst, cost int,
+) (string, rate.Limit, int, int) {
+	policy := m.opts.Retries
+	if policy == nil || policy.retryAttempt(c) == 0 {
+		return bucket, r, burst, cost
+	}
+	if policy.Cost > 0 {
+		cost = policy.Cost
+	}
+	if policy.Rate != 0 {
+		bucket, r, burst = bucket+retryKeySuffix, policy.Rate, max(policy.Burst, cost)
+	}
+	return bucket, r, burst, cost
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRetries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(retries *Retries) *gin.Engine {
+		r := gin.New()
+		r.Use(New(Options{
+			Rate:    rate.Every(time.Hour),
+			Burst:   3,
+			Retries: retries,
+		}))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		return r
+	}
+	serve := func(r *gin.Engine, header, attempt string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if attempt != "" {
+			req.Header.Set(header, attempt)
+		}
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Stricter", func(t *testing.T) {
+		r := newRouter(&Retries{Cost: 2})
+		// A retry takes two of the three tokens, leaving too few for
+		// another retry but enough for a fresh request.
+		assert.Equal(t, http.StatusOK, serve(r, DefaultRetryHeader, "1"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, DefaultRetryHeader, "2"))
+		assert.Equal(t, http.StatusOK, serve(r, DefaultRetryHeader, "0"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, DefaultRetryHeader, ""))
+	})
+
+	t.Run("Own bucket", func(t *testing.T) {
+		r := newRouter(&Retries{Header: "Retry-Attempt", Rate: rate.Every(time.Hour), Burst: 1})
+		assert.Equal(t, http.StatusOK, serve(r, "Retry-Attempt", "1"))
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "Retry-Attempt", "2"))
+		// Retries do not draw on the budget of fresh requests.
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(r, "Retry-Attempt", ""))
+		}
+		// Invalid attempt numbers are fresh requests.
+		assert.Equal(t, http.StatusTooManyRequests, serve(r, "Retry-Attempt", "again"))
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		r := newRouter(&Retries{Rate: rate.Inf})
+		for range 10 {
+			assert.Equal(t, http.StatusOK, serve(r, DefaultRetryHeader, "1"))
+		}
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// Exempt returns a middleware marking the routes it is registered on as
+// exempt from rate limiting, so exemptions live next to the routes:
+//
+//	r.GET("/healthz", ratelimit.Exempt(), health)
+//
+// The manager's middleware lets requests to such routes through untouched.
+func Exempt() gin.HandlerFunc {
+	return exemptRoute
+}
+
+// exemptRoute is the marker found in the handler chain of exempt routes.
+func exemptRoute(*gin.Context) {}
+
+// RouteLimit returns a middleware giving the routes it is registered on a
+// limit of their own instead of the manager's default, for example:
+//
+//	r.POST("/login", m.RouteLimit(ratelimit.LimitSpec{Requests: 5, Window: time.Minute}), login)
+//
+// Every route has its own buckets, which are otherwise handled like the
+// default ones: requests are identified, classified and keyed as usual and
+// bans apply. The manager's middleware defers to it on these routes. It
+// panics if spec is invalid.
+func (m *Manager) RouteLimit(spec LimitSpec) gin.HandlerFunc {
+	return m.RouteLimitWith(spec, m.opts.Consistency)
+}
+
+// routeLimit is the limit set on a route by RouteLimit.
+type routeLimit struct {
+	rate        rate.Limit
+	burst       int
+	consistency Consistency
+}
+
+// storeKey returns the key of the route's bucket for the client key.
+func (r *routeLimit) storeKey(c *gin.Context, key string) string {
+	return fmt.Sprintf("%s@%s %s", key, c.Request.Method, c.FullPath())
+}
+
+// routeBucketSuffix reports whether rest is what storeKey appends to a key:
+// "@", the method, a space and the route's path. Keys such as email
+// addresses may contain "@" too, but never the rest.
+func routeBucketSuffix(rest string) bool {
+	spec, ok := strings.CutPrefix(rest, "@")
+	if !ok {
+		return false
+	}
+	method, path, ok := strings.Cut(spec, " ")
+	return ok && method != "" && strings.HasPrefix(path, "/")
+}
+
+// routeAnnotation is what a route's handler chain says about its limit.
+type routeAnnotation uint8
+
+const (
+	routeDefault routeAnnotation = iota
+	routeExempt
+	routeLimited
+)
+
+// exemptRouteName is the name of exemptRoute in gin.Context.HandlerNames.
+var exemptRouteName = handlerName(exemptRoute)
+
+func handlerName(h gin.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+}
+
+// routeAnnotations caches the annotation of every route, keyed by method
+// and path pattern.
+type routeAnnotations struct {
+	// limitName is the name of the handlers returned by RouteLimit.
+	limitName string
+	cache     sync.Map
+	// endpoints maps routes to the default limits set by LimitEndpoints.
+	endpoints sync.Map
+	// limits maps the routes served by RouteLimit to their limits.
+	limits sync.Map
+}
+
+// LimitEndpoints gives every route registered on e so far a limit of spec
+// of its own, keyed by method and path pattern, so a client exhausting one
+// endpoint can still use the others. Routes marked with Exempt or
+// RouteLimit keep their annotations, and routes registered later get the
+// manager's default limit. The manager's middleware must already be in the
+// routes' handler chains, typically through e.Use before the routes are
+// registered. It panics if spec is invalid.
+func (m *Manager) LimitEndpoints(e *gin.Engine, spec LimitSpec) {
+	if err := spec.Validate(); err != nil {
+		panic(err)
+	}
+	route := &routeLimit{rate: spec.Rate(), burst: spec.BurstSize(), consistency: m.opts.Consistency}
+	for _, ri := range e.Routes() {
+		m.routes.endpoints.Store(ri.Method+" "+ri.Path, route)
+	}
+}
+
+// endpoint returns the limit LimitEndpoints set on the route of c, if any.
+func (a *routeAnnotations) endpoint(c *gin.Context) *routeLimit {
+	if v, ok := a.endpoints.Load(c.Request.Method + " " + c.FullPath()); ok {
+		return v.(*routeLimit)
+	}
+	return nil
+}
+
+// annotation returns the annotation of the route c was matched to.
+func (a *routeAnnotations) annotation(c *gin.Context) routeAnnotation {
+	path := c.FullPath()
+	if path == "" {
+		// Unmatched requests have no route to annotate.
+		return routeDefault
+	}
+	route := c.Request.Method + " " + path
+	if v, ok := a.cache.Load(route); ok {
+		return v.(routeAnnotation)
+	}
+	an := routeDefault
+	for _, name := range c.HandlerNames() {
+		switch name {
+		case exemptRouteName:
+			an = routeExempt
+		case a.limitName:
+			if an == routeDefault {
+				an = routeLimited
+			}
+		}
+	}
+	a.cache.Store(route, an)
+	return an
+}
+
+// limited records the limit RouteLimit set on the route of c.
+func (a *routeAnnotations) limited(c *gin.Context, route *routeLimit) {
+	key := c.Request.Method + " " + c.FullPath()
+	if _, ok := a.limits.Load(key); !ok {
+		a.limits.Store(key, route)
+	}
+}
+
+// lookup returns the limit of the route c was matched to, without its
+// handler chain, and whether the route is exempt. Routes are only known
+// to be exempt or to have a RouteLimit once they served a request; until
+// then they are reported with the limit set by LimitEndpoints, if any.
+func (a *routeAnnotations) lookup(c *gin.Context) (*routeLimit, bool) {
+	route := c.Request.Method + " " + c.FullPath()
+	if v, ok := a.cache.Load(route); ok {
+		switch v.(routeAnnotation) {
+		case routeExempt:
+			return nil, true
+		case routeLimited:
+			if l, ok := a.limits.Load(route); ok {
+				return l.(*routeLimit), false
+			}
+		}
+	}
+	return a.endpoint(c), false
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRouteAnnotations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{Rate: rate.Every(time.Minute), Burst: 1})
+	r := gin.New()
+	r.Use(m.Handler())
+	ok := func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	}
+	r.GET("/", ok)
+	r.GET("/other", ok)
+	r.GET("/healthz", Exempt(), ok)
+	r.POST("/login", m.RouteLimit(LimitSpec{Requests: 2, Window: time.Minute}), ok)
+	r.GET("/users/:id", m.RouteLimit(LimitSpec{Requests: 1, Window: time.Minute}), ok)
+	r.GET("/both", m.RouteLimit(LimitSpec{Requests: 1, Window: time.Minute}), Exempt(), ok)
+
+	serve := func(method, path, ip string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("GET", "/", "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/", "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/other", "203.0.113.1"))
+	})
+
+	t.Run("Exempt", func(t *testing.T) {
+		for range 5 {
+			assert.Equal(t, http.StatusOK, serve("GET", "/healthz", "203.0.113.2"))
+			assert.Equal(t, http.StatusOK, serve("GET", "/both", "203.0.113.2"))
+		}
+		// Exempt requests do not draw on the default budget.
+		assert.Equal(t, http.StatusOK, serve("GET", "/", "203.0.113.2"))
+	})
+
+	t.Run("RouteLimit", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("POST", "/login", "203.0.113.3"))
+		assert.Equal(t, http.StatusOK, serve("POST", "/login", "203.0.113.3"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("POST", "/login", "203.0.113.3"))
+
+		// Route buckets are separate from the default bucket and from
+		// other routes, and shared by all paths matching a pattern.
+		assert.Equal(t, http.StatusOK, serve("GET", "/", "203.0.113.3"))
+		assert.Equal(t, http.StatusOK, serve("GET", "/users/1", "203.0.113.3"))
+		assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/users/2", "203.0.113.3"))
+		assert.Equal(t, http.StatusOK, serve("POST", "/login", "203.0.113.4"))
+	})
+
+	t.Run("Banned", func(t *testing.T) {
+		assert.NoError(t, m.Ban(context.Background(), "203.0.113.5", time.Minute))
+		assert.Equal(t, http.StatusForbidden, serve("POST", "/login", "203.0.113.5"))
+		assert.Equal(t, http.StatusOK, serve("GET", "/healthz", "203.0.113.5"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Panics(t, func() {
+			m.RouteLimit(LimitSpec{Requests: -1, Window: time.Minute})
+		})
+	})
+}
+
+func TestLimitEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{Rate: rate.Every(time.Minute), Burst: 10})
+	r := gin.New()
+	r.Use(m.Handler())
+	ok := func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	}
+	r.GET("/items", ok)
+	r.POST("/items", ok)
+	r.GET("/items/:id", ok)
+	r.GET("/healthz", Exempt(), ok)
+	r.POST("/login", m.RouteLimit(LimitSpec{Requests: 2, Window: time.Minute}), ok)
+	m.LimitEndpoints(r, LimitSpec{Requests: 1, Window: time.Minute})
+	r.GET("/late", ok)
+
+	serve := func(method, path string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Every endpoint has its own budget, keyed by method and pattern.
+	assert.Equal(t, http.StatusOK, serve("GET", "/items"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/items"))
+	assert.Equal(t, http.StatusOK, serve("POST", "/items"))
+	assert.Equal(t, http.StatusOK, serve("GET", "/items/1"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/items/2"))
+
+	// Annotations win over the endpoint default.
+	for range 3 {
+		assert.Equal(t, http.StatusOK, serve("GET", "/healthz"))
+	}
+	assert.Equal(t, http.StatusOK, serve("POST", "/login"))
+	assert.Equal(t, http.StatusOK, serve("POST", "/login"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("POST", "/login"))
+
+	// Routes registered later get the default limit.
+	for range 3 {
+		assert.Equal(t, http.StatusOK, serve("GET", "/late"))
+	}
+
+	assert.Panics(t, func() {
+		m.LimitEndpoints(r, LimitSpec{Window: -time.Second})
+	})
+}
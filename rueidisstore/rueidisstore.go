@@ -0,0 +1,323 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package rueidisstore provides a Redis store for the ratelimit middleware
+// built on rueidis, which caches the state of hot keys in the process with
+// server-assisted client-side caching. It is a module of its own, so
+// applications using other stores do not depend on rueidis.
+//
+// The store keeps the GCRA arrival times of redisstore.NewGCRA, under the
+// same keys and in the same encoding, layout 1 of redisstore.LayoutVersion,
+// so instances using either share limits. Its script is its own, however,
+// as it decides cached denials in the process: it has none of the options
+// of redisstore. Request times always come from the instances' clocks, as
+// they do without redisstore.WithServerClock, skew is not observed and
+// hot keys are not counted, so the store cannot back Manager.Hydrate. It
+// does not read the layout recorded by redisstore either, so fleets
+// mixing both must move to a release of this package reading a new
+// layout before running redisstore.UpgradeLayout.
+//
+// Importing it registers the rueidis and rueidiss schemes with
+// ratelimit.NewStoreFromDSN, which take the URLs of rueidis.ParseURL with
+// the redis and rediss schemes renamed.
+package rueidisstore
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/ratelimit"
+	"github.com/redis/rueidis"
+	"golang.org/x/time/rate"
+)
+
+// gcraPrefix prefixes the Redis keys of the GCRA arrival times. They are
+// those of redisstore.NewGCRA, so instances using either share limits.
+const gcraPrefix = "ratelimit:gcra:"
+
+// CacheTTL is the longest the arrival time of a key is cached without
+// being read from the server. Redis invalidates cached keys when they
+// change, so it only bounds how long stale entries survive a lost
+// invalidation.
+const CacheTTL = time.Minute
+
+// gcraScript decides requests as the script of redisstore.NewGCRA does
+// without options, with one cost for every key: it keeps the theoretical arrival time of
+// every key, in microseconds, as a plain string that expires once the
+// bucket is full again. Arrival times are only advanced if every key
+// allows the requests. A non-positive interval denies every
+// request. It returns whether all levels allowed the requests, followed by
+// whether each level allowed them, its remaining requests and its wait in
+// microseconds, or -1 if the requests never will be allowed.
+var gcraScript = rueidis.NewLuaScript(`
+local now = tonumber(ARGV[1])
+local n = tonumber(ARGV[2])
+local need = math.max(n, 1)
+local res = {1}
+local tats = {}
+for i, key in ipairs(KEYS) do
+	local interval = tonumber(ARGV[1 + 2 * i])
+	local tolerance = tonumber(ARGV[2 + 2 * i])
+	local allowed, remaining, retry = 0, 0, -1
+	if interval > 0 then
+		local tat = math.max(tonumber(redis.call('GET', key) or now), now)
+		tats[i] = tat
+		remaining = math.max(0, math.floor((tolerance - (tat - now)) / interval))
+		local wait = tat + need * interval - now - tolerance
+		if wait <= 0 then
+			allowed, retry = 1, 0
+		elseif need * interval <= tolerance then
+			retry = wait
+		end
+	end
+	if allowed == 0 then
+		res[1] = 0
+	end
+	res[3 * i - 1] = allowed
+	res[3 * i] = remaining
+	res[3 * i + 1] = retry
+end
+if res[1] == 1 and n > 0 then
+	for i, key in ipairs(KEYS) do
+		local tat = tats[i] + n * tonumber(ARGV[1 + 2 * i])
+		redis.call('SET', key, string.format('%d', tat), 'PX', math.ceil((tat - now) / 1000))
+		res[3 * i] = math.max(0, res[3 * i] - n)
+	end
+end
+return res
+`)
+
+// minDeniedSweep is the fewest denied keys that trigger a sweep.
+const minDeniedSweep = 1024
+
+// store is a ratelimit.Store and ratelimit.Algorithm implementing the
+// generic cell rate algorithm with rueidis.
+type store struct {
+	client rueidis.Client
+
+	mu sync.Mutex
+	// denied holds the Redis keys that denied requests of this instance,
+	// with when they allow requests again in Unix microseconds.
+	denied  map[string]int64
+	sweepAt int
+}
+
+func init() { //nolint:gochecknoinits // importing the module registers its DSN schemes
+	ratelimit.RegisterStore("rueidis", newFromDSN)
+	ratelimit.RegisterStore("rueidiss", newFromDSN)
+}
+
+// newFromDSN creates a store from a rueidis:// or rueidiss:// DSN,
+// accepting the options of rueidis.ParseURL. The client connects right
+// away, so the DSN is rejected if the server cannot be reached.
+func newFromDSN(dsn *url.URL) (ratelimit.Store, error) {
+	u := *dsn
+	u.Scheme = "redis" + u.Scheme[len("rueidis"):]
+	opts, err := rueidis.ParseURL(u.String())
+	if err != nil {
+		return nil, err
+	}
+	client, err := rueidis.NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return New(client), nil
+}
+
+// New creates a Redis-based store with the token bucket semantics of
+// redisstore.New, sharing its keys; see the package documentation for how
+// its script differs.
+//
+// Keys that denied a request are read through the client-side cache
+// until they allow requests again. Arrival times only move forward, so a
+// cached one that denies a request is decided in the process: clients
+// over their limit, the hottest keys under abuse, cost no round trip
+// until another instance allows them a request and Redis invalidates the
+// entry. Other requests run the script, in one round trip. The client
+// must keep client-side caching enabled for this; without it, rejected
+// clients cost an extra read.
+func New(client rueidis.Client) ratelimit.Store {
+	return newStore(client)
+}
+
+// NewGCRA returns the store of New as a ratelimit.Algorithm.
+func NewGCRA(client rueidis.Client) ratelimit.Algorithm {
+	return newStore(client)
+}
+
+// newStore creates a store using client.
+func newStore(client rueidis.Client) *store {
+	return &store{client: client, denied: make(map[string]int64), sweepAt: minDeniedSweep}
+}
+
+// Allow implements ratelimit.Store.
+func (s *store) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return s.Take(ctx, key, limit.Rate, limit.Burst, limit.N, time.Now())
+}
+
+// Take implements ratelimit.Algorithm.
+func (s *store) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (ratelimit.Allowance, error) {
+	a, _, err := s.TakeLevels(ctx, []ratelimit.Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// level is a limited level with its GCRA parameters, in microseconds.
+type level struct {
+	index     int
+	key       string
+	interval  int64
+	tolerance int64
+}
+
+// TakeLevels implements ratelimit.LevelAlgorithm, checking all levels in
+// one round trip, or none if the cached arrival times deny the requests.
+// On Redis Cluster, the keys of the levels must hash to the same slot, or
+// rueidis panics.
+func (s *store) TakeLevels(
+	ctx context.Context, levels []ratelimit.Level, n int, now time.Time,
+) (ratelimit.Allowance, int, error) {
+	as := make([]ratelimit.Allowance, len(levels))
+	var limited []level
+	for i, lv := range levels {
+		if lv.Rate == rate.Inf {
+			as[i] = ratelimit.Allowance{Allowed: true, Remaining: lv.Burst}
+			continue
+		}
+		var interval int64
+		if lv.Rate > 0 {
+			interval = max(1, int64(1e6/float64(lv.Rate)))
+		}
+		limited = append(limited, level{i, gcraPrefix + lv.Key, interval, interval * int64(lv.Burst)})
+	}
+	if len(limited) == 0 {
+		i := ratelimit.MostRestrictive(as)
+		return as[i], i, nil
+	}
+
+	micros := now.UnixMicro()
+	if n > 0 && s.wasDenied(limited, micros) {
+		denied, err := s.cachedDenial(ctx, limited, n, micros, as)
+		if err != nil {
+			return ratelimit.Allowance{}, 0, err
+		}
+		if denied {
+			i := ratelimit.MostRestrictive(as)
+			return as[i], i, nil
+		}
+	}
+
+	keys := make([]string, len(limited))
+	args := []string{strconv.FormatInt(micros, 10), strconv.Itoa(n)}
+	for j, lv := range limited {
+		keys[j] = lv.key
+		args = append(args, strconv.FormatInt(lv.interval, 10), strconv.FormatInt(lv.tolerance, 10))
+	}
+	res, err := gcraScript.Exec(ctx, s.client, keys, args).AsIntSlice()
+	if err != nil {
+		return ratelimit.Allowance{}, 0, err
+	}
+	for j, lv := range limited {
+		lr := res[1+3*j:]
+		as[lv.index] = allowance(lr[0] == 1, lr[1], lr[2])
+	}
+	s.remember(limited, res, micros)
+	i := ratelimit.MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// wasDenied reports whether one of the levels denied requests that it
+// would still deny at now.
+func (s *store) wasDenied(levels []level, now int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, lv := range levels {
+		if now < s.denied[lv.key] {
+			return true
+		}
+	}
+	return false
+}
+
+// remember records the levels that denied the requests, with the result
+// res of the script, and forgets them once they allow requests.
+func (s *store) remember(levels []level, res []int64, now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for j, lv := range levels {
+		lr := res[1+3*j:]
+		switch {
+		case lr[0] == 1:
+			delete(s.denied, lv.key)
+		case lr[2] > 0:
+			s.denied[lv.key] = now + lr[2]
+		}
+	}
+	if len(s.denied) >= s.sweepAt {
+		for key, until := range s.denied {
+			if until <= now {
+				delete(s.denied, key)
+			}
+		}
+		s.sweepAt = max(minDeniedSweep, 2*len(s.denied))
+	}
+}
+
+// cachedDenial decides the levels from their cached arrival times, as the
+// script would. It reports whether a level denies the requests, in which
+// case every limited level's allowance is set in as. Cached arrival times
+// may lag behind, so allowed requests are left to the script.
+func (s *store) cachedDenial(ctx context.Context, levels []level, n int, now int64, as []ratelimit.Allowance) (
+	bool, error,
+) {
+	cmds := make([]rueidis.CacheableTTL, len(levels))
+	for j, lv := range levels {
+		cmds[j] = rueidis.CT(s.client.B().Get().Key(lv.key).Cache(), CacheTTL)
+	}
+	denied := false
+	need := int64(max(n, 1))
+	for j, res := range s.client.DoMultiCache(ctx, cmds...) {
+		tat := now
+		v, err := res.AsInt64()
+		if err == nil {
+			tat = max(v, now)
+		} else if !rueidis.IsRedisNil(err) {
+			return false, err
+		}
+
+		lv := levels[j]
+		allowed, remaining, retry := false, int64(0), int64(-1)
+		if lv.interval > 0 {
+			remaining = max(0, (lv.tolerance-(tat-now))/lv.interval)
+			wait := tat + need*lv.interval - now - lv.tolerance
+			if wait <= 0 {
+				allowed, retry = true, 0
+			} else if need*lv.interval <= lv.tolerance {
+				retry = wait
+			}
+		}
+		denied = denied || !allowed
+		as[lv.index] = allowance(allowed, remaining, retry)
+	}
+	return denied, nil
+}
+
+// allowance converts the decision of a level, with its wait in
+// microseconds or -1 if the requests never will be allowed.
+func allowance(allowed bool, remaining, retry int64) ratelimit.Allowance {
+	a := ratelimit.Allowance{Allowed: allowed, Remaining: int(remaining), RetryAfter: -1}
+	if retry >= 0 {
+		a.RetryAfter = time.Duration(retry) * time.Microsecond
+	}
+	return a
+}
+
+// Reset implements ratelimit.Algorithm.
+func (s *store) Reset(ctx context.Context, key string) error {
+	return s.client.Do(ctx, s.client.B().Del().Key(gcraPrefix+key).Build()).Error()
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rueidisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-contrib/ratelimit"
+	"github.com/redis/rueidis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// newClient returns a client of a test server. The test server does not
+// support client-side caching, so reads go to the server, and would be
+// taken for a cluster.
+func newClient(t *testing.T) rueidis.Client {
+	mr := miniredis.RunT(t)
+	client, err := rueidis.NewClient(rueidis.ClientOption{
+		InitAddress:       []string{mr.Addr()},
+		DisableCache:      true,
+		ForceSingleClient: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestGCRA(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	start := time.Now()
+	r := rate.Every(10 * time.Second)
+
+	// Two instances sharing the server share the limit.
+	a, b := NewGCRA(client), NewGCRA(client)
+	for i, alg := range []ratelimit.Algorithm{a, b, a} {
+		allowance, err := alg.Take(ctx, "k", r, 3, 1, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed, i)
+		assert.Equal(t, 2-i, allowance.Remaining, i)
+	}
+
+	// The denial is decided from the stored arrival time.
+	allowance, err := b.Take(ctx, "k", r, 3, 1, start)
+	require.NoError(t, err)
+	assert.False(t, allowance.Allowed)
+	assert.Zero(t, allowance.Remaining)
+	assert.Equal(t, 10*time.Second, allowance.RetryAfter)
+
+	// Keys are only read through the cache while they deny requests.
+	assert.Contains(t, b.(*store).denied, gcraPrefix+"k")
+	assert.NotContains(t, a.(*store).denied, gcraPrefix+"k")
+	allowance, err = b.Take(ctx, "k", r, 3, 1, start.Add(5*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowance.Allowed)
+	assert.Equal(t, 5*time.Second, allowance.RetryAfter)
+
+	allowance, err = b.Take(ctx, "k", r, 3, 1, start.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowance.Allowed)
+	assert.NotContains(t, b.(*store).denied, gcraPrefix+"k")
+
+	t.Run("Never allowed", func(t *testing.T) {
+		allowance, err := a.Take(ctx, "k", r, 3, 4, start)
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, time.Duration(-1), allowance.RetryAfter)
+
+		allowance, err = a.Take(ctx, "zero", 0, 3, 1, start)
+		require.NoError(t, err)
+		assert.False(t, allowance.Allowed)
+		assert.Equal(t, time.Duration(-1), allowance.RetryAfter)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		require.NoError(t, a.Reset(ctx, "k"))
+		allowance, err := a.Take(ctx, "k", r, 3, 3, start)
+		require.NoError(t, err)
+		assert.True(t, allowance.Allowed)
+	})
+}
+
+func TestTakeLevels(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	start := time.Now().Truncate(time.Minute)
+	alg := NewGCRA(client).(ratelimit.LevelAlgorithm)
+	levels := func(user string) []ratelimit.Level {
+		return []ratelimit.Level{
+			{Key: "org:acme", Rate: ratelimit.Per(4, time.Minute), Burst: 4},
+			{Key: user, Rate: ratelimit.Per(3, time.Minute), Burst: 3},
+			{Key: "unlimited", Rate: rate.Inf, Burst: 1},
+		}
+	}
+
+	for range 3 {
+		a, _, err := alg.TakeLevels(ctx, levels("alice"), 1, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	}
+	a, i, err := alg.TakeLevels(ctx, levels("alice"), 1, start)
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, 20*time.Second, a.RetryAfter)
+
+	// Denied requests do not use up the other levels.
+	a, _, err = alg.TakeLevels(ctx, levels("bob"), 1, start)
+	require.NoError(t, err)
+	assert.True(t, a.Allowed)
+	a, i, err = alg.TakeLevels(ctx, levels("bob"), 1, start)
+	require.NoError(t, err)
+	assert.False(t, a.Allowed)
+	assert.Equal(t, 0, i)
+}
+
+func TestStore(t *testing.T) {
+	client := newClient(t)
+	s := New(client)
+	limit := ratelimit.Limit{Rate: rate.Every(time.Hour), Burst: 1, N: 1}
+
+	res, err := s.Allow(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = s.Allow(context.Background(), "k", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	ttl, err := client.Do(context.Background(), client.B().Pttl().Key(gcraPrefix+"k").Build()).AsInt64()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Milliseconds(), ttl, float64(time.Minute.Milliseconds()))
+}
+
+func TestNewStoreFromDSN(t *testing.T) {
+	assert.Subset(t, ratelimit.StoreSchemes(), []string{"rueidis", "rueidiss"})
+
+	t.Run("Rueidis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		s, err := ratelimit.NewStoreFromDSN("rueidis://" + mr.Addr() + "/0?client_cache=0")
+		require.NoError(t, err)
+		t.Cleanup(s.(*store).client.Close)
+		limit := ratelimit.Limit{Rate: rate.Every(time.Minute), Burst: 1, N: 1}
+		res, err := s.Allow(context.Background(), "k", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.True(t, mr.Exists(gcraPrefix+"k"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := ratelimit.NewStoreFromDSN("rueidis://localhost:6379/zero")
+		assert.Error(t, err)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		addr := mr.Addr()
+		mr.Close()
+		_, err := ratelimit.NewStoreFromDSN("rueidiss://" + addr)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignSessionCookie returns the cookie value "<id>.<signature>" accepted by
+// SignedSessionCookieResolver, where the signature is the unpadded
+// base64url HMAC-SHA256 of id under secret.
+func SignSessionCookie(secret []byte, id string) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(sessionMAC(secret, id))
+}
+
+func sessionMAC(secret []byte, id string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return mac.Sum(nil)
+}
+
+// verifySessionCookie returns the session ID of a value produced by
+// SignSessionCookie, and whether its signature is valid.
+func verifySessionCookie(secret []byte, value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return "", false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil {
+		return "", false
+	}
+	id := value[:i]
+	return id, hmac.Equal(sig, sessionMAC(secret, id))
+}
+
+// SignedSessionCookieResolver identifies requests by the session ID in the
+// named cookie, whose value must be signed with secret as by
+// SignSessionCookie. Cookies that are missing, malformed or forged are
+// ignored, so attackers cannot obtain new buckets by inventing sessions.
+func SignedSessionCookieResolver(name string, secret []byte) IdentityResolver {
+	return IdentityResolverFunc(func(c *gin.Context) (Identity, bool) {
+		value, err := c.Cookie(name)
+		if err != nil {
+			return Identity{}, false
+		}
+		id, ok := verifySessionCookie(secret, value)
+		if !ok {
+			return Identity{}, false
+		}
+		return Identity{Type: IdentitySession, ID: id}, true
+	})
+}
+
+// SessionCookieKey returns a KeyFunc that limits clients by their signed
+// session cookie, as verified by SignedSessionCookieResolver, and clients
+// without a valid one by their IP address.
+func SessionCookieKey(name string, secret []byte) func(*gin.Context) string {
+	r := IdentityChain(SignedSessionCookieResolver(name, secret), ClientIPResolver())
+	return func(c *gin.Context) string {
+		id, _ := r.Resolve(c)
+		return id.Key()
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionCookieKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := []byte("secret")
+	key := SessionCookieKey("session", secret)
+	keyFor := func(cookie string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.RemoteAddr = "203.0.113.7:1234"
+		if cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+		}
+		return key(c)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.Equal(t, "session:abc", keyFor(SignSessionCookie(secret, "abc")))
+		// Session IDs may themselves contain dots.
+		assert.Equal(t, "session:a.b", keyFor(SignSessionCookie(secret, "a.b")))
+	})
+
+	t.Run("Forged", func(t *testing.T) {
+		for _, cookie := range []string{
+			"",
+			"abc",
+			"abc.",
+			".sig",
+			"abc.!!!",
+			SignSessionCookie([]byte("other"), "abc"),
+			"xyz" + SignSessionCookie(secret, "abc")[3:],
+		} {
+			assert.Equal(t, "ip:203.0.113.7", keyFor(cookie), cookie)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Shard counts of the in-memory store.
+const (
+	// maxAutoShards is the most shards picked automatically.
+	maxAutoShards = 256
+	// minShardKeys is the fewest keys a shard holds under a key cap, so
+	// evictions of the least recently used keys stay meaningful.
+	minShardKeys = 64
+)
+
+// MemoryStoreOption configures a store created by NewMemoryStore.
+type MemoryStoreOption func(*shardedStore)
+
+// WithShards partitions the keys of the store between n shards, each with
+// its own lock. If n is not positive, the count is picked automatically.
+func WithShards(n int) MemoryStoreOption {
+	return func(s *shardedStore) { s.n = n }
+}
+
+// WithMaxKeys caps the number of keys of the store, as Options.MaxKeys
+// does for the default store. Every shard keeps its share of the cap.
+func WithMaxKeys(n int) MemoryStoreOption {
+	return func(s *shardedStore) { s.max = n }
+}
+
+// WithIdleTTL evicts the keys unused for longer than ttl, as
+// Options.IdleTTL does for the default store, until the store is closed.
+func WithIdleTTL(ttl time.Duration) MemoryStoreOption {
+	return func(s *shardedStore) { s.ttl = ttl }
+}
+
+// shardedStore is an in-memory store partitioning its keys between
+// memoryStores, so requests for different keys rarely contend for a lock.
+type shardedStore struct {
+	shards []*memoryStore
+	seed   maphash.Seed
+	// n, max and ttl are the configured shard count, key cap and TTL.
+	n   int
+	max int
+	ttl time.Duration
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemoryStore creates an in-memory store, such as the one used when
+// Options.Store is nil. Its keys are partitioned between shards with a lock
+// each, four per CPU by default, so busy servers do not contend for a
+// single lock. The store implements LimiterStore, and io.Closer to stop
+// evicting idle keys.
+func NewMemoryStore(opts ...MemoryStoreOption) Store {
+	return newShardedStore(opts...)
+}
+
+func newShardedStore(opts ...MemoryStoreOption) *shardedStore {
+	s := &shardedStore{seed: maphash.MakeSeed()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.n = shardCount(s.n, s.max)
+	s.shards = make([]*memoryStore, s.n)
+	for i := range s.shards {
+		shard := newMemoryStore()
+		shard.max = shardMax(s.max, s.n)
+		shard.ttl = max(s.ttl, 0)
+		s.shards[i] = shard
+	}
+	if s.ttl > 0 {
+		s.done = make(chan struct{})
+		go s.janitor(s.ttl / 2)
+	}
+	return s
+}
+
+// autoShards returns the power of two at or above four shards per CPU,
+// up to maxAutoShards.
+func autoShards() int {
+	n := 1
+	for n < 4*runtime.GOMAXPROCS(0) && n < maxAutoShards {
+		n *= 2
+	}
+	return n
+}
+
+// shardCount returns the number of shards of a store configured with n
+// shards and a cap of maxKeys keys: n, or the automatic count if n is not
+// positive, lowered so every shard holds at least minShardKeys keys.
+func shardCount(n, maxKeys int) int {
+	if n <= 0 {
+		n = autoShards()
+	}
+	if maxKeys > 0 {
+		n = max(1, min(n, maxKeys/minShardKeys))
+	}
+	return n
+}
+
+// shardMax returns the share of a cap of maxKeys keys held by each of n
+// shards, rounded up so the shards hold at least maxKeys together, or zero
+// without a cap.
+func shardMax(maxKeys, n int) int {
+	if maxKeys <= 0 {
+		return 0
+	}
+	return (maxKeys + n - 1) / n
+}
+
+// shard returns the shard holding key.
+func (s *shardedStore) shard(key string) *memoryStore {
+	if len(s.shards) == 1 {
+		return s.shards[0]
+	}
+	return s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+}
+
+// Allow implements Store.
+func (s *shardedStore) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	return allowLimiter(s.shard(key), key, limit, time.Now()), nil
+}
+
+// Get implements LimiterStore.
+func (s *shardedStore) Get(key string) (*rate.Limiter, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Set implements LimiterStore.
+func (s *shardedStore) Set(key string, limiter *rate.Limiter) {
+	s.shard(key).Set(key, limiter)
+}
+
+// GetOrCreate implements LimiterCreator.
+func (s *shardedStore) GetOrCreate(key string, create func() *rate.Limiter) *rate.Limiter {
+	return s.shard(key).GetOrCreate(key, create)
+}
+
+// Size implements SizedStore.
+func (s *shardedStore) Size() ComponentMemory {
+	var cm ComponentMemory
+	for _, shard := range s.shards {
+		sc := shard.Size()
+		cm.Entries += sc.Entries
+		cm.Bytes += sc.Bytes
+	}
+	return cm
+}
+
+// evicted returns the number of keys evicted to stay within the key cap.
+func (s *shardedStore) evicted() uint64 {
+	var n uint64
+	for _, shard := range s.shards {
+		n += shard.evicted.Load()
+	}
+	return n
+}
+
+// setOnEvict calls f with the number of keys evicted at once.
+func (s *shardedStore) setOnEvict(f func(n int)) {
+	for _, shard := range s.shards {
+		shard.onEvict = f
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestNewMemoryStore(t *testing.T) {
+	t.Run("Shards", func(t *testing.T) {
+		s := NewMemoryStore(WithShards(8)).(*shardedStore)
+		require.Len(t, s.shards, 8)
+		for i := range 1000 {
+			s.Set(strconv.Itoa(i), rate.NewLimiter(1, 1))
+		}
+		assert.Equal(t, 1000, s.Size().Entries)
+		for _, shard := range s.shards {
+			assert.NotEmpty(t, shard.limiters)
+		}
+		_, ok := s.Get("42")
+		assert.True(t, ok)
+
+		a, err := s.Allow(context.Background(), "42", Limit{Rate: 1, Burst: 1, N: 1})
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+
+	t.Run("Automatic", func(t *testing.T) {
+		n := len(newShardedStore().shards)
+		assert.GreaterOrEqual(t, n, 4)
+		assert.Zero(t, n&(n-1), "a power of two")
+
+		// Every shard keeps enough keys to evict the least recently used.
+		s := newShardedStore(WithShards(64), WithMaxKeys(1000))
+		assert.Len(t, s.shards, 1000/minShardKeys)
+		assert.Equal(t, 67, s.shards[0].max)
+		assert.Len(t, newShardedStore(WithMaxKeys(10)).shards, 1)
+	})
+
+	t.Run("Idle TTL", func(t *testing.T) {
+		s := NewMemoryStore(WithShards(4), WithIdleTTL(20*time.Millisecond))
+		defer s.(io.Closer).Close()
+		s.(LimiterStore).Set("k", rate.NewLimiter(1, 1))
+		assert.Eventually(t, func() bool {
+			return s.(SizedStore).Size().Entries == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func BenchmarkMemoryStore(b *testing.B) {
+	for _, shards := range []int{1, 64} {
+		b.Run(fmt.Sprintf("Shards=%d", shards), func(b *testing.B) {
+			s := NewMemoryStore(WithShards(shards))
+			limit := Limit{Rate: rate.Inf, Burst: 1, N: 1}
+			ctx := context.Background()
+			var next atomic.Int64
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				key := strconv.FormatInt(next.Add(1), 10)
+				for pb.Next() {
+					_, _ = s.Allow(ctx, key, limit)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkMemoryStoreHandler measures the middleware itself, with its
+// per-key statistics, serving many clients at once.
+func BenchmarkMemoryStoreHandler(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	for _, shards := range []int{1, 64} {
+		b.Run(fmt.Sprintf("Shards=%d", shards), func(b *testing.B) {
+			r := gin.New()
+			r.Use(New(Options{
+				Rate:      rate.Inf,
+				Burst:     1,
+				Store:     NewMemoryStore(WithShards(shards)),
+				Headers:   HeadersNone,
+				Anonymize: &Anonymizer{Secret: []byte("secret")},
+			}))
+			r.GET("/", func(*gin.Context) {})
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = fmt.Sprintf("10.0.%d.1:1234", next.Add(1))
+				w := httptest.NewRecorder()
+				for pb.Next() {
+					r.ServeHTTP(w, req)
+				}
+			})
+		})
+	}
+}
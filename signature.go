@@ -0,0 +1,164 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// IdentitySigned is the identity type of requests bearing a valid
+// signature; see Signatures.
+const IdentitySigned = "signed"
+
+// Headers carrying request signatures.
+const (
+	SignatureKeyHeader       = "X-Signature-Key"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureHeader          = "X-Signature"
+)
+
+// Defaults for the Signatures options.
+const (
+	DefaultSignatureMaxSkew = 5 * time.Minute
+	DefaultSignatureMaxBody = 1 << 20
+)
+
+// Signatures verifies HMAC request signatures and gives signed requests
+// the limits of an authenticated tier, while unsigned requests, and those
+// whose signature does not verify, keep the anonymous limits of Options.
+//
+// Clients sign requests with SignRequest: X-Signature is the hex-encoded
+// HMAC-SHA256, keyed by the secret of the key in X-Signature-Key, of the
+// Unix time in X-Signature-Timestamp, the method, the request URI and the
+// hex-encoded SHA-256 of the body, separated by newlines.
+type Signatures struct {
+	// Secret returns the secret of a key, reporting false for unknown
+	// keys.
+	Secret func(keyID string) ([]byte, bool)
+
+	// Rate and Burst are the limits of signed requests, which are keyed
+	// by identity as "signed:<key ID>".
+	Rate  rate.Limit
+	Burst int
+
+	// MaxSkew is how far the signature timestamp may be from the server's
+	// clock, bounding replays. If zero, DefaultSignatureMaxSkew is used.
+	MaxSkew time.Duration
+	// MaxBody is the largest body verified; requests with larger bodies
+	// are treated as unsigned. If zero, DefaultSignatureMaxBody is used.
+	MaxBody int64
+}
+
+// SignRequest signs req for Signatures with the given key and secret, at
+// now. The body is read and replaced so it can still be sent.
+func SignRequest(req *http.Request, keyID string, secret []byte, now time.Time) error {
+	body, err := readBody(req, -1)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(SignatureKeyHeader, keyID)
+	req.Header.Set(SignatureTimestampHeader, ts)
+	req.Header.Set(SignatureHeader, hex.EncodeToString(signature(secret, ts, req, body)))
+	return nil
+}
+
+// signature returns the HMAC of a request with the given timestamp.
+func signature(secret []byte, ts string, req *http.Request, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "\n" + req.Method + "\n" + req.URL.RequestURI() + "\n"))
+	mac.Write([]byte(hex.EncodeToString(sum[:])))
+	return mac.Sum(nil)
+}
+
+// errBodyTooLarge is returned by readBody for bodies over the limit.
+var errBodyTooLarge = errors.New("ratelimit: body too large to verify")
+
+// readBody reads up to limit bytes of the body of req, or all of it if
+// limit is negative, leaving the body intact for later readers.
+func readBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	r := io.Reader(req.Body)
+	if limit >= 0 {
+		r = io.LimitReader(req.Body, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 && int64(len(body)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
+
+// signatureVerifier verifies the signatures of requests.
+type signatureVerifier struct {
+	cfg Signatures
+}
+
+// newSignatureVerifier creates a verifier, applying defaults.
+func newSignatureVerifier(cfg Signatures) *signatureVerifier {
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = DefaultSignatureMaxSkew
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = DefaultSignatureMaxBody
+	}
+	return &signatureVerifier{cfg: cfg}
+}
+
+// Resolve implements IdentityResolver, identifying requests with a valid
+// signature by their key.
+func (v *signatureVerifier) Resolve(c *gin.Context) (Identity, bool) {
+	keyID, ts := c.GetHeader(SignatureKeyHeader), c.GetHeader(SignatureTimestampHeader)
+	sig, err := hex.DecodeString(c.GetHeader(SignatureHeader))
+	if keyID == "" || ts == "" || err != nil || len(sig) == 0 || v.cfg.Secret == nil {
+		return Identity{}, false
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return Identity{}, false
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > v.cfg.MaxSkew || skew < -v.cfg.MaxSkew {
+		return Identity{}, false
+	}
+	secret, ok := v.cfg.Secret(keyID)
+	if !ok {
+		return Identity{}, false
+	}
+	body, err := readBody(c.Request, v.cfg.MaxBody)
+	if err != nil {
+		return Identity{}, false
+	}
+	if !hmac.Equal(sig, signature(secret, ts, c.Request, body)) {
+		return Identity{}, false
+	}
+	return Identity{Type: IdentitySigned, ID: keyID}, true
+}
+
+// signed reports whether key is the key of a signed identity.
+func signed(key string) bool {
+	return strings.HasPrefix(key, IdentitySigned+":")
+}
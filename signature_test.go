@@ -0,0 +1,90 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestSignatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secrets := map[string][]byte{"partner": []byte("s3cret")}
+
+	m := NewManager(Options{
+		Rate:  rate.Every(time.Hour),
+		Burst: 1,
+		Signatures: &Signatures{
+			Secret: func(keyID string) ([]byte, bool) {
+				secret, ok := secrets[keyID]
+				return secret, ok
+			},
+			Rate:    rate.Every(time.Hour),
+			Burst:   3,
+			MaxBody: 16,
+		},
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.POST("/orders", func(c *gin.Context) {
+		id, _ := IdentityFrom(c)
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, id.Key()+" "+string(body))
+	})
+	send := func(ip string, req *http.Request) *httptest.ResponseRecorder {
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	signed := func(keyID string, secret []byte, body string, at time.Time) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "/orders?id=1", strings.NewReader(body))
+		require.NoError(t, SignRequest(req, keyID, secret, at))
+		return req
+	}
+
+	t.Run("Signed tier", func(t *testing.T) {
+		for range 3 {
+			w := send("203.0.113.1", signed("partner", secrets["partner"], "{}", time.Now()))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "signed:partner {}", w.Body.String(), "the body is left for handlers")
+		}
+		w := send("203.0.113.1", signed("partner", secrets["partner"], "{}", time.Now()))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("Unsigned fall back", func(t *testing.T) {
+		for i, tt := range []struct {
+			name string
+			req  *http.Request
+		}{
+			{"wrong secret", signed("partner", []byte("guess"), "{}", time.Now())},
+			{"unknown key", signed("stranger", []byte("s3cret"), "{}", time.Now())},
+			{"stale", signed("partner", secrets["partner"], "{}", time.Now().Add(-time.Hour))},
+			{"large body", signed("partner", secrets["partner"], strings.Repeat("x", 17), time.Now())},
+		} {
+			ip := fmt.Sprintf("198.51.100.%d", i+1)
+			w := send(ip, tt.req)
+			assert.Equal(t, http.StatusOK, w.Code, tt.name)
+			assert.True(t, strings.HasPrefix(w.Body.String(), "ip:"+ip), tt.name)
+		}
+
+		// Tampering with the body breaks the signature.
+		req := signed("partner", secrets["partner"], "{}", time.Now())
+		req.Body = io.NopCloser(strings.NewReader(`{"evil":1}`))
+		w := send("192.0.2.1", req)
+		assert.Equal(t, "ip:192.0.2.1 {\"evil\":1}", w.Body.String())
+	})
+}
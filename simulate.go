@@ -0,0 +1,164 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SimulatedRequest is a request of the traffic replayed by Simulate.
+type SimulatedRequest struct {
+	// Key is the rate limiting key of the request.
+	Key string
+	// At is when the request arrives, from the start of the simulation.
+	At time.Duration
+	// Cost is the number of tokens the request takes. If zero, it takes
+	// one.
+	Cost int
+}
+
+// SimulationResult is how one algorithm handled the simulated traffic.
+type SimulationResult struct {
+	Algorithm string
+	Allowed   int
+	Denied    int
+	// Delayed is the number of allowed requests the algorithm held, and
+	// Delay their total wait.
+	Delayed int
+	Delay   time.Duration
+}
+
+// Divergence is a simulated request the algorithms did not all handle
+// alike.
+type Divergence struct {
+	Request SimulatedRequest
+	// Allowed and Denied are the algorithms that allowed and denied the
+	// request, in name order.
+	Allowed []string
+	Denied  []string
+}
+
+// Comparison is the outcome of Simulate.
+type Comparison struct {
+	// Requests is the number of simulated requests.
+	Requests int
+	// Results holds the outcome of every algorithm, in name order.
+	Results []SimulationResult
+	// Divergences are the requests not handled alike, in arrival order.
+	Divergences []Divergence
+}
+
+// SimulationAlgorithms returns new instances of the algorithms Simulate
+// compares by default: the token buckets of the middleware, the sliding
+// window log and GCRA.
+func SimulationAlgorithms() map[string]Algorithm {
+	return map[string]Algorithm{
+		"token bucket":   &tokenBucket{s: newMemoryStore()},
+		"sliding window": SlidingWindowLog(),
+		"gcra":           GCRA(),
+	}
+}
+
+// Simulate replays the same traffic through every algorithm with the given
+// limit, in simulated time, and reports how each handled it and which
+// requests they disagreed on, to help pick an algorithm with data.
+// Algorithms keep the state of earlier calls, so pass new instances. If
+// algorithms is nil, SimulationAlgorithms are used.
+func Simulate(
+	ctx context.Context, traffic []SimulatedRequest, limit LimitSpec, algorithms map[string]Algorithm,
+) (Comparison, error) {
+	if err := limit.Validate(); err != nil {
+		return Comparison{}, err
+	}
+	if algorithms == nil {
+		algorithms = SimulationAlgorithms()
+	}
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	traffic = slices.Clone(traffic)
+	slices.SortStableFunc(traffic, func(a, b SimulatedRequest) int {
+		return cmp.Compare(a.At, b.At)
+	})
+
+	c := Comparison{Requests: len(traffic), Results: make([]SimulationResult, len(names))}
+	for i, name := range names {
+		c.Results[i].Algorithm = name
+	}
+	r, burst := limit.Rate(), limit.BurstSize()
+	// A fixed start keeps clock-aligned algorithms deterministic.
+	start := time.Unix(0, 0)
+	for _, req := range traffic {
+		var d Divergence
+		for i, name := range names {
+			a, err := algorithms[name].Take(ctx, req.Key, r, burst, max(req.Cost, 1), start.Add(req.At))
+			if err != nil {
+				return c, fmt.Errorf("ratelimit: simulating %s: %w", name, err)
+			}
+			res := &c.Results[i]
+			if !a.Allowed {
+				res.Denied++
+				d.Denied = append(d.Denied, name)
+				continue
+			}
+			res.Allowed++
+			d.Allowed = append(d.Allowed, name)
+			if a.Delay > 0 {
+				res.Delayed++
+				res.Delay += a.Delay
+			}
+		}
+		if len(d.Allowed) > 0 && len(d.Denied) > 0 {
+			d.Request = req
+			c.Divergences = append(c.Divergences, d)
+		}
+	}
+	return c, nil
+}
+
+// String formats the comparison as a table of the outcomes of every
+// algorithm, followed by the number of requests they disagreed on.
+func (c Comparison) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "algorithm\tallowed\tdenied\tdelayed")
+	for _, res := range c.Results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", res.Algorithm, res.Allowed, res.Denied, res.Delayed)
+	}
+	_ = w.Flush()
+	fmt.Fprintf(&b, "%d of %d requests handled differently\n", len(c.Divergences), c.Requests)
+	return b.String()
+}
+
+// tokenBucket is the Algorithm of the token buckets of a LimiterStore,
+// deciding requests at the given time rather than the current one.
+type tokenBucket struct {
+	s *memoryStore
+}
+
+// Take implements Algorithm.
+func (t *tokenBucket) Take(
+	_ context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	return allowLimiter(t.s, key, Limit{Rate: r, Burst: burst, N: n}, now), nil
+}
+
+// Reset implements Algorithm.
+func (t *tokenBucket) Reset(_ context.Context, key string) error {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	delete(t.s.limiters, key)
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulate(t *testing.T) {
+	ctx := context.Background()
+	limit := LimitSpec{Requests: 2, Window: time.Second}
+	traffic := []SimulatedRequest{
+		{Key: "a", At: time.Second},
+		{Key: "a"},
+		{Key: "a"},
+		{Key: "a", At: 900 * time.Millisecond},
+		{Key: "b", At: 900 * time.Millisecond, Cost: 3},
+	}
+
+	c, err := Simulate(ctx, traffic, limit, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, c.Requests)
+	assert.Equal(t, []SimulationResult{
+		{Algorithm: "gcra", Allowed: 4, Denied: 1},
+		{Algorithm: "sliding window", Allowed: 3, Denied: 2},
+		{Algorithm: "token bucket", Allowed: 4, Denied: 1},
+	}, c.Results)
+
+	// Token buckets refill continuously, while the log waits for the
+	// whole window to pass.
+	assert.Equal(t, []Divergence{{
+		Request: SimulatedRequest{Key: "a", At: 900 * time.Millisecond},
+		Allowed: []string{"gcra", "token bucket"},
+		Denied:  []string{"sliding window"},
+	}}, c.Divergences)
+	assert.Equal(t, "algorithm       allowed  denied  delayed\n"+
+		"gcra            4        1       0\n"+
+		"sliding window  3        2       0\n"+
+		"token bucket    4        1       0\n"+
+		"1 of 5 requests handled differently\n", c.String())
+
+	t.Run("Delays", func(t *testing.T) {
+		c, err := Simulate(ctx, traffic[1:3], limit, map[string]Algorithm{"leaky bucket": LeakyBucket()})
+		require.NoError(t, err)
+		assert.Equal(t, []SimulationResult{
+			{Algorithm: "leaky bucket", Allowed: 2, Delayed: 1, Delay: 500 * time.Millisecond},
+		}, c.Results)
+		assert.Empty(t, c.Divergences)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := Simulate(ctx, traffic, LimitSpec{Requests: -1}, nil)
+		require.Error(t, err)
+	})
+}
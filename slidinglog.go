@@ -0,0 +1,146 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// slidingLog holds the times of the requests of one key in the current
+// window, oldest first.
+type slidingLog struct {
+	times  []time.Time
+	window time.Duration
+}
+
+// prune drops the requests that left the window at now.
+func (l *slidingLog) prune(now time.Time) {
+	if l.window <= 0 {
+		return
+	}
+	i := 0
+	for i < len(l.times) && !l.times[i].After(now.Add(-l.window)) {
+		i++
+	}
+	l.times = l.times[i:]
+}
+
+// slidingWindowLog is the in-memory sliding window log algorithm.
+type slidingWindowLog struct {
+	mu   sync.Mutex
+	logs map[string]*slidingLog
+	// sweepAt is the number of logs at which empty ones are next dropped.
+	sweepAt int
+}
+
+// minLogSweep is the fewest logs that trigger a sweep.
+const minLogSweep = 1024
+
+// SlidingWindowLog returns an Algorithm recording the time of every
+// request per key, in memory. Unlike token buckets, which may admit a
+// whole burst at the end of one window and another at the start of the
+// next, it never allows more than burst requests in any window of
+// burst/rate, at the cost of keeping up to burst timestamps per key.
+// The Redis-backed variant is redisstore.NewSlidingWindowLog.
+func SlidingWindowLog() Algorithm {
+	return &slidingWindowLog{
+		logs:    make(map[string]*slidingLog),
+		sweepAt: minLogSweep,
+	}
+}
+
+// Take implements Algorithm.
+func (s *slidingWindowLog) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	a, _, err := s.TakeLevels(ctx, []Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements LevelAlgorithm.
+func (s *slidingWindowLog) TakeLevels(_ context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	as := make([]Allowance, len(levels))
+	logs := make([]*slidingLog, len(levels))
+	allowed := true
+	for i, lv := range levels {
+		if lv.Rate == rate.Inf {
+			as[i] = Allowance{Allowed: true, Remaining: lv.Burst}
+			continue
+		}
+		logs[i] = s.log(lv.Key, now)
+		logs[i].window = logWindow(lv.Rate, lv.Burst)
+		logs[i].prune(now)
+		as[i] = logs[i].allowance(lv.Burst, n, now)
+		allowed = allowed && as[i].Allowed
+	}
+	if allowed {
+		for i, l := range logs {
+			if l == nil {
+				continue
+			}
+			for range n {
+				l.times = append(l.times, now)
+			}
+			as[i].Remaining -= n
+		}
+	}
+	i := MostRestrictive(as)
+	return as[i], i, nil
+}
+
+// log returns the log of key, creating it if needed.
+func (s *slidingWindowLog) log(key string, now time.Time) *slidingLog {
+	l, ok := s.logs[key]
+	if !ok {
+		l = &slidingLog{}
+		s.logs[key] = l
+	}
+	return l
+}
+
+// allowance reports whether n more requests fit in the log's limit of
+// burst, without recording them.
+func (l *slidingLog) allowance(burst, n int, now time.Time) Allowance {
+	count, need := len(l.times), max(n, 1)
+	if count+need <= burst {
+		return Allowance{Allowed: true, Remaining: burst - count}
+	}
+	a := Allowance{Remaining: max(0, burst-count), RetryAfter: -1}
+	if need <= burst && l.window > 0 {
+		// Wait for enough of the oldest requests to leave the window.
+		a.RetryAfter = l.times[count+need-burst-1].Add(l.window).Sub(now)
+	}
+	return a
+}
+
+// sweep drops the logs without requests in their window, once there are
+// sweepAt logs.
+func (s *slidingWindowLog) sweep(now time.Time) {
+	if len(s.logs) < s.sweepAt {
+		return
+	}
+	for key, l := range s.logs {
+		if l.prune(now); len(l.times) == 0 {
+			delete(s.logs, key)
+		}
+	}
+	s.sweepAt = max(minLogSweep, 2*len(s.logs))
+}
+
+// Reset implements Algorithm.
+func (s *slidingWindowLog) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, key)
+	return nil
+}
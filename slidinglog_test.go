@@ -0,0 +1,162 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestSlidingWindowLog(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	// 3 requests per 30s window.
+	r := Per(3, 30*time.Second)
+
+	t.Run("Window", func(t *testing.T) {
+		alg := SlidingWindowLog()
+		for i, at := range []time.Duration{0, 10 * time.Second, 20 * time.Second} {
+			a, err := alg.Take(ctx, "k", r, 3, 1, start.Add(at))
+			require.NoError(t, err)
+			assert.True(t, a.Allowed)
+			assert.Equal(t, 2-i, a.Remaining)
+		}
+
+		a, err := alg.Take(ctx, "k", r, 3, 1, start.Add(25*time.Second))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 5*time.Second, a.RetryAfter)
+
+		// The first request leaves the window after 30s.
+		a, err = alg.Take(ctx, "k", r, 3, 1, start.Add(30*time.Second))
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 0, a.Remaining)
+	})
+
+	t.Run("NoBoundaryBursts", func(t *testing.T) {
+		// A token bucket admits a full burst late in one window and
+		// refills for the next; the log never admits more than 3 in 30s.
+		alg := SlidingWindowLog()
+		allowed := 0
+		for at := 29 * time.Second; at < 40*time.Second; at += time.Second {
+			a, err := alg.Take(ctx, "k", r, 3, 1, start.Add(at))
+			require.NoError(t, err)
+			if a.Allowed {
+				allowed++
+			}
+		}
+		assert.Equal(t, 3, allowed)
+	})
+
+	t.Run("Cost", func(t *testing.T) {
+		alg := SlidingWindowLog()
+		a, err := alg.Take(ctx, "k", r, 3, 2, start)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+
+		a, err = alg.Take(ctx, "k", r, 3, 2, start.Add(time.Second))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 1, a.Remaining)
+		assert.Equal(t, 29*time.Second, a.RetryAfter)
+
+		a, err = alg.Take(ctx, "k", r, 3, 4, start)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Negative(t, a.RetryAfter)
+	})
+
+	t.Run("Peek", func(t *testing.T) {
+		alg := SlidingWindowLog()
+		for range 3 {
+			_, err := alg.Take(ctx, "k", r, 3, 1, start)
+			require.NoError(t, err)
+		}
+		a, err := alg.Take(ctx, "k", r, 3, 0, start.Add(10*time.Second))
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, 20*time.Second, a.RetryAfter)
+
+		require.NoError(t, alg.Reset(ctx, "k"))
+		a, err = alg.Take(ctx, "k", r, 3, 0, start.Add(10*time.Second))
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 3, a.Remaining)
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		alg := SlidingWindowLog().(*slidingWindowLog)
+		for i := range minLogSweep {
+			_, err := alg.Take(ctx, fmt.Sprint(i), r, 3, 1, start)
+			require.NoError(t, err)
+		}
+		_, err := alg.Take(ctx, "late", r, 3, 1, start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Len(t, alg.logs, 1)
+	})
+}
+
+func TestAlgorithmMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var retryAfter int
+	m := NewManager(Options{
+		Limit:     &LimitSpec{Requests: 2, Window: time.Minute},
+		Algorithm: SlidingWindowLog(),
+		OnLimitExceeded: func(c *gin.Context, l *rate.Limiter) {
+			retryAfter = newDenyData(c, l, time.Now()).RetryAfter
+			c.String(http.StatusTooManyRequests, "Too Many Requests")
+		},
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func() int {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, http.StatusTooManyRequests, serve())
+	assert.Equal(t, 60, retryAfter)
+
+	ev, err := m.Evaluate(SyntheticRequest{IP: "203.0.113.1"})
+	require.NoError(t, err)
+	assert.False(t, ev.Allowed)
+	assert.InDelta(t, time.Minute, ev.RetryAfter, float64(time.Second))
+
+	require.NoError(t, m.Reset(context.Background(), "203.0.113.1"))
+	assert.Equal(t, http.StatusOK, serve())
+}
+
+func TestAllowanceLimiter(t *testing.T) {
+	now := time.Now()
+	r := Per(10, time.Minute)
+
+	l := allowanceLimiter(r, 10, Allowance{Allowed: true, Remaining: 4}, now)
+	assert.InDelta(t, 4, l.TokensAt(now), 1e-6)
+
+	for _, wait := range []time.Duration{time.Second, 6 * time.Second, 45 * time.Second} {
+		l = allowanceLimiter(r, 10, Allowance{RetryAfter: wait}, now)
+		d := newDenyData(&gin.Context{}, l, now)
+		assert.Equal(t, int(wait.Seconds()), d.RetryAfter, wait.String())
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ClassStats counts the decisions made for a set of requests.
+type ClassStats struct {
+	Requests uint64 `json:"requests"`
+	Denied   uint64 `json:"denied"`
+}
+
+// classCounter counts the decisions made for a set of requests without
+// locking.
+type classCounter struct {
+	requests atomic.Uint64
+	denied   atomic.Uint64
+}
+
+// add counts a decision.
+func (cc *classCounter) add(allowed bool) {
+	cc.requests.Add(1)
+	if !allowed {
+		cc.denied.Add(1)
+	}
+}
+
+// load returns the counts.
+func (cc *classCounter) load() ClassStats {
+	return ClassStats{Requests: cc.requests.Load(), Denied: cc.denied.Load()}
+}
+
+// GuardrailStatus reports the state of a guardrail.
+type GuardrailStatus struct {
+	Class   string `json:"class"`
+	Tripped bool   `json:"tripped"`
+}
+
+// Stats summarizes the decisions made by a manager since it was created.
+// Counters only grow, so rates are the difference between two snapshots
+// divided by the time between them.
+type Stats struct {
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Total counts every decision.
+	Total ClassStats `json:"total"`
+	// Classes counts the decisions per traffic class.
+	Classes map[string]ClassStats `json:"classes,omitempty"`
+	// Bypassed counts the requests that bypassed limiting, which are not
+	// part of Total, by reason.
+	Bypassed map[BypassReason]uint64 `json:"bypassed,omitempty"`
+	// Keys is the number of keys with statistics: the keys seen, up to
+	// Options.MaxKeys.
+	Keys int `json:"keys"`
+	// Evicted counts the token buckets dropped from the in-memory store to
+	// stay within Options.MaxKeys.
+	Evicted uint64 `json:"evicted,omitempty"`
+	// Utilization is the global utilization reported in the backpressure
+	// header, between 0 and 1. It is nil without Options.Backpressure.
+	Utilization *float64 `json:"utilization,omitempty"`
+	// Guardrails reports the state of every guardrail.
+	Guardrails []GuardrailStatus `json:"guardrails,omitempty"`
+	// Learned lists the limits the outbound transport learned from
+	// upstream servers.
+	Learned []LearnedLimit `json:"learned,omitempty"`
+	// Anomalies lists the keys whose request rate is anomalous. It is
+	// empty without Options.Anomalies.
+	Anomalies []AnomalyStatus `json:"anomalies,omitempty"`
+	// Clock reports the wall-clock jumps detected and the skew observed by
+	// the Algorithm, if any.
+	Clock *ClockStats `json:"clock,omitempty"`
+	// Memory reports the memory held by the manager. It is nil without
+	// Options.MemoryStats.
+	Memory *MemoryStats `json:"memory,omitempty"`
+}
+
+// Stats returns a snapshot of the manager's decision counters.
+func (m *Manager) Stats() Stats {
+	now := time.Now()
+	st := Stats{Time: now}
+
+	st.Total = m.stats.total.load()
+	st.Keys = m.stats.len()
+	m.stats.bypassed.Range(func(reason, n any) bool {
+		if st.Bypassed == nil {
+			st.Bypassed = make(map[BypassReason]uint64)
+		}
+		st.Bypassed[reason.(BypassReason)] = n.(*atomic.Uint64).Load()
+		return true
+	})
+	m.stats.classes.Range(func(class, cc any) bool {
+		if st.Classes == nil {
+			st.Classes = make(map[string]ClassStats)
+		}
+		st.Classes[class.(string)] = cc.(*classCounter).load()
+		return true
+	})
+	if m.owned != nil {
+		st.Evicted = m.owned.evicted()
+	}
+
+	if m.backpressure != nil {
+		u := m.backpressure.current(now)
+		st.Utilization = &u
+	}
+	for _, g := range m.guardrails {
+		st.Guardrails = append(st.Guardrails, GuardrailStatus{
+			Class:   g.cfg.Class,
+			Tripped: g.isTripped(),
+		})
+	}
+	st.Learned = m.learned.list(now)
+	if m.anomalies != nil {
+		st.Anomalies = m.anomalies.flagged()
+		for i := range st.Anomalies {
+			st.Anomalies[i].Key = m.anonymize(st.Anomalies[i].Key, now)
+		}
+	}
+	st.Clock = m.clockStats()
+	if m.opts.MemoryStats {
+		st.Memory = m.memoryStats()
+	}
+	return st
+}
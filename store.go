@@ -0,0 +1,125 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StoreFactory creates a store from a DSN, such as
+// "redis://localhost:6379/0".
+type StoreFactory func(dsn *url.URL) (Store, error)
+
+var (
+	storesMu sync.RWMutex
+	// storeFactories create stores from DSNs, by URL scheme.
+	storeFactories = map[string]StoreFactory{
+		"memory": newMemoryStoreFromDSN,
+	}
+)
+
+// RegisterStore makes a store backend available to NewStoreFromDSN, and
+// so to OptionsFromEnv, under the given URL scheme. Modules providing
+// backends call it from an init function, so importing them is enough:
+//
+//	func init() {
+//		ratelimit.RegisterStore("aerospike", newStore)
+//	}
+//
+// RegisterStore panics if the scheme is empty or already registered, or if
+// factory is nil.
+func RegisterStore(scheme string, factory StoreFactory) {
+	if scheme == "" {
+		panic("ratelimit: RegisterStore with empty scheme")
+	}
+	if factory == nil {
+		panic("ratelimit: RegisterStore with nil factory for " + scheme)
+	}
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if _, dup := storeFactories[scheme]; dup {
+		panic("ratelimit: RegisterStore called twice for " + scheme)
+	}
+	storeFactories[scheme] = factory
+}
+
+// StoreSchemes returns the sorted schemes NewStoreFromDSN accepts.
+func StoreSchemes() []string {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	schemes := make([]string, 0, len(storeFactories))
+	for scheme := range storeFactories {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// NewStoreFromDSN creates a store from a DSN whose scheme names the
+// backend, so stores can be configured with a single string:
+//
+//	memory://                      in-memory store
+//	memory://?max=10000            in-memory store keeping at most 10000 keys
+//	memory://?ttl=10m              in-memory store evicting keys unused for 10m
+//	memory://?shards=64            in-memory store with 64 shards
+//	redis://:password@host:6379/0  Redis store; rediss:// for TLS
+//	rueidis://host:6379/0          Redis store on rueidis; rueidiss:// for TLS
+//	etcd://host1:2379,host2:2379   etcd store
+//
+// Backends other than memory are registered with RegisterStore by the
+// modules providing them, so Redis DSNs need an import of
+// github.com/gin-contrib/ratelimit/redisstore, and the others of
+// rueidisstore or etcdstore.
+func NewStoreFromDSN(dsn string) (Store, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: invalid store DSN: %w", err)
+	}
+	storesMu.RLock()
+	factory, ok := storeFactories[u.Scheme]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("ratelimit: unknown store %q", u.Scheme)
+	}
+	store, err := factory(u)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit: invalid %s store DSN: %w", u.Scheme, err)
+	}
+	return store, nil
+}
+
+func newMemoryStoreFromDSN(dsn *url.URL) (Store, error) {
+	var opts []MemoryStoreOption
+	for name, values := range dsn.Query() {
+		switch name {
+		case "max":
+			n, err := strconv.Atoi(values[0])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid max %q", values[0])
+			}
+			opts = append(opts, WithMaxKeys(n))
+		case "ttl":
+			ttl, err := time.ParseDuration(values[0])
+			if err != nil || ttl < 0 {
+				return nil, fmt.Errorf("invalid ttl %q", values[0])
+			}
+			opts = append(opts, WithIdleTTL(ttl))
+		case "shards":
+			n, err := strconv.Atoi(values[0])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid shards %q", values[0])
+			}
+			opts = append(opts, WithShards(n))
+		default:
+			return nil, fmt.Errorf("unknown option %q", name)
+		}
+	}
+	return newShardedStore(opts...), nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestNewStoreFromDSN(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		s, err := NewStoreFromDSN("memory://")
+		require.NoError(t, err)
+		assert.IsType(t, &shardedStore{}, s)
+
+		s, err = NewStoreFromDSN("memory://?max=10000")
+		require.NoError(t, err)
+		assert.Equal(t, 10000, s.(*shardedStore).max)
+
+		s, err = NewStoreFromDSN("memory://?shards=8")
+		require.NoError(t, err)
+		assert.Len(t, s.(*shardedStore).shards, 8)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, dsn := range []string{
+			"",
+			"postgres://localhost/ratelimit",
+			"memory://?max=many",
+			"memory://?shards=-1",
+			"memory://?size=10",
+			"redis://localhost:6379/0",
+			"::",
+		} {
+			_, err := NewStoreFromDSN(dsn)
+			assert.Error(t, err, dsn)
+		}
+	})
+}
+
+func TestRegisterStore(t *testing.T) {
+	t.Cleanup(func() {
+		storesMu.Lock()
+		delete(storeFactories, "test")
+		storesMu.Unlock()
+	})
+
+	var got *url.URL
+	RegisterStore("test", func(dsn *url.URL) (Store, error) {
+		got = dsn
+		if dsn.Host == "" {
+			return nil, errors.New("missing host")
+		}
+		return newMemoryStore(), nil
+	})
+	assert.Equal(t, []string{"memory", "test"}, StoreSchemes())
+
+	s, err := NewStoreFromDSN("test://cluster-1/ns?replicas=3")
+	require.NoError(t, err)
+	assert.NotNil(t, s)
+	assert.Equal(t, "cluster-1", got.Host)
+	assert.Equal(t, "3", got.Query().Get("replicas"))
+
+	_, err = NewStoreFromDSN("test:///ns")
+	assert.EqualError(t, err, "ratelimit: invalid test store DSN: missing host")
+
+	assert.Panics(t, func() { RegisterStore("test", newMemoryStoreFromDSN) })
+	assert.Panics(t, func() { RegisterStore("", newMemoryStoreFromDSN) })
+	assert.Panics(t, func() { RegisterStore("other", nil) })
+}
+
+func TestMemoryStoreMax(t *testing.T) {
+	s := newMemoryStore()
+	s.max = 3
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		l := rate.NewLimiter(rate.Every(time.Hour), 1)
+		if i > 0 {
+			l.AllowN(now, 1)
+		}
+		s.Set(fmt.Sprint(i), l)
+	}
+
+	// Full limiters make room first.
+	s.Set("3", rate.NewLimiter(1, 1))
+	_, ok := s.Get("0")
+	assert.False(t, ok)
+	assert.Len(t, s.limiters, 3)
+
+	// Without any, the least recently used limiter is dropped.
+	s.limiters["3"].limiter.AllowN(now, 1)
+	s.limiters["1"].used.Store(now.Add(time.Second).UnixNano())
+	s.limiters["2"].used.Store(now.Add(-time.Second).UnixNano())
+	s.Set("4", rate.NewLimiter(1, 1))
+	assert.Len(t, s.limiters, 3)
+	for key, kept := range map[string]bool{"1": true, "2": false, "3": true, "4": true} {
+		_, ok = s.Get(key)
+		assert.Equal(t, kept, ok, key)
+	}
+	assert.Equal(t, uint64(2), s.evicted.Load())
+
+	// Replacing a limiter never evicts.
+	s.Set("4", rate.NewLimiter(1, 1))
+	assert.Len(t, s.limiters, 3)
+}
+
+func TestMemoryStoreEvictsBatches(t *testing.T) {
+	s := newMemoryStore()
+	s.max = 64
+	var passes []int
+	s.onEvict = func(n int) { passes = append(passes, n) }
+	now := time.Now()
+	for i := range 64 {
+		l := rate.NewLimiter(rate.Every(time.Hour), 1)
+		if i > 0 {
+			l.AllowN(now, 1)
+		}
+		s.Set(fmt.Sprint(i), l)
+	}
+
+	// A single full limiter does not make a batch: the least recently used
+	// make up the rest, so the next new keys need no pass.
+	for i := range 4 {
+		l := rate.NewLimiter(rate.Every(time.Hour), 1)
+		l.AllowN(now, 1)
+		s.Set(fmt.Sprint("new", i), l)
+	}
+	assert.Equal(t, []int{4}, passes)
+	assert.Len(t, s.limiters, 64)
+	_, ok := s.Get("0")
+	assert.False(t, ok)
+}
+
+func TestMaxKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var events []Event
+	m := NewManager(Options{
+		Rate:    rate.Every(time.Hour),
+		Burst:   2,
+		MaxKeys: 32,
+		OnEvent: func(e Event) { events = append(events, e) },
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	serve := func(key string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = key + ":1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A flood of new keys evicts the least recently used ones, two at a
+	// time, and keeps the active client.
+	for i := range 40 {
+		assert.Equal(t, http.StatusOK, serve(fmt.Sprintf("10.0.1.%d", i)))
+		serve("10.0.0.1")
+	}
+	assert.LessOrEqual(t, m.owned.Size().Entries, 32)
+	assert.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1"))
+
+	st := m.Stats()
+	assert.Equal(t, uint64(10), st.Evicted)
+	require.Len(t, events, 5)
+	assert.Equal(t, EventKeysEvicted, events[0].Type)
+	assert.Equal(t, "evicted 2 keys to stay within 32", events[0].Message)
+}
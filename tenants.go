@@ -0,0 +1,206 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Defaults for the Tenants options.
+const (
+	DefaultTenantMaxFailures = 3
+	DefaultTenantCooldown    = 10 * time.Second
+)
+
+// FailurePolicy is what a TenantRouter decides for the requests of a
+// tenant whose backend fails.
+type FailurePolicy int
+
+// Failure policies.
+const (
+	// FailOpen allows the requests.
+	FailOpen FailurePolicy = iota
+	// FailClosed denies the requests.
+	FailClosed
+)
+
+// TenantBackend is the algorithm a tenant is limited with, typically one
+// backed by the tenant's own store.
+type TenantBackend struct {
+	Algorithm Algorithm
+	// OnFailure is the decision for the tenant's requests while its
+	// algorithm fails.
+	OnFailure FailurePolicy
+}
+
+// Tenants configures a TenantRouter.
+type Tenants struct {
+	// Tenant returns the tenant of a key.
+	Tenant func(key string) string
+	// Backends are the backends of the tenants that have their own.
+	Backends map[string]TenantBackend
+	// Default is the backend of all other tenants.
+	Default TenantBackend
+
+	// MaxFailures is the number of consecutive failures after which a
+	// backend is considered unhealthy. If zero, DefaultTenantMaxFailures
+	// is used.
+	MaxFailures int
+	// Cooldown is how long an unhealthy backend is left alone, its
+	// tenant's requests getting its failure policy right away, before it
+	// is tried again. If zero, DefaultTenantCooldown is used.
+	Cooldown time.Duration
+}
+
+// TenantHealth reports the health of a tenant's backend.
+type TenantHealth struct {
+	Healthy bool `json:"healthy"`
+	// Failures is the number of consecutive failures.
+	Failures int `json:"failures"`
+	// LastError is the last error of the backend, if any.
+	LastError string `json:"lastError,omitempty"`
+	// RetryAt is when an unhealthy backend is tried again.
+	RetryAt time.Time `json:"retryAt"`
+}
+
+// TenantRouter is an Algorithm routing every key to the backend of its
+// tenant, such as a tenant's dedicated Redis. The health of each backend
+// is tracked on its own, so a failing backend only applies its failure
+// policy to its own tenant while the others are limited as usual.
+type TenantRouter struct {
+	cfg Tenants
+
+	mu     sync.Mutex
+	health map[string]*TenantHealth
+}
+
+// NewTenantRouter creates a router from the given configuration.
+func NewTenantRouter(cfg Tenants) *TenantRouter {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = DefaultTenantMaxFailures
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultTenantCooldown
+	}
+	return &TenantRouter{cfg: cfg, health: make(map[string]*TenantHealth)}
+}
+
+// route returns the backend name and backend of key. Tenants without a
+// backend of their own share the default one, named "".
+func (t *TenantRouter) route(key string) (string, TenantBackend) {
+	if t.cfg.Tenant != nil {
+		tenant := t.cfg.Tenant(key)
+		if b, ok := t.cfg.Backends[tenant]; ok {
+			return tenant, b
+		}
+	}
+	return "", t.cfg.Default
+}
+
+// Take implements Algorithm.
+func (t *TenantRouter) Take(
+	ctx context.Context, key string, r rate.Limit, burst, n int, now time.Time,
+) (Allowance, error) {
+	a, _, err := t.TakeLevels(ctx, []Level{{Key: key, Rate: r, Burst: burst}}, n, now)
+	return a, err
+}
+
+// TakeLevels implements LevelAlgorithm. The levels are routed by the key
+// of the last one, the request's own key.
+func (t *TenantRouter) TakeLevels(ctx context.Context, levels []Level, n int, now time.Time) (Allowance, int, error) {
+	last := len(levels) - 1
+	name, b := t.route(levels[last].Key)
+	if !t.available(name, now) {
+		return t.fail(b), last, nil
+	}
+	a, i, err := takeLevels(ctx, b.Algorithm, levels, n, now)
+	t.report(name, err, now)
+	if err != nil {
+		return t.fail(b), last, nil
+	}
+	return a, i, nil
+}
+
+// TakeCosts implements CostAlgorithm, routing the levels as TakeLevels
+// does. It returns ErrCostsUnsupported, without counting a failure, if
+// the backend cannot take the costs in one step.
+func (t *TenantRouter) TakeCosts(ctx context.Context, levels []Level, costs []int, now time.Time) (
+	Allowance, int, error,
+) {
+	last := len(levels) - 1
+	name, b := t.route(levels[last].Key)
+	if !t.available(name, now) {
+		return t.fail(b), last, nil
+	}
+	a, i, err := takeCosts(ctx, b.Algorithm, levels, costs, now)
+	if errors.Is(err, ErrCostsUnsupported) {
+		return Allowance{}, 0, err
+	}
+	t.report(name, err, now)
+	if err != nil {
+		return t.fail(b), last, nil
+	}
+	return a, i, nil
+}
+
+// Reset implements Algorithm.
+func (t *TenantRouter) Reset(ctx context.Context, key string) error {
+	_, b := t.route(key)
+	return b.Algorithm.Reset(ctx, key)
+}
+
+// Health returns the health of every backend used so far, by tenant; the
+// default backend is reported under "".
+func (t *TenantRouter) Health() map[string]TenantHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	health := make(map[string]TenantHealth, len(t.health))
+	for name, h := range t.health {
+		health[name] = *h
+	}
+	return health
+}
+
+// available reports whether the named backend should be tried at now.
+func (t *TenantRouter) available(name string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.health[name]
+	return !ok || h.Healthy || !now.Before(h.RetryAt)
+}
+
+// report records the outcome of a call to the named backend.
+func (t *TenantRouter) report(name string, err error, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.health[name]
+	if !ok {
+		h = &TenantHealth{Healthy: true}
+		t.health[name] = h
+	}
+	if err == nil {
+		*h = TenantHealth{Healthy: true}
+		return
+	}
+	h.Failures++
+	h.LastError = err.Error()
+	if h.Failures >= t.cfg.MaxFailures {
+		h.Healthy = false
+		h.RetryAt = now.Add(t.cfg.Cooldown)
+	}
+}
+
+// fail returns the allowance the failure policy of b decides.
+func (t *TenantRouter) fail(b TenantBackend) Allowance {
+	if b.OnFailure == FailClosed {
+		return Allowance{RetryAfter: t.cfg.Cooldown}
+	}
+	return Allowance{Allowed: true}
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// brokenAlgorithm fails every call, counting them.
+type brokenAlgorithm struct {
+	calls int
+}
+
+func (b *brokenAlgorithm) Take(context.Context, string, rate.Limit, int, int, time.Time) (Allowance, error) {
+	b.calls++
+	return Allowance{}, errors.New("connection refused")
+}
+
+func (b *brokenAlgorithm) Reset(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+func TestTenantRouter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	r := rate.Every(time.Hour)
+
+	broken := &brokenAlgorithm{}
+	router := NewTenantRouter(Tenants{
+		Tenant: func(key string) string {
+			tenant, _, _ := strings.Cut(key, "/")
+			return tenant
+		},
+		Backends: map[string]TenantBackend{
+			"acme":    {Algorithm: broken, OnFailure: FailClosed},
+			"initech": {Algorithm: broken, OnFailure: FailOpen},
+		},
+		Default:     TenantBackend{Algorithm: GCRA()},
+		MaxFailures: 2,
+		Cooldown:    time.Minute,
+	})
+
+	t.Run("Failure policies", func(t *testing.T) {
+		a, err := router.Take(ctx, "acme/alice", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed)
+		assert.Equal(t, time.Minute, a.RetryAfter)
+
+		a, err = router.Take(ctx, "initech/bob", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+	})
+
+	t.Run("Other tenants are unaffected", func(t *testing.T) {
+		a, err := router.Take(ctx, "globex/carol", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.True(t, a.Allowed)
+		a, err = router.Take(ctx, "globex/carol", r, 1, 1, now)
+		require.NoError(t, err)
+		assert.False(t, a.Allowed, "the default backend still limits")
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		_, err := router.Take(ctx, "acme/alice", r, 1, 1, now)
+		require.NoError(t, err)
+		health := router.Health()
+		assert.False(t, health["acme"].Healthy)
+		assert.Equal(t, "connection refused", health["acme"].LastError)
+		assert.True(t, health["initech"].Healthy, "one failure is below MaxFailures")
+		assert.True(t, health[""].Healthy)
+
+		// Unhealthy backends are left alone until the cooldown is over.
+		calls := broken.calls
+		_, err = router.Take(ctx, "acme/alice", r, 1, 1, now.Add(30*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, calls, broken.calls)
+		_, err = router.Take(ctx, "acme/alice", r, 1, 1, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, calls+1, broken.calls)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		require.NoError(t, router.Reset(ctx, "globex/carol"))
+		assert.Error(t, router.Reset(ctx, "acme/alice"))
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// SetQuota records q as the quota of the request, as the middleware does
+// before calling the next handler, so handlers reading QuotaFrom can be
+// tested with a context from gin.CreateTestContext, without the
+// middleware.
+func SetQuota(c *gin.Context, q Quota) {
+	c.Set(quotaKey, q)
+}
+
+// Deny responds to the request as the middleware does when its key is out
+// of tokens: it sets the headers of an empty bucket of the key's limits,
+// calls OnLimitExceeded and aborts. Nothing is recorded and no tokens are
+// taken. Deny is a gin.HandlerFunc, so tests can also use it in place of
+// the middleware to check how handlers and deny responses behave under
+// 429 without exhausting a limit in real time.
+func (m *Manager) Deny(c *gin.Context) {
+	m.identify(c)
+	m.classify(c)
+	key := m.key(c)
+	r, burst := m.limitsFor(key)
+	now := time.Now()
+	limiter := rate.NewLimiter(r, burst)
+	limiter.AllowN(now, burst)
+
+	setQuota(c, key, limiter, false)
+	m.setHeaders(c, limiter)
+	c.Set(limitKeyContextKey, key)
+	m.setRetryAfter(c, limiter)
+	m.opts.OnLimitExceeded(c, limiter)
+	c.Abort()
+}
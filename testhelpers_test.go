@@ -0,0 +1,92 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestTestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func() (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+		return c, w
+	}
+
+	t.Run("Middleware", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		c, _ := newContext()
+		m.Handler()(c)
+		assert.False(t, c.IsAborted())
+		assert.Equal(t, 0, Remaining(c))
+
+		c, w := newContext()
+		m.Handler()(c)
+		assert.True(t, c.IsAborted())
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		// Contexts without a request are let through.
+		c, _ = gin.CreateTestContext(httptest.NewRecorder())
+		m.Handler()(c)
+		assert.False(t, c.IsAborted())
+	})
+
+	t.Run("SetQuota", func(t *testing.T) {
+		c, _ := newContext()
+		SetQuota(c, Quota{Key: "alice", Allowed: true, Limit: 10, Remaining: 3})
+		assert.Equal(t, 3, Remaining(c))
+		assert.Equal(t, 10, LimitFrom(c))
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		var key string
+		m := NewManager(Options{
+			Rate:  rate.Every(time.Minute),
+			Burst: 5,
+			OnLimitExceeded: func(c *gin.Context, l *rate.Limiter) {
+				key = newDenyData(c, l, time.Now()).Key
+				c.String(http.StatusTooManyRequests, "slow down")
+			},
+		})
+		c, w := newContext()
+		m.Deny(c)
+		assert.True(t, c.IsAborted())
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "slow down", w.Body.String())
+		assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		assert.Equal(t, c.ClientIP(), key)
+		q, ok := QuotaFrom(c)
+		assert.True(t, ok)
+		assert.False(t, q.Allowed)
+
+		// No tokens were taken.
+		c, _ = newContext()
+		m.Handler()(c)
+		assert.Equal(t, 4, Remaining(c))
+
+		// Deny can replace the middleware in front of handlers.
+		r := gin.New()
+		r.Use(m.Deny)
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, "OK")
+		})
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TransportHostKey limits outbound requests per destination host.
+func TransportHostKey(r *http.Request) string {
+	return r.URL.Host
+}
+
+// TransportTokenKey limits outbound requests per destination host and
+// credential, so every API token sent in the Authorization header gets its
+// own budget. Credentials are hashed rather than stored.
+func TransportTokenKey(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return r.URL.Host
+	}
+	sum := sha256.Sum256([]byte(auth))
+	return r.URL.Host + "#" + hex.EncodeToString(sum[:8])
+}
+
+// transport paces outbound requests with a manager's limits.
+type transport struct {
+	m    *Manager
+	base http.RoundTripper
+	key  func(*http.Request) string
+
+	mu sync.Mutex
+	// retryAfter holds the keys a server asked to back off, and until when.
+	retryAfter map[string]time.Time
+}
+
+// Transport returns an http.RoundTripper that paces requests sent through
+// base with the manager's limits, so one facility limits both incoming and
+// outgoing traffic. Requests wait for a token of their key, as returned by
+// key, rather than failing; TransportHostKey is used if key is nil. With
+// Options.Algorithm or a Store deciding requests itself, such as the Redis
+// store, tokens are taken from it, so all instances sharing it share the
+// third party's quota; otherwise they come from the manager's buckets. When a response is 429 Too Many Requests or 503 Service
+// Unavailable with a Retry-After header, further requests with the same
+// key are held until the server is ready. Limits advertised by the server
+// in RateLimit or X-RateLimit headers, and 429 responses, lower the
+// key's limit for a while; see Stats.Learned. If base is nil,
+// http.DefaultTransport is used.
+//
+// A request fails with its context's error if the context is done before
+// it may be sent.
+func (m *Manager) Transport(base http.RoundTripper, key func(*http.Request) string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if key == nil {
+		key = TransportHostKey
+	}
+	return &transport{
+		m:          m,
+		base:       base,
+		key:        key,
+		retryAfter: make(map[string]time.Time),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := normalizeKey(t.key(req), t.m.opts.MaxKeyLength)
+	if err := t.backOff(ctx, key); err != nil {
+		return nil, err
+	}
+	if err := t.wait(ctx, key); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	wait, ok := t.m.learn(key, resp, now)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, retry := parseRetryAfter(resp.Header.Get("Retry-After"), now); retry {
+			wait, ok = max(wait, d), true
+		}
+	}
+	if ok {
+		t.mu.Lock()
+		t.retryAfter[key] = now.Add(wait)
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// wait waits for a token of key. Tokens are taken from the manager's
+// Algorithm if it has one, waiting out its denials and delays; as in the
+// middleware, a failing Algorithm lets requests through.
+func (t *transport) wait(ctx context.Context, key string) error {
+	alg := t.m.opts.Algorithm
+	if alg == nil {
+		return t.m.limiter(key).Wait(ctx)
+	}
+	r, burst := t.m.limitsFor(key)
+	for {
+		a, err := alg.Take(ctx, key, r, burst, 1, time.Now())
+		switch {
+		case err != nil:
+			return nil
+		case a.Allowed:
+			return sleep(ctx, a.Delay)
+		case a.RetryAfter < 0:
+			return fmt.Errorf("ratelimit: outbound limit of %q allows no requests", key)
+		}
+		if err := sleep(ctx, max(a.RetryAfter, time.Millisecond)); err != nil {
+			return err
+		}
+	}
+}
+
+// backOff waits until the server's last Retry-After for key has passed.
+func (t *transport) backOff(ctx context.Context, key string) error {
+	t.mu.Lock()
+	until, ok := t.retryAfter[key]
+	if ok && !time.Now().Before(until) {
+		delete(t.retryAfter, key)
+		ok = false
+	}
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return sleep(ctx, time.Until(until))
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or
+// as an HTTP date, into the delay from now.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(0, seconds)) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, t.Sub(now)), true
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestTransport(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 && r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	get := func(ctx context.Context, client *http.Client, path string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	t.Run("Pace", func(t *testing.T) {
+		calls.Store(10)
+		m := NewManager(Options{Rate: 20, Burst: 1})
+		client := &http.Client{Transport: m.Transport(nil, nil)}
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			resp, err := get(context.Background(), client, "/")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+		// Requests waited for tokens instead of failing.
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+		// The limiter is shared with the manager under the host key.
+		_, ok := m.limiters.Get(srv.Listener.Addr().String())
+		assert.True(t, ok)
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 1})
+		client := &http.Client{Transport: m.Transport(nil, nil)}
+
+		_, err := get(context.Background(), client, "/")
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = get(ctx, client, "/")
+		assert.Error(t, err)
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		// Instances sharing a store share the quota of the server.
+		shared := GCRA()
+		var clients []*http.Client
+		for range 2 {
+			m := NewManager(Options{Rate: rate.Every(time.Hour), Burst: 2, Algorithm: shared})
+			clients = append(clients, &http.Client{Transport: m.Transport(nil, nil)})
+		}
+		for _, client := range clients {
+			_, err := get(context.Background(), client, "/")
+			require.NoError(t, err)
+		}
+		for _, client := range clients {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			_, err := get(ctx, client, "/")
+			cancel()
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("SharedPace", func(t *testing.T) {
+		shared := GCRA()
+		m := NewManager(Options{Rate: 20, Burst: 1, Algorithm: shared})
+		client := &http.Client{Transport: m.Transport(nil, nil)}
+		start := time.Now()
+		for range 3 {
+			_, err := get(context.Background(), client, "/")
+			require.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		calls.Store(0)
+		m := NewManager(Options{Rate: rate.Inf})
+		client := &http.Client{Transport: m.Transport(nil, nil)}
+
+		resp, err := get(context.Background(), client, "/busy")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+		// The next request is held until the server is ready again.
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = get(ctx, client, "/")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		start := time.Now()
+		resp, err = get(context.Background(), client, "/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = parseRetryAfter("Mon, 01 Jan 2024 00:00:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	d, ok = parseRetryAfter("Sun, 31 Dec 2023 00:00:00 GMT", now)
+	assert.True(t, ok)
+	assert.Zero(t, d)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+}
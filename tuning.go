@@ -0,0 +1,224 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Defaults of the Tuning options.
+const (
+	// DefaultTargetDenial is the share of requests the suggested limits
+	// deny when Tuning.TargetDenial is zero.
+	DefaultTargetDenial = 0.01
+	// DefaultTuningKeys is the most keys sampled per route and class when
+	// Tuning.MaxKeys is zero.
+	DefaultTuningKeys = 1000
+	// DefaultTuningSamples is the most requests kept per key when
+	// Tuning.MaxSamples is zero.
+	DefaultTuningSamples = 1000
+)
+
+// Tuning observes the traffic of every route and traffic class and
+// suggests the limits that would have denied a target share of it, to take
+// the guesswork out of configuring new limits. It is typically enabled
+// along with DryRun, so the traffic observed is not shaped by limits being
+// tuned. Suggestions are served by SuggestLimits and the admin endpoint
+// GET /suggestions.
+type Tuning struct {
+	// TargetDenial is the share of requests the suggested limits would
+	// have denied, between 0 and 1. If zero, DefaultTargetDenial is used.
+	TargetDenial float64
+
+	// MaxKeys is the most keys sampled per route and class; requests of
+	// further keys are not observed. If zero, DefaultTuningKeys is used.
+	MaxKeys int
+
+	// MaxSamples is the most recent requests kept per key. If zero,
+	// DefaultTuningSamples is used.
+	MaxSamples int
+}
+
+// Suggestion is the limit suggested for the requests of a route and
+// traffic class.
+type Suggestion struct {
+	// Route is the route pattern, or empty for unmatched requests.
+	Route string `json:"route"`
+	// Class is the traffic class assigned by Options.Classifier, if any.
+	Class string `json:"class,omitempty"`
+	// Rate and Burst are the suggested limit of every key.
+	Rate  rate.Limit `json:"rate"`
+	Burst int        `json:"burst"`
+	// Keys and Requests are the numbers of keys and requests observed.
+	Keys     int `json:"keys"`
+	Requests int `json:"requests"`
+	// Denial is the share of the observed requests the suggested limit
+	// would have denied.
+	Denial float64 `json:"denial"`
+}
+
+// tuningGroup identifies the requests a limit is suggested for.
+type tuningGroup struct {
+	route string
+	class string
+}
+
+// tuningKey holds the recent requests of a key.
+type tuningKey struct {
+	times []time.Time
+	// truncated reports whether older requests were dropped.
+	truncated bool
+}
+
+// tuner samples the requests of every group.
+type tuner struct {
+	cfg   Tuning
+	start time.Time
+
+	mu     sync.Mutex
+	groups map[tuningGroup]map[string]*tuningKey
+}
+
+// newTuner creates a tuner with the defaults applied.
+func newTuner(cfg Tuning, now time.Time) *tuner {
+	if cfg.TargetDenial <= 0 {
+		cfg.TargetDenial = DefaultTargetDenial
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = DefaultTuningKeys
+	}
+	if cfg.MaxSamples <= 0 {
+		cfg.MaxSamples = DefaultTuningSamples
+	}
+	return &tuner{
+		cfg:    cfg,
+		start:  now,
+		groups: make(map[tuningGroup]map[string]*tuningKey),
+	}
+}
+
+// observe samples a request of key.
+func (t *tuner) observe(route, class, key string, now time.Time) {
+	g := tuningGroup{route: route, class: class}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys, ok := t.groups[g]
+	if !ok {
+		keys = make(map[string]*tuningKey)
+		t.groups[g] = keys
+	}
+	k, ok := keys[key]
+	if !ok {
+		if len(keys) >= t.cfg.MaxKeys {
+			return
+		}
+		k = &tuningKey{}
+		keys[key] = k
+	}
+	if len(k.times) >= t.cfg.MaxSamples {
+		k.times = slices.Delete(k.times, 0, 1)
+		k.truncated = true
+	}
+	k.times = append(k.times, now)
+}
+
+// suggest returns the suggested limit of every group, sorted by route and
+// class.
+func (t *tuner) suggest(now time.Time) []Suggestion {
+	t.mu.Lock()
+	groups := make(map[tuningGroup][][]time.Time, len(t.groups))
+	windows := make(map[tuningGroup][]time.Duration, len(t.groups))
+	for g, keys := range t.groups {
+		for _, k := range keys {
+			from := t.start
+			if k.truncated {
+				from = k.times[0]
+			}
+			groups[g] = append(groups[g], slices.Clone(k.times))
+			windows[g] = append(windows[g], now.Sub(from))
+		}
+	}
+	t.mu.Unlock()
+
+	suggestions := make([]Suggestion, 0, len(groups))
+	for g, keys := range groups {
+		s := suggestLimit(keys, windows[g], t.cfg.TargetDenial)
+		s.Route, s.Class = g.route, g.class
+		suggestions = append(suggestions, s)
+	}
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Or(cmp.Compare(a.Route, b.Route), cmp.Compare(a.Class, b.Class))
+	})
+	return suggestions
+}
+
+// suggestLimit suggests a limit for keys with the given requests, observed
+// over the given windows. The rate is the one that all but a target share
+// of the keys stay below on average, and the burst the smallest that keeps
+// the denied requests within the target.
+func suggestLimit(keys [][]time.Time, windows []time.Duration, target float64) Suggestion {
+	s := Suggestion{Keys: len(keys)}
+	rates := make([]float64, len(keys))
+	maxBurst := 1
+	for i, times := range keys {
+		s.Requests += len(times)
+		maxBurst = max(maxBurst, len(times))
+		rates[i] = float64(len(times)) / max(windows[i].Seconds(), 1)
+	}
+	slices.Sort(rates)
+	i := min(len(rates)-1, int(math.Ceil((1-target)*float64(len(rates))))-1)
+	s.Rate = rate.Limit(rates[max(i, 0)])
+
+	// The denials only decrease as the burst grows, and none are left
+	// with a burst as large as the most requests of a key.
+	allowed := int(math.Floor(target * float64(s.Requests)))
+	s.Burst = maxBurst
+	lo, hi := 1, maxBurst
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		if simulateDenials(keys, s.Rate, mid) <= allowed {
+			s.Burst, hi = mid, mid-1
+		} else {
+			lo = mid + 1
+		}
+	}
+	s.Denial = float64(simulateDenials(keys, s.Rate, s.Burst)) / float64(s.Requests)
+	return s
+}
+
+// simulateDenials returns the number of requests a token bucket of every
+// key with the given limit would have denied.
+func simulateDenials(keys [][]time.Time, r rate.Limit, burst int) int {
+	denied := 0
+	for _, times := range keys {
+		tokens := float64(burst)
+		for i, t := range times {
+			if i > 0 {
+				tokens = min(float64(burst), tokens+t.Sub(times[i-1]).Seconds()*float64(r))
+			}
+			if tokens >= 1 {
+				tokens--
+			} else {
+				denied++
+			}
+		}
+	}
+	return denied
+}
+
+// SuggestLimits returns the limits suggested by Options.Tuning for the
+// traffic observed so far, or nil without Tuning.
+func (m *Manager) SuggestLimits() []Suggestion {
+	if m.tuner == nil {
+		return nil
+	}
+	return m.tuner.suggest(time.Now())
+}
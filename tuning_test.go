@@ -0,0 +1,104 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestSuggestLimit(t *testing.T) {
+	start := time.Now()
+	window := 100 * time.Second
+	// 99 keys send a request every 10 seconds, and one sends 50 at once.
+	var keys [][]time.Time
+	var windows []time.Duration
+	for range 99 {
+		var times []time.Time
+		for i := range 10 {
+			times = append(times, start.Add(time.Duration(i)*10*time.Second))
+		}
+		keys = append(keys, times)
+		windows = append(windows, window)
+	}
+	var burst []time.Time
+	for range 50 {
+		burst = append(burst, start)
+	}
+	keys = append(keys, burst)
+	windows = append(windows, window)
+
+	s := suggestLimit(keys, windows, 0.04)
+	assert.Equal(t, 100, s.Keys)
+	assert.Equal(t, 1040, s.Requests)
+	assert.InDelta(t, 0.1, float64(s.Rate), 1e-9)
+	// A burst of 9 denies 41 requests of the bursting key, the most the
+	// target allows.
+	assert.Equal(t, 9, s.Burst)
+	assert.InDelta(t, 41.0/1040, s.Denial, 1e-9)
+
+	assert.Equal(t, 0, simulateDenials(keys, s.Rate, 50))
+	assert.Equal(t, 49, simulateDenials(keys, s.Rate, 1))
+}
+
+func TestTuning(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewManager(Options{
+		Rate:   rate.Every(time.Hour),
+		Burst:  1,
+		DryRun: true,
+		Tuning: &Tuning{TargetDenial: 0.1, MaxKeys: 2, MaxSamples: 5},
+	})
+	r := gin.New()
+	r.Use(m.Handler())
+	r.GET("/items", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	admin := gin.New()
+	m.RegisterAdmin(admin)
+	for i := range 3 {
+		for range 8 {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+			req.RemoteAddr = fmt.Sprintf("203.0.113.%d:1234", i)
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+		}
+	}
+
+	// The third key is not sampled, and only the last five requests of
+	// each are kept. They were sent at once, so the burst must hold them.
+	suggestions := m.SuggestLimits()
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "/items", suggestions[0].Route)
+	assert.Equal(t, 2, suggestions[0].Keys)
+	assert.Equal(t, 10, suggestions[0].Requests)
+	assert.Equal(t, 5, suggestions[0].Burst)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/suggestions", nil)
+	admin.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		TargetDenial float64      `json:"targetDenial"`
+		Suggestions  []Suggestion `json:"suggestions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.InDelta(t, 0.1, body.TargetDenial, 0)
+	assert.Equal(t, suggestions[0].Burst, body.Suggestions[0].Burst)
+
+	m = NewManager(Options{})
+	assert.Nil(t, m.SuggestLimits())
+}
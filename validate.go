@@ -0,0 +1,135 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Severity is how serious a validation finding is.
+type Severity string
+
+// Severities of validation findings.
+const (
+	// SeverityError marks configurations that do not work as written.
+	SeverityError Severity = "error"
+	// SeverityWarning marks configurations that work but are likely
+	// mistakes.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem found by ValidateConfig.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Field locates the problem, such as "store" or "rules[2]".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// String formats the finding as "severity: field: message".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Field, f.Message)
+}
+
+// validateProbeKey is the key the store is probed with. Probes take no
+// tokens.
+const validateProbeKey = "ratelimit:validate"
+
+// ValidateConfig checks a configuration for mistakes before it is
+// deployed: rules that are unreachable because an earlier rule matches all
+// their routes, default limits that never bind or share a window, bursts
+// too low for a second of requests, and a store that cannot be created or
+// reached. Reaching the store needs its backend registered, as by
+// importing redisstore, and stops when ctx is done.
+func ValidateConfig(ctx context.Context, cfg Config) []Finding {
+	var findings []Finding
+	add := func(severity Severity, field, format string, args ...any) {
+		findings = append(findings, Finding{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, spec := range cfg.Limits {
+		field := fmt.Sprintf("limits[%d]", i)
+		checkSpec(spec, field, add)
+		for j, other := range cfg.Limits[:i] {
+			switch {
+			case spec.Window == other.Window && spec.Window > 0:
+				add(SeverityWarning, field, "%s has the same window as limits[%d] (%s)", spec, j, other)
+			case dominates(spec, other):
+				add(SeverityWarning, field, "%s never binds: limits[%d] (%s) is stricter", spec, j, other)
+			case dominates(other, spec):
+				add(SeverityWarning, fmt.Sprintf("limits[%d]", j), "%s never binds: %s (%s) is stricter",
+					other, field, spec)
+			}
+		}
+	}
+
+	names := make(map[string]int)
+	for i, rule := range cfg.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if rule.Name != "" {
+			field += " (" + rule.Name + ")"
+			if j, dup := names[rule.Name]; dup {
+				add(SeverityWarning, field, "has the same name as rules[%d]", j)
+			} else {
+				names[rule.Name] = i
+			}
+		}
+		if !strings.HasPrefix(rule.Path, "/") {
+			add(SeverityError, field, "path %q does not start with /", rule.Path)
+		}
+		if rule.Method != "" && !validMethod(rule.Method) {
+			add(SeverityError, field, "unknown method %q", rule.Method)
+		}
+		checkSpec(rule.Limit, field, add)
+		for j, earlier := range cfg.Rules[:i] {
+			if earlier.covers(rule) {
+				add(SeverityError, field, "is unreachable: rules[%d] matches all its routes first", j)
+				break
+			}
+		}
+	}
+
+	if cfg.Store != "" {
+		store, err := NewStoreFromDSN(cfg.Store)
+		if err != nil {
+			add(SeverityError, "store", "%v", err)
+		} else if _, err := store.Allow(ctx, validateProbeKey, Limit{Rate: 1, Burst: 1}); err != nil {
+			add(SeverityError, "store", "unreachable: %v", err)
+		}
+	}
+	return findings
+}
+
+// checkSpec reports invalid limits and bursts too low for a second of
+// requests, which deny concurrent requests of clients within their rate.
+func checkSpec(spec LimitSpec, field string, add func(Severity, string, string, ...any)) {
+	if err := spec.Validate(); err != nil {
+		add(SeverityError, field, "%v", err)
+		return
+	}
+	if perSecond := float64(spec.Rate()); spec.Window > 0 && perSecond >= 2 && float64(spec.BurstSize()) < perSecond {
+		add(SeverityWarning, field, "burst %d is less than one second of requests at %s", spec.BurstSize(), spec)
+	}
+}
+
+// dominates reports whether every request a allows is allowed by b too,
+// so a never denies anything b does not.
+func dominates(a, b LimitSpec) bool {
+	return a.Rate() >= b.Rate() && a.BurstSize() >= b.BurstSize()
+}
+
+// validMethod reports whether method is a standard HTTP method.
+func validMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
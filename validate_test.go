@@ -0,0 +1,89 @@
+// Copyright 2024 Gin Core Team. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.Empty(t, ValidateConfig(ctx, Config{
+			Store: "memory://",
+			Limits: []LimitSpec{
+				{Requests: 10, Window: time.Second},
+				{Requests: 1000, Window: time.Hour},
+			},
+			Rules: []Rule{
+				{Name: "users", Method: "GET", Path: "/api/users", Limit: LimitSpec{Requests: 5, Window: time.Minute}},
+				{Name: "api", Path: "/api/*", Limit: LimitSpec{Requests: 100, Window: time.Minute}},
+			},
+		}))
+	})
+
+	t.Run("Findings", func(t *testing.T) {
+		findings := ValidateConfig(ctx, Config{
+			Store: "nosuch://",
+			Limits: []LimitSpec{
+				{Requests: 100, Window: time.Second, Burst: 1},
+				{Requests: 10000, Window: time.Minute},
+				{Requests: 50, Window: time.Second},
+			},
+			Rules: []Rule{
+				{Name: "api", Path: "/api/*", Limit: LimitSpec{Requests: 100, Window: time.Minute}},
+				{Name: "users", Method: "GET", Path: "/api/users", Limit: LimitSpec{Requests: 5, Window: time.Minute}},
+				{Path: "login", Method: "FETCH", Limit: LimitSpec{Requests: 5, Window: time.Minute}},
+				{Name: "api", Path: "/", Limit: LimitSpec{Requests: -1, Window: time.Minute}},
+			},
+		})
+		var got []string
+		for _, f := range findings {
+			got = append(got, f.String())
+		}
+		assert.Equal(t, []string{
+			"warning: limits[0]: burst 1 is less than one second of requests at 100/second burst 1",
+			"warning: limits[1]: 10000/minute never binds: limits[0] (100/second burst 1) is stricter",
+			"warning: limits[2]: 50/second has the same window as limits[0] (100/second burst 1)",
+			"warning: limits[1]: 10000/minute never binds: limits[2] (50/second) is stricter",
+			"error: rules[1] (users): is unreachable: rules[0] matches all its routes first",
+			`error: rules[2]: path "login" does not start with /`,
+			`error: rules[2]: unknown method "FETCH"`,
+			"warning: rules[3] (api): has the same name as rules[0]",
+			"error: rules[3] (api): ratelimit: limit has negative requests -1",
+			`error: store: ratelimit: unknown store "nosuch"`,
+		}, got)
+	})
+
+	t.Run("Unreachable store", func(t *testing.T) {
+		t.Cleanup(func() {
+			storesMu.Lock()
+			delete(storeFactories, "down")
+			storesMu.Unlock()
+		})
+		RegisterStore("down", func(*url.URL) (Store, error) {
+			return downStore{}, nil
+		})
+		assert.Equal(t, []Finding{{
+			Severity: SeverityError,
+			Field:    "store",
+			Message:  "unreachable: connection refused",
+		}}, ValidateConfig(ctx, Config{Store: "down://localhost"}))
+	})
+}
+
+// downStore is a Store whose backend is unreachable.
+type downStore struct{}
+
+func (downStore) Allow(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}